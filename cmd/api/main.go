@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -40,6 +41,12 @@ func main() {
 
 	mux := pkg.NewMux(repo, svc)
 
+	// Deliver queued email in the background for the life of the process.
+	go pkg.RunEmailWorker(context.Background(), repo, pkg.MailerFromEnv(), pkg.EmailWorkerInterval())
+
+	// Roll students over to UNCONFIRMED / the next year as terms end.
+	go pkg.RunRolloverWorker(context.Background(), repo, pkg.RolloverInterval())
+
 	addr := ":8080"
 	if a := os.Getenv("PORT"); a != "" {
 		addr = ":" + a
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
 	"os"
 
 	"mactrack/pkg"
 
+	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
 	"github.com/joho/godotenv"
@@ -16,6 +19,13 @@ func init() {
 	_ = godotenv.Load()
 }
 
+// scheduledJob is the payload sent by the EventBridge schedules in
+// template.yaml, e.g. {"job": "drain-email-queue"}. The name is one of the
+// jobs registered for POST /api/admin/jobs/{name}.
+type scheduledJob struct {
+	Job string `json:"job"`
+}
+
 func main() {
 	dsn := os.Getenv("DATABASE_URL")
 	if dsn == "" {
@@ -31,5 +41,25 @@ func main() {
 	mux := pkg.NewMux(repo, svc)
 
 	// httpadapter.NewV2 adapts a standard http.Handler for API Gateway HTTP API (v2).
-	lambda.Start(httpadapter.NewV2(pkg.CORS(mux)).ProxyWithContext)
+	adapter := httpadapter.NewV2(pkg.CORS(mux))
+
+	// The same function serves HTTP requests and scheduled jobs, so background
+	// work (like draining the email queue) runs in its own invocation instead
+	// of a goroutine that Lambda may freeze mid-send.
+	lambda.Start(func(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+		var sj scheduledJob
+		if err := json.Unmarshal(raw, &sj); err == nil && sj.Job != "" {
+			summary, err := pkg.RunJob(repo, 0, sj.Job)
+			if err != nil {
+				return nil, err
+			}
+			return summary, nil
+		}
+
+		var req events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			return nil, err
+		}
+		return adapter.ProxyWithContext(ctx, req)
+	})
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// Base URL for the McMaster academic calendar
+	baseURL = "https://academiccalendars.romcmaster.ca"
+	catoid  = "58"
+	dbPath  = "database/courses.db"
+	// Delay between requests to avoid hammering the server
+	requestDelay = 300 * time.Millisecond
+)
+
+// reUnits matches the unit line on a course page, e.g. "3 unit(s)" or
+// "1.5 units"
+var reUnits = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?)\s+units?\b(?:\(s\))?`)
+
+func main() {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		log.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	// WAL mode so reads and writes don't block each other
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		log.Fatalf("set WAL: %v", err)
+	}
+
+	// --- Step 1: Load catalogue courses with a coid and no units yet ---
+	// Curated units are never touched, and re-runs skip what's been scraped
+	rows, err := db.Query(`
+		SELECT catalog_id, subject, course_number, coid
+		FROM catalog_courses
+		WHERE coid IS NOT NULL AND units_source IS NULL
+		ORDER BY subject, course_number
+	`)
+	if err != nil {
+		log.Fatalf("query catalog courses: %v", err)
+	}
+
+	type courseEntry struct {
+		catalogID    int
+		subject      string
+		courseNumber string
+		coid         int
+	}
+	var entries []courseEntry
+	for rows.Next() {
+		var e courseEntry
+		if err := rows.Scan(&e.catalogID, &e.subject, &e.courseNumber, &e.coid); err != nil {
+			log.Printf("scan: %v", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	rows.Close() // Close before writes to avoid locking
+
+	log.Printf("Found %d courses without units to scrape", len(entries))
+
+	// --- Step 2: Fetch each course page and record its units ---
+	found, notFound := 0, 0
+	for i, e := range entries {
+		units, ok, err := scrapeCourseUnits(e.coid)
+		if err != nil {
+			log.Printf("[%d/%d] %s %s: %v — skipping", i+1, len(entries), e.subject, e.courseNumber, err)
+			time.Sleep(requestDelay)
+			continue
+		}
+		if !ok {
+			log.Printf("[%d/%d] %s %s: no units on page", i+1, len(entries), e.subject, e.courseNumber)
+			notFound++
+			time.Sleep(requestDelay)
+			continue
+		}
+
+		// Re-check the source so a value curated while we ran isn't overwritten
+		_, err = db.Exec(`
+			UPDATE catalog_courses SET units = ?, units_source = 'scraped'
+			WHERE catalog_id = ? AND (units_source IS NULL OR units_source = 'scraped')
+		`, units, e.catalogID)
+		if err != nil {
+			log.Printf("[%d/%d] %s %s: update: %v", i+1, len(entries), e.subject, e.courseNumber, err)
+		} else {
+			log.Printf("[%d/%d] %s %s: %d units", i+1, len(entries), e.subject, e.courseNumber, units)
+			found++
+		}
+
+		time.Sleep(requestDelay)
+	}
+
+	log.Printf("Done. Recorded units for %d courses; %d pages had none.", found, notFound)
+}
+
+// scrapeCourseUnits fetches the course detail page for coid and reads its
+// unit value, rounded to a whole number. ok is false if the page has none.
+func scrapeCourseUnits(coid int) (units int, ok bool, err error) {
+	url := fmt.Sprintf("%s/preview_course.php?catoid=%s&coid=%d", baseURL, catoid, coid)
+	doc, err := goquery.NewDocument(url)
+	if err != nil {
+		return 0, false, fmt.Errorf("fetch %s: %w", url, err)
+	}
+
+	// The unit line sits in the course block under the title; fall back to
+	// the whole page if the layout changes
+	text := doc.Find("td.block_content").Text()
+	if text == "" {
+		text = doc.Find("body").Text()
+	}
+	m := reUnits.FindStringSubmatch(text)
+	if m == nil {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false, nil
+	}
+	return int(math.Round(f)), true, nil
+}
@@ -0,0 +1,18 @@
+-- 014_user_roles_audit_log.sql
+-- Adds a role to every user (student by default) and an append-only log of
+-- every action taken through the /api/admin surface.
+
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'student';
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    audit_id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_user_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    action        TEXT      NOT NULL,   -- e.g. "user.update", "review.delete", "job.run"
+    target_type   TEXT      NOT NULL,   -- e.g. "user", "course_review", "requisite", "job"
+    target_id     TEXT,                 -- primary key of the target row (or job name)
+    details       TEXT,                 -- JSON blob describing the change
+    created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_actor  ON admin_audit_log(actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_target ON admin_audit_log(target_type, target_id);
@@ -0,0 +1,23 @@
+-- 015_oidc.sql
+-- Single sign-on via OpenID Connect.
+--   oidc_login_states  – short-lived state/nonce/PKCE verifier for an in-flight login
+--   user_identities    – links an IdP subject to a local user
+-- Works for both SQLite and PostgreSQL.
+
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state          TEXT      NOT NULL PRIMARY KEY,
+    code_verifier  TEXT      NOT NULL,
+    nonce          TEXT      NOT NULL,
+    expires_at     TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer      TEXT      NOT NULL,
+    subject     TEXT      NOT NULL,   -- the IdP's stable "sub" claim
+    user_id     INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    email       TEXT,                 -- verified email at link time, for support
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
@@ -0,0 +1,26 @@
+-- 016_outbound_emails.sql
+-- Durable queue for outgoing email. Handlers insert a row; a worker (goroutine
+-- in cmd/api, scheduled invocation in cmd/lambda) delivers it with retries.
+--
+-- status: pending  – waiting for next_attempt_at
+--         sending  – claimed by a worker; next_attempt_at is the lease expiry,
+--                    after which another worker may reclaim it
+--         sent     – delivered
+--         dead     – gave up after the maximum number of attempts
+
+CREATE TABLE IF NOT EXISTS outbound_emails (
+    email_id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    to_address       TEXT      NOT NULL,
+    reply_to         TEXT,
+    subject          TEXT      NOT NULL,
+    text_body        TEXT      NOT NULL,
+    html_body        TEXT,
+    status           TEXT      NOT NULL DEFAULT 'pending',
+    attempts         INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP NOT NULL,
+    last_error       TEXT,
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at          TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_emails_due ON outbound_emails(status, next_attempt_at);
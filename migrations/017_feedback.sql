@@ -0,0 +1,25 @@
+-- 017_feedback.sql
+-- Every submission to POST /api/feedback, so nothing is lost if email fails,
+-- plus free-form tags admins use to triage it.
+
+CREATE TABLE IF NOT EXISTS feedback (
+    feedback_id  INTEGER PRIMARY KEY AUTOINCREMENT,
+    message      TEXT      NOT NULL,
+    page         TEXT,
+    email        TEXT,                 -- optional reply-to address typed by the user
+    user_id      INTEGER REFERENCES users(user_id) ON DELETE SET NULL,  -- set when signed in
+    user_agent   TEXT,
+    status       TEXT      NOT NULL DEFAULT 'open',   -- open | resolved
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at  TIMESTAMP,
+    resolved_by  INTEGER REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS feedback_tags (
+    feedback_id  INTEGER NOT NULL REFERENCES feedback(feedback_id) ON DELETE CASCADE,
+    tag          TEXT    NOT NULL,
+    PRIMARY KEY (feedback_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_feedback_status ON feedback(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_feedback_tags_tag ON feedback_tags(tag);
@@ -0,0 +1,38 @@
+-- 018_courses_fts.sql
+-- Full-text index for course search. Requires SQLite built with FTS5 — the
+-- sqlite3 CLI is, and Go binaries must be built with `-tags sqlite_fts5` (see
+-- the README).
+-- A binary built without it falls back to LIKE search, and can update other
+-- columns of `courses` (coid, catalog_id), but can't insert, delete or change
+-- an indexed column while these triggers exist.
+--
+-- courses_fts is an external-content table: it stores only the index and
+-- reads column values back from `courses`. The triggers keep it in sync;
+-- POST /api/admin/jobs/rebuild-search-index rebuilds it from scratch.
+
+CREATE VIRTUAL TABLE IF NOT EXISTS courses_fts USING fts5(
+    subject, course_number, course_name, professor,
+    content = 'courses', content_rowid = 'id',
+    tokenize = 'unicode61',
+    prefix = '2 3'
+);
+
+CREATE TRIGGER IF NOT EXISTS courses_fts_ai AFTER INSERT ON courses BEGIN
+    INSERT INTO courses_fts(rowid, subject, course_number, course_name, professor)
+    VALUES (new.id, new.subject, new.course_number, new.course_name, new.professor);
+END;
+
+CREATE TRIGGER IF NOT EXISTS courses_fts_ad AFTER DELETE ON courses BEGIN
+    INSERT INTO courses_fts(courses_fts, rowid, subject, course_number, course_name, professor)
+    VALUES ('delete', old.id, old.subject, old.course_number, old.course_name, old.professor);
+END;
+
+CREATE TRIGGER IF NOT EXISTS courses_fts_au
+AFTER UPDATE OF subject, course_number, course_name, professor ON courses BEGIN
+    INSERT INTO courses_fts(courses_fts, rowid, subject, course_number, course_name, professor)
+    VALUES ('delete', old.id, old.subject, old.course_number, old.course_name, old.professor);
+    INSERT INTO courses_fts(rowid, subject, course_number, course_name, professor)
+    VALUES (new.id, new.subject, new.course_number, new.course_name, new.professor);
+END;
+
+INSERT INTO courses_fts(courses_fts) VALUES ('rebuild');
@@ -0,0 +1,67 @@
+-- 019_course_catalog.sql
+-- Splits catalogue identity from per-term data. catalog_courses holds one row
+-- per (subject, course_number) with its name and coid; each `courses` row is
+-- now an offering of a catalogue course in one term, with its instructors in
+-- course_instructors as before.
+--
+-- `courses` keeps its ids so course_instructors, course_outlines and the
+-- search index don't move. Existing tools that insert into `courses` keep
+-- working: the triggers below file each new row under its catalogue course.
+
+CREATE TABLE IF NOT EXISTS catalog_courses (
+    catalog_id    INTEGER PRIMARY KEY AUTOINCREMENT,
+    subject       TEXT NOT NULL,
+    course_number TEXT NOT NULL,
+    course_name   TEXT,
+    coid          INTEGER,
+    UNIQUE(subject, course_number)
+);
+
+ALTER TABLE courses ADD COLUMN catalog_id INTEGER REFERENCES catalog_courses(catalog_id);
+
+-- Where terms disagree on a course's name, keep the latest non-empty one.
+INSERT OR IGNORE INTO catalog_courses (subject, course_number, course_name, coid)
+SELECT subject, course_number,
+       (SELECT c2.course_name FROM courses c2
+         WHERE c2.subject = c.subject AND c2.course_number = c.course_number
+           AND c2.course_name IS NOT NULL AND c2.course_name <> ''
+         ORDER BY c2.id DESC LIMIT 1),
+       MAX(coid)
+FROM courses c
+GROUP BY subject, course_number;
+
+UPDATE courses SET catalog_id = (
+    SELECT cc.catalog_id FROM catalog_courses cc
+    WHERE cc.subject = courses.subject AND cc.course_number = courses.course_number
+);
+
+CREATE INDEX IF NOT EXISTS idx_courses_catalog ON courses(catalog_id, term);
+
+CREATE TRIGGER IF NOT EXISTS courses_catalog_ai AFTER INSERT ON courses
+WHEN new.catalog_id IS NULL BEGIN
+    INSERT INTO catalog_courses (subject, course_number, course_name, coid)
+    VALUES (new.subject, new.course_number, NULLIF(new.course_name, ''), new.coid)
+    ON CONFLICT(subject, course_number) DO UPDATE SET
+        course_name = COALESCE(excluded.course_name, catalog_courses.course_name),
+        coid        = COALESCE(excluded.coid, catalog_courses.coid);
+    UPDATE courses SET catalog_id = (
+        SELECT catalog_id FROM catalog_courses
+        WHERE subject = new.subject AND course_number = new.course_number
+    ) WHERE id = new.id;
+END;
+
+-- cmd/backfillcoids and renames fill these in on the offering rows.
+CREATE TRIGGER IF NOT EXISTS courses_catalog_au AFTER UPDATE OF course_name, coid ON courses
+WHEN new.catalog_id IS NOT NULL BEGIN
+    UPDATE catalog_courses SET
+        course_name = CASE WHEN new.course_name IS NOT old.course_name
+                           THEN COALESCE(NULLIF(new.course_name, ''), course_name)
+                           ELSE course_name END,
+        coid        = COALESCE(new.coid, coid)
+    WHERE catalog_id = new.catalog_id;
+END;
+
+DROP VIEW IF EXISTS v_course_catalog;
+CREATE VIEW v_course_catalog AS
+SELECT subject, course_number, course_name
+FROM catalog_courses;
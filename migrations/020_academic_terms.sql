@@ -0,0 +1,34 @@
+-- 020_academic_terms.sql
+-- The academic calendar. code matches courses.term ("2025 Fall"); ordinal
+-- is year * 4 + the season's place in the calendar year (Winter 0, Spring 1,
+-- Summer 2, Fall 3), so ordering by it is chronological. Dates are ISO text,
+-- inclusive, and may be edited to match the published sessional dates.
+--
+-- users.start_year anchors a student's plan (year_index 1 Fall = Fall of
+-- start_year) to real terms. When NULL it is inferred from year_of_study.
+
+CREATE TABLE IF NOT EXISTS academic_terms (
+    term_id    INTEGER PRIMARY KEY AUTOINCREMENT,
+    code       TEXT    NOT NULL UNIQUE,
+    season     TEXT    NOT NULL CHECK (season IN ('Fall','Winter','Spring','Summer')),
+    year       INTEGER NOT NULL,
+    start_date TEXT    NOT NULL,
+    end_date   TEXT    NOT NULL,
+    ordinal    INTEGER NOT NULL UNIQUE,
+    UNIQUE(year, season)
+);
+
+WITH RECURSIVE years(y) AS (
+    SELECT 2015 UNION ALL SELECT y + 1 FROM years WHERE y < 2040
+),
+seasons(season, idx, start_md, end_md) AS (
+    VALUES ('Winter', 0, '01-01', '04-30'),
+           ('Spring', 1, '05-01', '06-30'),
+           ('Summer', 2, '07-01', '08-31'),
+           ('Fall',   3, '09-01', '12-31')
+)
+INSERT OR IGNORE INTO academic_terms (code, season, year, start_date, end_date, ordinal)
+SELECT y || ' ' || season, season, y, y || '-' || start_md, y || '-' || end_md, y * 4 + idx
+FROM years, seasons;
+
+ALTER TABLE users ADD COLUMN start_year INTEGER;
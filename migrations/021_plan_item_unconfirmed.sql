@@ -0,0 +1,31 @@
+-- 021_plan_item_unconfirmed.sql
+-- Adds the UNCONFIRMED plan item status: a PLANNED or IN_PROGRESS course
+-- whose term has ended, waiting for the student to say whether they
+-- completed or dropped it. Set by the term-rollover job and by
+-- POST /api/users/{id}/advance-year.
+--
+-- SQLite can't alter a CHECK constraint, so plan_items is rebuilt. Nothing
+-- references plan_items, so the copy keeps every id.
+
+BEGIN TRANSACTION;
+
+CREATE TABLE plan_items_new (
+  plan_item_id   INTEGER PRIMARY KEY AUTOINCREMENT,
+  plan_term_id   INTEGER NOT NULL REFERENCES plan_terms(plan_term_id) ON DELETE CASCADE,
+  subject        TEXT NOT NULL,
+  course_number  TEXT NOT NULL,
+  status         TEXT NOT NULL CHECK (status IN ('PLANNED','IN_PROGRESS','COMPLETED','DROPPED','UNCONFIRMED')),
+  grade          TEXT,
+  note           TEXT,
+  UNIQUE(plan_term_id, subject, course_number)
+);
+
+INSERT INTO plan_items_new (plan_item_id, plan_term_id, subject, course_number, status, grade, note)
+SELECT plan_item_id, plan_term_id, subject, course_number, status, grade, note FROM plan_items;
+
+DROP TABLE plan_items;
+ALTER TABLE plan_items_new RENAME TO plan_items;
+
+CREATE INDEX IF NOT EXISTS idx_plan_items_course ON plan_items(subject, course_number);
+
+COMMIT;
@@ -0,0 +1,24 @@
+-- 022_plan_events.sql
+-- Append-only history of every change to a plan item, written in the same
+-- transaction as the change. before_state / after_state are JSON snapshots
+-- of the item including its year_index and season (NULL before an add and
+-- after a delete), which is what undo and restore-to-a-time replay.
+
+CREATE TABLE IF NOT EXISTS plan_events (
+    event_id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id          INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    plan_item_id     INTEGER   NOT NULL,  -- no FK: the item may since have been deleted
+    action           TEXT      NOT NULL CHECK (action IN ('add','update','delete')),
+    before_state     TEXT,
+    after_state      TEXT,
+    actor_user_id    INTEGER REFERENCES users(user_id) ON DELETE SET NULL,  -- NULL for system jobs
+    source           TEXT      NOT NULL,  -- edit, advance-year, rollover, undo, restore
+    reverts_event_id INTEGER REFERENCES plan_events(event_id),
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_events_user ON plan_events(user_id, event_id);
+
+CREATE TRIGGER IF NOT EXISTS plan_events_append_only BEFORE UPDATE ON plan_events BEGIN
+    SELECT RAISE(ABORT, 'plan_events is append-only');
+END;
@@ -0,0 +1,7 @@
+-- 023_plan_item_custom.sql
+-- Flags plan items that aren't in the course catalogue: transfer credit,
+-- courses taken elsewhere, or anything else the student wants on the plan.
+-- POST /api/users/{id}/plan rejects unknown courses unless is_custom is set.
+-- Works for both SQLite and PostgreSQL.
+
+ALTER TABLE plan_items ADD COLUMN is_custom INTEGER NOT NULL DEFAULT 0;
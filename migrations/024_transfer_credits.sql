@@ -0,0 +1,23 @@
+-- 024_transfer_credits.sql
+-- Credit granted for work done before or outside McMaster (AP, IB, college
+-- or other university courses). Unlike plan_items these have no term. Each
+-- is either a specific McMaster equivalent (subject + course_number) or
+-- unspecified credit at a level, optionally in a subject ("COMPSCI 1--").
+-- They count towards degree requirements but not towards the GPA.
+
+CREATE TABLE IF NOT EXISTS transfer_credits (
+    transfer_id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id         INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    institution     TEXT      NOT NULL,
+    original_course TEXT      NOT NULL,
+    subject         TEXT,
+    course_number   TEXT,     -- NULL for unspecified credit
+    units           INTEGER   NOT NULL CHECK (units > 0),
+    level           INTEGER   CHECK (level BETWEEN 1 AND 4),
+    note            TEXT,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (course_number IS NULL OR subject IS NOT NULL),
+    CHECK (course_number IS NOT NULL OR level IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_credits_user ON transfer_credits(user_id);
@@ -0,0 +1,22 @@
+-- 025_plan_shares.sql
+-- Read-only links to a student's plan, for an advisor or a friend who
+-- doesn't have the student's password. Only a SHA-256 hash of the link
+-- token is stored; the token itself is shown once, when the link is made.
+-- A link can expire, can be revoked, and can hide grades (and so the GPA).
+-- program_id, if set, is the program the shared plan is validated against.
+
+CREATE TABLE IF NOT EXISTS plan_shares (
+    share_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    token_hash     TEXT      NOT NULL UNIQUE,
+    label          TEXT,
+    program_id     INTEGER   REFERENCES programs(program_id) ON DELETE SET NULL,
+    hide_grades    INTEGER   NOT NULL DEFAULT 0,
+    expires_at     TIMESTAMP,          -- NULL: never expires
+    revoked_at     TIMESTAMP,          -- NULL until revoked
+    last_viewed_at TIMESTAMP,
+    view_count     INTEGER   NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_plan_shares_user ON plan_shares(user_id);
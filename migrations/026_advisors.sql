@@ -0,0 +1,30 @@
+-- 026_advisors.sql
+-- Advisors (users.role = 'advisor') see the plans of the students on their
+-- caseload. An advisor invites a student by email; the link only grants
+-- access once the student accepts it, and either side can end it later.
+-- program_id, if set, is the program the advisor reviews the student
+-- against. Advisors' comments on plan items are kept apart from the
+-- student's own plan_items.note.
+
+CREATE TABLE IF NOT EXISTS advisor_students (
+    link_id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    advisor_id   INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    student_id   INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    program_id   INTEGER   REFERENCES programs(program_id) ON DELETE SET NULL,
+    status       TEXT      NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'active', 'declined', 'ended')),
+    invited_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP,            -- accepted, declined or ended
+    UNIQUE (advisor_id, student_id)
+);
+
+CREATE TABLE IF NOT EXISTS plan_item_comments (
+    comment_id   INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_item_id INTEGER   NOT NULL REFERENCES plan_items(plan_item_id) ON DELETE CASCADE,
+    author_id    INTEGER   REFERENCES users(user_id) ON DELETE SET NULL,
+    body         TEXT      NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_advisor_students_student ON advisor_students(student_id);
+CREATE INDEX IF NOT EXISTS idx_plan_item_comments_item  ON plan_item_comments(plan_item_id);
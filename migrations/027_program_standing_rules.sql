@@ -0,0 +1,25 @@
+-- 027_program_standing_rules.sql
+-- Program-level continuation and entry rules that the scraped requirement
+-- groups only carry as prose ("Admission to Level II requires ..."):
+--   min_gpa    cumulative GPA on the McMaster 12-point scale, over the
+--              courses completed by the end of by_year (all years if NULL)
+--   min_grade  at least min_grade (a letter) in subject course_number
+--   min_units  at least min_units completed by the end of by_year
+-- Validation reports each rule as pass, at_risk or fail in its "standing"
+-- section.
+
+CREATE TABLE IF NOT EXISTS program_standing_rules (
+    rule_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    program_id    INTEGER NOT NULL REFERENCES programs(program_id) ON DELETE CASCADE,
+    kind          TEXT    NOT NULL CHECK (kind IN ('min_gpa', 'min_grade', 'min_units')),
+    label         TEXT    NOT NULL,  -- e.g. "Admission to Level II"
+    by_year       INTEGER,
+    min_gpa       REAL,
+    subject       TEXT,
+    course_number TEXT,
+    min_grade     TEXT,
+    min_units     INTEGER,
+    display_order INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_program_standing_rules_program ON program_standing_rules(program_id);
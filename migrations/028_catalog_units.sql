@@ -0,0 +1,12 @@
+-- 028_catalog_units.sql
+-- Authoritative unit values per catalogue course. Until now units were
+-- guessed from the last two characters of the course number, which gets
+-- "1ZA3" and zero-unit labs like "ENGINEER 1A00" wrong.
+--   units         NULL means unknown; the course number guess is used
+--   units_source  'scraped' from the academic calendar (cmd/scrapeunits)
+--                 or 'curated' by an admin; a scrape never overwrites a
+--                 curated value
+-- Works for both SQLite and PostgreSQL (see postgres_schema.sql).
+
+ALTER TABLE catalog_courses ADD COLUMN units INTEGER CHECK (units >= 0);
+ALTER TABLE catalog_courses ADD COLUMN units_source TEXT CHECK (units_source IN ('scraped', 'curated'));
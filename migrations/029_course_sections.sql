@@ -0,0 +1,81 @@
+-- 029_course_sections.sql
+-- A course can run as several sections in one term ("C01", "C02"), each its
+-- own `courses` row with its own instructors. Adds courses.section and moves
+-- the offering's unique key from (subject, course_number, term) to include
+-- it. NULL is a term's one unnamed section; the index compares it as '', so
+-- a term still can't hold two of those.
+--
+-- SQLite can't drop a table's UNIQUE constraint, so `courses` is rebuilt
+-- with its ids intact (course_instructors, course_outlines and courses_fts
+-- point at them), and the indexes and the triggers from 018 and 019, which
+-- go with the old table, are created again.
+
+PRAGMA foreign_keys=OFF;
+BEGIN TRANSACTION;
+
+CREATE TABLE courses_new (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    subject       TEXT NOT NULL,
+    course_number TEXT NOT NULL,
+    course_name   TEXT,
+    professor     TEXT,
+    term          TEXT NOT NULL,
+    coid          INTEGER,
+    catalog_id    INTEGER REFERENCES catalog_courses(catalog_id),
+    section       TEXT
+);
+
+INSERT INTO courses_new (id, subject, course_number, course_name, professor, term, coid, catalog_id)
+SELECT id, subject, course_number, course_name, professor, term, coid, catalog_id FROM courses;
+
+DROP TABLE courses;
+ALTER TABLE courses_new RENAME TO courses;
+
+CREATE UNIQUE INDEX idx_courses_offering ON courses(subject, course_number, term, COALESCE(section, ''));
+CREATE INDEX IF NOT EXISTS idx_courses_subject_term ON courses(subject, term);
+CREATE INDEX IF NOT EXISTS idx_courses_coid ON courses(coid);
+CREATE INDEX IF NOT EXISTS idx_courses_catalog ON courses(catalog_id, term);
+
+CREATE TRIGGER IF NOT EXISTS courses_fts_ai AFTER INSERT ON courses BEGIN
+    INSERT INTO courses_fts(rowid, subject, course_number, course_name, professor)
+    VALUES (new.id, new.subject, new.course_number, new.course_name, new.professor);
+END;
+
+CREATE TRIGGER IF NOT EXISTS courses_fts_ad AFTER DELETE ON courses BEGIN
+    INSERT INTO courses_fts(courses_fts, rowid, subject, course_number, course_name, professor)
+    VALUES ('delete', old.id, old.subject, old.course_number, old.course_name, old.professor);
+END;
+
+CREATE TRIGGER IF NOT EXISTS courses_fts_au
+AFTER UPDATE OF subject, course_number, course_name, professor ON courses BEGIN
+    INSERT INTO courses_fts(courses_fts, rowid, subject, course_number, course_name, professor)
+    VALUES ('delete', old.id, old.subject, old.course_number, old.course_name, old.professor);
+    INSERT INTO courses_fts(rowid, subject, course_number, course_name, professor)
+    VALUES (new.id, new.subject, new.course_number, new.course_name, new.professor);
+END;
+
+CREATE TRIGGER IF NOT EXISTS courses_catalog_ai AFTER INSERT ON courses
+WHEN new.catalog_id IS NULL BEGIN
+    INSERT INTO catalog_courses (subject, course_number, course_name, coid)
+    VALUES (new.subject, new.course_number, NULLIF(new.course_name, ''), new.coid)
+    ON CONFLICT(subject, course_number) DO UPDATE SET
+        course_name = COALESCE(excluded.course_name, catalog_courses.course_name),
+        coid        = COALESCE(excluded.coid, catalog_courses.coid);
+    UPDATE courses SET catalog_id = (
+        SELECT catalog_id FROM catalog_courses
+        WHERE subject = new.subject AND course_number = new.course_number
+    ) WHERE id = new.id;
+END;
+
+CREATE TRIGGER IF NOT EXISTS courses_catalog_au AFTER UPDATE OF course_name, coid ON courses
+WHEN new.catalog_id IS NOT NULL BEGIN
+    UPDATE catalog_courses SET
+        course_name = CASE WHEN new.course_name IS NOT old.course_name
+                           THEN COALESCE(NULLIF(new.course_name, ''), course_name)
+                           ELSE course_name END,
+        coid        = COALESCE(new.coid, coid)
+    WHERE catalog_id = new.catalog_id;
+END;
+
+COMMIT;
+PRAGMA foreign_keys=ON;
@@ -4,6 +4,16 @@
 --
 -- Or paste it in Supabase → SQL Editor.
 
+CREATE TABLE IF NOT EXISTS catalog_courses (
+    catalog_id    SERIAL PRIMARY KEY,
+    subject       TEXT NOT NULL,
+    course_number TEXT NOT NULL,
+    course_name   TEXT,
+    coid          INTEGER,
+    UNIQUE(subject, course_number)
+);
+
+-- One row per offering of a catalogue course: a term, or a section of one.
 CREATE TABLE IF NOT EXISTS courses (
     id            SERIAL PRIMARY KEY,
     subject       TEXT NOT NULL,
@@ -11,8 +21,7 @@ CREATE TABLE IF NOT EXISTS courses (
     course_name   TEXT,
     professor     TEXT,
     term          TEXT NOT NULL,
-    coid          INTEGER,
-    UNIQUE(subject, course_number, term)
+    coid          INTEGER
 );
 
 CREATE TABLE IF NOT EXISTS instructors (
@@ -65,7 +74,79 @@ CREATE TABLE IF NOT EXISTS users (
     password_hash TEXT NOT NULL,
     created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     program       TEXT,
-    year_of_study INTEGER
+    year_of_study INTEGER,
+    role          TEXT NOT NULL DEFAULT 'student'
+);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    token       TEXT        NOT NULL PRIMARY KEY,
+    user_id     INTEGER     NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ
+);
+
+-- ── admin ────────────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    audit_id      SERIAL PRIMARY KEY,
+    actor_user_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    action        TEXT        NOT NULL,
+    target_type   TEXT        NOT NULL,
+    target_id     TEXT,
+    details       TEXT,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ── single sign-on ───────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state          TEXT      NOT NULL PRIMARY KEY,
+    code_verifier  TEXT      NOT NULL,
+    nonce          TEXT      NOT NULL,
+    expires_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer      TEXT      NOT NULL,
+    subject     TEXT      NOT NULL,
+    user_id     INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    email       TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
+
+-- ── email queue ──────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS outbound_emails (
+    email_id         SERIAL PRIMARY KEY,
+    to_address       TEXT      NOT NULL,
+    reply_to         TEXT,
+    subject          TEXT      NOT NULL,
+    text_body        TEXT      NOT NULL,
+    html_body        TEXT,
+    status           TEXT      NOT NULL DEFAULT 'pending',
+    attempts         INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL,
+    last_error       TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at          TIMESTAMPTZ
+);
+
+-- ── feedback ─────────────────────────────────────────────────────────────────
+CREATE TABLE IF NOT EXISTS feedback (
+    feedback_id  SERIAL PRIMARY KEY,
+    message      TEXT      NOT NULL,
+    page         TEXT,
+    email        TEXT,
+    user_id      INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    user_agent   TEXT,
+    status       TEXT      NOT NULL DEFAULT 'open',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at  TIMESTAMPTZ,
+    resolved_by  INTEGER REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS feedback_tags (
+    feedback_id  INTEGER NOT NULL REFERENCES feedback(feedback_id) ON DELETE CASCADE,
+    tag          TEXT    NOT NULL,
+    PRIMARY KEY (feedback_id, tag)
 );
 
 -- ── reviews & stats ──────────────────────────────────────────────────────────
@@ -118,7 +199,7 @@ CREATE TABLE IF NOT EXISTS plan_items (
     plan_term_id  INTEGER NOT NULL REFERENCES plan_terms(plan_term_id) ON DELETE CASCADE,
     subject       TEXT NOT NULL,
     course_number TEXT NOT NULL,
-    status        TEXT NOT NULL CHECK (status IN ('PLANNED','IN_PROGRESS','COMPLETED','DROPPED')),
+    status        TEXT NOT NULL CHECK (status IN ('PLANNED','IN_PROGRESS','COMPLETED','DROPPED','UNCONFIRMED')),
     grade         TEXT,
     note          TEXT,
     UNIQUE(plan_term_id, subject, course_number)
@@ -158,6 +239,21 @@ CREATE TABLE IF NOT EXISTS requirement_courses (
     adhoc_text      TEXT
 );
 
+-- See 027_program_standing_rules.sql.
+CREATE TABLE IF NOT EXISTS program_standing_rules (
+    rule_id       SERIAL PRIMARY KEY,
+    program_id    INTEGER NOT NULL REFERENCES programs(program_id) ON DELETE CASCADE,
+    kind          TEXT    NOT NULL CHECK (kind IN ('min_gpa', 'min_grade', 'min_units')),
+    label         TEXT    NOT NULL,
+    by_year       INTEGER,
+    min_gpa       DOUBLE PRECISION,
+    subject       TEXT,
+    course_number TEXT,
+    min_grade     TEXT,
+    min_units     INTEGER,
+    display_order INTEGER NOT NULL DEFAULT 0
+);
+
 -- ── view ─────────────────────────────────────────────────────────────────────
 CREATE OR REPLACE VIEW v_course_rating AS
 SELECT
@@ -170,9 +266,190 @@ SELECT
 FROM course_reviews
 GROUP BY subject, course_number;
 
+-- ── course search ────────────────────────────────────────────────────────────
+-- search_vector is a generated column, so Postgres keeps it in sync with every
+-- INSERT/UPDATE on courses. Weights: code A, name B, professor C. The 'simple'
+-- config (no stemming) keeps prefix queries like "algo:*" predictable.
+-- pg_trgm backs the fuzzy fallback on course_name.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(subject, '') || ' ' || coalesce(course_number, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(course_name, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(professor, '')), 'C')
+    ) STORED;
+
+-- ── course catalogue ─────────────────────────────────────────────────────────
+-- Each courses row is an offering of a catalog_courses row (see
+-- 019_course_catalog.sql). The trigger files new offerings under their
+-- catalogue course; the backfill makes re-running this file on an existing
+-- database migrate it.
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS catalog_id INTEGER REFERENCES catalog_courses(catalog_id);
+
+-- Sections (029_course_sections.sql): an offering is unique per section,
+-- with NULL the term's one unnamed section.
+ALTER TABLE courses ADD COLUMN IF NOT EXISTS section TEXT;
+ALTER TABLE courses DROP CONSTRAINT IF EXISTS courses_subject_course_number_term_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_courses_offering ON courses(subject, course_number, term, COALESCE(section, ''));
+
+-- Authoritative units per catalogue course. See 028_catalog_units.sql.
+ALTER TABLE catalog_courses ADD COLUMN IF NOT EXISTS units INTEGER CHECK (units >= 0);
+ALTER TABLE catalog_courses ADD COLUMN IF NOT EXISTS units_source TEXT CHECK (units_source IN ('scraped', 'curated'));
+
+INSERT INTO catalog_courses (subject, course_number, course_name, coid)
+SELECT DISTINCT ON (subject, course_number) subject, course_number, course_name, coid
+FROM courses
+ORDER BY subject, course_number, (course_name IS NULL OR course_name = ''), id DESC
+ON CONFLICT (subject, course_number) DO NOTHING;
+
+UPDATE courses c SET catalog_id = cc.catalog_id
+FROM catalog_courses cc
+WHERE c.catalog_id IS NULL AND cc.subject = c.subject AND cc.course_number = c.course_number;
+
+-- An update only renames the catalogue course when it changes course_name.
+CREATE OR REPLACE FUNCTION courses_catalog_sync() RETURNS trigger AS $$
+DECLARE
+    new_name TEXT := NULLIF(NEW.course_name, '');
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.course_name IS NOT DISTINCT FROM OLD.course_name THEN
+        new_name := NULL;
+    END IF;
+    INSERT INTO catalog_courses (subject, course_number, course_name, coid)
+    VALUES (NEW.subject, NEW.course_number, new_name, NEW.coid)
+    ON CONFLICT (subject, course_number) DO UPDATE SET
+        course_name = COALESCE(EXCLUDED.course_name, catalog_courses.course_name),
+        coid        = COALESCE(EXCLUDED.coid, catalog_courses.coid)
+    RETURNING catalog_id INTO NEW.catalog_id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS courses_catalog_sync ON courses;
+CREATE TRIGGER courses_catalog_sync
+    BEFORE INSERT OR UPDATE OF subject, course_number, course_name, coid ON courses
+    FOR EACH ROW EXECUTE FUNCTION courses_catalog_sync();
+
+-- ── academic calendar ────────────────────────────────────────────────────────
+-- See 020_academic_terms.sql. Dates are ISO text to match SQLite.
+CREATE TABLE IF NOT EXISTS academic_terms (
+    term_id    SERIAL  PRIMARY KEY,
+    code       TEXT    NOT NULL UNIQUE,
+    season     TEXT    NOT NULL CHECK (season IN ('Fall','Winter','Spring','Summer')),
+    year       INTEGER NOT NULL,
+    start_date TEXT    NOT NULL,
+    end_date   TEXT    NOT NULL,
+    ordinal    INTEGER NOT NULL UNIQUE,
+    UNIQUE(year, season)
+);
+
+INSERT INTO academic_terms (code, season, year, start_date, end_date, ordinal)
+SELECT y || ' ' || s.season, s.season, y, y || '-' || s.start_md, y || '-' || s.end_md, y * 4 + s.idx
+FROM generate_series(2015, 2040) AS y,
+     (VALUES ('Winter', 0, '01-01', '04-30'),
+             ('Spring', 1, '05-01', '06-30'),
+             ('Summer', 2, '07-01', '08-31'),
+             ('Fall',   3, '09-01', '12-31')) AS s(season, idx, start_md, end_md)
+ON CONFLICT (code) DO NOTHING;
+
+-- Roles (014_user_roles_audit_log.sql) on databases created before them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'student';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS start_year INTEGER;
+
+-- UNCONFIRMED (021_plan_item_unconfirmed.sql) on databases created before it.
+ALTER TABLE plan_items DROP CONSTRAINT IF EXISTS plan_items_status_check;
+ALTER TABLE plan_items ADD CONSTRAINT plan_items_status_check
+    CHECK (status IN ('PLANNED','IN_PROGRESS','COMPLETED','DROPPED','UNCONFIRMED'));
+
+-- Custom / transfer plan items (023_plan_item_custom.sql).
+ALTER TABLE plan_items ADD COLUMN IF NOT EXISTS is_custom INTEGER NOT NULL DEFAULT 0;
+
+-- ── plan history ─────────────────────────────────────────────────────────────
+-- See 022_plan_events.sql.
+CREATE TABLE IF NOT EXISTS plan_events (
+    event_id         SERIAL      PRIMARY KEY,
+    user_id          INTEGER     NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    plan_item_id     INTEGER     NOT NULL,
+    action           TEXT        NOT NULL CHECK (action IN ('add','update','delete')),
+    before_state     TEXT,
+    after_state      TEXT,
+    actor_user_id    INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    source           TEXT        NOT NULL,
+    reverts_event_id INTEGER REFERENCES plan_events(event_id),
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION plan_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'plan_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS plan_events_append_only ON plan_events;
+CREATE TRIGGER plan_events_append_only BEFORE UPDATE ON plan_events
+    FOR EACH ROW EXECUTE FUNCTION plan_events_append_only();
+
+-- ── transfer credit ──────────────────────────────────────────────────────────
+-- See 024_transfer_credits.sql.
+CREATE TABLE IF NOT EXISTS transfer_credits (
+    transfer_id     SERIAL      PRIMARY KEY,
+    user_id         INTEGER     NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    institution     TEXT        NOT NULL,
+    original_course TEXT        NOT NULL,
+    subject         TEXT,
+    course_number   TEXT,
+    units           INTEGER     NOT NULL CHECK (units > 0),
+    level           INTEGER     CHECK (level BETWEEN 1 AND 4),
+    note            TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (course_number IS NULL OR subject IS NOT NULL),
+    CHECK (course_number IS NOT NULL OR level IS NOT NULL)
+);
+
+-- ── plan share links ─────────────────────────────────────────────────────────
+-- See 025_plan_shares.sql.
+CREATE TABLE IF NOT EXISTS plan_shares (
+    share_id       SERIAL      PRIMARY KEY,
+    user_id        INTEGER     NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    token_hash     TEXT        NOT NULL UNIQUE,
+    label          TEXT,
+    program_id     INTEGER     REFERENCES programs(program_id) ON DELETE SET NULL,
+    hide_grades    INTEGER     NOT NULL DEFAULT 0,
+    expires_at     TIMESTAMPTZ,
+    revoked_at     TIMESTAMPTZ,
+    last_viewed_at TIMESTAMPTZ,
+    view_count     INTEGER     NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ── advisors ─────────────────────────────────────────────────────────────────
+-- See 026_advisors.sql.
+CREATE TABLE IF NOT EXISTS advisor_students (
+    link_id      SERIAL      PRIMARY KEY,
+    advisor_id   INTEGER     NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    student_id   INTEGER     NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    program_id   INTEGER     REFERENCES programs(program_id) ON DELETE SET NULL,
+    status       TEXT        NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'active', 'declined', 'ended')),
+    invited_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMPTZ,
+    UNIQUE (advisor_id, student_id)
+);
+
+CREATE TABLE IF NOT EXISTS plan_item_comments (
+    comment_id   SERIAL      PRIMARY KEY,
+    plan_item_id INTEGER     NOT NULL REFERENCES plan_items(plan_item_id) ON DELETE CASCADE,
+    author_id    INTEGER     REFERENCES users(user_id) ON DELETE SET NULL,
+    body         TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
 -- ── indexes ───────────────────────────────────────────────────────────────────
 CREATE INDEX IF NOT EXISTS idx_courses_subject_term         ON courses(subject, term);
 CREATE INDEX IF NOT EXISTS idx_courses_coid                 ON courses(coid);
+CREATE INDEX IF NOT EXISTS idx_courses_catalog              ON courses(catalog_id, term);
+CREATE INDEX IF NOT EXISTS idx_courses_search               ON courses USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_courses_name_trgm            ON courses USING GIN (course_name gin_trgm_ops);
 CREATE INDEX IF NOT EXISTS idx_instructors_name             ON instructors(name);
 CREATE INDEX IF NOT EXISTS idx_course_instructors_instructor ON course_instructors(instructor_id);
 CREATE INDEX IF NOT EXISTS idx_outlines_course_row          ON course_outlines(course_row_id);
@@ -181,7 +458,19 @@ CREATE INDEX IF NOT EXISTS idx_course_reviews_course        ON course_reviews(su
 CREATE INDEX IF NOT EXISTS idx_instructor_reviews_prof      ON instructor_reviews(instructor_id);
 CREATE INDEX IF NOT EXISTS idx_course_stats_course_term     ON course_stats(subject, course_number, term);
 CREATE INDEX IF NOT EXISTS idx_plan_items_course            ON plan_items(subject, course_number);
+CREATE INDEX IF NOT EXISTS idx_plan_events_user             ON plan_events(user_id, event_id);
+CREATE INDEX IF NOT EXISTS idx_transfer_credits_user        ON transfer_credits(user_id);
+CREATE INDEX IF NOT EXISTS idx_plan_shares_user             ON plan_shares(user_id);
+CREATE INDEX IF NOT EXISTS idx_advisor_students_student     ON advisor_students(student_id);
+CREATE INDEX IF NOT EXISTS idx_plan_item_comments_item      ON plan_item_comments(plan_item_id);
+CREATE INDEX IF NOT EXISTS idx_program_standing_rules_program ON program_standing_rules(program_id);
 CREATE INDEX IF NOT EXISTS idx_req_groups_program           ON requirement_groups(program_id);
 CREATE INDEX IF NOT EXISTS idx_req_groups_parent            ON requirement_groups(parent_group_id);
 CREATE INDEX IF NOT EXISTS idx_req_courses_group            ON requirement_courses(group_id);
 CREATE INDEX IF NOT EXISTS idx_req_courses_coid             ON requirement_courses(coid);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_actor            ON admin_audit_log(actor_user_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_target           ON admin_audit_log(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_user_identities_user         ON user_identities(user_id);
+CREATE INDEX IF NOT EXISTS idx_outbound_emails_due          ON outbound_emails(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_feedback_status              ON feedback(status, created_at);
+CREATE INDEX IF NOT EXISTS idx_feedback_tags_tag             ON feedback_tags(tag);
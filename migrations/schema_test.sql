@@ -1,11 +1,23 @@
 -- schema_test.sql  –  DDL-only fixture used by Go unit tests.
 -- Contains NO INSERT/seed data so newTestRepo() runs in milliseconds.
 -- Keep in sync with the numbered migrations whenever a new table or
--- column is added (migrations 000, 002, 004, 005, 008).
+-- column is added (migrations 000, 002, 004, 005, 008, 013, 014, 015, 016, 017, 019, 020, 021, 022, 023, 024, 025, 026, 027, 028, 029).
 
 PRAGMA foreign_keys=ON;
 
 -- ── core course catalogue ────────────────────────────────────────────────────
+CREATE TABLE catalog_courses (
+    catalog_id    INTEGER PRIMARY KEY AUTOINCREMENT,
+    subject       TEXT NOT NULL,
+    course_number TEXT NOT NULL,
+    course_name   TEXT,
+    coid          INTEGER,
+    units         INTEGER CHECK (units >= 0),
+    units_source  TEXT CHECK (units_source IN ('scraped', 'curated')),
+    UNIQUE(subject, course_number)
+);
+
+-- One row per offering of a catalogue course: a term, or a section of one.
 CREATE TABLE courses (
     id            INTEGER PRIMARY KEY AUTOINCREMENT,
     subject       TEXT NOT NULL,
@@ -14,8 +26,33 @@ CREATE TABLE courses (
     professor     TEXT,
     term          TEXT NOT NULL,
     coid          INTEGER,
-    UNIQUE(subject, course_number, term)
+    catalog_id    INTEGER REFERENCES catalog_courses(catalog_id),
+    section       TEXT
 );
+CREATE UNIQUE INDEX idx_courses_offering ON courses(subject, course_number, term, COALESCE(section, ''));
+
+CREATE TRIGGER courses_catalog_ai AFTER INSERT ON courses
+WHEN new.catalog_id IS NULL BEGIN
+    INSERT INTO catalog_courses (subject, course_number, course_name, coid)
+    VALUES (new.subject, new.course_number, NULLIF(new.course_name, ''), new.coid)
+    ON CONFLICT(subject, course_number) DO UPDATE SET
+        course_name = COALESCE(excluded.course_name, catalog_courses.course_name),
+        coid        = COALESCE(excluded.coid, catalog_courses.coid);
+    UPDATE courses SET catalog_id = (
+        SELECT catalog_id FROM catalog_courses
+        WHERE subject = new.subject AND course_number = new.course_number
+    ) WHERE id = new.id;
+END;
+
+CREATE TRIGGER courses_catalog_au AFTER UPDATE OF course_name, coid ON courses
+WHEN new.catalog_id IS NOT NULL BEGIN
+    UPDATE catalog_courses SET
+        course_name = CASE WHEN new.course_name IS NOT old.course_name
+                           THEN COALESCE(NULLIF(new.course_name, ''), course_name)
+                           ELSE course_name END,
+        coid        = COALESCE(new.coid, coid)
+    WHERE catalog_id = new.catalog_id;
+END;
 
 CREATE TABLE instructors (
     instructor_id     INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -67,7 +104,80 @@ CREATE TABLE users (
     password_hash TEXT NOT NULL,
     created_at    TEXT NOT NULL DEFAULT (datetime('now')),
     program       TEXT,
-    year_of_study INTEGER
+    year_of_study INTEGER,
+    role          TEXT NOT NULL DEFAULT 'student',
+    start_year    INTEGER
+);
+
+CREATE TABLE password_reset_tokens (
+    token       TEXT      NOT NULL PRIMARY KEY,
+    user_id     INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    expires_at  TIMESTAMP NOT NULL,
+    used_at     TIMESTAMP
+);
+
+-- ── admin (migration 014) ────────────────────────────────────────────────────
+CREATE TABLE admin_audit_log (
+    audit_id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_user_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    action        TEXT      NOT NULL,
+    target_type   TEXT      NOT NULL,
+    target_id     TEXT,
+    details       TEXT,
+    created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ── single sign-on (migration 015) ───────────────────────────────────────────
+CREATE TABLE oidc_login_states (
+    state          TEXT      NOT NULL PRIMARY KEY,
+    code_verifier  TEXT      NOT NULL,
+    nonce          TEXT      NOT NULL,
+    expires_at     TIMESTAMP NOT NULL
+);
+
+CREATE TABLE user_identities (
+    issuer      TEXT      NOT NULL,
+    subject     TEXT      NOT NULL,
+    user_id     INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    email       TEXT,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (issuer, subject)
+);
+
+-- ── email queue (migration 016) ──────────────────────────────────────────────
+CREATE TABLE outbound_emails (
+    email_id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    to_address       TEXT      NOT NULL,
+    reply_to         TEXT,
+    subject          TEXT      NOT NULL,
+    text_body        TEXT      NOT NULL,
+    html_body        TEXT,
+    status           TEXT      NOT NULL DEFAULT 'pending',
+    attempts         INTEGER   NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMP NOT NULL,
+    last_error       TEXT,
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at          TIMESTAMP
+);
+
+-- ── feedback (migration 017) ─────────────────────────────────────────────────
+CREATE TABLE feedback (
+    feedback_id  INTEGER PRIMARY KEY AUTOINCREMENT,
+    message      TEXT      NOT NULL,
+    page         TEXT,
+    email        TEXT,
+    user_id      INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    user_agent   TEXT,
+    status       TEXT      NOT NULL DEFAULT 'open',
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at  TIMESTAMP,
+    resolved_by  INTEGER REFERENCES users(user_id) ON DELETE SET NULL
+);
+
+CREATE TABLE feedback_tags (
+    feedback_id  INTEGER NOT NULL REFERENCES feedback(feedback_id) ON DELETE CASCADE,
+    tag          TEXT    NOT NULL,
+    PRIMARY KEY (feedback_id, tag)
 );
 
 -- ── reviews & stats ──────────────────────────────────────────────────────────
@@ -106,6 +216,18 @@ CREATE TABLE course_stats (
     created_at    TEXT NOT NULL DEFAULT (datetime('now'))
 );
 
+-- ── academic calendar (migration 020; unseeded here) ─────────────────────────
+CREATE TABLE academic_terms (
+    term_id    INTEGER PRIMARY KEY AUTOINCREMENT,
+    code       TEXT    NOT NULL UNIQUE,
+    season     TEXT    NOT NULL CHECK (season IN ('Fall','Winter','Spring','Summer')),
+    year       INTEGER NOT NULL,
+    start_date TEXT    NOT NULL,
+    end_date   TEXT    NOT NULL,
+    ordinal    INTEGER NOT NULL UNIQUE,
+    UNIQUE(year, season)
+);
+
 -- ── degree planner (migration 004) ───────────────────────────────────────────
 CREATE TABLE plan_terms (
     plan_term_id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -120,12 +242,83 @@ CREATE TABLE plan_items (
     plan_term_id  INTEGER NOT NULL REFERENCES plan_terms(plan_term_id) ON DELETE CASCADE,
     subject       TEXT NOT NULL,
     course_number TEXT NOT NULL,
-    status        TEXT NOT NULL CHECK (status IN ('PLANNED','IN_PROGRESS','COMPLETED','DROPPED')),
+    status        TEXT NOT NULL CHECK (status IN ('PLANNED','IN_PROGRESS','COMPLETED','DROPPED','UNCONFIRMED')),
     grade         TEXT,
     note          TEXT,
+    is_custom     INTEGER NOT NULL DEFAULT 0,
     UNIQUE(plan_term_id, subject, course_number)
 );
 
+-- ── plan history (migration 022) ─────────────────────────────────────────────
+CREATE TABLE plan_events (
+    event_id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id          INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    plan_item_id     INTEGER   NOT NULL,
+    action           TEXT      NOT NULL CHECK (action IN ('add','update','delete')),
+    before_state     TEXT,
+    after_state      TEXT,
+    actor_user_id    INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+    source           TEXT      NOT NULL,
+    reverts_event_id INTEGER REFERENCES plan_events(event_id),
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER plan_events_append_only BEFORE UPDATE ON plan_events BEGIN
+    SELECT RAISE(ABORT, 'plan_events is append-only');
+END;
+
+-- ── transfer credit (migration 024) ──────────────────────────────────────────
+CREATE TABLE transfer_credits (
+    transfer_id     INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id         INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    institution     TEXT      NOT NULL,
+    original_course TEXT      NOT NULL,
+    subject         TEXT,
+    course_number   TEXT,
+    units           INTEGER   NOT NULL CHECK (units > 0),
+    level           INTEGER   CHECK (level BETWEEN 1 AND 4),
+    note            TEXT,
+    created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (course_number IS NULL OR subject IS NOT NULL),
+    CHECK (course_number IS NOT NULL OR level IS NOT NULL)
+);
+
+-- ── plan share links (migration 025) ────────────────────────────────────────
+CREATE TABLE plan_shares (
+    share_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    token_hash     TEXT      NOT NULL UNIQUE,
+    label          TEXT,
+    program_id     INTEGER   REFERENCES programs(program_id) ON DELETE SET NULL,
+    hide_grades    INTEGER   NOT NULL DEFAULT 0,
+    expires_at     TIMESTAMP,
+    revoked_at     TIMESTAMP,
+    last_viewed_at TIMESTAMP,
+    view_count     INTEGER   NOT NULL DEFAULT 0,
+    created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ── advisors (migration 026) ────────────────────────────────────────────────
+CREATE TABLE advisor_students (
+    link_id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    advisor_id   INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    student_id   INTEGER   NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    program_id   INTEGER   REFERENCES programs(program_id) ON DELETE SET NULL,
+    status       TEXT      NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'active', 'declined', 'ended')),
+    invited_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP,
+    UNIQUE (advisor_id, student_id)
+);
+
+CREATE TABLE plan_item_comments (
+    comment_id   INTEGER PRIMARY KEY AUTOINCREMENT,
+    plan_item_id INTEGER   NOT NULL REFERENCES plan_items(plan_item_id) ON DELETE CASCADE,
+    author_id    INTEGER   REFERENCES users(user_id) ON DELETE SET NULL,
+    body         TEXT      NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 -- ── programs & requirements ───────────────────────────────────────────────────
 CREATE TABLE programs (
     program_id   INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -160,6 +353,20 @@ CREATE TABLE requirement_courses (
     adhoc_text      TEXT
 );
 
+CREATE TABLE program_standing_rules (
+    rule_id       INTEGER PRIMARY KEY AUTOINCREMENT,
+    program_id    INTEGER NOT NULL REFERENCES programs(program_id) ON DELETE CASCADE,
+    kind          TEXT    NOT NULL CHECK (kind IN ('min_gpa', 'min_grade', 'min_units')),
+    label         TEXT    NOT NULL,
+    by_year       INTEGER,
+    min_gpa       REAL,
+    subject       TEXT,
+    course_number TEXT,
+    min_grade     TEXT,
+    min_units     INTEGER,
+    display_order INTEGER NOT NULL DEFAULT 0
+);
+
 -- ── view ─────────────────────────────────────────────────────────────────────
 CREATE VIEW v_course_rating AS
 SELECT
@@ -175,6 +382,7 @@ GROUP BY subject, course_number;
 -- ── indexes ───────────────────────────────────────────────────────────────────
 CREATE INDEX idx_courses_subject_term        ON courses(subject, term);
 CREATE INDEX idx_courses_coid                ON courses(coid);
+CREATE INDEX idx_courses_catalog             ON courses(catalog_id, term);
 CREATE INDEX idx_instructors_name            ON instructors(name);
 CREATE INDEX idx_course_instructors_instructor ON course_instructors(instructor_id);
 CREATE INDEX idx_outlines_course_row         ON course_outlines(course_row_id);
@@ -183,7 +391,19 @@ CREATE INDEX idx_course_reviews_course       ON course_reviews(subject, course_n
 CREATE INDEX idx_instructor_reviews_prof     ON instructor_reviews(instructor_id);
 CREATE INDEX idx_course_stats_course_term    ON course_stats(subject, course_number, term);
 CREATE INDEX idx_plan_items_course           ON plan_items(subject, course_number);
+CREATE INDEX idx_plan_events_user            ON plan_events(user_id, event_id);
+CREATE INDEX idx_transfer_credits_user       ON transfer_credits(user_id);
+CREATE INDEX idx_plan_shares_user            ON plan_shares(user_id);
+CREATE INDEX idx_advisor_students_student    ON advisor_students(student_id);
+CREATE INDEX idx_plan_item_comments_item     ON plan_item_comments(plan_item_id);
+CREATE INDEX idx_program_standing_rules_program ON program_standing_rules(program_id);
 CREATE INDEX idx_req_groups_program          ON requirement_groups(program_id);
 CREATE INDEX idx_req_groups_parent           ON requirement_groups(parent_group_id);
 CREATE INDEX idx_req_courses_group           ON requirement_courses(group_id);
 CREATE INDEX idx_req_courses_coid            ON requirement_courses(coid);
+CREATE INDEX idx_admin_audit_actor           ON admin_audit_log(actor_user_id);
+CREATE INDEX idx_admin_audit_target          ON admin_audit_log(target_type, target_id);
+CREATE INDEX idx_user_identities_user        ON user_identities(user_id);
+CREATE INDEX idx_outbound_emails_due         ON outbound_emails(status, next_attempt_at);
+CREATE INDEX idx_feedback_status             ON feedback(status, created_at);
+CREATE INDEX idx_feedback_tags_tag            ON feedback_tags(tag);
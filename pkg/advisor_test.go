@@ -0,0 +1,192 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAdvisorCaseload(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	studentID := seedExportPlan(t, repo)
+	programID := seedProgram(t, repo, 9, "COMPSCI 1MD3", "COMPSCI 1XC3", "MATH 1ZA3")
+	studentToken, err := GenerateAccessToken(studentID, "export@example.com", RoleStudent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	advisorID, advisorToken := seedUser(t, repo, "advisor@example.com", RoleAdvisor)
+	_, strangerToken := seedUser(t, repo, "stranger@example.com", RoleAdvisor)
+
+	do := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	userPath := "/api/users/" + strconv.Itoa(studentID)
+	items, _ := repo.GetPlanItems(studentID)
+	itemPath := userPath + "/plan/" + strconv.Itoa(items[0].PlanItemID)
+
+	// Only advisors can invite, and only existing students.
+	if rr := do("POST", "/api/advisor/invitations", studentToken, `{"email":"advisor@example.com"}`); rr.Code != 403 {
+		t.Errorf("student inviting: expected 403, got %d", rr.Code)
+	}
+	if rr := do("POST", "/api/advisor/invitations", advisorToken, `{"email":"nobody@example.com"}`); rr.Code != 404 {
+		t.Errorf("unknown email: expected 404, got %d", rr.Code)
+	}
+	if rr := do("POST", "/api/advisor/invitations", advisorToken, `{"email":"stranger@example.com"}`); rr.Code != 400 {
+		t.Errorf("inviting an advisor: expected 400, got %d", rr.Code)
+	}
+	rr := do("POST", "/api/advisor/invitations", advisorToken,
+		`{"email":"Export@example.com","program_id":`+strconv.Itoa(programID)+`}`)
+	if rr.Code != 201 {
+		t.Fatalf("invite: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var n int
+	repo.DB.QueryRow(`SELECT COUNT(*) FROM outbound_emails WHERE to_address = 'export@example.com'`).Scan(&n)
+	if n != 1 {
+		t.Errorf("expected an invitation email, got %d", n)
+	}
+
+	// A pending invitation grants nothing.
+	if rr := do("GET", userPath+"/plan", advisorToken, ""); rr.Code != 403 {
+		t.Errorf("plan before acceptance: expected 403, got %d", rr.Code)
+	}
+	rr = do("GET", userPath+"/advisors", studentToken, "")
+	var links []AdvisorLink
+	json.NewDecoder(rr.Body).Decode(&links)
+	if len(links) != 1 || links[0].Status != AdvisorPending || links[0].AdvisorID != advisorID {
+		t.Fatalf("student's advisors: %s", rr.Body.String())
+	}
+	if rr := do("POST", userPath+"/advisors/"+strconv.Itoa(advisorID)+"/accept", studentToken, ""); rr.Code != 204 {
+		t.Fatalf("accept: expected 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do("POST", "/api/advisor/invitations", advisorToken, `{"email":"export@example.com"}`); rr.Code != 409 {
+		t.Errorf("re-inviting an advisee: expected 409, got %d", rr.Code)
+	}
+
+	// Accepted: the advisor can read, not write.
+	for _, path := range []string{"/plan", "/gpa", "/plan/history", "/transfer-credits", "/plan/export?format=csv",
+		"/validation?program_id=" + strconv.Itoa(programID)} {
+		if rr := do("GET", userPath+path, advisorToken, ""); rr.Code != 200 {
+			t.Errorf("advisor GET %s: expected 200, got %d", path, rr.Code)
+		}
+		if rr := do("GET", userPath+path, strangerToken, ""); rr.Code != 403 {
+			t.Errorf("other advisor GET %s: expected 403, got %d", path, rr.Code)
+		}
+	}
+	for _, c := range []struct{ method, path, body string }{
+		{"PATCH", "/plan/" + strconv.Itoa(items[0].PlanItemID), `{"status":"DROPPED"}`},
+		{"POST", "/plan", `{"year_index":1,"season":"Fall","subject":"COMPSCI","course_number":"1XC3"}`},
+		{"POST", "/transfer-credits", `{}`},
+		{"GET", "/plan/shares", ""},
+		{"GET", "/advisors", ""},
+	} {
+		if rr := do(c.method, userPath+c.path, advisorToken, c.body); rr.Code != 403 {
+			t.Errorf("advisor %s %s: expected 403, got %d", c.method, c.path, rr.Code)
+		}
+	}
+
+	rr = do("GET", "/api/advisor/students", advisorToken, "")
+	var caseload []CaseloadEntry
+	if err := json.NewDecoder(rr.Body).Decode(&caseload); err != nil || rr.Code != 200 {
+		t.Fatalf("caseload: %d %v", rr.Code, err)
+	}
+	if len(caseload) != 1 {
+		t.Fatalf("caseload: %+v", caseload)
+	}
+	e := caseload[0]
+	if e.StudentID != studentID || e.GPA == nil || e.Validation == nil || e.Program == nil || *e.Program != "Test Program" {
+		t.Errorf("caseload entry: %+v", e)
+	} else if e.Validation.TotalUnitsCompleted != 6 || len(e.Validation.Outstanding) != 1 {
+		t.Errorf("caseload validation: %+v", e.Validation)
+	}
+
+	// Comments: advisors write, the student reads.
+	rr = do("POST", itemPath+"/comments", advisorToken, `{"body":"Consider taking this in Winter."}`)
+	if rr.Code != 201 {
+		t.Fatalf("comment: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var comment PlanItemComment
+	json.NewDecoder(rr.Body).Decode(&comment)
+	if rr := do("POST", itemPath+"/comments", studentToken, `{"body":"me too"}`); rr.Code != 403 {
+		t.Errorf("student commenting: expected 403, got %d", rr.Code)
+	}
+	if rr := do("POST", itemPath+"/comments", strangerToken, `{"body":"hi"}`); rr.Code != 403 {
+		t.Errorf("other advisor commenting: expected 403, got %d", rr.Code)
+	}
+	rr = do("GET", itemPath+"/comments", studentToken, "")
+	var comments []PlanItemComment
+	json.NewDecoder(rr.Body).Decode(&comments)
+	if len(comments) != 1 || comments[0].Body != "Consider taking this in Winter." ||
+		comments[0].AuthorName == nil || *comments[0].AuthorName != "Test" {
+		t.Errorf("comments: %s", rr.Body.String())
+	}
+	if got, _ := repo.GetPlanItems(studentID); got[0].Note != nil && strings.Contains(*got[0].Note, "Winter") {
+		t.Error("comment should not touch the student's note")
+	}
+	commentPath := itemPath + "/comments/" + strconv.Itoa(comment.CommentID)
+	if rr := do("DELETE", commentPath, studentToken, ""); rr.Code != 404 {
+		t.Errorf("student deleting advisor's comment: expected 404, got %d", rr.Code)
+	}
+	if rr := do("DELETE", commentPath, advisorToken, ""); rr.Code != 204 {
+		t.Errorf("advisor deleting own comment: expected 204, got %d", rr.Code)
+	}
+
+	// Removing the advisor takes their access away.
+	if rr := do("DELETE", userPath+"/advisors/"+strconv.Itoa(advisorID), studentToken, ""); rr.Code != 204 {
+		t.Fatalf("remove advisor: expected 204, got %d", rr.Code)
+	}
+	if rr := do("GET", userPath+"/plan", advisorToken, ""); rr.Code != 403 {
+		t.Errorf("plan after removal: expected 403, got %d", rr.Code)
+	}
+	rr = do("GET", "/api/advisor/students", advisorToken, "")
+	if strings.TrimSpace(rr.Body.String()) != "[]" {
+		t.Errorf("caseload after removal: %s", rr.Body.String())
+	}
+}
+
+func TestAdvisorInvitation_DeclineAndReinvite(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	advisorID, _ := seedUser(t, repo, "advisor@example.com", RoleAdvisor)
+	studentID, _ := seedUser(t, repo, "student@example.com", RoleStudent)
+
+	if _, err := repo.InviteStudent(advisorID, "student@example.com", nil); err != nil {
+		t.Fatalf("InviteStudent: %v", err)
+	}
+	if err := repo.RespondToAdvisor(studentID, advisorID, false); err != nil {
+		t.Fatalf("decline: %v", err)
+	}
+	if err := repo.RespondToAdvisor(studentID, advisorID, true); err != ErrNotFound {
+		t.Errorf("accepting a declined invitation: got %v, want ErrNotFound", err)
+	}
+	if ok, _ := repo.IsAdvisorOf(advisorID, studentID); ok {
+		t.Error("declined advisor should have no access")
+	}
+
+	link, err := repo.InviteStudent(advisorID, "student@example.com", nil)
+	if err != nil || link.Status != AdvisorPending || link.RespondedAt != nil {
+		t.Fatalf("re-invite: %+v, %v", link, err)
+	}
+	if err := repo.RespondToAdvisor(studentID, advisorID, true); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if ok, _ := repo.IsAdvisorOf(advisorID, studentID); !ok {
+		t.Error("accepted advisor should have access")
+	}
+
+	// Access follows the role: an advisor demoted to student loses it.
+	if _, err := repo.DB.Exec(`UPDATE users SET role = 'student' WHERE user_id = ?`, advisorID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := repo.IsAdvisorOf(advisorID, studentID); ok {
+		t.Error("demoted advisor should have no access")
+	}
+}
@@ -5,9 +5,9 @@ import (
 	"errors"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
-	"strconv"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -33,20 +33,43 @@ const (
 	RefreshToken TokenType = "refresh"
 )
 
+// Role controls which parts of the API a user may reach.
+// Every account starts as a student; admins and advisors are promoted through
+// the admin API (or directly in the database for the very first admin).
+type Role string
+
+const (
+	RoleStudent Role = "student"
+	RoleAdmin   Role = "admin"
+	// RoleAdvisor can invite students and, once a student accepts, read
+	// their plan and comment on it.
+	RoleAdvisor Role = "advisor"
+)
+
+// validRoles is the set of roles accepted when an admin changes a user's role.
+var validRoles = map[Role]bool{
+	RoleStudent: true,
+	RoleAdmin:   true,
+	RoleAdvisor: true,
+}
+
 // Claims is the payload embedded in every JWT.
 type Claims struct {
 	UserID    int       `json:"user_id"`
 	Email     string    `json:"email"`
+	Role      Role      `json:"role,omitempty"`
 	TokenType TokenType `json:"token_type"`
 	jwt.RegisteredClaims
 }
 
 // GenerateAccessToken creates a short-lived JWT (15 minutes).
 // This is what the frontend sends on every API request.
-func GenerateAccessToken(userID int, email string) (string, error) {
+// The role is embedded so RequireRole can authorize without a DB round-trip.
+func GenerateAccessToken(userID int, email string, role Role) (string, error) {
 	claims := Claims{
 		UserID:    userID,
 		Email:     email,
+		Role:      role,
 		TokenType: AccessToken,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
@@ -138,31 +161,112 @@ func RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// OptionalAuth attaches the caller's claims to the request when a valid
+// access token is sent, and otherwise lets the request through anonymously.
+// For public endpoints that behave slightly differently for signed-in users.
+func OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			if claims, err := ParseToken(parts[1]); err == nil && claims.TokenType == AccessToken {
+				r = withClaims(r, claims)
+			}
+		}
+		next(w, r)
+	}
+}
+
 func RequireOwner(next http.HandlerFunc) http.HandlerFunc {
-    return func(w http.ResponseWriter, r *http.Request) {
-        claims := GetClaimsFromContext(r)
-        if claims == nil {
-            http.Error(w, "unauthorized", http.StatusUnauthorized)
-            return
-        }
-
-        // Extract the user ID from the URL — expect /api/users/{id}/...
-        path := strings.TrimPrefix(r.URL.Path, "/api/users/")
-        idStr := strings.SplitN(path, "/", 2)[0]
-        urlUserID, err := strconv.Atoi(idStr)
-        if err != nil || urlUserID == 0 {
-            http.Error(w, "invalid user id", http.StatusBadRequest)
-            return
-        }
-
-        // Reject if the token's user_id doesn't match the URL's user id
-        if claims.UserID != urlUserID {
-            http.Error(w, "forbidden", http.StatusForbidden)
-            return
-        }
-
-        next(w, r)
-    }
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := GetClaimsFromContext(r)
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		urlUserID, ok := pathUserID(r)
+		if !ok {
+			http.Error(w, "invalid user id", http.StatusBadRequest)
+			return
+		}
+
+		// Reject if the token's user_id doesn't match the URL's user id
+		if claims.UserID != urlUserID {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// RequireOwnerOrAdvisor is RequireOwner that also lets through an advisor
+// the student has accepted (see StudentAdvisorHandler). Use it only on routes
+// an advisor may use, and only for the methods they may use:
+//
+//	RequireAuth(RequireOwnerOrAdvisor(repo)(handler))
+func RequireOwnerOrAdvisor(repo *Repository) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaimsFromContext(r)
+			if claims == nil {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			urlUserID, ok := pathUserID(r)
+			if !ok {
+				http.Error(w, "invalid user id", http.StatusBadRequest)
+				return
+			}
+			if claims.UserID == urlUserID {
+				next(w, r)
+				return
+			}
+			if claims.Role == RoleAdvisor {
+				advises, err := repo.IsAdvisorOf(claims.UserID, urlUserID)
+				if err != nil {
+					http.Error(w, "internal error", http.StatusInternalServerError)
+					return
+				}
+				if advises {
+					next(w, r)
+					return
+				}
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		}
+	}
+}
+
+// pathUserID reads the user ID from a /api/users/{id}/... path.
+func pathUserID(r *http.Request) (int, bool) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/users/")
+	id, err := strconv.Atoi(strings.SplitN(path, "/", 2)[0])
+	return id, err == nil && id != 0
+}
+
+// RequireRole returns a middleware that only lets through requests whose
+// token carries one of the given roles. Must be wrapped by RequireAuth so the
+// claims are already in the context:
+//
+//	RequireAuth(RequireRole(RoleAdmin)(handler))
+func RequireRole(roles ...Role) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaimsFromContext(r)
+			if claims == nil {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			for _, role := range roles {
+				if claims.Role == role {
+					next(w, r)
+					return
+				}
+			}
+			http.Error(w, "forbidden", http.StatusForbidden)
+		}
+	}
 }
 
 // withClaims attaches JWT claims to the request's context and returns the
@@ -179,3 +283,12 @@ func GetClaimsFromContext(r *http.Request) *Claims {
 	claims, _ := r.Context().Value(claimsContextKey).(*Claims)
 	return claims
 }
+
+// actorID returns the authenticated user's ID for recording who made a
+// change, or 0 when the request carries no claims.
+func actorID(r *http.Request) int {
+	if claims := GetClaimsFromContext(r); claims != nil {
+		return claims.UserID
+	}
+	return 0
+}
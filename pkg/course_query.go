@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// subjectAliases maps the abbreviations students actually type to the subject
+// codes used in the catalogue. Canonical codes map to themselves so joined
+// tokens like "comp sci" → "COMPSCI" are recognised too.
+var subjectAliases = map[string]string{
+	"CS":       "COMPSCI",
+	"COMPSCI":  "COMPSCI",
+	"SFWR":     "SFWRENG",
+	"SFWRENG":  "SFWRENG",
+	"ENG":      "ENGINEER",
+	"ENGINEER": "ENGINEER",
+}
+
+// McMaster course numbers are a level digit, one or two letters and enough
+// digits to make four characters: 2C03, 1ZA3, 4HC3. courseNumberRe also
+// accepts a dropped leading zero ("2c3"); courseCodeRe matches a subject
+// glued to the number ("cs2c03").
+var (
+	courseNumberRe = regexp.MustCompile(`^[1-9][A-Z]{1,2}[0-9]{1,2}$`)
+	courseCodeRe   = regexp.MustCompile(`^([A-Z]{2,})([1-9][A-Z]{1,2}[0-9]{1,2})$`)
+)
+
+// normalizeCourseQuery rewrites course-code shaped input into the form the
+// catalogue uses: "comp sci 2c3", "CS 2C03" and "cs2c03" all become
+// "COMPSCI 2C03", and "2c3" becomes "2C03". Words that aren't part of a code
+// are kept as typed. ok is false when q contains no course number, so free
+// text like "software eng" is left alone.
+func normalizeCourseQuery(q string) (normalized string, ok bool) {
+	tokens := searchTokens(q)
+	for i := range tokens {
+		tokens[i] = strings.ToUpper(tokens[i])
+	}
+
+	var out []string
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if m := courseCodeRe.FindStringSubmatch(tok); m != nil {
+			out = append(out, canonicalSubject(m[1]), padCourseNumber(m[2]))
+			ok = true
+			continue
+		}
+		if !courseNumberRe.MatchString(tok) {
+			out = append(out, tok)
+			continue
+		}
+		ok = true
+
+		// The subject is whatever letters precede the number, possibly split
+		// over up to three words ("sfwr eng", "comp sci"). Longer joins only
+		// count when they're a known subject; a single word always does.
+		for k := min(3, len(out)); k >= 1; k-- {
+			words := out[len(out)-k:]
+			if !allLetters(words) {
+				continue
+			}
+			joined := strings.Join(words, "")
+			if _, known := subjectAliases[joined]; k > 1 && !known {
+				continue
+			}
+			out = append(out[:len(out)-k], canonicalSubject(joined))
+			break
+		}
+		out = append(out, padCourseNumber(tok))
+	}
+	return strings.Join(out, " "), ok
+}
+
+// canonicalSubject expands a subject alias, or returns s unchanged.
+func canonicalSubject(s string) string {
+	if canon, ok := subjectAliases[s]; ok {
+		return canon
+	}
+	return s
+}
+
+// padCourseNumber restores a dropped zero: "2C3" → "2C03".
+func padCourseNumber(n string) string {
+	if len(n) >= 4 {
+		return n
+	}
+	return n[:len(n)-1] + strings.Repeat("0", 4-len(n)) + n[len(n)-1:]
+}
+
+func allLetters(words []string) bool {
+	for _, w := range words {
+		for _, r := range w {
+			if r < 'A' || r > 'Z' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// fuzzyNameMatch reports whether every word of the query (of four letters
+// or more; shorter words are ignored) is within a small edit distance of,
+// or a prefix of, some word of name. Both are token lists from searchTokens.
+func fuzzyNameMatch(query, name []string) bool {
+	matched := false
+	for _, q := range query {
+		n := utf8.RuneCountInString(q)
+		if n < 4 {
+			continue
+		}
+		maxEdits := 1
+		if n >= 6 {
+			maxEdits = 2
+		}
+		found := false
+		for _, w := range name {
+			if strings.HasPrefix(w, q) || levenshtein(q, w) <= maxEdits {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+		matched = true
+	}
+	return matched
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,189 @@
+package pkg
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Keyset ("cursor") pagination for the public listings. Instead of an
+// offset, the client sends back an opaque cursor naming the sort keys of the
+// row it stopped at, and the next page is whatever sorts after it. That
+// stays fast on deep pages and doesn't skip or repeat rows when rows are
+// inserted between requests. Keys are natural ones (subject, number, term)
+// first, with the row ID last only to break ties between sections.
+
+// ErrInvalidCursor is returned for a cursor that can't be decoded or was
+// issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sortKey is one column of an ORDER BY. nullsLast marks a nullable column
+// whose NULLs sort after every value, whichever the direction.
+type sortKey struct {
+	expr      string
+	args      []interface{}
+	desc      bool
+	nullsLast bool
+}
+
+// pageCursor is the decoded form of a cursor: the sort it belongs to, the
+// key values of the boundary row, and whether it points backwards (the page
+// before that row) or forwards (the page after it).
+type pageCursor struct {
+	Sort   string        `json:"s"`
+	Before bool          `json:"b,omitempty"`
+	Keys   []interface{} `json:"k"`
+}
+
+func encodeCursor(c pageCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor parses s and checks it was issued for sortName with the
+// given number of keys.
+func decodeCursor(s, sortName string, nKeys int) (*pageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sortName || len(c.Keys) != nKeys {
+		return nil, ErrInvalidCursor
+	}
+	for _, k := range c.Keys {
+		switch k.(type) {
+		case nil, string, float64:
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
+}
+
+// orderByClause renders keys as an ORDER BY list, reversed when paging
+// backwards. Returns the clause and the arguments of any key expressions.
+func orderByClause(keys []sortKey, reverse bool) (string, []interface{}) {
+	parts := make([]string, len(keys))
+	var args []interface{}
+	for i, k := range keys {
+		desc := k.desc != reverse
+		part := k.expr
+		if desc {
+			part += " DESC"
+		} else {
+			part += " ASC"
+		}
+		if k.nullsLast {
+			if reverse {
+				part += " NULLS FIRST"
+			} else {
+				part += " NULLS LAST"
+			}
+		}
+		parts[i] = part
+		args = append(args, k.args...)
+	}
+	return strings.Join(parts, ", "), args
+}
+
+// keysetCondition returns a WHERE condition matching the rows strictly after
+// (or, for before, strictly before) the row whose keys are values, in the
+// order given by keys.
+func keysetCondition(keys []sortKey, values []interface{}, before bool) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i := range keys {
+		var ands []string
+		var andArgs []interface{}
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				ands = append(ands, keys[j].expr+" IS NULL")
+				andArgs = append(andArgs, keys[j].args...)
+			} else {
+				ands = append(ands, keys[j].expr+" = ?")
+				andArgs = append(andArgs, keys[j].args...)
+				andArgs = append(andArgs, values[j])
+			}
+		}
+		cond, condArgs, ok := keysetStep(keys[i], values[i], before)
+		if !ok {
+			continue
+		}
+		ands = append(ands, cond)
+		andArgs = append(andArgs, condArgs...)
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+		args = append(args, andArgs...)
+	}
+	if len(ors) == 0 {
+		return "1 = 0", nil
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+// keysetStep is the condition for one key to sort strictly past v. ok is
+// false when nothing can, i.e. v is NULL and NULLs already come last.
+func keysetStep(k sortKey, v interface{}, before bool) (string, []interface{}, bool) {
+	op := ">"
+	if k.desc != before {
+		op = "<"
+	}
+	args := append([]interface{}{}, k.args...)
+	switch {
+	case v == nil && before:
+		return k.expr + " IS NOT NULL", args, true
+	case v == nil:
+		return "", nil, false
+	case k.nullsLast && !before:
+		args = append(args, v)
+		args = append(args, k.args...)
+		return "(" + k.expr + " " + op + " ? OR " + k.expr + " IS NULL)", args, true
+	default:
+		return k.expr + " " + op + " ?", append(args, v), true
+	}
+}
+
+// keyColumns renders keys as a SELECT list so each row's cursor can be built.
+func keyColumns(keys []sortKey) (string, []interface{}) {
+	exprs := make([]string, len(keys))
+	var args []interface{}
+	for i, k := range keys {
+		exprs[i] = k.expr
+		args = append(args, k.args...)
+	}
+	return strings.Join(exprs, ", "), args
+}
+
+// keyValue normalises a scanned key column for JSON: text may come back as
+// []byte and integers as int64, which would otherwise not round-trip.
+func keyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []byte:
+		return string(t)
+	case int64:
+		return float64(t)
+	case int:
+		return float64(t)
+	}
+	return v
+}
+
+// pageLinks returns the cursors for a page. rowKeys are the key values of
+// the page's rows in display order; more reports whether the query found
+// rows beyond the page in the direction it was read (fetch limit+1 to
+// know). A page read forwards has a previous page when it wasn't the first;
+// one read backwards always has a next page, the one it came from.
+func pageLinks(sortName string, rowKeys [][]interface{}, more bool, offset int, cur *pageCursor) (next, prev string) {
+	if len(rowKeys) == 0 {
+		return "", ""
+	}
+	backward := cur != nil && cur.Before
+	if backward || more {
+		next = encodeCursor(pageCursor{Sort: sortName, Keys: rowKeys[len(rowKeys)-1]})
+	}
+	if (backward && more) || (!backward && (cur != nil || offset > 0)) {
+		prev = encodeCursor(pageCursor{Sort: sortName, Before: true, Keys: rowKeys[0]})
+	}
+	return next, prev
+}
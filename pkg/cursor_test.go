@@ -0,0 +1,205 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// seedRatedCourses inserts n ZZCUR courses; every third has no instructor
+// (so no rating) and the rest share ratings in pairs, to exercise NULLs and
+// ties in the keyset.
+func seedRatedCourses(t *testing.T, repo *Repository, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		res, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, professor, term)
+			VALUES ('ZZCUR', ?, 'Cursor Course', 'Prof', '2025')`, fmt.Sprintf("%dA%02d", 1+i%4, i))
+		if err != nil {
+			t.Fatalf("seed course %d: %v", i, err)
+		}
+		if i%3 == 0 {
+			continue
+		}
+		courseID, _ := res.LastInsertId()
+		name := fmt.Sprintf("Cursor Prof %02d", i)
+		res, err = repo.DB.Exec(`INSERT INTO instructors(name, name_normalized, ext_avg_rating, ext_num_ratings)
+			VALUES (?, ?, ?, 10)`, name, strings.ToLower(name), float64(i/2))
+		if err != nil {
+			t.Fatalf("seed instructor %d: %v", i, err)
+		}
+		instructorID, _ := res.LastInsertId()
+		if _, err := repo.DB.Exec(`INSERT INTO course_instructors(course_row_id, instructor_id) VALUES (?, ?)`, courseID, instructorID); err != nil {
+			t.Fatalf("link instructor %d: %v", i, err)
+		}
+	}
+}
+
+func TestFindCourses_Cursor(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	seedRatedCourses(t, repo, 11)
+
+	for _, sortName := range []string{"", "code", "rating", "difficulty", "num_ratings"} {
+		t.Run("sort="+sortName, func(t *testing.T) {
+			all, err := repo.FindCourses(CourseQuery{Q: "ZZCUR", Sort: sortName})
+			if err != nil {
+				t.Fatalf("FindCourses: %v", err)
+			}
+			want := courseCodes(all.Courses)
+
+			// Walk forwards two at a time...
+			var got []string
+			var pages []*CourseResults
+			cq := CourseQuery{Q: "ZZCUR", Sort: sortName, Limit: 2, SkipTotal: true}
+			for {
+				page, err := repo.FindCourses(cq)
+				if err != nil {
+					t.Fatalf("FindCourses(cursor %q): %v", cq.Cursor, err)
+				}
+				if page.Total != -1 {
+					t.Fatalf("expected Total=-1 with SkipTotal, got %d", page.Total)
+				}
+				got = append(got, courseCodes(page.Courses)...)
+				pages = append(pages, page)
+				if page.NextCursor == "" {
+					break
+				}
+				cq.Cursor = page.NextCursor
+			}
+			if strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Fatalf("forward walk = %v\nwant %v", got, want)
+			}
+			if pages[0].PrevCursor != "" {
+				t.Fatalf("first page should have no prev_cursor")
+			}
+
+			// ...then back again from the last page.
+			var back []string
+			cq.Cursor = pages[len(pages)-1].PrevCursor
+			for cq.Cursor != "" {
+				page, err := repo.FindCourses(cq)
+				if err != nil {
+					t.Fatalf("FindCourses(prev %q): %v", cq.Cursor, err)
+				}
+				if page.NextCursor == "" {
+					t.Fatalf("a page reached backwards should have a next_cursor")
+				}
+				back = append(courseCodes(page.Courses), back...)
+				cq.Cursor = page.PrevCursor
+			}
+			back = append(back, courseCodes(pages[len(pages)-1].Courses)...)
+			if strings.Join(back, ",") != strings.Join(want, ",") {
+				t.Fatalf("backward walk = %v\nwant %v", back, want)
+			}
+		})
+	}
+
+	t.Run("next page is stable when rows are inserted before it", func(t *testing.T) {
+		first, err := repo.FindCourses(CourseQuery{Q: "ZZCUR", Sort: "code", Limit: 3})
+		if err != nil {
+			t.Fatalf("FindCourses: %v", err)
+		}
+		if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, term)
+			VALUES ('ZZCUR', '0Z99', 'Inserted Early', '2025')`); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		next, err := repo.FindCourses(CourseQuery{Q: "ZZCUR", Sort: "code", Limit: 3, Cursor: first.NextCursor})
+		if err != nil {
+			t.Fatalf("FindCourses: %v", err)
+		}
+		last := first.Courses[len(first.Courses)-1]
+		if next.Courses[0].CourseNumber <= last.CourseNumber {
+			t.Fatalf("page 2 starts at %s, not after %s", next.Courses[0].CourseNumber, last.CourseNumber)
+		}
+	})
+
+	t.Run("invalid or mismatched cursor", func(t *testing.T) {
+		page, err := repo.FindCourses(CourseQuery{Q: "ZZCUR", Sort: "code", Limit: 2})
+		if err != nil {
+			t.Fatalf("FindCourses: %v", err)
+		}
+		for _, cq := range []CourseQuery{
+			{Q: "ZZCUR", Limit: 2, Cursor: "not a cursor"},
+			{Q: "ZZCUR", Limit: 2, Sort: "rating", Cursor: page.NextCursor},
+		} {
+			if _, err := repo.FindCourses(cq); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("expected ErrInvalidCursor for %+v, got %v", cq, err)
+			}
+		}
+	})
+}
+
+func TestFindCourses_CursorSections(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	// Sections of one course in one term tie on every key but the row id.
+	for _, section := range []string{"C02", "C01", "C03"} {
+		if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, term, section)
+			VALUES ('ZZSEC', '1A03', 'Sectioned', '2025 Fall', ?)`, section); err != nil {
+			t.Fatalf("seed %s: %v", section, err)
+		}
+	}
+
+	seen := map[int]bool{}
+	cq := CourseQuery{Q: "ZZSEC", Sort: "code", Limit: 1}
+	for {
+		page, err := repo.FindCourses(cq)
+		if err != nil {
+			t.Fatalf("FindCourses(cursor %q): %v", cq.Cursor, err)
+		}
+		for _, c := range page.Courses {
+			if seen[c.ID] {
+				t.Fatalf("course %d repeated", c.ID)
+			}
+			seen[c.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cq.Cursor = page.NextCursor
+	}
+	if len(seen) != 3 {
+		t.Fatalf("walked %d sections, want 3", len(seen))
+	}
+}
+
+func TestFindInstructors_Cursor(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	// Two instructors share a display name; name_normalized tells them apart.
+	for _, n := range [][2]string{{"Ada", "ada"}, {"Bo", "bo"}, {"Bo", "bo-2"}, {"Cy", "cy"}, {"Di", "di"}} {
+		if _, err := repo.DB.Exec(`INSERT INTO instructors(name, name_normalized) VALUES (?, ?)`, n[0], n[1]); err != nil {
+			t.Fatalf("seed %v: %v", n, err)
+		}
+	}
+
+	var got []string
+	iq := InstructorQuery{Limit: 2}
+	for i := 0; ; i++ {
+		page, err := repo.FindInstructors(iq)
+		if err != nil {
+			t.Fatalf("FindInstructors: %v", err)
+		}
+		if page.Total != 5 {
+			t.Fatalf("expected total=5, got %d", page.Total)
+		}
+		if (i == 0) != (page.PrevCursor == "") {
+			t.Fatalf("page %d: unexpected prev_cursor %q", i, page.PrevCursor)
+		}
+		for _, in := range page.Instructors {
+			got = append(got, in.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		iq.Cursor = page.NextCursor
+	}
+	if strings.Join(got, ",") != "Ada,Bo,Bo,Cy,Di" {
+		t.Fatalf("walk = %v", got)
+	}
+
+	if _, err := repo.FindInstructors(InstructorQuery{Limit: 2, Cursor: "%%%"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	// emailMaxAttempts is how many deliveries are tried before a message is
+	// dead-lettered. With emailBackoff that spans roughly a day.
+	emailMaxAttempts = 12
+	// emailBatchSize caps how many messages one drain pass sends.
+	emailBatchSize = 25
+	// emailClaimLease is how long a claimed message is reserved for the
+	// worker sending it before another worker may retry it.
+	emailClaimLease = 5 * time.Minute
+)
+
+// emailBackoff returns the delay before retry number attempt (1-based):
+// 1m, 2m, 4m, … capped at 6h.
+func emailBackoff(attempt int) time.Duration {
+	d := time.Minute
+	for i := 1; i < attempt && d < 6*time.Hour; i++ {
+		d *= 2
+	}
+	if d > 6*time.Hour {
+		d = 6 * time.Hour
+	}
+	return d
+}
+
+// EmailDrainResult summarises one pass over the queue.
+type EmailDrainResult struct {
+	Sent    int `json:"sent"`
+	Retried int `json:"retried"`
+	Dead    int `json:"dead"`
+}
+
+// DrainEmailQueue sends every message that is currently due, in batches,
+// until the queue has nothing due. Failed sends are rescheduled with
+// exponential backoff or dead-lettered after emailMaxAttempts.
+func DrainEmailQueue(repo *Repository, mailer Mailer) (EmailDrainResult, error) {
+	var res EmailDrainResult
+	for {
+		batch, err := repo.ClaimDueEmails(emailBatchSize, emailClaimLease)
+		if err != nil {
+			return res, err
+		}
+		for _, e := range batch {
+			sendErr := mailer.Send(e.Message)
+			if sendErr == nil {
+				if err := repo.MarkEmailSent(e.EmailID); err != nil {
+					return res, err
+				}
+				res.Sent++
+				continue
+			}
+
+			attempt := e.Attempts + 1
+			var retryAt *time.Time
+			if attempt < emailMaxAttempts {
+				t := time.Now().Add(emailBackoff(attempt))
+				retryAt = &t
+				res.Retried++
+			} else {
+				res.Dead++
+			}
+			log.Printf("[email] send #%d to %s failed (attempt %d): %v", e.EmailID, e.To, attempt, sendErr)
+			if err := repo.MarkEmailFailed(e.EmailID, sendErr, retryAt); err != nil {
+				return res, err
+			}
+		}
+		if len(batch) < emailBatchSize {
+			return res, nil
+		}
+	}
+}
+
+// RunEmailWorker drains the queue every interval until ctx is cancelled.
+// Used by cmd/api; the Lambda deployment drains on a schedule instead.
+func RunEmailWorker(ctx context.Context, repo *Repository, mailer Mailer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if res, err := DrainEmailQueue(repo, mailer); err != nil {
+			log.Printf("[email] drain error: %v", err)
+		} else if res != (EmailDrainResult{}) {
+			log.Printf("[email] drained: %d sent, %d retrying, %d dead", res.Sent, res.Retried, res.Dead)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// EmailWorkerInterval reads EMAIL_WORKER_INTERVAL (a Go duration such as
+// "30s"), defaulting to 10s.
+func EmailWorkerInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("EMAIL_WORKER_INTERVAL")); err == nil && d > 0 {
+		return d
+	}
+	return 10 * time.Second
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailBackoff(t *testing.T) {
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{5, 16 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, 6 * time.Hour},
+		{50, 6 * time.Hour},
+	}
+	for _, c := range cases {
+		if got := emailBackoff(c.attempt); got != c.want {
+			t.Errorf("emailBackoff(%d) = %v, want %v", c.attempt, got, c.want)
+		}
+	}
+}
+
+func TestEmailQueue(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	t.Run("feedback handler queues and worker delivers", func(t *testing.T) {
+		t.Setenv("FEEDBACK_TO", "ops@example.com")
+		req := httptest.NewRequest("POST", "/api/feedback", strings.NewReader(`{"message":"Great app","page":"/search"}`))
+		rr := httptest.NewRecorder()
+		FeedbackHandler(repo)(rr, req)
+		if rr.Code != 202 {
+			t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		mailer := &MemoryMailer{}
+		res, err := DrainEmailQueue(repo, mailer)
+		if err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+		sent := mailer.Sent()
+		if res.Sent != 1 || len(sent) != 1 || sent[0].To != "ops@example.com" || !strings.Contains(sent[0].Text, "Great app") {
+			t.Fatalf("unexpected drain %+v / sent %+v", res, sent)
+		}
+
+		// Nothing left to send.
+		if res, _ := DrainEmailQueue(repo, mailer); res.Sent != 0 {
+			t.Fatalf("expected empty queue, sent %d", res.Sent)
+		}
+	})
+
+	t.Run("failed send backs off then dead-letters", func(t *testing.T) {
+		id, err := repo.EnqueueEmail(Message{To: "a@example.com", Subject: "s", Text: "t"})
+		if err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+		failing := &MemoryMailer{Err: errors.New("connection refused")}
+
+		res, err := DrainEmailQueue(repo, failing)
+		if err != nil || res.Retried != 1 {
+			t.Fatalf("expected 1 retry, got %+v (err %v)", res, err)
+		}
+		e, _ := repo.GetOutboundEmail(id)
+		if e.Status != EmailPending || e.Attempts != 1 || e.LastError == nil || *e.LastError != "connection refused" {
+			t.Fatalf("unexpected row after failure: %+v", e)
+		}
+		if !e.NextAttemptAt.After(time.Now().Add(30 * time.Second)) {
+			t.Fatalf("expected next attempt about a minute out, got %v", e.NextAttemptAt)
+		}
+
+		// Not due yet, so another pass does nothing.
+		if res, _ := DrainEmailQueue(repo, failing); res != (EmailDrainResult{}) {
+			t.Fatalf("expected no work before backoff elapses, got %+v", res)
+		}
+
+		// Fast-forward to the final attempt.
+		repo.DB.Exec(`UPDATE outbound_emails SET attempts = ?, next_attempt_at = ? WHERE email_id = ?`,
+			emailMaxAttempts-1, time.Now().Add(-time.Second), id)
+		if res, _ := DrainEmailQueue(repo, failing); res.Dead != 1 {
+			t.Fatalf("expected dead-letter, got %+v", res)
+		}
+		if e, _ := repo.GetOutboundEmail(id); e.Status != EmailDead {
+			t.Fatalf("expected dead status, got %s", e.Status)
+		}
+
+		stuck, total, err := repo.ListOutboundEmails("stuck", 0, 0)
+		if err != nil || total != 1 || stuck[0].EmailID != id {
+			t.Fatalf("expected message in stuck list, got %+v (err %v)", stuck, err)
+		}
+	})
+
+	t.Run("expired lease is reclaimed", func(t *testing.T) {
+		id, _ := repo.EnqueueEmail(Message{To: "b@example.com", Subject: "s", Text: "t"})
+		claimed, err := repo.ClaimDueEmails(10, time.Hour)
+		if err != nil || len(claimed) != 1 || claimed[0].EmailID != id {
+			t.Fatalf("expected to claim #%d, got %+v (err %v)", id, claimed, err)
+		}
+		if again, _ := repo.ClaimDueEmails(10, time.Hour); len(again) != 0 {
+			t.Fatalf("claimed message was handed out twice: %+v", again)
+		}
+
+		repo.DB.Exec(`UPDATE outbound_emails SET next_attempt_at = ? WHERE email_id = ?`, time.Now().Add(-time.Second), id)
+		if again, _ := repo.ClaimDueEmails(10, time.Hour); len(again) != 1 {
+			t.Fatalf("expected expired lease to be reclaimed, got %+v", again)
+		}
+	})
+}
+
+func TestAdminEmailRoutes(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+	_, adminToken := seedUser(t, repo, "admin@example.com", RoleAdmin)
+
+	id, _ := repo.EnqueueEmail(Message{To: "a@example.com", Subject: "s", Text: "t"})
+	repo.MarkEmailFailed(id, errors.New("boom"), nil)
+
+	req := httptest.NewRequest("GET", "/api/admin/emails", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != 200 || !strings.Contains(rr.Body.String(), `"status":"dead"`) {
+		t.Fatalf("expected dead email in listing, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/admin/emails/"+strconv.Itoa(id)+"/retry", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if e, _ := repo.GetOutboundEmail(id); e.Status != EmailPending || e.Attempts != 0 {
+		t.Fatalf("expected message requeued, got %+v", e)
+	}
+}
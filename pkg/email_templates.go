@@ -0,0 +1,122 @@
+package pkg
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Email templates live in templates/email and are compiled into the binary so
+// the Lambda build needs no extra files. Each email has a <name>.txt.tmpl
+// plain-text body and a <name>.html.tmpl that fills in the blocks of
+// layout.html.tmpl ("title", "body", "footer").
+//
+//go:embed templates/email/*.tmpl
+var emailTemplateFS embed.FS
+
+// emailTimestampFormat is how send times are shown inside email bodies.
+const emailTimestampFormat = "Mon, Jan 2 2006 · 15:04 MST"
+
+// renderEmail executes the named template pair and returns a Message with
+// both bodies filled in. The caller sets To, ReplyTo and Subject.
+func renderEmail(name string, data interface{}) (Message, error) {
+	txt, err := texttemplate.ParseFS(emailTemplateFS, "templates/email/"+name+".txt.tmpl")
+	if err != nil {
+		return Message{}, fmt.Errorf("parse %s text template: %w", name, err)
+	}
+	html, err := htmltemplate.ParseFS(emailTemplateFS, "templates/email/layout.html.tmpl", "templates/email/"+name+".html.tmpl")
+	if err != nil {
+		return Message{}, fmt.Errorf("parse %s html template: %w", name, err)
+	}
+
+	var textBuf, htmlBuf bytes.Buffer
+	if err := txt.Execute(&textBuf, data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := html.ExecuteTemplate(&htmlBuf, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+	return Message{Text: textBuf.String(), HTML: strings.TrimSpace(htmlBuf.String())}, nil
+}
+
+// FeedbackEmailData feeds the feedback templates.
+type FeedbackEmailData struct {
+	Timestamp string
+	Email     string
+	Page      string
+	Message   string
+}
+
+// PasswordResetEmailData feeds the password_reset templates.
+type PasswordResetEmailData struct {
+	Timestamp string
+	Name      string
+	Email     string
+	ResetURL  string
+}
+
+// AdvisorInvitationEmailData feeds the advisor_invitation templates.
+type AdvisorInvitationEmailData struct {
+	Timestamp    string
+	Name         string
+	AdvisorName  string
+	AdvisorEmail string
+	AdvisorsURL  string
+}
+
+// feedbackEmail builds the message sent to FEEDBACK_TO for a submission.
+func feedbackEmail(to string, req FeedbackRequest, now time.Time) (Message, error) {
+	msg, err := renderEmail("feedback", FeedbackEmailData{
+		Timestamp: now.Format(emailTimestampFormat),
+		Email:     req.Email,
+		Page:      notEmpty(req.Page, "unknown"),
+		Message:   req.Message,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	msg.To = to
+	// The address is whatever the submitter typed; only reply to a real one.
+	if addr, err := parseMailAddress(req.Email); err == nil {
+		msg.ReplyTo = addr.Address
+	}
+	msg.Subject = fmt.Sprintf("MacTrack Feedback — %s", now.Format("Jan 2, 2006 15:04 MST"))
+	return msg, nil
+}
+
+// passwordResetEmail builds the reset-link message for a user.
+func passwordResetEmail(toEmail, displayName, resetURL string, now time.Time) (Message, error) {
+	msg, err := renderEmail("password_reset", PasswordResetEmailData{
+		Timestamp: now.Format(emailTimestampFormat),
+		Name:      notEmpty(displayName, "there"),
+		Email:     toEmail,
+		ResetURL:  resetURL,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	msg.To = toEmail
+	msg.Subject = "MacTrack — Reset Your Password"
+	return msg, nil
+}
+
+// advisorInvitationEmail tells a student an advisor has invited them.
+func advisorInvitationEmail(link AdvisorLink, advisorsURL string, now time.Time) (Message, error) {
+	msg, err := renderEmail("advisor_invitation", AdvisorInvitationEmailData{
+		Timestamp:    now.Format(emailTimestampFormat),
+		Name:         notEmpty(link.StudentName, "there"),
+		AdvisorName:  notEmpty(link.AdvisorName, "An advisor"),
+		AdvisorEmail: link.AdvisorEmail,
+		AdvisorsURL:  advisorsURL,
+	})
+	if err != nil {
+		return Message{}, err
+	}
+	msg.To = link.StudentEmail
+	msg.Subject = "MacTrack — " + notEmpty(link.AdvisorName, "An advisor") + " invited you to share your plan"
+	return msg, nil
+}
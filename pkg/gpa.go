@@ -0,0 +1,437 @@
+package pkg
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Grading scales. Grades are stored as entered: a McMaster letter grade
+// ("A-"), a percentage ("87" or "87%"), or a notation that carries no
+// grade points (P, COM, W, ...). ParseGrade reads any of them onto the
+// McMaster letter bands, and each GradeScale turns a letter into grade
+// points, so a GPA can be reported on any scale from the same grades.
+
+// letterBands are the McMaster letter grades, best first, with the lowest
+// percentage that earns each.
+var letterBands = []struct {
+	Letter     string
+	MinPercent float64
+}{
+	{"A+", 90}, {"A", 85}, {"A-", 80},
+	{"B+", 77}, {"B", 73}, {"B-", 70},
+	{"C+", 67}, {"C", 63}, {"C-", 60},
+	{"D+", 57}, {"D", 53}, {"D-", 50},
+	{"F", 0},
+}
+
+// GradeScale maps McMaster letter grades to grade points.
+type GradeScale struct {
+	Key    string             `json:"key"`
+	Name   string             `json:"name"`
+	Max    float64            `json:"max"`
+	Points map[string]float64 `json:"points"`
+	// UsePercent makes percentage grades count as themselves rather than
+	// as their letter's points.
+	UsePercent bool `json:"-"`
+}
+
+// Grade scale keys, for ?scale=.
+const (
+	ScaleMcMaster12 = "mcmaster12"
+	ScaleOMSAS4     = "omsas4"
+	ScalePercentage = "percentage"
+)
+
+var (
+	// McMaster12 is McMaster's 12-point scale, used on transcripts.
+	McMaster12 = &GradeScale{
+		Key: ScaleMcMaster12, Name: "McMaster 12-point", Max: 12,
+		Points: map[string]float64{
+			"A+": 12, "A": 11, "A-": 10,
+			"B+": 9, "B": 8, "B-": 7,
+			"C+": 6, "C": 5, "C-": 4,
+			"D+": 3, "D": 2, "D-": 1,
+			"F": 0,
+		},
+	}
+
+	// OMSAS4 is the OMSAS conversion of McMaster letter grades to 4.0,
+	// used by Ontario medical schools (and commonly asked for elsewhere).
+	OMSAS4 = &GradeScale{
+		Key: ScaleOMSAS4, Name: "OMSAS 4.0", Max: 4,
+		Points: map[string]float64{
+			"A+": 4.0, "A": 3.9, "A-": 3.7,
+			"B+": 3.3, "B": 3.0, "B-": 2.7,
+			"C+": 2.3, "C": 2.0, "C-": 1.7,
+			"D+": 1.3, "D": 1.0, "D-": 0.7,
+			"F": 0.0,
+		},
+	}
+
+	// Percentage averages percentage grades as entered; a letter grade
+	// counts as the middle of its band.
+	Percentage = &GradeScale{
+		Key: ScalePercentage, Name: "Percentage", Max: 100, UsePercent: true,
+		Points: map[string]float64{
+			"A+": 95, "A": 87, "A-": 82,
+			"B+": 78, "B": 74.5, "B-": 71,
+			"C+": 68, "C": 64.5, "C-": 61,
+			"D+": 58, "D": 54.5, "D-": 51,
+			"F": 24.5,
+		},
+	}
+
+	// GradeScales lists every scale GPAs are reported on, McMaster's first.
+	GradeScales = []*GradeScale{McMaster12, OMSAS4, Percentage}
+)
+
+// LookupGradeScale returns the scale with the given key, or nil.
+func LookupGradeScale(key string) *GradeScale {
+	for _, s := range GradeScales {
+		if s.Key == key {
+			return s
+		}
+	}
+	return nil
+}
+
+// Letter returns the letter grade whose points are nearest to points on
+// this scale. A value exactly halfway between two letters gets the higher.
+// On a percentage scale it's the letter whose band the average falls in.
+func (s *GradeScale) Letter(points float64) string {
+	if s.UsePercent {
+		return LetterForPercent(points)
+	}
+	best, bestDiff := "", math.Inf(1)
+	for _, b := range letterBands {
+		// Letters are best first, so a tie (within rounding) keeps the higher.
+		if diff := math.Abs(s.Points[b.Letter] - points); diff < bestDiff-1e-9 {
+			best, bestDiff = b.Letter, diff
+		}
+	}
+	return best
+}
+
+// GradePoints returns g's points on this scale; ok is false if g doesn't
+// count towards a GPA.
+func (s *GradeScale) GradePoints(g Grade) (points float64, ok bool) {
+	if !g.Counts() {
+		return 0, false
+	}
+	if s.UsePercent && g.Percent != nil {
+		return *g.Percent, true
+	}
+	return s.Points[g.Letter], true
+}
+
+// Reasons a grade doesn't count towards the GPA.
+const (
+	GradeExcludedPassFail     = "pass/fail" // P, COM, CR, NC: units only
+	GradeExcludedNoCredit     = "no grade"  // withdrawn, in progress, audit, ...
+	GradeExcludedUnrecognised = "unrecognised grade"
+)
+
+// nonGPAGrades are the notations that carry no grade points.
+var nonGPAGrades = map[string]string{
+	"P": GradeExcludedPassFail, "PASS": GradeExcludedPassFail,
+	"COM": GradeExcludedPassFail, "CR": GradeExcludedPassFail,
+	"NC": GradeExcludedPassFail, "FAIL": GradeExcludedPassFail,
+	"W": GradeExcludedNoCredit, "WD": GradeExcludedNoCredit,
+	"IP": GradeExcludedNoCredit, "INC": GradeExcludedNoCredit,
+	"DEF": GradeExcludedNoCredit, "AUD": GradeExcludedNoCredit,
+}
+
+// Grade is a grade as entered, read onto the McMaster letter bands.
+type Grade struct {
+	Raw     string   `json:"raw"`
+	Letter  string   `json:"letter,omitempty"`  // "" unless it counts towards the GPA
+	Percent *float64 `json:"percent,omitempty"` // set for percentage grades
+	// Excluded says why the grade doesn't count towards the GPA.
+	Excluded string `json:"excluded,omitempty"`
+}
+
+// Counts reports whether the grade counts towards the GPA.
+func (g Grade) Counts() bool { return g.Letter != "" }
+
+// Passed reports whether the grade earns credit (a D- or better, or a
+// pass).
+func (g Grade) Passed() bool {
+	switch {
+	case g.Counts():
+		return g.Letter != "F"
+	case g.Excluded == GradeExcludedPassFail:
+		r := strings.ToUpper(g.Raw)
+		return r != "NC" && r != "FAIL"
+	}
+	return false
+}
+
+// ParseGrade reads a grade as entered: a letter grade (any case), a
+// percentage from 0 to 100 with or without a "%", or one of the non-GPA
+// notations.
+func ParseGrade(raw string) Grade {
+	g := Grade{Raw: strings.TrimSpace(raw)}
+	s := strings.ToUpper(g.Raw)
+	if _, ok := McMaster12.Points[s]; ok {
+		g.Letter = s
+		return g
+	}
+	if reason, ok := nonGPAGrades[s]; ok {
+		g.Excluded = reason
+		return g
+	}
+	if pct, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(s, "%")), 64); err == nil && pct >= 0 && pct <= 100 {
+		g.Percent = &pct
+		g.Letter = LetterForPercent(pct)
+		return g
+	}
+	g.Excluded = GradeExcludedUnrecognised
+	return g
+}
+
+// LetterForPercent returns the McMaster letter grade for a percentage.
+func LetterForPercent(pct float64) string {
+	for _, b := range letterBands {
+		if pct >= b.MinPercent {
+			return b.Letter
+		}
+	}
+	return "F"
+}
+
+// GradedUnits is one graded course's contribution to a GPA.
+type GradedUnits struct {
+	Units int
+	Grade Grade
+}
+
+// ScaleGPA is a GPA on one scale.
+type ScaleGPA struct {
+	Scale  string  `json:"scale"`
+	Name   string  `json:"name"`
+	GPA    float64 `json:"gpa"`
+	Max    float64 `json:"max"`
+	Letter string  `json:"letter_grade"`
+}
+
+// ComputeGPA is the unit-weighted average of the courses' grade points on
+// scale. units is how many units counted; ok is false if none did.
+func ComputeGPA(courses []GradedUnits, scale *GradeScale) (gpa float64, units int, ok bool) {
+	total := 0.0
+	for _, c := range courses {
+		points, counts := scale.GradePoints(c.Grade)
+		if !counts || c.Units <= 0 {
+			continue
+		}
+		total += points * float64(c.Units)
+		units += c.Units
+	}
+	if units == 0 {
+		return 0, 0, false
+	}
+	return total / float64(units), units, true
+}
+
+// GPAOnScales computes the GPA on every scale in GradeScales.
+func GPAOnScales(courses []GradedUnits) (scales []ScaleGPA, units int, ok bool) {
+	scales = []ScaleGPA{}
+	for _, s := range GradeScales {
+		gpa, n, counted := ComputeGPA(courses, s)
+		if !counted {
+			return []ScaleGPA{}, 0, false
+		}
+		scales = append(scales, ScaleGPA{
+			Scale: s.Key, Name: s.Name, GPA: gpa, Max: s.Max, Letter: s.Letter(gpa),
+		})
+		units = n
+	}
+	return scales, units, true
+}
+
+// Reasons a completed plan item doesn't count towards the GPA, besides its
+// grade's.
+const (
+	GradeExcludedCustom    = "custom course" // not a McMaster course
+	GradeExcludedNotGraded = "no grade recorded"
+)
+
+// DefaultLastUnits is how many of the most recent units a "last N units"
+// GPA covers unless asked otherwise: two full-time years, as most graduate
+// and professional programs ask for.
+const DefaultLastUnits = 60
+
+// GPAFigure is a GPA over some set of courses on one scale.
+type GPAFigure struct {
+	GPA       float64 `json:"gpa"`
+	Letter    string  `json:"letter_grade"`
+	Units     int     `json:"units"`
+	HasGrades bool    `json:"has_grades"`
+}
+
+func gpaFigure(courses []GradedCourse, scale *GradeScale) GPAFigure {
+	var f GPAFigure
+	f.GPA, f.Units, f.HasGrades = ComputeGPA(gradedUnits(courses), scale)
+	if f.HasGrades {
+		f.Letter = scale.Letter(f.GPA)
+	}
+	return f
+}
+
+// TermGPA is one term's GPA and the cumulative GPA at the end of it.
+type TermGPA struct {
+	YearIndex int    `json:"year_index"`
+	Season    string `json:"season"`
+	GPAFigure
+	Cumulative *float64 `json:"cumulative_gpa"`
+}
+
+// YearGPA is one year's sessional average.
+type YearGPA struct {
+	YearIndex int `json:"year_index"`
+	GPAFigure
+}
+
+// SubjectGPA is the GPA over one subject's courses.
+type SubjectGPA struct {
+	Subject string `json:"subject"`
+	GPAFigure
+}
+
+// RestrictedGPA is the GPA over the courses in a set of subjects, e.g. a
+// major GPA.
+type RestrictedGPA struct {
+	Subjects []string `json:"subjects"`
+	GPAFigure
+}
+
+// LastUnitsGPA is the GPA over the most recent graded units. The course that
+// reaches the requested units counts in full, so Units may be a little over;
+// it's under when there aren't that many graded units yet.
+type LastUnitsGPA struct {
+	Requested int `json:"requested_units"`
+	GPAFigure
+	FromYearIndex int    `json:"from_year_index,omitempty"`
+	FromSeason    string `json:"from_season,omitempty"`
+}
+
+// ExcludedCourse is a completed plan item left out of the GPA.
+type ExcludedCourse struct {
+	PlanItemID   int    `json:"plan_item_id"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	YearIndex    int    `json:"year_index"`
+	Season       string `json:"season"`
+	Grade        string `json:"grade"`
+	Reason       string `json:"reason"`
+}
+
+// GPABreakdown is the cumulative GPA on one scale and the ways it breaks
+// down: by term (with the cumulative trend), by year, by subject, over a
+// restricted set of subjects, and over the last N units.
+type GPABreakdown struct {
+	Scale string `json:"scale"`
+	GPAFigure
+	Scales     []ScaleGPA       `json:"scales"`
+	Terms      []TermGPA        `json:"terms"`
+	Years      []YearGPA        `json:"years"`
+	BySubject  []SubjectGPA     `json:"by_subject"`
+	Restricted *RestrictedGPA   `json:"subject_gpa,omitempty"`
+	LastUnits  LastUnitsGPA     `json:"last_units"`
+	Excluded   []ExcludedCourse `json:"excluded"`
+}
+
+// excludedReason says why a completed course doesn't count towards the GPA,
+// or "" if it does.
+func excludedReason(c GradedCourse) string {
+	switch {
+	case c.IsCustom:
+		return GradeExcludedCustom
+	case c.Grade.Raw == "":
+		return GradeExcludedNotGraded
+	}
+	return c.Grade.Excluded
+}
+
+// BuildGPABreakdown breaks down the GPA over completed, which must be in
+// academic order as GetCompletedCourses returns it. subjects, if any,
+// restrict the subject GPA; lastUnits is N for the last N units GPA.
+func BuildGPABreakdown(completed []GradedCourse, scale *GradeScale, subjects []string, lastUnits int) GPABreakdown {
+	b := GPABreakdown{
+		Scale:     scale.Key,
+		Terms:     []TermGPA{},
+		Years:     []YearGPA{},
+		BySubject: []SubjectGPA{},
+		Excluded:  []ExcludedCourse{},
+	}
+
+	var counted []GradedCourse
+	for _, c := range completed {
+		if reason := excludedReason(c); reason != "" {
+			b.Excluded = append(b.Excluded, ExcludedCourse{
+				PlanItemID: c.PlanItemID, Subject: c.Subject, CourseNumber: c.CourseNumber,
+				YearIndex: c.YearIndex, Season: c.Season, Grade: c.Grade.Raw, Reason: reason,
+			})
+			continue
+		}
+		counted = append(counted, c)
+	}
+	b.GPAFigure = gpaFigure(counted, scale)
+	b.Scales, _, _ = GPAOnScales(gradedUnits(counted))
+
+	// Terms and years: every term and year with a completed item, so a term
+	// of only pass/fail courses still shows up, without a GPA.
+	var term, year, soFar []GradedCourse
+	for i, c := range completed {
+		if excludedReason(c) == "" {
+			term = append(term, c)
+			year = append(year, c)
+			soFar = append(soFar, c)
+		}
+		last := i == len(completed)-1
+		if last || completed[i+1].YearIndex != c.YearIndex || completed[i+1].Season != c.Season {
+			t := TermGPA{YearIndex: c.YearIndex, Season: c.Season, GPAFigure: gpaFigure(term, scale)}
+			if cum := gpaFigure(soFar, scale); cum.HasGrades {
+				t.Cumulative = &cum.GPA
+			}
+			b.Terms = append(b.Terms, t)
+			term = nil
+		}
+		if last || completed[i+1].YearIndex != c.YearIndex {
+			b.Years = append(b.Years, YearGPA{YearIndex: c.YearIndex, GPAFigure: gpaFigure(year, scale)})
+			year = nil
+		}
+	}
+
+	bySubject := map[string][]GradedCourse{}
+	for _, c := range counted {
+		bySubject[c.Subject] = append(bySubject[c.Subject], c)
+	}
+	for subject, cs := range bySubject {
+		b.BySubject = append(b.BySubject, SubjectGPA{Subject: subject, GPAFigure: gpaFigure(cs, scale)})
+	}
+	sort.Slice(b.BySubject, func(i, j int) bool { return b.BySubject[i].Subject < b.BySubject[j].Subject })
+
+	if len(subjects) > 0 {
+		var cs []GradedCourse
+		for _, s := range subjects {
+			cs = append(cs, bySubject[s]...)
+		}
+		b.Restricted = &RestrictedGPA{Subjects: subjects, GPAFigure: gpaFigure(cs, scale)}
+	}
+
+	// Last N units: walk back from the most recent course until there are
+	// enough.
+	b.LastUnits.Requested = lastUnits
+	start, units := len(counted), 0
+	for start > 0 && units < lastUnits {
+		start--
+		units += counted[start].Units
+	}
+	b.LastUnits.GPAFigure = gpaFigure(counted[start:], scale)
+	if start < len(counted) {
+		b.LastUnits.FromYearIndex, b.LastUnits.FromSeason = counted[start].YearIndex, counted[start].Season
+	}
+	return b
+}
@@ -0,0 +1,295 @@
+package pkg
+
+import (
+	"encoding/json"
+	"math"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestParseGrade(t *testing.T) {
+	tests := []struct {
+		raw      string
+		letter   string
+		percent  float64 // 0: not a percentage
+		excluded string
+		passed   bool
+	}{
+		{"A+", "A+", 0, "", true},
+		{" b- ", "B-", 0, "", true},
+		{"F", "F", 0, "", false},
+		{"87", "A", 87, "", true},
+		{"89.5%", "A", 89.5, "", true},
+		{"90", "A+", 90, "", true},
+		{"49", "F", 49, "", false},
+		{"50", "D-", 50, "", true},
+		{"100", "A+", 100, "", true},
+		{"P", "", 0, GradeExcludedPassFail, true},
+		{"com", "", 0, GradeExcludedPassFail, true},
+		{"CR", "", 0, GradeExcludedPassFail, true},
+		{"NC", "", 0, GradeExcludedPassFail, false},
+		{"W", "", 0, GradeExcludedNoCredit, false},
+		{"IP", "", 0, GradeExcludedNoCredit, false},
+		{"101", "", 0, GradeExcludedUnrecognised, false},
+		{"-5", "", 0, GradeExcludedUnrecognised, false},
+		{"E", "", 0, GradeExcludedUnrecognised, false},
+	}
+	for _, tt := range tests {
+		g := ParseGrade(tt.raw)
+		if g.Letter != tt.letter || g.Excluded != tt.excluded || g.Passed() != tt.passed {
+			t.Errorf("ParseGrade(%q) = %+v (passed %v); want letter %q excluded %q passed %v",
+				tt.raw, g, g.Passed(), tt.letter, tt.excluded, tt.passed)
+		}
+		if (tt.percent != 0) != (g.Percent != nil) || (g.Percent != nil && *g.Percent != tt.percent) {
+			t.Errorf("ParseGrade(%q).Percent = %v, want %v", tt.raw, g.Percent, tt.percent)
+		}
+	}
+}
+
+// courses builds 3-unit courses with the given grades.
+func courses(grades ...string) []GradedUnits {
+	out := make([]GradedUnits, len(grades))
+	for i, g := range grades {
+		out[i] = GradedUnits{Units: 3, Grade: ParseGrade(g)}
+	}
+	return out
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestMcMaster12Scale(t *testing.T) {
+	gpa, units, ok := ComputeGPA(courses("A+", "B", "P", "W"), McMaster12)
+	if !ok || units != 6 || !approx(gpa, 10) {
+		t.Errorf("ComputeGPA = %v, %d, %v; want 10 over 6 units (P and W don't count)", gpa, units, ok)
+	}
+
+	// Unit weighting: a 6-unit F outweighs a 3-unit A+.
+	weighted := []GradedUnits{{Units: 3, Grade: ParseGrade("A+")}, {Units: 6, Grade: ParseGrade("F")}}
+	if gpa, _, _ := ComputeGPA(weighted, McMaster12); !approx(gpa, 4) {
+		t.Errorf("weighted GPA = %v, want 4", gpa)
+	}
+
+	// A percentage counts as its letter.
+	if gpa, _, _ := ComputeGPA(courses("87", "72%"), McMaster12); !approx(gpa, 9) {
+		t.Errorf("percentage grades on 12-point = %v, want (11+7)/2 = 9", gpa)
+	}
+
+	if _, _, ok := ComputeGPA(courses("P", "COM", "IP"), McMaster12); ok {
+		t.Error("pass/fail only should have no GPA")
+	}
+
+	// Ties go to the higher letter.
+	for _, tt := range []struct {
+		gpa  float64
+		want string
+	}{
+		{12, "A+"}, {11.6, "A+"}, {11.5, "A+"}, {11.4, "A"}, {8.5, "B+"}, {7.49, "B-"}, {0.4, "F"}, {0.5, "D-"},
+	} {
+		if got := McMaster12.Letter(tt.gpa); got != tt.want {
+			t.Errorf("Letter(%v) = %q, want %q", tt.gpa, got, tt.want)
+		}
+	}
+}
+
+func TestOMSAS4Scale(t *testing.T) {
+	gpa, _, _ := ComputeGPA(courses("A+", "A", "A-", "B+"), OMSAS4)
+	if !approx(gpa, (4.0+3.9+3.7+3.3)/4) {
+		t.Errorf("OMSAS GPA = %v", gpa)
+	}
+	for _, tt := range []struct {
+		gpa  float64
+		want string
+	}{
+		{4.0, "A+"}, {3.95, "A+"}, {3.8, "A"}, {3.5, "A-"}, {3.0, "B"}, {0.35, "D-"}, {0.34, "F"},
+	} {
+		if got := OMSAS4.Letter(tt.gpa); got != tt.want {
+			t.Errorf("OMSAS4.Letter(%v) = %q, want %q", tt.gpa, got, tt.want)
+		}
+	}
+	// Every McMaster letter has an OMSAS value, and the order is the same.
+	prev := math.Inf(1)
+	for _, b := range letterBands {
+		p, ok := OMSAS4.Points[b.Letter]
+		if !ok || p >= prev {
+			t.Errorf("OMSAS4 %s = %v (ok %v), previous %v", b.Letter, p, ok, prev)
+		}
+		prev = p
+	}
+}
+
+func TestPercentageScale(t *testing.T) {
+	// Percentages count as entered; a letter counts as its band's middle.
+	gpa, units, ok := ComputeGPA(courses("91", "84%", "B"), Percentage)
+	if !ok || units != 9 || !approx(gpa, (91+84+74.5)/3) {
+		t.Errorf("percentage average = %v, %d, %v", gpa, units, ok)
+	}
+	for _, tt := range []struct {
+		avg  float64
+		want string
+	}{
+		{90, "A+"}, {89.99, "A"}, {84.9, "A-"}, {50, "D-"}, {49.9, "F"},
+	} {
+		if got := Percentage.Letter(tt.avg); got != tt.want {
+			t.Errorf("Percentage.Letter(%v) = %q, want %q", tt.avg, got, tt.want)
+		}
+	}
+}
+
+func TestPassFailGrades(t *testing.T) {
+	for _, s := range GradeScales {
+		gpa, units, _ := ComputeGPA(courses("A", "P", "COM", "NC", "CR"), s)
+		if units != 3 || !approx(gpa, s.Points["A"]) {
+			t.Errorf("%s: pass/fail grades should be left out: %v over %d", s.Key, gpa, units)
+		}
+	}
+}
+
+func TestGetUserGPAHandler_Scales(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedExportPlan(t, repo) // A+ and B, both 3 units
+
+	get := func(query string) (int, map[string]interface{}) {
+		rr := httptest.NewRecorder()
+		GetUserGPAHandler(repo).ServeHTTP(rr, httptest.NewRequest("GET", "/api/users/"+strconv.Itoa(userID)+"/gpa"+query, nil))
+		var out map[string]interface{}
+		json.NewDecoder(rr.Body).Decode(&out)
+		return rr.Code, out
+	}
+
+	code, out := get("")
+	if code != 200 || out["gpa"] != 10.0 || out["letter_grade"] != "A-" || out["scale"] != ScaleMcMaster12 ||
+		out["has_grades"] != true || out["units"] != 6.0 {
+		t.Errorf("default scale: %d %v", code, out)
+	}
+	if scales, _ := out["scales"].([]interface{}); len(scales) != len(GradeScales) {
+		t.Errorf("expected a GPA on every scale: %v", out["scales"])
+	}
+
+	code, out = get("?scale=omsas4")
+	if code != 200 || !approx(out["gpa"].(float64), 3.5) || out["letter_grade"] != "A-" {
+		t.Errorf("omsas4: %d %v", code, out)
+	}
+	code, out = get("?scale=percentage")
+	if code != 200 || !approx(out["gpa"].(float64), 84.75) || out["letter_grade"] != "A-" {
+		t.Errorf("percentage: %d %v", code, out)
+	}
+	if code, _ := get("?scale=gpa5"); code != 400 {
+		t.Errorf("unknown scale: expected 400, got %d", code)
+	}
+
+	code, out = get("?subject=compsci&last_units=3")
+	restricted, _ := out["subject_gpa"].(map[string]interface{})
+	last, _ := out["last_units"].(map[string]interface{})
+	if code != 200 || restricted == nil || restricted["gpa"] != 12.0 || last == nil || last["units"] != 3.0 {
+		t.Errorf("subject and last units: %d %v", code, out)
+	}
+	if terms, _ := out["terms"].([]interface{}); len(terms) != 1 {
+		t.Errorf("terms: %v", out["terms"])
+	}
+	if code, _ := get("?last_units=0"); code != 400 {
+		t.Errorf("bad last_units: expected 400, got %d", code)
+	}
+}
+
+func TestBuildGPABreakdown(t *testing.T) {
+	c := func(id, year int, season, subject, number, grade string) GradedCourse {
+		gc := GradedCourse{PlanItemID: id, Subject: subject, CourseNumber: number, YearIndex: year, Season: season,
+			Units: UnitsFromCourseNumber(number, 3)}
+		if grade != "" {
+			gc.Grade = ParseGrade(grade)
+		}
+		return gc
+	}
+	custom := c(6, 1, "Winter", "ARTSCI", "2XX3", "A+")
+	custom.IsCustom = true
+	completed := []GradedCourse{
+		c(1, 1, "Fall", "COMPSCI", "1MD3", "A+"),
+		c(2, 1, "Fall", "MATH", "1ZA3", "C"),
+		c(3, 1, "Winter", "COMPSCI", "1XC3", "B"),
+		c(4, 1, "Winter", "PNB", "1XD3", "P"),
+		custom,
+		c(7, 1, "Summer", "KINESIOL", "1Y03", "COM"),
+		c(8, 2, "Fall", "COMPSCI", "2C03", "A"),
+		c(9, 2, "Fall", "SFWRENG", "3A06", "88"),
+		c(10, 2, "Fall", "STATS", "2D03", ""),
+		c(11, 2, "Winter", "MATH", "2ZZ3", "Q"),
+	}
+
+	b := BuildGPABreakdown(completed, McMaster12, []string{"COMPSCI", "SFWRENG"}, 9)
+
+	// Counted: A+ 3, C 3, B 3, A 3 and an 88 (A) over 6 units: (36+15+24+33+66)/18.
+	if !b.HasGrades || b.Units != 18 || !approx(b.GPA, 174.0/18) || b.Scale != ScaleMcMaster12 {
+		t.Errorf("cumulative: %+v", b.GPAFigure)
+	}
+
+	type term struct {
+		year   int
+		season string
+		gpa    float64
+		has    bool
+		cum    float64
+	}
+	want := []term{
+		{1, "Fall", 8.5, true, 8.5},
+		{1, "Winter", 8, true, 25.0 / 3},
+		{1, "Summer", 0, false, 25.0 / 3},
+		{2, "Fall", 11, true, 174.0 / 18},
+		{2, "Winter", 0, false, 174.0 / 18},
+	}
+	if len(b.Terms) != len(want) {
+		t.Fatalf("terms: %+v", b.Terms)
+	}
+	for i, w := range want {
+		got := b.Terms[i]
+		if got.YearIndex != w.year || got.Season != w.season || got.HasGrades != w.has || !approx(got.GPA, w.gpa) ||
+			got.Cumulative == nil || !approx(*got.Cumulative, w.cum) {
+			t.Errorf("term %d: %+v (cumulative %v), want %+v", i, got, got.Cumulative, w)
+		}
+	}
+
+	if len(b.Years) != 2 || !approx(b.Years[0].GPA, 25.0/3) || b.Years[0].Units != 9 ||
+		!approx(b.Years[1].GPA, 11) || b.Years[1].Units != 9 {
+		t.Errorf("sessional averages: %+v", b.Years)
+	}
+
+	if len(b.BySubject) != 3 || b.BySubject[0].Subject != "COMPSCI" || !approx(b.BySubject[0].GPA, 31.0/3) ||
+		b.BySubject[1].Subject != "MATH" || b.BySubject[2].Subject != "SFWRENG" {
+		t.Errorf("by subject: %+v", b.BySubject)
+	}
+	if b.Restricted == nil || b.Restricted.Units != 15 || !approx(b.Restricted.GPA, 159.0/15) {
+		t.Errorf("subject gpa: %+v", b.Restricted)
+	}
+
+	// Last 9 units: SFWRENG 3A06 (6) and COMPSCI 2C03 (3), from Y2 Fall.
+	if l := b.LastUnits; l.Requested != 9 || l.Units != 9 || !approx(l.GPA, 11) || l.FromYearIndex != 2 || l.FromSeason != "Fall" {
+		t.Errorf("last units: %+v", l)
+	}
+	// Last 10 units takes in the whole of the course before.
+	if l := BuildGPABreakdown(completed, McMaster12, nil, 10).LastUnits; l.Units != 12 || !approx(l.GPA, (99+24)/12.0) {
+		t.Errorf("last 10 units: %+v", l)
+	}
+
+	reasons := map[int]string{}
+	for _, e := range b.Excluded {
+		reasons[e.PlanItemID] = e.Reason
+	}
+	wantReasons := map[int]string{
+		4: GradeExcludedPassFail, 6: GradeExcludedCustom, 7: GradeExcludedPassFail,
+		10: GradeExcludedNotGraded, 11: GradeExcludedUnrecognised,
+	}
+	if len(reasons) != len(wantReasons) {
+		t.Errorf("excluded: %+v", b.Excluded)
+	}
+	for id, reason := range wantReasons {
+		if reasons[id] != reason {
+			t.Errorf("item %d excluded for %q, want %q", id, reasons[id], reason)
+		}
+	}
+
+	empty := BuildGPABreakdown(nil, OMSAS4, nil, DefaultLastUnits)
+	if empty.HasGrades || len(empty.Terms) != 0 || empty.LastUnits.HasGrades || empty.Excluded == nil {
+		t.Errorf("no courses: %+v", empty)
+	}
+}
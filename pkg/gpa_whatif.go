@@ -0,0 +1,150 @@
+package pkg
+
+// What-if GPAs: the cumulative GPA projected from hypothetical grades for
+// planned and in-progress courses, and the average needed over the rest of
+// the plan to reach a target GPA.
+
+// LetterAtLeast returns the lowest letter grade worth at least points on
+// this scale, or "" if none is. On a percentage scale a letter is worth its
+// band's midpoint, not the bottom of the band, so an average of 83 needs an
+// A (87) even though 83 is an A-; above the A+ midpoint only a percentage
+// will do.
+func (s *GradeScale) LetterAtLeast(points float64) string {
+	best := ""
+	for _, b := range letterBands {
+		if s.Points[b.Letter] >= points-1e-9 {
+			best = b.Letter
+		}
+	}
+	return best
+}
+
+// WhatIfCourse is a planned or in-progress course in a projection, with the
+// hypothetical grade given for it, if any.
+type WhatIfCourse struct {
+	PlanItemID   int    `json:"plan_item_id"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	YearIndex    int    `json:"year_index"`
+	Season       string `json:"season"`
+	Status       string `json:"status"`
+	Units        int    `json:"units"`
+	Grade        string `json:"grade,omitempty"`
+}
+
+// GPATarget is what it takes to reach a target GPA. RequiredAverage is the
+// average grade points needed over the remaining units; it's nil when no
+// planned units are left to raise the GPA with.
+type GPATarget struct {
+	TargetGPA       float64  `json:"target_gpa"`
+	RemainingUnits  int      `json:"remaining_units"`
+	RequiredAverage *float64 `json:"required_average"`
+	RequiredLetter  string   `json:"required_letter,omitempty"`
+	// Reachable is false when even the best grade in every remaining course
+	// falls short; Guaranteed is true when even an F in all of them doesn't.
+	Reachable  bool `json:"reachable"`
+	Guaranteed bool `json:"guaranteed"`
+	// Best and worst case cumulative GPAs over the remaining courses.
+	MaxGPA *float64 `json:"max_gpa"`
+	MinGPA *float64 `json:"min_gpa"`
+}
+
+// WhatIfResult is a GPA projection.
+type WhatIfResult struct {
+	Scale        string         `json:"scale"`
+	Current      GPAFigure      `json:"current"`
+	Projected    GPAFigure      `json:"projected"`
+	Hypothetical []WhatIfCourse `json:"hypothetical"`
+	// Remaining are the planned courses with no hypothetical grade, which
+	// the target is worked out over.
+	Remaining []WhatIfCourse `json:"remaining"`
+	Target    *GPATarget     `json:"target,omitempty"`
+}
+
+func whatIfCourse(c GradedCourse) WhatIfCourse {
+	return WhatIfCourse{
+		PlanItemID: c.PlanItemID, Subject: c.Subject, CourseNumber: c.CourseNumber,
+		YearIndex: c.YearIndex, Season: c.Season, Status: c.Status, Units: c.Units,
+	}
+}
+
+// ProjectGPA projects the cumulative GPA on scale from the completed courses
+// and hypothetical grades for some of the remaining ones, keyed by plan item
+// id. Custom remaining courses never count and are left out. If target is
+// set it also works out the average needed over the remaining courses
+// without a hypothetical grade.
+func ProjectGPA(completed, remaining []GradedCourse, hypothetical map[int]Grade, scale *GradeScale, target *float64) WhatIfResult {
+	res := WhatIfResult{Scale: scale.Key, Hypothetical: []WhatIfCourse{}, Remaining: []WhatIfCourse{}}
+
+	var counted []GradedCourse
+	for _, c := range completed {
+		if excludedReason(c) == "" {
+			counted = append(counted, c)
+		}
+	}
+	res.Current = gpaFigure(counted, scale)
+
+	projected := counted
+	var rest []GradedCourse
+	for _, c := range remaining {
+		if c.IsCustom {
+			continue
+		}
+		wc := whatIfCourse(c)
+		if g, ok := hypothetical[c.PlanItemID]; ok {
+			c.Grade = g
+			wc.Grade = g.Raw
+			res.Hypothetical = append(res.Hypothetical, wc)
+			if g.Counts() {
+				projected = append(projected, c)
+			}
+			continue
+		}
+		res.Remaining = append(res.Remaining, wc)
+		rest = append(rest, c)
+	}
+	res.Projected = gpaFigure(projected, scale)
+
+	if target != nil {
+		res.Target = gpaTarget(projected, rest, scale, *target)
+	}
+	return res
+}
+
+func gpaTarget(projected, rest []GradedCourse, scale *GradeScale, target float64) *GPATarget {
+	t := &GPATarget{TargetGPA: target}
+	points := 0.0
+	units := 0
+	for _, c := range projected {
+		p, _ := scale.GradePoints(c.Grade)
+		points += p * float64(c.Units)
+		units += c.Units
+	}
+	for _, c := range rest {
+		t.RemainingUnits += c.Units
+	}
+
+	if t.RemainingUnits == 0 {
+		t.Reachable = units > 0 && points/float64(units) >= target-1e-9
+		t.Guaranteed = t.Reachable
+		return t
+	}
+
+	best, worst := scale.Points[letterBands[0].Letter], scale.Points["F"]
+	if scale.UsePercent {
+		best, worst = 100, 0
+	}
+	total := float64(units + t.RemainingUnits)
+	maxGPA := (points + best*float64(t.RemainingUnits)) / total
+	minGPA := (points + worst*float64(t.RemainingUnits)) / total
+	t.MaxGPA, t.MinGPA = &maxGPA, &minGPA
+
+	required := (target*total - points) / float64(t.RemainingUnits)
+	t.RequiredAverage = &required
+	t.Reachable = required <= best+1e-9
+	t.Guaranteed = required <= worst+1e-9
+	if t.Reachable {
+		t.RequiredLetter = scale.LetterAtLeast(required)
+	}
+	return t
+}
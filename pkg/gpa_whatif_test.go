@@ -0,0 +1,153 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProjectGPA(t *testing.T) {
+	completed := []GradedCourse{
+		{PlanItemID: 1, Units: 3, Grade: ParseGrade("A+")},
+		{PlanItemID: 2, Units: 3, Grade: ParseGrade("B")},
+		{PlanItemID: 3, Units: 3, Grade: ParseGrade("P")},
+	}
+	remaining := []GradedCourse{
+		{PlanItemID: 10, Units: 3, Status: "IN_PROGRESS"},
+		{PlanItemID: 11, Units: 6, Status: "PLANNED"},
+		{PlanItemID: 12, Units: 3, Status: "PLANNED", IsCustom: true},
+	}
+	f := func(v float64) *float64 { return &v }
+
+	// A hypothetical A on the 3-unit course: (36+24+33)/9.
+	res := ProjectGPA(completed, remaining, map[int]Grade{10: ParseGrade("A")}, McMaster12, nil)
+	if !approx(res.Current.GPA, 10) || res.Current.Units != 6 || !approx(res.Projected.GPA, 93.0/9) || res.Projected.Units != 9 {
+		t.Errorf("projection: current %+v projected %+v", res.Current, res.Projected)
+	}
+	if len(res.Hypothetical) != 1 || res.Hypothetical[0].Grade != "A" || len(res.Remaining) != 1 || res.Remaining[0].PlanItemID != 11 {
+		t.Errorf("hypothetical %+v remaining %+v (custom items are left out)", res.Hypothetical, res.Remaining)
+	}
+	if res.Target != nil {
+		t.Error("no target asked for")
+	}
+
+	for _, tt := range []struct {
+		target     float64
+		required   float64
+		letter     string
+		reachable  bool
+		guaranteed bool
+	}{
+		// 93 points over 9 units now, 6 units left: (target*15 - 93)/6.
+		{11, 12, "A+", true, false},
+		{11.2, 12.5, "", false, false},
+		{10, 9.5, "A-", true, false},
+		{6, -0.5, "F", true, true},
+	} {
+		tg := ProjectGPA(completed, remaining, map[int]Grade{10: ParseGrade("A")}, McMaster12, f(tt.target)).Target
+		if tg == nil || tg.RemainingUnits != 6 || tg.RequiredAverage == nil || !approx(*tg.RequiredAverage, tt.required) ||
+			tg.RequiredLetter != tt.letter || tg.Reachable != tt.reachable || tg.Guaranteed != tt.guaranteed {
+			t.Errorf("target %v: %+v", tt.target, tg)
+			continue
+		}
+		if !approx(*tg.MaxGPA, (93+72)/15.0) || !approx(*tg.MinGPA, 93/15.0) {
+			t.Errorf("target %v: best %v worst %v", tt.target, *tg.MaxGPA, *tg.MinGPA)
+		}
+	}
+
+	// Nothing left to plan: the target is met or it isn't.
+	all := map[int]Grade{10: ParseGrade("A"), 11: ParseGrade("B")}
+	if tg := ProjectGPA(completed, remaining, all, McMaster12, f(9)).Target; !tg.Reachable || tg.RequiredAverage != nil {
+		t.Errorf("met with nothing left: %+v", tg)
+	}
+	if tg := ProjectGPA(completed, remaining, all, McMaster12, f(9.5)).Target; tg.Reachable {
+		t.Errorf("missed with nothing left: %+v", tg)
+	}
+
+	// On OMSAS, an average of 3.75 needs an A (3.9), not an A- (3.7).
+	tg := ProjectGPA(nil, remaining, nil, OMSAS4, f(3.75)).Target
+	if tg.RequiredLetter != "A" || !approx(*tg.RequiredAverage, 3.75) {
+		t.Errorf("omsas target: %+v", tg)
+	}
+	// On percentages a letter counts as its band's midpoint: 82 is an A-'s
+	// worth, but 83, still in the A- band, needs an A (87), and above the
+	// A+ midpoint (95) no letter is enough though a percentage can be.
+	for target, letter := range map[float64]string{82: "A-", 83: "A", 96: ""} {
+		tg = ProjectGPA(nil, remaining, nil, Percentage, f(target)).Target
+		if tg.RequiredLetter != letter || !tg.Reachable {
+			t.Errorf("percentage target %v: %+v", target, tg)
+		}
+	}
+}
+
+func TestGPAWhatIfHandler(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+	userID := seedExportPlan(t, repo)
+	token, err := GenerateAccessToken(userID, "export@example.com", RoleStudent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherToken := seedUser(t, repo, "other@example.com", RoleStudent)
+
+	items, _ := repo.GetPlanItems(userID)
+	ids := map[string]string{}
+	for _, it := range items {
+		ids[it.CourseNumber] = strconv.Itoa(it.PlanItemID)
+	}
+	path := "/api/users/" + strconv.Itoa(userID) + "/gpa/what-if"
+	post := func(bearer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := post(token, `{"grades":[{"plan_item_id":`+ids["1XC3"]+`,"grade":"A"}],"target_gpa":10}`)
+	if rr.Code != 200 {
+		t.Fatalf("what-if: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var res WhatIfResult
+	json.NewDecoder(rr.Body).Decode(&res)
+	if !approx(res.Current.GPA, 10) || !approx(res.Projected.GPA, 93.0/9) || res.Target == nil || !res.Target.Reachable ||
+		res.Target.RemainingUnits != 0 {
+		t.Errorf("what-if: %+v %+v", res, res.Target)
+	}
+	items, _ = repo.GetPlanItems(userID)
+	for _, it := range items {
+		if it.CourseNumber == "1XC3" && it.Grade != nil {
+			t.Error("what-if must not save grades")
+		}
+	}
+
+	// 3.5 over 6 units now; 3.6 over 9 needs a 3.8 in COMPSCI 1XC3, and 3.8
+	// needs a 4.4.
+	for target, letter := range map[string]string{"3.6": "A", "3.8": ""} {
+		rr = post(token, `{"scale":"omsas4","target_gpa":`+target+`}`)
+		res = WhatIfResult{}
+		json.NewDecoder(rr.Body).Decode(&res)
+		if rr.Code != 200 || res.Scale != ScaleOMSAS4 || res.Target.RemainingUnits != 3 ||
+			res.Target.RequiredLetter != letter || res.Target.Reachable != (letter != "") {
+			t.Errorf("omsas target %s: %d %+v", target, rr.Code, res.Target)
+		}
+	}
+
+	for _, body := range []string{
+		`{"grades":[{"plan_item_id":` + ids["1MD3"] + `,"grade":"A"}]}`, // already completed
+		`{"grades":[{"plan_item_id":` + ids["2XX3"] + `,"grade":"A"}]}`, // custom
+		`{"grades":[{"plan_item_id":` + ids["1XC3"] + `,"grade":"Z"}]}`,
+		`{"scale":"omsas4","target_gpa":5}`,
+		`{"scale":"gpa5"}`,
+	} {
+		if rr := post(token, body); rr.Code != 400 {
+			t.Errorf("%s: expected 400, got %d", body, rr.Code)
+		}
+	}
+	if rr := post(otherToken, `{}`); rr.Code != 403 {
+		t.Errorf("another student: expected 403, got %d", rr.Code)
+	}
+}
@@ -3,16 +3,21 @@ package pkg
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // PostUserPlanHandler serves POST /api/users/{id}/plan
 // Accepts year_index + season instead of plan_term_id — the handler
 // resolves or creates the plan_terms row internally so the frontend
-// doesn't need to know the term ID.
+// doesn't need to know the term ID. The course must be in the catalogue
+// unless is_custom is set. Returns 201 with the created item; 400 or 409
+// (already in that term) with { "error", "fields": [...] } otherwise.
 func PostUserPlanHandler(repo *Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -29,12 +34,15 @@ func PostUserPlanHandler(repo *Repository) http.HandlerFunc {
 			return
 		}
 
-		// Decode request body — frontend sends subject, course_number, year_index, season
+		// Decode request body — frontend sends subject, course_number, year_index, season.
+		// is_custom marks a course outside the catalogue (e.g. transfer credit).
 		var body struct {
-			Subject      string `json:"subject"`
-			CourseNumber string `json:"course_number"`
-			YearIndex    int    `json:"year_index"`
-			Season       string `json:"season"`
+			Subject      string  `json:"subject"`
+			CourseNumber string  `json:"course_number"`
+			YearIndex    int     `json:"year_index"`
+			Season       string  `json:"season"`
+			Note         *string `json:"note"`
+			IsCustom     bool    `json:"is_custom"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, "invalid request body", http.StatusBadRequest)
@@ -44,46 +52,54 @@ func PostUserPlanHandler(repo *Repository) http.HandlerFunc {
 		log.Printf("received: userID=%d yearIndex=%d season=%s subject=%s courseNumber=%s",
 			userID, body.YearIndex, body.Season, body.Subject, body.CourseNumber)
 
-		// Resolve existing plan_terms row or create a new one
-		var planTermID int
-		err = repo.QueryRow(`
-			SELECT plan_term_id FROM plan_terms
-			WHERE user_id = ? AND year_index = ? AND season = ?`,
-			userID, body.YearIndex, body.Season,
-		).Scan(&planTermID)
-
+		item := PlanItemState{
+			UserID: userID, YearIndex: body.YearIndex, Season: body.Season,
+			Subject: body.Subject, CourseNumber: body.CourseNumber,
+			Note: body.Note, IsCustom: body.IsCustom,
+		}
+		fields, err := repo.CheckPlanItem(&item)
 		if err != nil {
-			// No existing term — insert a new one
-			id, insertErr := repo.ExecReturningID(
-				`INSERT INTO plan_terms (user_id, year_index, season) VALUES (?, ?, ?)`,
-				"plan_term_id",
-				userID, body.YearIndex, body.Season,
-			)
-			if insertErr != nil {
-				log.Printf("failed to create plan term: %v", insertErr)
-				http.Error(w, "failed to create plan term", http.StatusInternalServerError)
-				return
-			}
-			planTermID = int(id)
+			log.Printf("check plan item: %v", err)
+			http.Error(w, "failed to check plan item", http.StatusInternalServerError)
+			return
+		}
+		if len(fields) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, "invalid plan item", fields)
+			return
 		}
 
-		// Insert the course into the resolved/created term
-		// status must be uppercase to satisfy the CHECK constraint
-		_, err = repo.Exec(`
-			INSERT INTO plan_items (plan_term_id, subject, course_number, status)
-			VALUES (?, ?, ?, 'PLANNED')`,
-			planTermID, body.Subject, body.CourseNumber,
-		)
+		// Resolves or creates the plan_terms row and records the change in
+		// the plan history.
+		created, err := repo.AddPlanItem(actorID(r), item)
+		if err != nil && isUniqueViolation(err) {
+			writeFieldErrors(w, http.StatusConflict, "course is already in this term", []FieldError{
+				{Field: "course_number", Message: fmt.Sprintf("%s %s is already planned for year %d %s",
+					item.Subject, item.CourseNumber, item.YearIndex, item.Season)},
+			})
+			return
+		}
 		if err != nil {
 			log.Printf("failed to insert plan item: %v", err)
 			http.Error(w, "failed to insert plan item", http.StatusInternalServerError)
 			return
 		}
 
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
 	}
 }
 
+// writeFieldErrors writes { "error": msg, "fields": [{ "field", "message" }] }.
+func writeFieldErrors(w http.ResponseWriter, status int, msg string, fields []FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  msg,
+		"fields": fields,
+	})
+}
+
 // PatchUserPlanItemHandler serves PATCH /api/users/{id}/plan/{itemId}
 // Updates the status and optionally the grade of a plan item.
 // Verifies ownership before updating.
@@ -125,10 +141,7 @@ func PatchUserPlanItemHandler(repo *Repository) http.HandlerFunc {
 		}
 
 		// Validate status is one of the allowed CHECK constraint values
-		allowed := map[string]bool{
-			"PLANNED": true, "IN_PROGRESS": true, "COMPLETED": true, "DROPPED": true,
-		}
-		if !allowed[body.Status] {
+		if !validPlanStatuses[body.Status] {
 			http.Error(w, "invalid status", http.StatusBadRequest)
 			return
 		}
@@ -154,10 +167,7 @@ func PatchUserPlanItemHandler(repo *Repository) http.HandlerFunc {
 		}
 
 		// Update status and grade — grade may be NULL if not provided
-		_, err = repo.Exec(`
-			UPDATE plan_items SET status = ?, grade = ? WHERE plan_item_id = ?
-		`, body.Status, body.Grade, itemID)
-		if err != nil {
+		if err := repo.SetPlanItemStatus(actorID(r), itemID, body.Status, body.Grade); err != nil {
 			log.Printf("failed to update plan item: %v", err)
 			http.Error(w, "failed to update plan item", http.StatusInternalServerError)
 			return
@@ -218,7 +228,7 @@ func DeleteUserPlanItemHandler(repo *Repository) http.HandlerFunc {
 		}
 
 		// Delete the plan item
-		if _, err := repo.Exec(`DELETE FROM plan_items WHERE plan_item_id = ?`, itemID); err != nil {
+		if err := repo.RemovePlanItem(actorID(r), itemID); err != nil {
 			http.Error(w, "failed to delete plan item", http.StatusInternalServerError)
 			return
 		}
@@ -264,39 +274,8 @@ func GetUserValidationHandler(repo *Repository, svc *Service) http.HandlerFunc {
 			return
 		}
 
-		// Load the user's plan items
-		rows, err := repo.Query(`
-            SELECT pi.plan_item_id, pi.plan_term_id, pi.subject,
-                   pi.course_number, pi.status, pi.grade, pi.note
-            FROM plan_items pi
-            JOIN plan_terms pt ON pt.plan_term_id = pi.plan_term_id
-            WHERE pt.user_id = ?`, userID)
-		if err != nil {
-			http.Error(w, "failed to load plan items", http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
-
-		var planItems []PlanItem
-		for rows.Next() {
-			var pi PlanItem
-			var grade, note sql.NullString
-			if err := rows.Scan(&pi.PlanItemID, &pi.PlanTermID, &pi.Subject,
-				&pi.CourseNumber, &pi.Status, &grade, &note); err != nil {
-				http.Error(w, "failed to scan plan item", http.StatusInternalServerError)
-				return
-			}
-			if grade.Valid {
-				pi.Grade = &grade.String
-			}
-			if note.Valid {
-				pi.Note = &note.String
-			}
-			planItems = append(planItems, pi)
-		}
-
-		// Run validation against the existing service
-		result, err := svc.ValidatePlan(planItems, program)
+		// Validate the plan and transfer credit against the existing service
+		result, err := svc.ValidateUserPlan(userID, program)
 		if err != nil {
 			log.Printf("validation error: %v", err)
 			http.Error(w, "validation failed", http.StatusInternalServerError)
@@ -309,7 +288,14 @@ func GetUserValidationHandler(repo *Repository, svc *Service) http.HandlerFunc {
 }
 
 // GetUserGPAHandler serves GET /api/users/{id}/gpa
-// Returns JSON: { gpa: float64, has_grades: bool, letter_grade: string }
+// Returns the cumulative GPA over the user's completed, graded McMaster
+// courses, on every scale, and broken down by term (with the cumulative
+// trend), by year (sessional averages), by subject and over the most recent
+// units, plus the completed items left out and why. Query parameters:
+//
+//	scale=mcmaster12|omsas4|percentage   scale of the breakdown (default mcmaster12)
+//	subject=COMPSCI,SFWRENG               subjects for subject_gpa, e.g. a major
+//	last_units=60                         N for the last N units GPA
 func GetUserGPAHandler(repo *Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -325,44 +311,131 @@ func GetUserGPAHandler(repo *Repository) http.HandlerFunc {
 			http.Error(w, "invalid user id", http.StatusBadRequest)
 			return
 		}
+		q := r.URL.Query()
+		scale := McMaster12
+		if key := q.Get("scale"); key != "" {
+			if scale = LookupGradeScale(key); scale == nil {
+				http.Error(w, "scale must be mcmaster12, omsas4 or percentage", http.StatusBadRequest)
+				return
+			}
+		}
+		lastUnits := DefaultLastUnits
+		if s := q.Get("last_units"); s != "" {
+			if lastUnits, err = strconv.Atoi(s); err != nil || lastUnits <= 0 {
+				http.Error(w, "last_units must be a positive number", http.StatusBadRequest)
+				return
+			}
+		}
+		var subjects []string
+		seen := map[string]bool{}
+		for _, v := range q["subject"] {
+			for _, s := range strings.Split(v, ",") {
+				s = strings.ToUpper(strings.TrimSpace(s))
+				if s != "" && !seen[s] {
+					seen[s] = true
+					subjects = append(subjects, s)
+				}
+			}
+		}
 
-		gpa, ok, err := repo.GetUserGPA(userID)
+		completed, err := repo.GetCompletedCourses(userID)
 		if err != nil {
 			log.Printf("failed to compute gpa: %v", err)
 			http.Error(w, "failed to compute gpa", http.StatusInternalServerError)
 			return
 		}
 
-		// Determine nearest letter grade from the mcmasterGPAScale
-		letter := ""
-		if ok {
-			// Find the grade with minimal absolute difference to the numeric gpa
-			best := ""
-			bestDiff := 1e9
-			for grade, val := range mcmasterGPAScale {
-				diff := val - gpa
-				if diff < 0 {
-					diff = -diff
-				}
-				if diff < bestDiff {
-					bestDiff = diff
-					best = grade
-				}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(BuildGPABreakdown(completed, scale, subjects, lastUnits))
+	}
+}
+
+// GPAWhatIfHandler serves POST /api/users/{id}/gpa/what-if
+// Projects the cumulative GPA from hypothetical grades for planned and
+// in-progress courses and, given a target, works out the average needed over
+// the planned courses left without one. Nothing is saved. Body:
+//
+//	{ "scale": "omsas4",
+//	  "grades": [{ "plan_item_id": 12, "grade": "A-" }, ...],
+//	  "target_gpa": 3.7 }
+//
+// scale defaults to mcmaster12; grades and target_gpa are optional.
+func GPAWhatIfHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			Scale  string `json:"scale"`
+			Grades []struct {
+				PlanItemID int    `json:"plan_item_id"`
+				Grade      string `json:"grade"`
+			} `json:"grades"`
+			TargetGPA *float64 `json:"target_gpa"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var fields []FieldError
+		scale := McMaster12
+		if body.Scale != "" {
+			if scale = LookupGradeScale(body.Scale); scale == nil {
+				fields = append(fields, FieldError{"scale", "must be mcmaster12, omsas4 or percentage"})
+				scale = McMaster12
+			}
+		}
+		if t := body.TargetGPA; t != nil && (*t < 0 || *t > scale.Max) {
+			fields = append(fields, FieldError{"target_gpa", fmt.Sprintf("must be between 0 and %g", scale.Max)})
+		}
+
+		completed, err := repo.GetCompletedCourses(userID)
+		if err != nil {
+			log.Printf("what-if gpa: %v", err)
+			http.Error(w, "failed to compute gpa", http.StatusInternalServerError)
+			return
+		}
+		remaining, err := repo.GetRemainingCourses(userID)
+		if err != nil {
+			log.Printf("what-if gpa: %v", err)
+			http.Error(w, "failed to compute gpa", http.StatusInternalServerError)
+			return
+		}
+		gradable := map[int]bool{}
+		for _, c := range remaining {
+			gradable[c.PlanItemID] = !c.IsCustom
+		}
+		hypothetical := map[int]Grade{}
+		for _, h := range body.Grades {
+			g := ParseGrade(h.Grade)
+			switch {
+			case !gradable[h.PlanItemID]:
+				fields = append(fields, FieldError{"grades", fmt.Sprintf("plan item %d is not a planned or in-progress McMaster course", h.PlanItemID)})
+			case g.Raw == "" || g.Excluded == GradeExcludedUnrecognised:
+				fields = append(fields, FieldError{"grades", fmt.Sprintf("plan item %d: unrecognised grade %q", h.PlanItemID, h.Grade)})
+			default:
+				hypothetical[h.PlanItemID] = g
 			}
-			letter = best
+		}
+		if len(fields) > 0 {
+			writeFieldErrors(w, http.StatusBadRequest, "invalid what-if", fields)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"gpa":          gpa,
-			"has_grades":   ok,
-			"letter_grade": letter,
-		})
+		json.NewEncoder(w).Encode(ProjectGPA(completed, remaining, hypothetical, scale, body.TargetGPA))
 	}
 }
 
 // PatchUserProfileHandler serves PATCH /api/users/{id}
-// Updates the user's program and/or year_of_study.
+// Updates the user's program, year_of_study and/or start_year.
 // Only supplied (non-null) JSON fields are applied; omitting a field leaves it unchanged.
 func PatchUserProfileHandler(repo *Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -381,17 +454,29 @@ func PatchUserProfileHandler(repo *Repository) http.HandlerFunc {
 		var body struct {
 			Program     *string `json:"program"`
 			YearOfStudy *int    `json:"year_of_study"`
+			StartYear   *int    `json:"start_year"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, "invalid request body", http.StatusBadRequest)
 			return
 		}
+		if body.StartYear != nil && (*body.StartYear < 1950 || *body.StartYear > 2100) {
+			http.Error(w, "start_year out of range", http.StatusBadRequest)
+			return
+		}
 
 		if err := repo.UpdateUserProfile(userID, body.Program, body.YearOfStudy); err != nil {
 			log.Printf("update profile: %v", err)
 			http.Error(w, "failed to update profile", http.StatusInternalServerError)
 			return
 		}
+		if body.StartYear != nil {
+			if err := repo.UpdateUserStartYear(userID, *body.StartYear); err != nil {
+				log.Printf("update start year: %v", err)
+				http.Error(w, "failed to update profile", http.StatusInternalServerError)
+				return
+			}
+		}
 
 		u, err := repo.GetUserByID(userID)
 		if err != nil || u == nil {
@@ -405,14 +490,16 @@ func PatchUserProfileHandler(repo *Repository) http.HandlerFunc {
 }
 
 // PostAdvanceYearHandler serves POST /api/users/{id}/advance-year
-// Increments the user's year_of_study by 1 and bulk-marks PLANNED/IN_PROGRESS
-// plan items from earlier year buckets as COMPLETED.
+// Increments the user's year_of_study by 1 and moves PLANNED/IN_PROGRESS
+// plan items from earlier year buckets to UNCONFIRMED, for the student to
+// mark completed or dropped. GET .../advance-year/preview shows the changes
+// first.
 //
 // Optional JSON body: { "specialization": "<program name>" }
 // When specialization is provided, users.program is also updated (used when
 // Engineering I students choose a discipline for Year 2+).
 //
-// Returns { "new_year": int, "completed_count": int, "new_program": string }.
+// Returns { "new_year": int, "unconfirmed_count": int, "new_program": string }.
 func PostAdvanceYearHandler(repo *Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -434,7 +521,7 @@ func PostAdvanceYearHandler(repo *Repository) http.HandlerFunc {
 		}
 		_ = json.NewDecoder(r.Body).Decode(&body)
 
-		newYear, completedCount, newProgram, err := repo.AdvanceUserYear(userID, body.Specialization)
+		newYear, unconfirmedCount, newProgram, err := repo.AdvanceUserYear(userID, body.Specialization, time.Now())
 		if err != nil {
 			log.Printf("advance year: %v", err)
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -443,13 +530,49 @@ func PostAdvanceYearHandler(repo *Repository) http.HandlerFunc {
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"new_year":        newYear,
-			"completed_count": completedCount,
-			"new_program":     newProgram,
+			"new_year":          newYear,
+			"unconfirmed_count": unconfirmedCount,
+			"new_program":       newProgram,
 		})
 	}
 }
 
+// AdvanceYearPreviewHandler serves GET /api/users/{id}/advance-year/preview?specialization={program}
+// A dry run of POST /api/users/{id}/advance-year: returns the year and
+// program it would set and the plan items it would move to UNCONFIRMED,
+// without changing anything.
+func AdvanceYearPreviewHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		idStr := strings.TrimPrefix(r.URL.Path, "/api/users/")
+		idStr = strings.TrimSuffix(idStr, "/advance-year/preview")
+		userID, err := strconv.Atoi(strings.Trim(idStr, "/"))
+		if err != nil || userID == 0 {
+			http.Error(w, "invalid user id", http.StatusBadRequest)
+			return
+		}
+
+		var specialization *string
+		if s := r.URL.Query().Get("specialization"); s != "" {
+			specialization = &s
+		}
+
+		adv, err := repo.PreviewAdvanceUserYear(userID, specialization, time.Now())
+		if err != nil {
+			log.Printf("preview advance year: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(adv)
+	}
+}
+
 // CourseBySubjectNumberHandler serves GET /api/courses/{subject}/{number}
 // Used by DegreePlanner and CourseDetail when navigating by subject+number
 // instead of numeric ID.
@@ -487,12 +610,27 @@ func CourseBySubjectNumberHandler(repo *Repository) http.HandlerFunc {
 // CoursesHandler serves GET /api/courses?q={query}&level={digit}&term={str}&limit={n}&offset={n}
 // Returns a paginated JSON envelope:
 //
-//	{ "courses": [...], "total": N, "limit": N, "offset": N }
+//	{ "courses": [...], "total": N, "limit": N, "offset": N, "facets": {...} }
 //
 // limit defaults to 20; callers may raise it up to maxLimit (200).
 // level filters by course_number prefix digit (e.g. "2" = 2000-level).
 // term filters by partial match on the term column (e.g. "Fall", "Winter").
+// subject, level, term and units may be repeated to match any of several
+// values (?subject=COMPSCI&subject=SFWRENG). min_rating and max_difficulty
+// filter on the average of the course's instructors, has_prereqs on whether
+// it has any prerequisites.
+// sort is one of relevance (default), code, rating, difficulty, num_ratings.
+// Pages can also be walked with ?cursor=, passing back the next_cursor or
+// prev_cursor of the previous response instead of an offset; cursors stay
+// valid when courses are added or reseeded. include_total=false skips the
+// count, and "total" is left out.
+// facets counts the matches by subject, level, term, has_prereqs and rating
+// bucket; each facet ignores its own filter so the other chips stay useful.
+//
 // Multi-token AND search is handled by SearchCourses — spaces in q act as AND.
+// When a full-text index is available results are ranked by relevance and
+// each course carries a highlighted "snippet". Course codes are normalised
+// ("cs 2c3" → "COMPSCI 2C03") and misspelt names are matched by edit distance.
 func CoursesHandler(repo *Repository) http.HandlerFunc {
 	const defaultLimit = 20
 	const maxLimit = 200
@@ -502,13 +640,12 @@ func CoursesHandler(repo *Repository) http.HandlerFunc {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-
-		q := r.URL.Query().Get("q")
+		query := r.URL.Query()
 
 		// Default page size = 20; callers may override with ?limit=N
 		// Capped at 200 to prevent abuse on this public endpoint.
 		limit := defaultLimit
-		if lStr := r.URL.Query().Get("limit"); lStr != "" {
+		if lStr := query.Get("limit"); lStr != "" {
 			if l, err := strconv.Atoi(lStr); err == nil && l > 0 {
 				limit = l
 			}
@@ -517,36 +654,107 @@ func CoursesHandler(repo *Repository) http.HandlerFunc {
 			limit = maxLimit
 		}
 		offset := 0
-		if oStr := r.URL.Query().Get("offset"); oStr != "" {
+		if oStr := query.Get("offset"); oStr != "" {
 			if o, err := strconv.Atoi(oStr); err == nil && o >= 0 {
 				offset = o
 			}
 		}
 
-		level := r.URL.Query().Get("level")
-		term := r.URL.Query().Get("term")
+		cq := CourseQuery{
+			Q:        query.Get("q"),
+			Subjects: query["subject"],
+			Levels:   query["level"],
+			Terms:    query["term"],
+			Sort:     query.Get("sort"),
+			Limit:    limit,
+			Offset:   offset,
+			Cursor:   query.Get("cursor"),
+			Facets:   true,
+		}
+		includeTotal, err := parseIncludeTotal(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		cq.SkipTotal = !includeTotal
+		if _, ok := courseSortOrders[cq.Sort]; cq.Sort != "" && !ok {
+			http.Error(w, "invalid sort", http.StatusBadRequest)
+			return
+		}
+		for _, u := range query["units"] {
+			n, err := strconv.Atoi(u)
+			if err != nil || n <= 0 {
+				http.Error(w, "invalid units", http.StatusBadRequest)
+				return
+			}
+			cq.Units = append(cq.Units, n)
+		}
+		for _, p := range []struct {
+			name string
+			dst  **float64
+		}{{"min_rating", &cq.MinRating}, {"max_difficulty", &cq.MaxDifficulty}} {
+			if v := query.Get(p.name); v != "" {
+				f, err := strconv.ParseFloat(v, 64)
+				if err != nil || f < 0 {
+					http.Error(w, "invalid "+p.name, http.StatusBadRequest)
+					return
+				}
+				*p.dst = &f
+			}
+		}
+		if v := query.Get("has_prereqs"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				http.Error(w, "invalid has_prereqs", http.StatusBadRequest)
+				return
+			}
+			cq.HasPrereqs = &b
+		}
 
-		courses, total, err := repo.SearchCourses(q, level, term, limit, offset)
+		res, err := repo.FindCourses(cq)
+		if errors.Is(err, ErrInvalidCursor) {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			log.Printf("search courses: %v", err)
 			http.Error(w, "failed to search courses", http.StatusInternalServerError)
 			return
 		}
-		// Return empty array instead of null when no results
-		if courses == nil {
-			courses = []Course{}
-		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"courses": courses,
-			"total":   total,
+		resp := map[string]interface{}{
+			"courses": res.Courses,
 			"limit":   limit,
 			"offset":  offset,
-		})
+			"facets":  res.Facets,
+		}
+		if includeTotal {
+			resp["total"] = res.Total
+		}
+		if res.NextCursor != "" {
+			resp["next_cursor"] = res.NextCursor
+		}
+		if res.PrevCursor != "" {
+			resp["prev_cursor"] = res.PrevCursor
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
 	}
 }
 
+// parseIncludeTotal reads ?include_total=, which defaults to true.
+func parseIncludeTotal(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("include_total")
+	if v == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New("invalid include_total")
+	}
+	return b, nil
+}
+
 // CourseHandler serves GET /api/courses/{id}
 // Fetches a single course by its numeric database ID.
 func CourseHandler(repo *Repository) http.HandlerFunc {
@@ -829,77 +1037,59 @@ func GetUserPlanHandler(repo *Repository, svc *Service) http.HandlerFunc {
 			return
 		}
 
-		// Join plan_items → plan_terms → courses to get course name in one query.
-		// All non-aggregate columns must appear in GROUP BY for PostgreSQL.
-		rows, err := repo.Query(`
-			SELECT pi.plan_item_id, pi.plan_term_id,
-			       pi.subject, pi.course_number,
-			       pi.status, pi.grade, pi.note,
-			       pt.year_index, pt.season,
-			       MAX(c.course_name) as course_name
-			FROM plan_items pi
-			JOIN plan_terms pt ON pt.plan_term_id = pi.plan_term_id
-			LEFT JOIN courses c ON c.subject = pi.subject
-			       AND c.course_number = pi.course_number
-			WHERE pt.user_id = ?
-			GROUP BY pi.plan_item_id, pi.plan_term_id,
-			         pi.subject, pi.course_number,
-			         pi.status, pi.grade, pi.note,
-			         pt.year_index, pt.season
-			ORDER BY pt.year_index, pt.season, pi.subject, pi.course_number`,
-			userID)
+		items, err := repo.GetUserPlan(userID)
 		if err != nil {
+			log.Printf("get plan for user %d: %v", userID, err)
 			http.Error(w, "failed to fetch plan items", http.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
 
-		type PlanItem struct {
-			PlanItemID   int     `json:"plan_item_id"`
-			PlanTermID   int     `json:"plan_term_id"`
-			Subject      string  `json:"subject"`
-			CourseNumber string  `json:"course_number"`
-			Status       string  `json:"status"`
-			Grade        *string `json:"grade"`
-			Note         *string `json:"note"`
-			YearIndex    int     `json:"year_index"`
-			Season       string  `json:"season"`
-			CourseName   *string `json:"course_name"`
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	}
+}
+
+// CourseOfferingsHandler serves GET /api/courses/{subject}/{number}/offerings
+// Returns the catalogue entry and every term it has been offered in, each
+// with its section and linked instructors.
+func CourseOfferingsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
 
-		var items []PlanItem
-		for rows.Next() {
-			var pi PlanItem
-			var grade, note, courseName sql.NullString
-			if err := rows.Scan(
-				&pi.PlanItemID, &pi.PlanTermID,
-				&pi.Subject, &pi.CourseNumber,
-				&pi.Status, &grade, &note,
-				&pi.YearIndex, &pi.Season,
-				&courseName,
-			); err != nil {
-				http.Error(w, "failed to scan plan item", http.StatusInternalServerError)
-				return
-			}
-			if grade.Valid {
-				pi.Grade = &grade.String
-			}
-			if note.Valid {
-				pi.Note = &note.String
-			}
-			if courseName.Valid {
-				pi.CourseName = &courseName.String
-			}
-			items = append(items, pi)
+		path := strings.TrimPrefix(r.URL.Path, "/api/courses/")
+		path = strings.TrimSuffix(path, "/offerings")
+		parts := strings.SplitN(path, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			http.Error(w, "expected /api/courses/<subject>/<number>/offerings", http.StatusBadRequest)
+			return
 		}
 
-		// Return empty array instead of null
-		if items == nil {
-			items = []PlanItem{}
+		course, err := repo.GetCatalogCourse(strings.ToUpper(parts[0]), strings.ToUpper(parts[1]))
+		if err != nil {
+			log.Printf("get catalog course error: %v", err)
+			http.Error(w, "failed to fetch course", http.StatusInternalServerError)
+			return
+		}
+		if course == nil {
+			http.Error(w, "course not found", http.StatusNotFound)
+			return
+		}
+
+		offerings, err := repo.GetCourseOfferings(course.CatalogID)
+		if err != nil {
+			log.Printf("get course offerings error: %v", err)
+			http.Error(w, "failed to fetch offerings", http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(items)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"course":    course,
+			"offerings": offerings,
+		})
 	}
 }
 
@@ -1004,7 +1194,8 @@ func CourseInstructorsHandler(repo *Repository) http.HandlerFunc {
 }
 
 // InstructorsHandler serves GET /api/instructors
-// Supports query params: q (search), department, min_rating, limit, offset
+// Supports query params: q (search), department, min_rating, limit, offset,
+// and cursor / include_total as described on CoursesHandler.
 func InstructorsHandler(repo *Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -1048,32 +1239,52 @@ func InstructorsHandler(repo *Repository) http.HandlerFunc {
 			}
 		}
 
-		instructors, total, err := repo.SearchInstructors(q, department, minRating, limit, offset)
+		includeTotal, err := parseIncludeTotal(r)
 		if err != nil {
-			log.Printf("search instructors error: %v", err)
-			http.Error(w, "failed to search instructors", http.StatusInternalServerError)
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
-		// Return empty array instead of null
-		if instructors == nil {
-			instructors = []Instructor{}
+		res, err := repo.FindInstructors(InstructorQuery{
+			Q:          q,
+			Department: department,
+			MinRating:  minRating,
+			Limit:      limit,
+			Offset:     offset,
+			Cursor:     r.URL.Query().Get("cursor"),
+			SkipTotal:  !includeTotal,
+		})
+		if errors.Is(err, ErrInvalidCursor) {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("search instructors error: %v", err)
+			http.Error(w, "failed to search instructors", http.StatusInternalServerError)
+			return
 		}
 
 		type response struct {
 			Instructors []Instructor `json:"instructors"`
-			Total       int          `json:"total"`
+			Total       *int         `json:"total,omitempty"`
 			Limit       int          `json:"limit"`
 			Offset      int          `json:"offset"`
+			NextCursor  string       `json:"next_cursor,omitempty"`
+			PrevCursor  string       `json:"prev_cursor,omitempty"`
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response{
-			Instructors: instructors,
-			Total:       total,
+		resp := response{
+			Instructors: res.Instructors,
 			Limit:       limit,
 			Offset:      offset,
-		})
+			NextCursor:  res.NextCursor,
+			PrevCursor:  res.PrevCursor,
+		}
+		if includeTotal {
+			resp.Total = &res.Total
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
 	}
 }
 
@@ -0,0 +1,821 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─── Data jobs ───────────────────────────────────────────────────────────────
+
+// AdminJob is a maintenance task that can be triggered with
+// POST /api/admin/jobs/{name}. It returns a small summary of what it did,
+// which is echoed to the caller and stored in the audit log.
+type AdminJob func(repo *Repository) (map[string]interface{}, error)
+
+// adminJobs is the registry of jobs exposed through the admin API.
+// Register new jobs here; the name is the URL segment.
+var adminJobs = map[string]AdminJob{
+	"link-instructors": func(repo *Repository) (map[string]interface{}, error) {
+		n, err := repo.LinkCourseInstructors()
+		return map[string]interface{}{"links_created": n}, err
+	},
+	"purge-reset-tokens": func(repo *Repository) (map[string]interface{}, error) {
+		n, err := repo.PurgeExpiredResetTokens()
+		return map[string]interface{}{"tokens_deleted": n}, err
+	},
+	"purge-oidc-states": func(repo *Repository) (map[string]interface{}, error) {
+		n, err := repo.PurgeExpiredOIDCLoginStates()
+		return map[string]interface{}{"states_deleted": n}, err
+	},
+	"drain-email-queue": func(repo *Repository) (map[string]interface{}, error) {
+		res, err := DrainEmailQueue(repo, MailerFromEnv())
+		return map[string]interface{}{"sent": res.Sent, "retried": res.Retried, "dead": res.Dead}, err
+	},
+	"rebuild-search-index": func(repo *Repository) (map[string]interface{}, error) {
+		backend, err := repo.RebuildSearchIndex()
+		return map[string]interface{}{"backend": backend}, err
+	},
+	"term-rollover": func(repo *Repository) (map[string]interface{}, error) {
+		res, err := RunTermRollover(repo, time.Now())
+		return map[string]interface{}{"users": res.Users, "advanced": res.Advanced, "unconfirmed": res.Unconfirmed}, err
+	},
+}
+
+// ErrUnknownJob is returned by RunJob for a name that isn't in adminJobs.
+var ErrUnknownJob = errors.New("unknown job")
+
+// RunJob runs the named job synchronously and records the run, with its
+// summary or error, in the audit log. actorID is the admin who triggered it,
+// or 0 for scheduled runs (see cmd/lambda); those are only audited when they
+// fail, so a once-a-minute schedule doesn't flood the log.
+func RunJob(repo *Repository, actorID int, name string) (map[string]interface{}, error) {
+	job, ok := adminJobs[name]
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	summary, jobErr := job(repo)
+	details := map[string]interface{}{"summary": summary}
+	if jobErr != nil {
+		details["error"] = jobErr.Error()
+		log.Printf("[jobs] %s: %v", name, jobErr)
+	} else if actorID == 0 {
+		return summary, nil
+	}
+	if err := repo.RecordAdminAction(actorID, "job.run", "job", name, details); err != nil {
+		log.Printf("[jobs] audit %s: %v", name, err)
+	}
+	return summary, jobErr
+}
+
+// ─── Helpers ─────────────────────────────────────────────────────────────────
+
+// adminPathParts splits the path after /api/admin/ into its segments,
+// e.g. "/api/admin/users/7" → ["users", "7"].
+func adminPathParts(r *http.Request) []string {
+	return strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/"), "/"), "/")
+}
+
+// adminPage parses ?limit= and ?offset= with a default page size of 50 (max 500).
+func adminPage(r *http.Request) (limit, offset int) {
+	limit = 50
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > 500 {
+		limit = 500
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	return limit, offset
+}
+
+// writeAdminError maps repository errors to HTTP status codes.
+func writeAdminError(w http.ResponseWriter, action string, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("[admin] %s: %v", action, err)
+	http.Error(w, "failed to "+action, http.StatusInternalServerError)
+}
+
+// ─── Users ───────────────────────────────────────────────────────────────────
+
+// AdminUsersHandler serves GET /api/admin/users?q=&role=&limit=&offset=
+func AdminUsersHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		limit, offset := adminPage(r)
+		users, total, err := repo.ListUsers(r.URL.Query().Get("q"), Role(r.URL.Query().Get("role")), limit, offset)
+		if err != nil {
+			writeAdminError(w, "list users", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"users":  users,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		})
+	}
+}
+
+// AdminUserHandler serves PATCH and DELETE /api/admin/users/{id}.
+// PATCH accepts any of { role, display_name, program, year_of_study }.
+// Admins cannot change their own role or delete themselves, so the last
+// admin can't accidentally lock everyone out.
+func AdminUserHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := adminPathParts(r)
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		userID, err := strconv.Atoi(parts[1])
+		if err != nil || userID == 0 {
+			http.Error(w, "invalid user id", http.StatusBadRequest)
+			return
+		}
+		actor := GetClaimsFromContext(r).UserID
+
+		switch r.Method {
+		case http.MethodPatch:
+			var upd AdminUserUpdate
+			if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if upd.Role != nil {
+				if !validRoles[*upd.Role] {
+					http.Error(w, "invalid role", http.StatusBadRequest)
+					return
+				}
+				if userID == actor {
+					http.Error(w, "cannot change your own role", http.StatusBadRequest)
+					return
+				}
+			}
+			if err := repo.AdminUpdateUser(actor, userID, upd); err != nil {
+				writeAdminError(w, "update user", err)
+				return
+			}
+			u, err := repo.GetUserByID(userID)
+			if err != nil || u == nil {
+				http.Error(w, "failed to fetch updated user", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(u)
+
+		case http.MethodDelete:
+			if userID == actor {
+				http.Error(w, "cannot delete your own account", http.StatusBadRequest)
+				return
+			}
+			if err := repo.AdminDeleteUser(actor, userID); err != nil {
+				writeAdminError(w, "delete user", err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// ─── Reviews ─────────────────────────────────────────────────────────────────
+
+// AdminReviewsHandler serves GET /api/admin/reviews?kind=course|instructor
+// kind defaults to "course".
+func AdminReviewsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		kind := ReviewKind(r.URL.Query().Get("kind"))
+		if kind == "" {
+			kind = CourseReview
+		}
+		if _, ok := reviewTables[kind]; !ok {
+			http.Error(w, "kind must be course or instructor", http.StatusBadRequest)
+			return
+		}
+
+		limit, offset := adminPage(r)
+		reviews, total, err := repo.ListReviews(kind, limit, offset)
+		if err != nil {
+			writeAdminError(w, "list reviews", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reviews": reviews,
+			"total":   total,
+			"limit":   limit,
+			"offset":  offset,
+		})
+	}
+}
+
+// AdminReviewHandler serves DELETE /api/admin/reviews/{kind}/{id}?reason=...
+// The optional reason is kept in the audit log.
+func AdminReviewHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		parts := adminPathParts(r)
+		if len(parts) != 3 {
+			http.NotFound(w, r)
+			return
+		}
+		kind := ReviewKind(parts[1])
+		if _, ok := reviewTables[kind]; !ok {
+			http.Error(w, "kind must be course or instructor", http.StatusBadRequest)
+			return
+		}
+		reviewID, err := strconv.Atoi(parts[2])
+		if err != nil || reviewID == 0 {
+			http.Error(w, "invalid review id", http.StatusBadRequest)
+			return
+		}
+
+		actor := GetClaimsFromContext(r).UserID
+		if err := repo.AdminDeleteReview(actor, kind, reviewID, r.URL.Query().Get("reason")); err != nil {
+			writeAdminError(w, "delete review", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ─── Requisites ──────────────────────────────────────────────────────────────
+
+// AdminRequisitesHandler serves
+//
+//	GET  /api/admin/requisites?subject=&course_number=  — rows with IDs
+//	POST /api/admin/requisites                          — create a row
+func AdminRequisitesHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			subject := strings.ToUpper(r.URL.Query().Get("subject"))
+			number := strings.ToUpper(r.URL.Query().Get("course_number"))
+			if subject == "" || number == "" {
+				http.Error(w, "subject and course_number are required", http.StatusBadRequest)
+				return
+			}
+			reqs, err := repo.ListRequisiteRows(subject, number)
+			if err != nil {
+				writeAdminError(w, "list requisites", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(reqs)
+
+		case http.MethodPost:
+			var rq Requisite
+			if err := json.NewDecoder(r.Body).Decode(&rq); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			rq.Subject = strings.ToUpper(strings.TrimSpace(rq.Subject))
+			rq.CourseNumber = strings.ToUpper(strings.TrimSpace(rq.CourseNumber))
+			rq.ReqSubject = strings.ToUpper(strings.TrimSpace(rq.ReqSubject))
+			rq.ReqCourseNumber = strings.ToUpper(strings.TrimSpace(rq.ReqCourseNumber))
+			rq.Kind = strings.ToUpper(strings.TrimSpace(rq.Kind))
+			if rq.Subject == "" || rq.CourseNumber == "" || rq.ReqSubject == "" || rq.ReqCourseNumber == "" {
+				http.Error(w, "subject, course_number, req_subject and req_course_number are required", http.StatusBadRequest)
+				return
+			}
+			if rq.Kind != "PREREQ" && rq.Kind != "COREQ" && rq.Kind != "ANTIREQ" {
+				http.Error(w, "kind must be PREREQ, COREQ or ANTIREQ", http.StatusBadRequest)
+				return
+			}
+			if rq.Subject == rq.ReqSubject && rq.CourseNumber == rq.ReqCourseNumber {
+				http.Error(w, "a course cannot be its own requisite", http.StatusBadRequest)
+				return
+			}
+
+			id, err := repo.AdminCreateRequisite(GetClaimsFromContext(r).UserID, rq)
+			if err != nil {
+				writeAdminError(w, "create requisite", err)
+				return
+			}
+			rq.ReqID = id
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(rq)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminRequisiteHandler serves DELETE /api/admin/requisites/{id}
+func AdminRequisiteHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		parts := adminPathParts(r)
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		reqID, err := strconv.Atoi(parts[1])
+		if err != nil || reqID == 0 {
+			http.Error(w, "invalid requisite id", http.StatusBadRequest)
+			return
+		}
+		if err := repo.AdminDeleteRequisite(GetClaimsFromContext(r).UserID, reqID); err != nil {
+			writeAdminError(w, "delete requisite", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ─── Requirement groups ──────────────────────────────────────────────────────
+
+// AdminRequirementGroupHandler serves
+//
+//	PATCH /api/admin/requirement-groups/{id}          — edit heading/units/flags
+//	POST  /api/admin/requirement-groups/{id}/courses  — append a course row
+func AdminRequirementGroupHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := adminPathParts(r)
+		if len(parts) < 2 {
+			http.NotFound(w, r)
+			return
+		}
+		groupID, err := strconv.Atoi(parts[1])
+		if err != nil || groupID == 0 {
+			http.Error(w, "invalid group id", http.StatusBadRequest)
+			return
+		}
+		actor := GetClaimsFromContext(r).UserID
+
+		switch {
+		case len(parts) == 2 && r.Method == http.MethodPatch:
+			var upd RequirementGroupUpdate
+			if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if err := repo.AdminUpdateRequirementGroup(actor, groupID, upd); err != nil {
+				writeAdminError(w, "update requirement group", err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		case len(parts) == 3 && parts[2] == "courses" && r.Method == http.MethodPost:
+			var rc RequirementCourse
+			if err := json.NewDecoder(r.Body).Decode(&rc); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			rc.CourseCode = strings.ToUpper(strings.TrimSpace(rc.CourseCode))
+			if rc.CourseCode == "" && rc.AdhocText == nil {
+				http.Error(w, "course_code or adhoc_text is required", http.StatusBadRequest)
+				return
+			}
+			id, err := repo.AdminAddRequirementCourse(actor, groupID, rc)
+			if err != nil {
+				writeAdminError(w, "add requirement course", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]int{"req_course_id": id})
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminRequirementCourseHandler serves DELETE /api/admin/requirement-courses/{id}
+func AdminRequirementCourseHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		parts := adminPathParts(r)
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil || id == 0 {
+			http.Error(w, "invalid requirement course id", http.StatusBadRequest)
+			return
+		}
+		if err := repo.AdminDeleteRequirementCourse(GetClaimsFromContext(r).UserID, id); err != nil {
+			writeAdminError(w, "delete requirement course", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ─── Standing rules ──────────────────────────────────────────────────────────
+
+// AdminStandingRulesHandler serves
+//
+//	GET  /api/admin/programs/{id}/standing-rules  — the program's rules
+//	POST /api/admin/programs/{id}/standing-rules  — add a rule
+//
+// A rule is one of
+//
+//	{ "kind": "min_gpa",   "label": "Admission to Level II", "min_gpa": 6.0, "by_year": 1 }
+//	{ "kind": "min_grade", "label": "...", "subject": "MATH", "course_number": "1ZA3", "min_grade": "C" }
+//	{ "kind": "min_units", "label": "...", "min_units": 24, "by_year": 1 }
+func AdminStandingRulesHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := adminPathParts(r)
+		if len(parts) != 3 || parts[2] != "standing-rules" {
+			http.NotFound(w, r)
+			return
+		}
+		programID, err := strconv.Atoi(parts[1])
+		if err != nil || programID == 0 {
+			http.Error(w, "invalid program id", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			rules, err := repo.ListStandingRules(programID)
+			if err != nil {
+				writeAdminError(w, "list standing rules", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(rules)
+
+		case http.MethodPost:
+			var rule StandingRule
+			if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if fields := rule.Check(); len(fields) > 0 {
+				writeFieldErrors(w, http.StatusBadRequest, "invalid standing rule", fields)
+				return
+			}
+			rule.ProgramID = programID
+			created, err := repo.AdminCreateStandingRule(GetClaimsFromContext(r).UserID, rule)
+			if err != nil {
+				writeAdminError(w, "create standing rule", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(created)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdminStandingRuleHandler serves DELETE /api/admin/standing-rules/{id}
+func AdminStandingRuleHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		parts := adminPathParts(r)
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil || id == 0 {
+			http.Error(w, "invalid standing rule id", http.StatusBadRequest)
+			return
+		}
+		if err := repo.AdminDeleteStandingRule(GetClaimsFromContext(r).UserID, id); err != nil {
+			writeAdminError(w, "delete standing rule", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ─── Course units ────────────────────────────────────────────────────────────
+
+// AdminCourseUnitsHandler serves PUT /api/admin/courses/{subject}/{number}/units
+// with { "units": 0 } to curate a course's units, or { "units": null } to
+// drop the curated value. Returns the catalogue course.
+func AdminCourseUnitsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		parts := adminPathParts(r)
+		if len(parts) != 4 || parts[3] != "units" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Units *int `json:"units"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if body.Units != nil && (*body.Units < 0 || *body.Units > 30) {
+			writeFieldErrors(w, http.StatusBadRequest, "invalid units", []FieldError{{"units", "must be between 0 and 30"}})
+			return
+		}
+		course, err := repo.AdminSetCourseUnits(GetClaimsFromContext(r).UserID,
+			strings.ToUpper(parts[1]), strings.ToUpper(parts[2]), body.Units)
+		if err != nil {
+			writeAdminError(w, "set course units", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(course)
+	}
+}
+
+// ─── Feedback ────────────────────────────────────────────────────────────────
+
+// AdminFeedbackListHandler serves GET /api/admin/feedback?status=&tag=&page=&q=&limit=&offset=
+func AdminFeedbackListHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		filter := FeedbackFilter{Status: q.Get("status"), Tag: q.Get("tag"), Page: q.Get("page"), Q: q.Get("q")}
+		if filter.Status != "" && filter.Status != FeedbackOpen && filter.Status != FeedbackResolved {
+			http.Error(w, "invalid status", http.StatusBadRequest)
+			return
+		}
+
+		limit, offset := adminPage(r)
+		items, total, err := repo.ListFeedback(filter, limit, offset)
+		if err != nil {
+			writeAdminError(w, "list feedback", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"feedback": items,
+			"total":    total,
+			"limit":    limit,
+			"offset":   offset,
+		})
+	}
+}
+
+// AdminFeedbackHandler serves GET and PATCH /api/admin/feedback/{id}, plus
+// GET /api/admin/feedback/tags. PATCH accepts { status, tags }; tags replaces
+// the item's whole tag set.
+func AdminFeedbackHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := adminPathParts(r)
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+
+		if parts[1] == "tags" && r.Method == http.MethodGet {
+			tags, err := repo.ListFeedbackTags()
+			if err != nil {
+				writeAdminError(w, "list feedback tags", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(tags)
+			return
+		}
+
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			http.Error(w, "invalid feedback id", http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPatch:
+			var upd FeedbackUpdate
+			if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if upd.Status != nil && *upd.Status != FeedbackOpen && *upd.Status != FeedbackResolved {
+				http.Error(w, "status must be open or resolved", http.StatusBadRequest)
+				return
+			}
+			if upd.Tags != nil {
+				for _, tag := range *upd.Tags {
+					if len(tag) > 40 {
+						http.Error(w, "tags must be at most 40 characters", http.StatusBadRequest)
+						return
+					}
+				}
+			}
+			if err := repo.AdminUpdateFeedback(GetClaimsFromContext(r).UserID, id, upd); err != nil {
+				writeAdminError(w, "update feedback", err)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		f, err := repo.GetFeedback(id)
+		if err != nil {
+			writeAdminError(w, "get feedback", err)
+			return
+		}
+		if f == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(f)
+	}
+}
+
+// ─── Email queue ─────────────────────────────────────────────────────────────
+
+// AdminEmailsHandler serves GET /api/admin/emails?status=&limit=&offset=
+// status is pending, sending, sent, dead, or "stuck" (the default): anything
+// undelivered that has failed at least once.
+func AdminEmailsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := r.URL.Query().Get("status")
+		switch status {
+		case "":
+			status = "stuck"
+		case "all":
+			status = ""
+		case "stuck", EmailPending, EmailSending, EmailSent, EmailDead:
+		default:
+			http.Error(w, "invalid status", http.StatusBadRequest)
+			return
+		}
+
+		limit, offset := adminPage(r)
+		emails, total, err := repo.ListOutboundEmails(status, limit, offset)
+		if err != nil {
+			writeAdminError(w, "list emails", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"emails": emails,
+			"total":  total,
+			"limit":  limit,
+			"offset": offset,
+		})
+	}
+}
+
+// AdminEmailRetryHandler serves POST /api/admin/emails/{id}/retry, putting a
+// dead or backed-off message back in the queue immediately.
+func AdminEmailRetryHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := adminPathParts(r)
+		if len(parts) != 3 || parts[2] != "retry" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			http.Error(w, "invalid email id", http.StatusBadRequest)
+			return
+		}
+
+		if err := repo.AdminRetryEmail(GetClaimsFromContext(r).UserID, id); err != nil {
+			writeAdminError(w, "retry email", err)
+			return
+		}
+		e, err := repo.GetOutboundEmail(id)
+		if err != nil || e == nil {
+			http.Error(w, "failed to fetch email", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(e)
+	}
+}
+
+// ─── Jobs ────────────────────────────────────────────────────────────────────
+
+// AdminJobsHandler serves
+//
+//	GET  /api/admin/jobs         — list registered job names
+//	POST /api/admin/jobs/{name}  — run a job synchronously
+//
+// Every run is written to the audit log with its summary or error, whether or
+// not it succeeded.
+func AdminJobsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := adminPathParts(r)
+
+		if len(parts) == 1 && r.Method == http.MethodGet {
+			names := make([]string, 0, len(adminJobs))
+			for name := range adminJobs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(names)
+			return
+		}
+
+		if len(parts) != 2 || r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name := parts[1]
+		summary, err := RunJob(repo, GetClaimsFromContext(r).UserID, name)
+		if errors.Is(err, ErrUnknownJob) {
+			http.Error(w, "unknown job", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "job failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"job":     name,
+			"summary": summary,
+		})
+	}
+}
+
+// ─── Audit log ───────────────────────────────────────────────────────────────
+
+// AdminAuditLogHandler serves GET /api/admin/audit-log?target_type=&actor_id=&limit=&offset=
+func AdminAuditLogHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		actorID, _ := strconv.Atoi(r.URL.Query().Get("actor_id"))
+		limit, offset := adminPage(r)
+		entries, total, err := repo.ListAuditLog(r.URL.Query().Get("target_type"), actorID, limit, offset)
+		if err != nil {
+			writeAdminError(w, "list audit log", err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"entries": entries,
+			"total":   total,
+			"limit":   limit,
+			"offset":  offset,
+		})
+	}
+}
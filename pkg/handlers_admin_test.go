@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// seedUser inserts a user with the given role and returns its ID plus a
+// signed access token for it.
+func seedUser(t *testing.T, repo *Repository, email string, role Role) (int, string) {
+	t.Helper()
+	res, err := repo.DB.Exec(`INSERT INTO users(email, display_name, password_hash, role) VALUES (?, 'Test', 'x', ?)`, email, role)
+	if err != nil {
+		t.Fatalf("seed user %s: %v", email, err)
+	}
+	id, _ := res.LastInsertId()
+	token, err := GenerateAccessToken(int(id), email, role)
+	if err != nil {
+		t.Fatalf("token for %s: %v", email, err)
+	}
+	return int(id), token
+}
+
+func TestAdminRoutes(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	adminID, adminToken := seedUser(t, repo, "admin@example.com", RoleAdmin)
+	studentID, studentToken := seedUser(t, repo, "student@example.com", RoleStudent)
+
+	do := func(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			json.NewEncoder(&buf).Encode(body)
+		}
+		req := httptest.NewRequest(method, path, &buf)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("missing token is 401", func(t *testing.T) {
+		if rr := do("GET", "/api/admin/users", "", nil); rr.Code != 401 {
+			t.Fatalf("expected 401, got %d", rr.Code)
+		}
+	})
+
+	t.Run("student token is 403", func(t *testing.T) {
+		if rr := do("GET", "/api/admin/users", studentToken, nil); rr.Code != 403 {
+			t.Fatalf("expected 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("admin lists users", func(t *testing.T) {
+		rr := do("GET", "/api/admin/users?role=student", adminToken, nil)
+		if rr.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Users []User `json:"users"`
+			Total int    `json:"total"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Total != 1 || resp.Users[0].UserID != studentID {
+			t.Fatalf("expected only the student, got %+v", resp)
+		}
+	})
+
+	t.Run("admin promotes student and it is audited", func(t *testing.T) {
+		rr := do("PATCH", "/api/admin/users/"+strconv.Itoa(studentID), adminToken, map[string]string{"role": "admin"})
+		if rr.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		u, err := repo.GetUserByID(studentID)
+		if err != nil || u == nil || u.Role != RoleAdmin {
+			t.Fatalf("expected role admin, got %+v (err %v)", u, err)
+		}
+
+		entries, total, err := repo.ListAuditLog("user", adminID, 0, 0)
+		if err != nil {
+			t.Fatalf("ListAuditLog: %v", err)
+		}
+		if total != 1 || entries[0].Action != "user.update" || entries[0].TargetID != strconv.Itoa(studentID) {
+			t.Fatalf("unexpected audit entries: %+v", entries)
+		}
+	})
+
+	t.Run("admin cannot change own role", func(t *testing.T) {
+		rr := do("PATCH", "/api/admin/users/"+strconv.Itoa(adminID), adminToken, map[string]string{"role": "student"})
+		if rr.Code != 400 {
+			t.Fatalf("expected 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("invalid role rejected", func(t *testing.T) {
+		rr := do("PATCH", "/api/admin/users/"+strconv.Itoa(studentID), adminToken, map[string]string{"role": "superuser"})
+		if rr.Code != 400 {
+			t.Fatalf("expected 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("create and delete requisite", func(t *testing.T) {
+		rr := do("POST", "/api/admin/requisites", adminToken, map[string]string{
+			"subject": "compsci", "course_number": "2c03",
+			"req_subject": "COMPSCI", "req_course_number": "1MD3", "kind": "PREREQ",
+		})
+		if rr.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var created Requisite
+		json.NewDecoder(rr.Body).Decode(&created)
+		if created.ReqID == 0 || created.Subject != "COMPSCI" || created.CourseNumber != "2C03" {
+			t.Fatalf("unexpected created requisite: %+v", created)
+		}
+
+		rr = do("DELETE", "/api/admin/requisites/"+strconv.Itoa(created.ReqID), adminToken, nil)
+		if rr.Code != 204 {
+			t.Fatalf("expected 204, got %d: %s", rr.Code, rr.Body.String())
+		}
+		rr = do("DELETE", "/api/admin/requisites/"+strconv.Itoa(created.ReqID), adminToken, nil)
+		if rr.Code != 404 {
+			t.Fatalf("expected 404 on second delete, got %d", rr.Code)
+		}
+	})
+
+	t.Run("link-instructors job links professors", func(t *testing.T) {
+		_, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, professor, term)
+			VALUES ('ZZTEST', '100X', 'Test', 'Ada Lovelace, TBA, Alan Turing', '2025')`)
+		if err != nil {
+			t.Fatalf("seed course: %v", err)
+		}
+		rr := do("POST", "/api/admin/jobs/link-instructors", adminToken, nil)
+		if rr.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Summary map[string]int `json:"summary"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Summary["links_created"] != 2 {
+			t.Fatalf("expected 2 links, got %+v", resp.Summary)
+		}
+
+		// Re-running is a no-op.
+		rr = do("POST", "/api/admin/jobs/link-instructors", adminToken, nil)
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.Summary["links_created"] != 0 {
+			t.Fatalf("expected 0 new links on re-run, got %+v", resp.Summary)
+		}
+
+		_, total, err := repo.ListAuditLog("job", 0, 0, 0)
+		if err != nil || total != 2 {
+			t.Fatalf("expected 2 job audit entries, got %d (err %v)", total, err)
+		}
+	})
+
+	t.Run("unknown job is 404", func(t *testing.T) {
+		if rr := do("POST", "/api/admin/jobs/nope", adminToken, nil); rr.Code != 404 {
+			t.Fatalf("expected 404, got %d", rr.Code)
+		}
+	})
+}
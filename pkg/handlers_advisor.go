@@ -0,0 +1,325 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Advisor routes live under /api/advisor/ and need the advisor role. The
+// student's side (accepting, declining, removing advisors) and comments
+// live under /api/users/{id}/.
+
+func advisorPathParts(r *http.Request) []string {
+	return strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/advisor/"), "/"), "/")
+}
+
+// AdvisorInvitationsHandler serves GET and POST /api/advisor/invitations
+// GET lists the advisor's invitations still waiting on the student. POST
+// invites a student by email, optionally with the program to review them
+// against, and emails them:
+//
+//	{ "email": "student@mcmaster.ca", "program_id": 12 }
+//
+// Returns 201 with the invitation, 404 if there's no such student, or 409
+// if they're already on the caseload.
+func AdvisorInvitationsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := GetClaimsFromContext(r)
+		switch r.Method {
+		case http.MethodGet:
+			links, err := repo.ListAdvisees(claims.UserID, AdvisorPending)
+			if err != nil {
+				log.Printf("list invitations: %v", err)
+				http.Error(w, "failed to fetch invitations", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(links)
+
+		case http.MethodPost:
+			var body struct {
+				Email     string `json:"email"`
+				ProgramID *int   `json:"program_id"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if strings.TrimSpace(body.Email) == "" {
+				writeFieldErrors(w, http.StatusBadRequest, "invalid invitation", []FieldError{{"email", "is required"}})
+				return
+			}
+			if body.ProgramID != nil {
+				program, err := repo.GetProgramWithGroups(*body.ProgramID)
+				if err != nil {
+					log.Printf("load program: %v", err)
+					http.Error(w, "failed to load program", http.StatusInternalServerError)
+					return
+				}
+				if program == nil {
+					writeFieldErrors(w, http.StatusBadRequest, "invalid invitation", []FieldError{{"program_id", "program not found"}})
+					return
+				}
+			}
+
+			link, err := repo.InviteStudent(claims.UserID, body.Email, body.ProgramID)
+			switch {
+			case errors.Is(err, ErrNotFound):
+				http.Error(w, "no student with that email", http.StatusNotFound)
+				return
+			case errors.Is(err, ErrNotAStudent):
+				http.Error(w, "only student accounts can be invited", http.StatusBadRequest)
+				return
+			case errors.Is(err, ErrAlreadyAdvising):
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			case err != nil:
+				log.Printf("invite student: %v", err)
+				http.Error(w, "failed to invite student", http.StatusInternalServerError)
+				return
+			}
+
+			// The invitation stands even if the email can't be queued; the
+			// student also sees it under /api/users/{id}/advisors.
+			if msg, err := advisorInvitationEmail(*link, appURL()+"/advisors", time.Now()); err != nil {
+				log.Printf("[advisor] render invitation: %v", err)
+			} else if _, err := repo.EnqueueEmail(msg); err != nil {
+				log.Printf("[advisor] queue invitation: %v", err)
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(link)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// AdvisorCaseloadHandler serves GET /api/advisor/students
+// Returns each student who has accepted the advisor with their GPA and, if
+// the advisor set a program, a summary of their validation against it and
+// any prerequisite warnings.
+func AdvisorCaseloadHandler(svc *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		caseload, err := svc.Caseload(GetClaimsFromContext(r).UserID)
+		if err != nil {
+			log.Printf("caseload: %v", err)
+			http.Error(w, "failed to load caseload", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(caseload)
+	}
+}
+
+// AdvisorStudentHandler serves DELETE /api/advisor/students/{studentId}
+// Removes the student from the caseload, or withdraws a pending invitation.
+func AdvisorStudentHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		parts := advisorPathParts(r)
+		studentID, err := strconv.Atoi(parts[len(parts)-1])
+		if len(parts) != 2 || err != nil || studentID == 0 {
+			http.Error(w, "invalid student id", http.StatusBadRequest)
+			return
+		}
+		err = repo.EndAdvisorLink(GetClaimsFromContext(r).UserID, studentID)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("end advisor link: %v", err)
+			http.Error(w, "failed to remove student", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// StudentAdvisorsHandler serves GET /api/users/{id}/advisors
+// Lists the student's current advisors and invitations awaiting a reply.
+func StudentAdvisorsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		links, err := repo.ListStudentAdvisors(userID)
+		if err != nil {
+			log.Printf("list advisors: %v", err)
+			http.Error(w, "failed to fetch advisors", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(links)
+	}
+}
+
+// StudentAdvisorHandler serves
+//
+//	POST   /api/users/{id}/advisors/{advisorId}/accept
+//	POST   /api/users/{id}/advisors/{advisorId}/decline
+//	DELETE /api/users/{id}/advisors/{advisorId}
+//
+// Accepting gives the advisor read access to the plan; DELETE takes it away.
+func StudentAdvisorHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, parts, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// parts should be: ["{id}", "advisors", "{advisorId}"(, "accept"|"decline")]
+		if len(parts) < 3 || len(parts) > 4 {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		advisorID, err := strconv.Atoi(parts[2])
+		if err != nil || advisorID == 0 {
+			http.Error(w, "invalid advisor id", http.StatusBadRequest)
+			return
+		}
+
+		switch {
+		case r.Method == http.MethodPost && len(parts) == 4 && (parts[3] == "accept" || parts[3] == "decline"):
+			err = repo.RespondToAdvisor(userID, advisorID, parts[3] == "accept")
+		case r.Method == http.MethodDelete && len(parts) == 3:
+			err = repo.EndAdvisorLink(advisorID, userID)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("advisor link %d/%d: %v", advisorID, userID, err)
+			http.Error(w, "failed to update advisor", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// PlanItemCommentsHandler serves
+//
+//	GET    /api/users/{id}/plan/{itemId}/comments
+//	POST   /api/users/{id}/plan/{itemId}/comments   { "body": "..." }
+//	DELETE /api/users/{id}/plan/{itemId}/comments/{commentId}
+//
+// The student and their advisors can read the comments. Only advisors can
+// post them (the student has the item's note), and only the author can
+// delete one.
+func PlanItemCommentsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, parts, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// parts should be: ["{id}", "plan", "{itemId}", "comments"(, "{commentId}")]
+		if len(parts) < 4 || len(parts) > 5 {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		itemID, err := strconv.Atoi(parts[2])
+		if err != nil || itemID == 0 {
+			http.Error(w, "invalid plan item id", http.StatusBadRequest)
+			return
+		}
+		claims := GetClaimsFromContext(r)
+
+		switch {
+		case r.Method == http.MethodGet && len(parts) == 4:
+			comments, err := repo.ListPlanItemComments(userID, itemID)
+			if errors.Is(err, ErrNotFound) {
+				http.Error(w, "plan item not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("list comments: %v", err)
+				http.Error(w, "failed to fetch comments", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(comments)
+
+		case r.Method == http.MethodPost && len(parts) == 4:
+			if claims.UserID == userID || claims.Role != RoleAdvisor {
+				http.Error(w, "only advisors can comment; use the item's note instead", http.StatusForbidden)
+				return
+			}
+			var body struct {
+				Body string `json:"body"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			body.Body = strings.TrimSpace(body.Body)
+			if body.Body == "" || len(body.Body) > 4000 {
+				writeFieldErrors(w, http.StatusBadRequest, "invalid comment", []FieldError{{"body", "must be 1 to 4000 characters"}})
+				return
+			}
+			c, err := repo.AddPlanItemComment(userID, itemID, claims.UserID, body.Body)
+			if errors.Is(err, ErrNotFound) {
+				http.Error(w, "plan item not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("add comment: %v", err)
+				http.Error(w, "failed to save comment", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(c)
+
+		case r.Method == http.MethodDelete && len(parts) == 5:
+			commentID, err := strconv.Atoi(parts[4])
+			if err != nil || commentID == 0 {
+				http.Error(w, "invalid comment id", http.StatusBadRequest)
+				return
+			}
+			if owned, err := repo.planItemOwned(userID, itemID); err != nil || !owned {
+				http.Error(w, "plan item not found", http.StatusNotFound)
+				return
+			}
+			err = repo.DeletePlanItemComment(itemID, commentID, claims.UserID)
+			if errors.Is(err, ErrNotFound) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				log.Printf("delete comment %d: %v", commentID, err)
+				http.Error(w, "failed to delete comment", http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
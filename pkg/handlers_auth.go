@@ -31,6 +31,7 @@ type AuthResponse struct {
 	DisplayName  string  `json:"display_name"`
 	Program      *string `json:"program"`
 	YearOfStudy  *int    `json:"year_of_study"`
+	Role         Role    `json:"role"`
 }
 
 type RefreshRequest struct {
@@ -80,28 +81,7 @@ func RegisterHandler(repo *Repository) http.HandlerFunc {
 		}
 
 		// Issue tokens immediately so the user is logged in right after registering
-		accessToken, err := GenerateAccessToken(user.UserID, user.Email)
-		if err != nil {
-			http.Error(w, "failed to generate token", http.StatusInternalServerError)
-			return
-		}
-		refreshToken, err := GenerateRefreshToken(user.UserID, user.Email)
-		if err != nil {
-			http.Error(w, "failed to generate token", http.StatusInternalServerError)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(AuthResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-			UserID:       user.UserID,
-			Email:        user.Email,
-			DisplayName:  user.DisplayName,
-			Program:      user.Program,
-			YearOfStudy:  user.YearOfStudy,
-		})
+		writeAuthResponse(w, http.StatusCreated, user)
 	}
 }
 
@@ -130,34 +110,45 @@ func LoginHandler(repo *Repository) http.HandlerFunc {
 			return
 		}
 
-		accessToken, err := GenerateAccessToken(user.UserID, user.Email)
-		if err != nil {
-			http.Error(w, "failed to generate token", http.StatusInternalServerError)
-			return
-		}
-		refreshToken, err := GenerateRefreshToken(user.UserID, user.Email)
-		if err != nil {
-			http.Error(w, "failed to generate token", http.StatusInternalServerError)
-			return
-		}
+		writeAuthResponse(w, http.StatusOK, user)
+	}
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(AuthResponse{
-			AccessToken:  accessToken,
-			RefreshToken: refreshToken,
-			UserID:       user.UserID,
-			Email:        user.Email,
-			DisplayName:  user.DisplayName,
-			Program:      user.Program,
-			YearOfStudy:  user.YearOfStudy,
-		})
+// writeAuthResponse issues a fresh token pair for user and writes the
+// AuthResponse body. Shared by register, password login and SSO so every
+// way of signing in hands the frontend the same shape.
+func writeAuthResponse(w http.ResponseWriter, status int, user *User) {
+	accessToken, err := GenerateAccessToken(user.UserID, user.Email, user.Role)
+	if err != nil {
+		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		return
 	}
+	refreshToken, err := GenerateRefreshToken(user.UserID, user.Email)
+	if err != nil {
+		http.Error(w, "failed to generate token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(AuthResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		UserID:       user.UserID,
+		Email:        user.Email,
+		DisplayName:  user.DisplayName,
+		Program:      user.Program,
+		YearOfStudy:  user.YearOfStudy,
+		Role:         user.Role,
+	})
 }
 
 // RefreshHandler handles POST /api/auth/refresh.
 // Takes a valid refresh token and returns a new access token.
-// No repo needed — this only parses and re-signs a token.
-func RefreshHandler() http.HandlerFunc {
+// The user row is reloaded so role changes (e.g. an admin being demoted)
+// take effect on the next refresh instead of lasting for the refresh
+// token's full 7-day lifetime.
+func RefreshHandler(repo *Repository) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req RefreshRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -177,8 +168,18 @@ func RefreshHandler() http.HandlerFunc {
 			return
 		}
 
+		user, err := repo.GetUserByID(claims.UserID)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+			return
+		}
+
 		// Issue a new short-lived access token
-		newAccessToken, err := GenerateAccessToken(claims.UserID, claims.Email)
+		newAccessToken, err := GenerateAccessToken(user.UserID, user.Email, user.Role)
 		if err != nil {
 			http.Error(w, "failed to generate token", http.StatusInternalServerError)
 			return
@@ -0,0 +1,153 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFeedbackSubmissionAndTriage(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	_, adminToken := seedUser(t, repo, "admin@example.com", RoleAdmin)
+	studentID, studentToken := seedUser(t, repo, "student@example.com", RoleStudent)
+
+	do := func(method, path, token string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("User-Agent", "test-agent/1.0")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	submit := func(token, body string) int {
+		t.Helper()
+		rr := do("POST", "/api/feedback", token, body)
+		if rr.Code != 202 {
+			t.Fatalf("expected 202, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			FeedbackID int `json:"feedback_id"`
+		}
+		json.NewDecoder(rr.Body).Decode(&resp)
+		return resp.FeedbackID
+	}
+
+	t.Run("anonymous submission is stored without email", func(t *testing.T) {
+		t.Setenv("FEEDBACK_TO", "")
+		id := submit("", `{"message":"Search is slow","page":"/search"}`)
+		f, err := repo.GetFeedback(id)
+		if err != nil || f == nil {
+			t.Fatalf("GetFeedback: %+v %v", f, err)
+		}
+		if f.UserID != nil || f.Status != FeedbackOpen || *f.Page != "/search" || *f.UserAgent != "test-agent/1.0" {
+			t.Fatalf("unexpected stored feedback: %+v", f)
+		}
+		if _, n, _ := repo.ListOutboundEmails("", 0, 0); n != 0 {
+			t.Fatalf("expected no email queued without FEEDBACK_TO, got %d", n)
+		}
+	})
+
+	t.Run("signed-in submission is attributed and notifies", func(t *testing.T) {
+		t.Setenv("FEEDBACK_TO", "ops@example.com")
+		id := submit(studentToken, `{"message":"Love the planner","email":"me@example.com"}`)
+		f, _ := repo.GetFeedback(id)
+		if f.UserID == nil || *f.UserID != studentID || *f.Email != "me@example.com" {
+			t.Fatalf("expected attribution to student, got %+v", f)
+		}
+		if _, n, _ := repo.ListOutboundEmails(EmailPending, 0, 0); n != 1 {
+			t.Fatalf("expected 1 queued notification, got %d", n)
+		}
+	})
+
+	t.Run("invalid token still accepted anonymously", func(t *testing.T) {
+		id := submit("garbage", `{"message":"hello"}`)
+		if f, _ := repo.GetFeedback(id); f.UserID != nil {
+			t.Fatalf("expected anonymous feedback, got user %v", *f.UserID)
+		}
+	})
+
+	t.Run("students cannot triage", func(t *testing.T) {
+		if rr := do("GET", "/api/admin/feedback", studentToken, ""); rr.Code != 403 {
+			t.Fatalf("expected 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("admin tags, filters and resolves", func(t *testing.T) {
+		items, _, _ := repo.ListFeedback(FeedbackFilter{Q: "slow"}, 0, 0)
+		if len(items) != 1 {
+			t.Fatalf("expected 1 match for q=slow, got %d", len(items))
+		}
+		id := strconv.Itoa(items[0].FeedbackID)
+
+		body, _ := json.Marshal(map[string]interface{}{"tags": []string{"Performance", " search ", "performance"}})
+		rr := do("PATCH", "/api/admin/feedback/"+id, adminToken, string(body))
+		if rr.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var f Feedback
+		json.NewDecoder(rr.Body).Decode(&f)
+		if strings.Join(f.Tags, ",") != "performance,search" {
+			t.Fatalf("expected normalised tags, got %v", f.Tags)
+		}
+
+		rr = do("GET", "/api/admin/feedback?tag=performance", adminToken, "")
+		var list struct {
+			Feedback []Feedback `json:"feedback"`
+			Total    int        `json:"total"`
+		}
+		json.NewDecoder(rr.Body).Decode(&list)
+		if list.Total != 1 || list.Feedback[0].FeedbackID != f.FeedbackID {
+			t.Fatalf("tag filter returned %+v", list)
+		}
+
+		rr = do("PATCH", "/api/admin/feedback/"+id, adminToken, `{"status":"resolved"}`)
+		json.NewDecoder(rr.Body).Decode(&f)
+		if f.Status != FeedbackResolved || f.ResolvedAt == nil || f.ResolvedBy == nil {
+			t.Fatalf("expected resolved with timestamp and resolver, got %+v", f)
+		}
+		if _, open, _ := repo.ListFeedback(FeedbackFilter{Status: FeedbackOpen}, 0, 0); open != 2 {
+			t.Fatalf("expected 2 open items left, got %d", open)
+		}
+
+		if rr := do("PATCH", "/api/admin/feedback/"+id, adminToken, `{"status":"done"}`); rr.Code != 400 {
+			t.Fatalf("expected 400 for bad status, got %d", rr.Code)
+		}
+		if rr := do("PATCH", "/api/admin/feedback/99999", adminToken, `{"status":"resolved"}`); rr.Code != 404 {
+			t.Fatalf("expected 404, got %d", rr.Code)
+		}
+
+		if body := do("GET", "/api/admin/feedback/tags", adminToken, "").Body.String(); !strings.Contains(body, `"performance":1`) {
+			t.Fatalf("unexpected tag counts: %s", body)
+		}
+	})
+}
+
+func TestOptionalString(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc"},
+		{"cafés", 4, "caf"}, // é is two bytes; keep it whole or not at all
+		{"été", 5, "été"},   // exactly fits
+		{"ok\xffok", 10, "ok\uFFFDok"},
+	} {
+		got := optionalString(tt.in, tt.max)
+		if got == nil || *got != tt.want || !utf8.ValidString(*got) {
+			t.Errorf("optionalString(%q, %d) = %q, want %q", tt.in, tt.max, deref(got), tt.want)
+		}
+	}
+	if optionalString("", 10) != nil {
+		t.Error(`optionalString("") should be nil`)
+	}
+}
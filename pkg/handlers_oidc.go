@@ -0,0 +1,181 @@
+package pkg
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// oidcStateTTL bounds how long a user may sit on the IdP's login page.
+const oidcStateTTL = 10 * time.Minute
+
+// oidcStateCookie ties a login's state to the browser that started it. The
+// callback only accepts a state that matches it, so nobody can start a login
+// with their own IdP account and have someone else's browser finish it.
+const oidcStateCookie = "mactrack_oidc_state"
+
+// ssoPasswordHash is stored for accounts created through SSO. It is not a
+// valid bcrypt hash, so password login always fails until the user sets one
+// via the forgot-password flow.
+const ssoPasswordHash = "!sso"
+
+// OIDCCallbackRequest is the body accepted by POST /api/auth/oidc/callback.
+// The frontend page registered as OIDC_REDIRECT_URL forwards the code and
+// state it received from the IdP, with credentials so the state cookie set by
+// OIDCStartHandler goes along.
+type OIDCCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// OIDCStartHandler serves GET /api/auth/oidc/start.
+// Generates state, nonce and a PKCE verifier, remembers them server-side,
+// sets the state cookie and redirects the browser to the IdP's authorization
+// endpoint.
+func OIDCStartHandler(repo *Repository, provider *OIDCProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var s OIDCLoginState
+		var err error
+		if s.State, err = randomURLToken(32); err == nil {
+			if s.Nonce, err = randomURLToken(32); err == nil {
+				s.CodeVerifier, err = randomURLToken(32)
+			}
+		}
+		if err != nil {
+			log.Printf("[oidc] rand error: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		authURL, err := provider.AuthCodeURL(r.Context(), s.State, s.Nonce, s.CodeVerifier)
+		if err != nil {
+			log.Printf("[oidc] %v", err)
+			http.Error(w, "identity provider unavailable", http.StatusBadGateway)
+			return
+		}
+
+		if err := repo.CreateOIDCLoginState(s, time.Now().Add(oidcStateTTL)); err != nil {
+			log.Printf("[oidc] store state error: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		setOIDCStateCookie(w, r, s.State, int(oidcStateTTL/time.Second))
+
+		http.Redirect(w, r, authURL, http.StatusFound)
+	}
+}
+
+// OIDCCallbackHandler serves GET and POST /api/auth/oidc/callback.
+// Accepts code and state either as query parameters or as a JSON body,
+// exchanges the code, and signs the user in:
+//
+//  1. a user already linked to the IdP subject is signed in directly;
+//  2. otherwise an existing account with the same (verified) email is linked;
+//  3. otherwise a new account is created.
+//
+// Responds with the same AuthResponse as LoginHandler.
+func OIDCCallbackHandler(repo *Repository, provider *OIDCProvider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req OIDCCallbackRequest
+		switch r.Method {
+		case http.MethodGet:
+			q := r.URL.Query()
+			if e := q.Get("error"); e != "" {
+				http.Error(w, "sign-in was cancelled or denied: "+e, http.StatusUnauthorized)
+				return
+			}
+			req.Code, req.State = q.Get("code"), q.Get("state")
+		case http.MethodPost:
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if req.Code == "" || req.State == "" {
+			http.Error(w, "code and state are required", http.StatusBadRequest)
+			return
+		}
+
+		c, err := r.Cookie(oidcStateCookie)
+		if err != nil || subtle.ConstantTimeCompare([]byte(c.Value), []byte(req.State)) != 1 {
+			http.Error(w, "sign-in was not started in this browser", http.StatusBadRequest)
+			return
+		}
+		setOIDCStateCookie(w, r, "", -1)
+
+		state, err := repo.ConsumeOIDCLoginState(req.State)
+		if err != nil {
+			log.Printf("[oidc] consume state error: %v", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if state == nil {
+			http.Error(w, "invalid or expired sign-in attempt", http.StatusBadRequest)
+			return
+		}
+
+		ident, err := provider.Exchange(r.Context(), req.Code, state.CodeVerifier, state.Nonce)
+		if err != nil {
+			log.Printf("[oidc] exchange error: %v", err)
+			http.Error(w, "sign-in failed", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := repo.GetUserByIdentity(ident.Issuer, ident.Subject)
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			// Only a verified email may be used to match or create an account;
+			// otherwise anyone could claim an address at a permissive IdP.
+			if ident.Email == "" || !ident.EmailVerified {
+				http.Error(w, "identity provider did not return a verified email", http.StatusForbidden)
+				return
+			}
+			if user, err = repo.GetUserByEmail(ident.Email); err != nil {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			if user == nil {
+				name := strings.TrimSpace(ident.Name)
+				if name == "" {
+					name = strings.SplitN(ident.Email, "@", 2)[0]
+				}
+				if user, err = repo.CreateUser(ident.Email, name, ssoPasswordHash, nil, nil); err != nil {
+					log.Printf("[oidc] create user error: %v", err)
+					http.Error(w, "failed to create user", http.StatusInternalServerError)
+					return
+				}
+			}
+			if err := repo.LinkUserIdentity(user.UserID, ident.Issuer, ident.Subject, ident.Email); err != nil {
+				log.Printf("[oidc] link identity error: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		writeAuthResponse(w, http.StatusOK, user)
+	}
+}
+
+// setOIDCStateCookie sets the state cookie, or clears it when maxAge < 0.
+// It's only sent back to the OIDC routes, and Lax so it survives the
+// top-level redirect back from the IdP.
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, state string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,365 @@
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stubIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint
+// that checks the PKCE verifier before returning an RS256-signed ID token.
+type stubIdP struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+	// issuer is what discovery and ID tokens name as the issuer; srv.URL
+	// unless a test changes it.
+	issuer string
+
+	mu          sync.Mutex
+	codes       map[string]stubGrant
+	jwksFetches int
+}
+
+type stubGrant struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T, clientID string) *stubIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	idp := &stubIdP{key: key, codes: map[string]stubGrant{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.issuer,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.mu.Lock()
+		idp.jwksFetches++
+		idp.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.mu.Lock()
+		grant, ok := idp.codes[r.Form.Get("code")]
+		delete(idp.codes, r.Form.Get("code"))
+		idp.mu.Unlock()
+		if !ok || r.Form.Get("client_id") != clientID || pkceChallenge(r.Form.Get("code_verifier")) != grant.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, grant.claims)
+		tok.Header["kid"] = "k1"
+		signed, _ := tok.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": signed})
+	})
+	idp.srv = httptest.NewServer(mux)
+	idp.issuer = idp.srv.URL
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+// authorize plays the part of the user logging in at the IdP: it reads the
+// authorization URL our API redirected to and issues a code bound to its
+// PKCE challenge and nonce.
+func (idp *stubIdP) authorize(t *testing.T, location, code string, claims jwt.MapClaims) (state string) {
+	t.Helper()
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatalf("parse redirect: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		t.Fatalf("expected S256 PKCE challenge, got %q", u.RawQuery)
+	}
+	base := jwt.MapClaims{
+		"iss":   idp.issuer,
+		"aud":   q.Get("client_id"),
+		"exp":   time.Now().Add(5 * time.Minute).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": q.Get("nonce"),
+	}
+	for k, v := range claims {
+		base[k] = v
+	}
+	idp.mu.Lock()
+	idp.codes[code] = stubGrant{challenge: q.Get("code_challenge"), claims: base}
+	idp.mu.Unlock()
+	return q.Get("state")
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newStubIdP(t, "mactrack")
+	t.Setenv("OIDC_ISSUER", idp.srv.URL)
+	t.Setenv("OIDC_CLIENT_ID", "mactrack")
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost:5173/auth/callback")
+
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	existingID, _ := seedUser(t, repo, "ada@mcmaster.ca", RoleStudent)
+
+	// cookies plays the browser: start sets the state cookie and callback
+	// sends it back.
+	var cookies []*http.Cookie
+	start := func(t *testing.T) string {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/auth/oidc/start", nil))
+		if rr.Code != http.StatusFound {
+			t.Fatalf("start: expected 302, got %d: %s", rr.Code, rr.Body.String())
+		}
+		cookies = rr.Result().Cookies()
+		return rr.Header().Get("Location")
+	}
+	callback := func(code, state string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/auth/oidc/callback?code="+code+"&state="+state, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("links existing user by verified email", func(t *testing.T) {
+		state := idp.authorize(t, start(t), "c1", jwt.MapClaims{
+			"sub": "sub-ada", "email": "Ada@McMaster.ca", "email_verified": true,
+		})
+		rr := callback("c1", state)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp AuthResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.UserID != existingID || resp.AccessToken == "" || resp.RefreshToken == "" {
+			t.Fatalf("unexpected auth response: %+v", resp)
+		}
+		if u, _ := repo.GetUserByIdentity(idp.srv.URL, "sub-ada"); u == nil || u.UserID != existingID {
+			t.Fatalf("expected identity linked to user %d, got %+v", existingID, u)
+		}
+	})
+
+	t.Run("linked subject signs in even if email changed", func(t *testing.T) {
+		state := idp.authorize(t, start(t), "c2", jwt.MapClaims{
+			"sub": "sub-ada", "email": "ada.lovelace@example.com", "email_verified": false,
+		})
+		rr := callback("c2", state)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp AuthResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if resp.UserID != existingID {
+			t.Fatalf("expected user %d, got %d", existingID, resp.UserID)
+		}
+	})
+
+	t.Run("creates new user", func(t *testing.T) {
+		state := idp.authorize(t, start(t), "c3", jwt.MapClaims{
+			"sub": "sub-alan", "email": "alan@mcmaster.ca", "email_verified": "true", "name": "Alan Turing",
+		})
+		rr := callback("c3", state)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		u, err := repo.GetUserByEmail("alan@mcmaster.ca")
+		if err != nil || u == nil || u.DisplayName != "Alan Turing" || u.Role != RoleStudent {
+			t.Fatalf("expected new student account, got %+v (err %v)", u, err)
+		}
+	})
+
+	t.Run("unverified email is rejected", func(t *testing.T) {
+		state := idp.authorize(t, start(t), "c4", jwt.MapClaims{
+			"sub": "sub-mallory", "email": "ada@mcmaster.ca", "email_verified": false,
+		})
+		if rr := callback("c4", state); rr.Code != http.StatusForbidden {
+			t.Fatalf("expected 403, got %d: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("state cannot be replayed", func(t *testing.T) {
+		state := idp.authorize(t, start(t), "c5", jwt.MapClaims{
+			"sub": "sub-ada", "email": "ada@mcmaster.ca", "email_verified": true,
+		})
+		if rr := callback("c5", state); rr.Code != http.StatusOK {
+			t.Fatalf("first callback: expected 200, got %d", rr.Code)
+		}
+		if rr := callback("c5", state); rr.Code != http.StatusBadRequest {
+			t.Fatalf("replay: expected 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("state from another browser is rejected", func(t *testing.T) {
+		// Mallory starts a login and signs in at the IdP...
+		state := idp.authorize(t, start(t), "c8", jwt.MapClaims{
+			"sub": "sub-mallory", "email": "mallory@mcmaster.ca", "email_verified": true,
+		})
+		// ...then has a browser that didn't start it finish it: one with no
+		// login under way, and one with its own.
+		start(t)
+		for _, browser := range [][]*http.Cookie{nil, cookies} {
+			cookies = browser
+			if rr := callback("c8", state); rr.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
+			}
+		}
+		if u, _ := repo.GetUserByEmail("mallory@mcmaster.ca"); u != nil {
+			t.Fatalf("expected no account for mallory, got %+v", u)
+		}
+	})
+
+	t.Run("unknown state is rejected", func(t *testing.T) {
+		if rr := callback("whatever", "not-a-state"); rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400, got %d", rr.Code)
+		}
+	})
+
+	t.Run("wrong audience is rejected", func(t *testing.T) {
+		state := idp.authorize(t, start(t), "c6", jwt.MapClaims{
+			"sub": "sub-ada", "email": "ada@mcmaster.ca", "email_verified": true, "aud": "someone-else",
+		})
+		if rr := callback("c6", state); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", rr.Code)
+		}
+	})
+
+	t.Run("tampered nonce is rejected", func(t *testing.T) {
+		state := idp.authorize(t, start(t), "c7", jwt.MapClaims{
+			"sub": "sub-ada", "email": "ada@mcmaster.ca", "email_verified": true, "nonce": "forged",
+		})
+		if rr := callback("c7", state); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", rr.Code)
+		}
+	})
+}
+
+// Some IdPs (Auth0, some Okta setups) give their issuer with a trailing
+// slash, and their ID tokens' iss has to match it exactly.
+func TestOIDCLogin_IssuerTrailingSlash(t *testing.T) {
+	idp := newStubIdP(t, "mactrack")
+	idp.issuer = idp.srv.URL + "/"
+	t.Setenv("OIDC_ISSUER", idp.issuer)
+	t.Setenv("OIDC_CLIENT_ID", "mactrack")
+	t.Setenv("OIDC_REDIRECT_URL", "http://localhost:5173/auth/callback")
+
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	login := func(code string, claims jwt.MapClaims) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/auth/oidc/start", nil))
+		if rr.Code != http.StatusFound {
+			t.Fatalf("start: expected 302, got %d: %s", rr.Code, rr.Body.String())
+		}
+		state := idp.authorize(t, rr.Header().Get("Location"), code, claims)
+		req := httptest.NewRequest("GET", "/api/auth/oidc/callback?code="+code+"&state="+state, nil)
+		for _, c := range rr.Result().Cookies() {
+			req.AddCookie(c)
+		}
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := login("c1", jwt.MapClaims{"sub": "sub-ada", "email": "ada@mcmaster.ca", "email_verified": true})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if u, _ := repo.GetUserByIdentity(idp.srv.URL, "sub-ada"); u == nil {
+		t.Fatal("expected the identity stored under the issuer without its trailing slash")
+	}
+
+	// The exact match still rejects a token from anyone else.
+	rr = login("c2", jwt.MapClaims{"sub": "sub-ada", "email": "ada@mcmaster.ca", "email_verified": true, "iss": idp.srv.URL})
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("mismatched iss: expected 401, got %d", rr.Code)
+	}
+}
+
+// Tokens naming made-up kids mustn't make us fetch the JWKS every time.
+func TestOIDCSigningKey_RefetchLimit(t *testing.T) {
+	idp := newStubIdP(t, "mactrack")
+	p := NewOIDCProvider(OIDCConfig{Issuer: idp.srv.URL, ClientID: "mactrack"})
+	ctx := context.Background()
+	fetches := func() int {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		return idp.jwksFetches
+	}
+
+	if _, err := p.signingKey(ctx, "k1"); err != nil {
+		t.Fatalf("k1: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := p.signingKey(ctx, fmt.Sprintf("bogus-%d", i)); err == nil {
+			t.Fatal("expected an unknown kid to be rejected")
+		}
+	}
+	if n := fetches(); n != 1 {
+		t.Fatalf("expected 1 JWKS fetch, got %d", n)
+	}
+
+	// Once the interval is up, concurrent lookups share one fetch.
+	p.mu.Lock()
+	p.keysFetched = p.keysFetched.Add(-jwksMinRefresh)
+	p.mu.Unlock()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.signingKey(ctx, "rotated")
+		}()
+	}
+	wg.Wait()
+	if n := fetches(); n != 2 {
+		t.Fatalf("expected 2 JWKS fetches, got %d", n)
+	}
+	if _, err := p.signingKey(ctx, "k1"); err != nil {
+		t.Fatalf("k1 after refetch: %v", err)
+	}
+}
+
+func TestOIDCRoutesDisabledWithoutConfig(t *testing.T) {
+	t.Setenv("OIDC_ISSUER", "")
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/auth/oidc/start", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
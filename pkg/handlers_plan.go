@@ -0,0 +1,422 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// planPathParts splits the path after /api/users/ and parses the user ID,
+// e.g. "/api/users/7/plan/history" → 7, ["7", "plan", "history"].
+func planPathParts(r *http.Request) (int, []string, error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/"), "/")
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil || userID == 0 {
+		return 0, nil, errors.New("invalid user id")
+	}
+	return userID, parts, nil
+}
+
+// PlanHistoryHandler serves GET /api/users/{id}/plan/history?limit={n}&offset={n}
+// Returns the user's plan events newest first, each with the item's state
+// before and after:
+//
+//	{ "events": [...], "total": N }
+func PlanHistoryHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit, offset := adminPage(r)
+		events, total, err := repo.ListPlanEvents(userID, limit, offset)
+		if err != nil {
+			log.Printf("list plan events: %v", err)
+			http.Error(w, "failed to fetch plan history", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"events": events,
+			"total":  total,
+		})
+	}
+}
+
+// UndoPlanEventHandler serves POST /api/users/{id}/plan/history/{eventId}/undo
+// Reverts one event. Responds 409 if the item has changed since, in which
+// case undo the later events first (or restore to a time instead).
+func UndoPlanEventHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, parts, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// parts should be: ["{id}", "plan", "history", "{eventId}", "undo"]
+		if len(parts) != 5 {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		eventID, err := strconv.Atoi(parts[3])
+		if err != nil || eventID == 0 {
+			http.Error(w, "invalid event id", http.StatusBadRequest)
+			return
+		}
+
+		e, err := repo.UndoPlanEvent(actorID(r), userID, eventID)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			http.Error(w, "event not found", http.StatusNotFound)
+			return
+		case errors.Is(err, ErrPlanConflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			log.Printf("undo plan event %d: %v", eventID, err)
+			http.Error(w, "failed to undo", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"undone": e})
+	}
+}
+
+// RestorePlanHandler serves POST /api/users/{id}/plan/restore
+// Body: { "as_of": "<RFC 3339 timestamp>" }. Rewinds the plan to how it was
+// at that time and returns { "reverted": N }, the number of events undone.
+func RestorePlanHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body struct {
+			AsOf string `json:"as_of"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		asOf, err := time.Parse(time.RFC3339, body.AsOf)
+		if err != nil {
+			http.Error(w, "as_of must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+
+		n, err := repo.RestorePlan(actorID(r), userID, asOf)
+		if errors.Is(err, ErrPlanConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("restore plan for user %d: %v", userID, err)
+			http.Error(w, "failed to restore plan", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"reverted": n})
+	}
+}
+
+// PlanBatchHandler serves POST /api/users/{id}/plan/batch?program_id={id}
+// Applies a list of add/move/update/delete operations (see PlanOp) in one
+// transaction, for drag-and-drop editing:
+//
+//	{ "operations": [ { "op": "move", "plan_item_id": 12, "year_index": 2, "season": "Winter" }, ... ] }
+//
+// On success returns the updated plan and, when program_id is given, the
+// plan validated against that program:
+//
+//	{ "plan": [...], "validation": {...} }
+//
+// If any operation fails nothing is applied, and the response is 400 with
+// { "errors": [ { "index": 0, "op": "move", "error": "..." } ] }.
+func PlanBatchHandler(repo *Repository, svc *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var body struct {
+			Operations []PlanOp `json:"operations"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(body.Operations) == 0 {
+			http.Error(w, "operations is required", http.StatusBadRequest)
+			return
+		}
+
+		// Load the program first so a bad program_id doesn't leave the
+		// batch applied behind an error response.
+		var program *Program
+		if pid := r.URL.Query().Get("program_id"); pid != "" {
+			programID, err := strconv.Atoi(pid)
+			if err != nil || programID == 0 {
+				http.Error(w, "invalid program_id", http.StatusBadRequest)
+				return
+			}
+			program, err = repo.GetProgramWithGroups(programID)
+			if err != nil {
+				log.Printf("load program: %v", err)
+				http.Error(w, "failed to load program", http.StatusInternalServerError)
+				return
+			}
+			if program == nil {
+				http.Error(w, "program not found", http.StatusNotFound)
+				return
+			}
+		}
+
+		err = repo.ApplyPlanBatch(actorID(r), userID, body.Operations)
+		var batchErr *PlanBatchError
+		if errors.As(err, &batchErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"errors": batchErr.Errors})
+			return
+		}
+		if err != nil {
+			log.Printf("plan batch for user %d: %v", userID, err)
+			http.Error(w, "failed to apply plan changes", http.StatusInternalServerError)
+			return
+		}
+
+		plan, err := repo.GetUserPlan(userID)
+		if err != nil {
+			log.Printf("get plan for user %d: %v", userID, err)
+			http.Error(w, "failed to fetch plan items", http.StatusInternalServerError)
+			return
+		}
+		var validation *ValidationResult
+		if program != nil {
+			result, err := svc.ValidateUserPlan(userID, program)
+			if err != nil {
+				log.Printf("validation error: %v", err)
+				http.Error(w, "validation failed", http.StatusInternalServerError)
+				return
+			}
+			validation = &result
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"plan":       plan,
+			"validation": validation,
+		})
+	}
+}
+
+// maxImportBytes caps an import body; a full transcript is a few KB.
+const maxImportBytes = 1 << 20
+
+// PlanImportHandler serves POST /api/users/{id}/plan/import?format=text|csv|json&commit=true
+// The body is a pasted unofficial transcript, a CSV, or a JSON plan
+// document from the export endpoint; format is detected when omitted.
+// Without commit it returns a preview of what would be added or updated,
+// with unknown courses and conflicts marked (see ImportPreview). Send the
+// same body again with commit=true to apply it.
+func PlanImportHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		u, err := repo.GetUserByID(userID)
+		if err != nil {
+			log.Printf("get user %d: %v", userID, err)
+			http.Error(w, "failed to load user", http.StatusInternalServerError)
+			return
+		}
+		if u == nil {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxImportBytes+1))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if len(body) > maxImportBytes {
+			http.Error(w, "import is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = DetectImportFormat(r.Header.Get("Content-Type"), body)
+		}
+		courses, err := ParseImport(format, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var preview *ImportPreview
+		if r.URL.Query().Get("commit") == "true" {
+			preview, err = repo.CommitPlanImport(actorID(r), u, format, courses, time.Now())
+		} else {
+			preview, err = repo.PreviewPlanImport(u, format, courses, time.Now())
+		}
+		if errors.Is(err, ErrPlanConflict) {
+			http.Error(w, "plan changed since the preview; preview the import again", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("plan import for user %d: %v", userID, err)
+			http.Error(w, "failed to import plan", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preview)
+	}
+}
+
+// PlanExportHandler serves GET /api/users/{id}/plan/export?format=csv|ics|html|json&program_id={id}
+//
+//	csv:  one row per course (term, status, grade), re-importable
+//	ics:  an all-day calendar event per plan term
+//	html: a self-contained printable degree audit; with program_id it
+//	      includes the plan validated against that program
+//	json: a versioned PlanDocument (the default), which
+//	      POST /api/users/{id}/plan/import reads back
+func PlanExportHandler(repo *Repository, svc *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = ExportFormatJSON
+		}
+		if format != ExportFormatCSV && format != ExportFormatICS && format != ExportFormatHTML && format != ExportFormatJSON {
+			http.Error(w, "format must be csv, ics, html or json", http.StatusBadRequest)
+			return
+		}
+
+		u, err := repo.GetUserByID(userID)
+		if err != nil {
+			log.Printf("get user %d: %v", userID, err)
+			http.Error(w, "failed to load user", http.StatusInternalServerError)
+			return
+		}
+		if u == nil {
+			http.Error(w, "user not found", http.StatusNotFound)
+			return
+		}
+		items, err := repo.GetUserPlan(userID)
+		if err != nil {
+			log.Printf("get plan for user %d: %v", userID, err)
+			http.Error(w, "failed to fetch plan items", http.StatusInternalServerError)
+			return
+		}
+
+		filename := "degree-plan." + format
+		switch format {
+		case ExportFormatCSV:
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+			err = writePlanCSV(w, items)
+
+		case ExportFormatICS:
+			w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+			err = writePlanICS(w, userID, groupPlanByTerm(items), time.Now())
+
+		case ExportFormatJSON:
+			startYear := u.StartYear
+			if startYear == nil {
+				if y, err := repo.UserStartYear(u, time.Now()); err == nil {
+					startYear = &y
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+			err = json.NewEncoder(w).Encode(planDocument(startYear, items))
+
+		case ExportFormatHTML:
+			data := PlanAuditData{
+				Name:      u.DisplayName,
+				Generated: time.Now().Format("January 2, 2006"),
+				Terms:     groupPlanByTerm(items),
+			}
+			if pid := r.URL.Query().Get("program_id"); pid != "" {
+				programID, err := strconv.Atoi(pid)
+				if err != nil || programID == 0 {
+					http.Error(w, "invalid program_id", http.StatusBadRequest)
+					return
+				}
+				data.Program, err = repo.GetProgramWithGroups(programID)
+				if err != nil {
+					log.Printf("load program: %v", err)
+					http.Error(w, "failed to load program", http.StatusInternalServerError)
+					return
+				}
+				if data.Program == nil {
+					http.Error(w, "program not found", http.StatusNotFound)
+					return
+				}
+				result, err := svc.ValidateUserPlan(userID, data.Program)
+				if err != nil {
+					log.Printf("validation error: %v", err)
+					http.Error(w, "validation failed", http.StatusInternalServerError)
+					return
+				}
+				data.Validation = &result
+			}
+			if gpa, ok, err := repo.GetUserGPA(userID); err == nil && ok {
+				data.GPA = &gpa
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			err = renderPlanHTML(w, data)
+		}
+		if err != nil {
+			log.Printf("plan export (%s) for user %d: %v", format, userID, err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SharedPlan is the read-only view of a plan behind a share link. It
+// deliberately carries nothing that identifies the account (no user or
+// plan item ids, no email) and leaves out the student's notes.
+type SharedPlan struct {
+	Name         string            `json:"name"`
+	Label        *string           `json:"label,omitempty"`
+	GradesHidden bool              `json:"grades_hidden"`
+	ExpiresAt    *time.Time        `json:"expires_at,omitempty"`
+	Items        []SharedPlanItem  `json:"items"`
+	Program      *string           `json:"program,omitempty"`
+	Validation   *ValidationResult `json:"validation,omitempty"`
+	GPA          *float64          `json:"gpa,omitempty"`
+}
+
+// SharedPlanItem is one course in a SharedPlan.
+type SharedPlanItem struct {
+	YearIndex    int     `json:"year_index"`
+	Season       string  `json:"season"`
+	Term         *Term   `json:"term,omitempty"`
+	Subject      string  `json:"subject"`
+	CourseNumber string  `json:"course_number"`
+	CourseName   *string `json:"course_name,omitempty"`
+	Status       string  `json:"status"`
+	Grade        *string `json:"grade,omitempty"`
+	IsCustom     bool    `json:"is_custom"`
+}
+
+// sharedPlan builds the view a share link unlocks: the plan, its
+// validation against the share's program (if it has one) and the GPA.
+// Grades and the GPA are left out when the share hides grades, and so is
+// the program standing, whose rules are measured on them.
+func sharedPlan(repo *Repository, svc *Service, share *PlanShare) (*SharedPlan, error) {
+	u, err := repo.GetUserByID(share.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrNotFound
+	}
+	items, err := repo.GetUserPlan(share.UserID)
+	if err != nil {
+		return nil, err
+	}
+	out := &SharedPlan{
+		Name:         u.DisplayName,
+		Label:        share.Label,
+		GradesHidden: share.HideGrades,
+		ExpiresAt:    share.ExpiresAt,
+		Items:        make([]SharedPlanItem, 0, len(items)),
+	}
+	for _, it := range items {
+		si := SharedPlanItem{
+			YearIndex: it.YearIndex, Season: it.Season, Term: it.Term,
+			Subject: it.Subject, CourseNumber: it.CourseNumber, CourseName: it.CourseName,
+			Status: it.Status, IsCustom: it.IsCustom,
+		}
+		if !share.HideGrades {
+			si.Grade = it.Grade
+		}
+		out.Items = append(out.Items, si)
+	}
+
+	if share.ProgramID != nil {
+		program, err := repo.GetProgramWithGroups(*share.ProgramID)
+		if err != nil {
+			return nil, err
+		}
+		if program != nil {
+			result, err := svc.ValidateUserPlan(share.UserID, program)
+			if err != nil {
+				return nil, err
+			}
+			if share.HideGrades {
+				result.Standing = nil
+			}
+			out.Program = &program.Name
+			out.Validation = &result
+		}
+	}
+	if !share.HideGrades {
+		gpa, ok, err := repo.GetUserGPA(share.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out.GPA = &gpa
+		}
+	}
+	return out, nil
+}
+
+// PlanSharesHandler serves GET and POST /api/users/{id}/plan/shares
+// GET lists the user's share links. POST makes one:
+//
+//	{ "label": "Advisor", "program_id": 12, "hide_grades": true,
+//	  "expires_at": "2025-06-01T00:00:00Z" }
+//
+// All fields are optional. Returns 201 with the share, its token and the
+// link to hand out; the token isn't shown again.
+func PlanSharesHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			shares, err := repo.ListPlanShares(userID)
+			if err != nil {
+				log.Printf("list plan shares: %v", err)
+				http.Error(w, "failed to fetch share links", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(shares)
+
+		case http.MethodPost:
+			var body struct {
+				Label      *string    `json:"label"`
+				ProgramID  *int       `json:"program_id"`
+				HideGrades bool       `json:"hide_grades"`
+				ExpiresAt  *time.Time `json:"expires_at"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			var fields []FieldError
+			if body.Label != nil {
+				if l := strings.TrimSpace(*body.Label); l == "" {
+					body.Label = nil
+				} else {
+					body.Label = &l
+				}
+			}
+			if body.ExpiresAt != nil && !body.ExpiresAt.After(time.Now()) {
+				fields = append(fields, FieldError{"expires_at", "must be in the future"})
+			}
+			if body.ProgramID != nil {
+				program, err := repo.GetProgramWithGroups(*body.ProgramID)
+				if err != nil {
+					log.Printf("load program: %v", err)
+					http.Error(w, "failed to load program", http.StatusInternalServerError)
+					return
+				}
+				if program == nil {
+					fields = append(fields, FieldError{"program_id", "program not found"})
+				}
+			}
+			if len(fields) > 0 {
+				writeFieldErrors(w, http.StatusBadRequest, "invalid share link", fields)
+				return
+			}
+
+			share, err := repo.CreatePlanShare(PlanShare{
+				UserID: userID, Label: body.Label, ProgramID: body.ProgramID,
+				HideGrades: body.HideGrades, ExpiresAt: body.ExpiresAt,
+			})
+			if err != nil {
+				log.Printf("create plan share: %v", err)
+				http.Error(w, "failed to create share link", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(struct {
+				*PlanShare
+				URL string `json:"url"`
+			}{share, appURL() + "/shared/" + share.Token})
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// RevokePlanShareHandler serves DELETE /api/users/{id}/plan/shares/{shareId}
+// The share stays in the list, marked revoked; its link stops working.
+func RevokePlanShareHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, parts, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// parts should be: ["{id}", "plan", "shares", "{shareId}"]
+		if len(parts) != 4 {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		shareID, err := strconv.Atoi(parts[3])
+		if err != nil || shareID == 0 {
+			http.Error(w, "invalid share id", http.StatusBadRequest)
+			return
+		}
+
+		err = repo.RevokePlanShare(userID, shareID)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("revoke plan share %d: %v", shareID, err)
+			http.Error(w, "failed to revoke share link", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SharedPlanHandler serves GET /api/shared/{token}
+// Public: the token is the only credential. Unknown, revoked and expired
+// links all get the same 404.
+func SharedPlanHandler(repo *Repository, svc *Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		token := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/shared/"), "/")
+		if token == "" || strings.Contains(token, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		share, err := repo.OpenPlanShare(token, time.Now())
+		if err != nil {
+			log.Printf("open plan share: %v", err)
+			http.Error(w, "failed to load shared plan", http.StatusInternalServerError)
+			return
+		}
+		if share == nil {
+			http.Error(w, "this link is invalid or has expired", http.StatusNotFound)
+			return
+		}
+		view, err := sharedPlan(repo, svc, share)
+		if err != nil {
+			log.Printf("shared plan %d: %v", share.ShareID, err)
+			http.Error(w, "failed to load shared plan", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "private, no-store")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		json.NewEncoder(w).Encode(view)
+	}
+}
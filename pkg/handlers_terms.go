@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// TermsHandler serves GET /api/terms?from={year}&to={year}
+// Returns the academic calendar for calendar years from..to, oldest first.
+// Defaults to the current academic year and the four after it.
+func TermsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		cur, err := repo.CurrentTerm(time.Now())
+		if err != nil {
+			log.Printf("current term error: %v", err)
+			http.Error(w, "failed to resolve current term", http.StatusInternalServerError)
+			return
+		}
+		from, to := cur.AcademicYear(), cur.AcademicYear()+5
+		for name, dst := range map[string]*int{"from": &from, "to": &to} {
+			if v := r.URL.Query().Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					http.Error(w, "invalid "+name, http.StatusBadRequest)
+					return
+				}
+				*dst = n
+			}
+		}
+		if to < from || to-from > 20 {
+			http.Error(w, "invalid year range", http.StatusBadRequest)
+			return
+		}
+
+		terms, err := repo.ListTerms(from, to)
+		if err != nil {
+			log.Printf("list terms error: %v", err)
+			http.Error(w, "failed to list terms", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(terms)
+	}
+}
+
+// CurrentTermHandler serves GET /api/terms/current
+// Returns the term in session today (or the one that just ended, between
+// terms).
+func CurrentTermHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		t, err := repo.CurrentTerm(time.Now())
+		if err != nil {
+			log.Printf("current term error: %v", err)
+			http.Error(w, "failed to resolve current term", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	}
+}
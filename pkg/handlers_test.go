@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -73,6 +74,100 @@ func TestCourseHandlers_GettersAndPlan(t *testing.T) {
 		if rr.Code != 200 {
 			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
 		}
+		var items []struct {
+			CourseName *string `json:"course_name"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&items); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(items) != 1 || items[0].CourseName == nil || *items[0].CourseName != "Data Structures" {
+			t.Fatalf("expected one item named from the catalogue, got %s", rr.Body.String())
+		}
+	})
+}
+
+func TestCourseOfferingsHandler(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	// Listed out of order, and the winter term has two sections.
+	var c01ID int64
+	for _, o := range []struct{ term, section string }{
+		{"2026 Winter", "C02"}, {"2025 Fall", ""}, {"2026 Winter", "C01"}, {"2025 Winter", ""},
+	} {
+		var section interface{}
+		if o.section != "" {
+			section = o.section
+		}
+		res, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, professor, term, section)
+			VALUES ('COMPSCI', '2C03', 'Data Structures', 'Dr X', ?, ?)`, o.term, section)
+		if err != nil {
+			t.Fatalf("seed %s %s: %v", o.term, o.section, err)
+		}
+		if o.section == "C01" {
+			c01ID, _ = res.LastInsertId()
+		}
+		term, _ := ParseTermCode(o.term)
+		if _, err := repo.DB.Exec(`INSERT OR IGNORE INTO academic_terms(code, season, year, start_date, end_date, ordinal)
+			VALUES (?, ?, ?, ?, ?, ?)`, term.Code, term.Season, term.Year, term.StartDate, term.EndDate, term.Ordinal); err != nil {
+			t.Fatalf("seed term %s: %v", o.term, err)
+		}
+	}
+	if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, term) VALUES ('COMPSCI', '2C03', '2025 Fall')`); err == nil {
+		t.Fatal("expected a second unnamed section of a term to be rejected")
+	}
+	res, err := repo.DB.Exec(`INSERT INTO instructors(name, name_normalized) VALUES ('Dr X', 'dr x')`)
+	if err != nil {
+		t.Fatalf("seed instructor: %v", err)
+	}
+	instructorID, _ := res.LastInsertId()
+	if _, err := repo.DB.Exec(`INSERT INTO course_instructors(course_row_id, instructor_id) VALUES (?, ?)`, c01ID, instructorID); err != nil {
+		t.Fatalf("link instructor: %v", err)
+	}
+
+	t.Run("lists every term of the catalogue course", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/courses/compsci/2c03/offerings", nil)
+		CourseOfferingsHandler(repo).ServeHTTP(rr, req)
+		if rr.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var body struct {
+			Course    CatalogCourse    `json:"course"`
+			Offerings []CourseOffering `json:"offerings"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if body.Course.CourseName != "Data Structures" {
+			t.Fatalf("unexpected course: %+v", body.Course)
+		}
+		var got []string
+		for _, o := range body.Offerings {
+			if o.Section != nil {
+				got = append(got, o.Term+" "+*o.Section)
+			} else {
+				got = append(got, o.Term)
+			}
+		}
+		if want := "2025 Winter,2025 Fall,2026 Winter C01,2026 Winter C02"; strings.Join(got, ",") != want {
+			t.Fatalf("offerings = %v, want %s", got, want)
+		}
+		if len(body.Offerings[0].Instructors) != 0 {
+			t.Fatalf("unexpected first offering: %+v", body.Offerings[0])
+		}
+		if got := body.Offerings[2].Instructors; len(got) != 1 || got[0].Name != "Dr X" {
+			t.Fatalf("expected Dr X on section C01, got %+v", got)
+		}
+	})
+
+	t.Run("unknown course", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/courses/COMPSCI/9Z99/offerings", nil)
+		CourseOfferingsHandler(repo).ServeHTTP(rr, req)
+		if rr.Code != 404 {
+			t.Fatalf("expected 404, got %d", rr.Code)
+		}
 	})
 }
 
@@ -285,6 +380,66 @@ func TestCoursesHandler(t *testing.T) {
 			t.Fatalf("expected total=3 for term=2025, got %d", resp.Total)
 		}
 	})
+
+	t.Run("multi-value subject filter with facets", func(t *testing.T) {
+		for _, subj := range []string{"ZZOTHER", "ZZTHIRD"} {
+			if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, professor, term) VALUES (?, '1A03', 'Other', 'Dr Z', '2025')`, subj); err != nil {
+				t.Fatalf("seed %s: %v", subj, err)
+			}
+		}
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/courses?subject=zztest&subject=ZZOTHER&sort=code", nil)
+		handler.ServeHTTP(rr, req)
+		if rr.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp struct {
+			Total  int                     `json:"total"`
+			Facets map[string][]FacetCount `json:"facets"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if resp.Total != 4 {
+			t.Fatalf("expected total=4 for two subjects, got %d", resp.Total)
+		}
+		// The subject facet ignores the subject filter, so all three show.
+		if got := resp.Facets["subject"]; len(got) != 3 {
+			t.Fatalf("expected 3 subject facets, got %+v", got)
+		}
+		if got := resp.Facets["level"]; len(got) != 3 || got[0] != (FacetCount{"1", 2}) {
+			t.Fatalf("unexpected level facets %+v", got)
+		}
+	})
+
+	t.Run("include_total=false omits total and returns cursor", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/courses?q=ZZTEST&limit=1&include_total=false", nil))
+		if rr.Code != 200 {
+			t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var resp map[string]interface{}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if _, ok := resp["total"]; ok {
+			t.Fatalf("expected no total, got %v", resp["total"])
+		}
+		if resp["next_cursor"] == nil || resp["prev_cursor"] != nil {
+			t.Fatalf("expected only next_cursor on the first page, got %v / %v", resp["next_cursor"], resp["prev_cursor"])
+		}
+	})
+
+	for _, query := range []string{"sort=popularity", "min_rating=abc", "max_difficulty=-1", "units=x", "has_prereqs=maybe",
+		"cursor=garbage", "include_total=maybe"} {
+		t.Run("invalid "+query+" returns 400", func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/courses?"+query, nil))
+			if rr.Code != 400 {
+				t.Fatalf("expected 400, got %d", rr.Code)
+			}
+		})
+	}
 }
 
 func TestPostUserPlanHandler(t *testing.T) {
@@ -296,38 +451,119 @@ func TestPostUserPlanHandler(t *testing.T) {
 		t.Fatalf("seed user: %v", err)
 	}
 	userID, _ := res.LastInsertId()
+	for _, num := range []string{"2C03", "3SH3", "2ME3"} {
+		if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, professor, term)
+			VALUES ('COMPSCI', ?, 'Course', 'Dr X', '2025 Fall')`, num); err != nil {
+			t.Fatalf("seed course: %v", err)
+		}
+	}
 
 	handler := PostUserPlanHandler(repo)
+	post := func(fields map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(fields)
+		req := httptest.NewRequest("POST", "/api/users/1/plan", bytes.NewReader(body))
+		req.URL.Path = "/api/users/" + strconv.FormatInt(userID, 10) + "/plan"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	fieldErrors := func(t *testing.T, rr *httptest.ResponseRecorder) map[string]string {
+		t.Helper()
+		var resp struct {
+			Fields []FieldError `json:"fields"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode field errors: %v", err)
+		}
+		out := map[string]string{}
+		for _, f := range resp.Fields {
+			out[f.Field] = f.Message
+		}
+		return out
+	}
 
 	t.Run("adds a course to plan", func(t *testing.T) {
-		body, _ := json.Marshal(map[string]any{
+		rr := post(map[string]any{
+			"subject":       "compsci",
+			"course_number": "2c03",
+			"year_index":    1,
+			"season":        "fall",
+		})
+		if rr.Code != 201 {
+			t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var item PlanItemState
+		if err := json.NewDecoder(rr.Body).Decode(&item); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if item.PlanItemID == 0 || item.Subject != "COMPSCI" || item.CourseNumber != "2C03" ||
+			item.Season != "Fall" || item.Status != "PLANNED" {
+			t.Errorf("created item: %+v", item)
+		}
+	})
+
+	t.Run("year_index=0 regression", func(t *testing.T) {
+		rr := post(map[string]any{
 			"subject":       "COMPSCI",
 			"course_number": "2C03",
+			"year_index":    0,
+			"season":        "Fall",
+		})
+		if rr.Code != 400 {
+			t.Fatalf("expected 400 for year_index=0, got %d", rr.Code)
+		}
+		if _, ok := fieldErrors(t, rr)["year_index"]; !ok {
+			t.Errorf("expected a year_index field error")
+		}
+	})
+
+	t.Run("rejects bad season and unknown course", func(t *testing.T) {
+		rr := post(map[string]any{
+			"subject":       "COMPSCI",
+			"course_number": "9ZZ9",
+			"year_index":    1,
+			"season":        "Autumn",
+		})
+		if rr.Code != 400 {
+			t.Fatalf("expected 400, got %d", rr.Code)
+		}
+		fields := fieldErrors(t, rr)
+		if _, ok := fields["season"]; !ok {
+			t.Errorf("expected a season field error, got %v", fields)
+		}
+		if _, ok := fields["course_number"]; !ok {
+			t.Errorf("expected a course_number field error, got %v", fields)
+		}
+	})
+
+	t.Run("custom course skips the catalogue", func(t *testing.T) {
+		rr := post(map[string]any{
+			"subject":       "HIST",
+			"course_number": "101",
 			"year_index":    1,
 			"season":        "Fall",
+			"is_custom":     true,
 		})
-		req := httptest.NewRequest("POST", "/api/users/1/plan", bytes.NewReader(body))
-		req.URL.Path = "/api/users/" + strconv.FormatInt(userID, 10) + "/plan"
-		rr := httptest.NewRecorder()
-		handler.ServeHTTP(rr, req)
 		if rr.Code != 201 {
 			t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
 		}
+		if !strings.Contains(rr.Body.String(), `"is_custom":true`) {
+			t.Errorf("expected is_custom in response: %s", rr.Body.String())
+		}
 	})
 
-	t.Run("year_index=0 regression", func(t *testing.T) {
-		body, _ := json.Marshal(map[string]any{
-			"subject":       "MATH",
-			"course_number": "1A03",
-			"year_index":    0,
+	t.Run("duplicate is a conflict", func(t *testing.T) {
+		rr := post(map[string]any{
+			"subject":       "COMPSCI",
+			"course_number": "2C03",
+			"year_index":    1,
 			"season":        "Fall",
 		})
-		req := httptest.NewRequest("POST", "/api/users/1/plan", bytes.NewReader(body))
-		req.URL.Path = "/api/users/" + strconv.FormatInt(userID, 10) + "/plan"
-		rr := httptest.NewRecorder()
-		handler.ServeHTTP(rr, req)
-		if rr.Code != 500 {
-			t.Fatalf("expected 500 for year_index=0, got %d", rr.Code)
+		if rr.Code != 409 {
+			t.Fatalf("expected 409, got %d: %s", rr.Code, rr.Body.String())
+		}
+		if _, ok := fieldErrors(t, rr)["course_number"]; !ok {
+			t.Errorf("expected a course_number field error")
 		}
 	})
 
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// TransferCreditsHandler serves GET and POST /api/users/{id}/transfer-credits
+// GET lists the user's transfer credit. POST records one:
+//
+//	{ "institution": "College Board", "original_course": "AP Calculus BC",
+//	  "subject": "MATH", "course_number": "1ZA3" }
+//
+// or, for unspecified credit, a level (and optionally subject) instead of
+// course_number, with units. Returns 201 with the created credit, or 400
+// with { "error", "fields": [...] }.
+func TransferCreditsHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			credits, err := repo.ListTransferCredits(userID)
+			if err != nil {
+				log.Printf("list transfer credits: %v", err)
+				http.Error(w, "failed to fetch transfer credits", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(credits)
+
+		case http.MethodPost:
+			var t TransferCredit
+			if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			t.UserID = userID
+			fields, err := repo.CheckTransferCredit(&t)
+			if err != nil {
+				log.Printf("check transfer credit: %v", err)
+				http.Error(w, "failed to check transfer credit", http.StatusInternalServerError)
+				return
+			}
+			if len(fields) > 0 {
+				writeFieldErrors(w, http.StatusBadRequest, "invalid transfer credit", fields)
+				return
+			}
+			created, err := repo.CreateTransferCredit(t)
+			if err != nil {
+				log.Printf("create transfer credit: %v", err)
+				http.Error(w, "failed to save transfer credit", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(created)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+// DeleteTransferCreditHandler serves DELETE /api/users/{id}/transfer-credits/{transferId}
+func DeleteTransferCreditHandler(repo *Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		userID, parts, err := planPathParts(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// parts should be: ["{id}", "transfer-credits", "{transferId}"]
+		if len(parts) != 3 {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+		transferID, err := strconv.Atoi(parts[2])
+		if err != nil || transferID == 0 {
+			http.Error(w, "invalid transfer id", http.StatusBadRequest)
+			return
+		}
+
+		err = repo.DeleteTransferCredit(userID, transferID)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("delete transfer credit %d: %v", transferID, err)
+			http.Error(w, "failed to delete transfer credit", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
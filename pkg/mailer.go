@@ -0,0 +1,304 @@
+package pkg
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a single outgoing email. Text is required; HTML is optional and,
+// when present, is sent as the preferred multipart/alternative part.
+type Message struct {
+	To      string `json:"to"`
+	ReplyTo string `json:"reply_to,omitempty"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// parseMailAddress parses a single address for a To or Reply-To header.
+// Addresses may come from user input, so one holding a line break is refused
+// rather than left to inject headers.
+func parseMailAddress(s string) (*mail.Address, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return nil, fmt.Errorf("line break in address %q", s)
+	}
+	return mail.ParseAddress(s)
+}
+
+// addresses parses msg's To and, if set, ReplyTo.
+func (msg Message) addresses() (to, replyTo *mail.Address, err error) {
+	if to, err = parseMailAddress(msg.To); err != nil {
+		return nil, nil, fmt.Errorf("to: %w", err)
+	}
+	if msg.ReplyTo != "" {
+		if replyTo, err = parseMailAddress(msg.ReplyTo); err != nil {
+			return nil, nil, fmt.Errorf("reply_to: %w", err)
+		}
+	}
+	return to, replyTo, nil
+}
+
+// Mailer delivers a Message. Implementations supply the From address.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// MailerFromEnv picks a Mailer based on the environment:
+//
+//	MAIL_OUTBOX_DIR – if set, messages are written as .eml files there (local dev)
+//	SMTP_USER, SMTP_PASSWORD – if both set, messages go out over SMTP
+//
+// With neither configured, messages are only logged so flows like password
+// reset still work on a laptop without mail credentials.
+func MailerFromEnv() Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if dir := os.Getenv("MAIL_OUTBOX_DIR"); dir != "" {
+		return &OutboxMailer{Dir: dir, From: notEmpty(from, "noreply@mactrack.local")}
+	}
+	user, pass := os.Getenv("SMTP_USER"), os.Getenv("SMTP_PASSWORD")
+	if user != "" && pass != "" {
+		return &SMTPMailer{
+			Host:     getEnvOrDefault("SMTP_HOST", "smtp.gmail.com"),
+			Port:     getEnvOrDefault("SMTP_PORT", "587"),
+			Username: user,
+			Password: pass,
+			From:     notEmpty(from, user),
+		}
+	}
+	return logMailer{}
+}
+
+// ─── MIME encoding ───────────────────────────────────────────────────────────
+
+// mailSenderName is the display name on every outgoing From header.
+const mailSenderName = "MacTrack"
+
+// Bytes renders msg as an RFC 5322 message from the given address. Bodies are
+// quoted-printable so long HTML lines and non-ASCII text survive any relay.
+func (msg Message) Bytes(from string, now time.Time) ([]byte, error) {
+	to, replyTo, err := msg.addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	var contentType string
+
+	writePart := func(w *bytes.Buffer, s string) error {
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(s)); err != nil {
+			return err
+		}
+		return qp.Close()
+	}
+
+	if msg.HTML == "" {
+		contentType = "text/plain; charset=UTF-8"
+		if err := writePart(&body, msg.Text); err != nil {
+			return nil, err
+		}
+	} else {
+		mw := multipart.NewWriter(&body)
+		contentType = "multipart/alternative; boundary=" + mw.Boundary()
+		for _, p := range []struct{ ctype, content string }{
+			{"text/plain; charset=UTF-8", msg.Text},
+			{"text/html; charset=UTF-8", msg.HTML},
+		} {
+			pw, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {p.ctype},
+				"Content-Transfer-Encoding": {"quoted-printable"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			var part bytes.Buffer
+			if err := writePart(&part, p.content); err != nil {
+				return nil, err
+			}
+			if _, err := pw.Write(part.Bytes()); err != nil {
+				return nil, err
+			}
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", (&mail.Address{Name: mailSenderName, Address: from}).String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	if replyTo != nil {
+		fmt.Fprintf(&buf, "Reply-To: %s\r\n", replyTo.String())
+	}
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n", contentType)
+	if msg.HTML == "" {
+		fmt.Fprintf(&buf, "Content-Transfer-Encoding: quoted-printable\r\n")
+	}
+	fmt.Fprintf(&buf, "\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// ─── SMTP ────────────────────────────────────────────────────────────────────
+
+const (
+	smtpDialTimeout    = 15 * time.Second
+	smtpSessionTimeout = 30 * time.Second
+)
+
+// SMTPMailer sends through an authenticated SMTP server. Port 465 uses
+// implicit TLS; any other port uses STARTTLS when the server offers it.
+// All network operations are bounded by explicit timeouts.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	raw, err := msg.Bytes(m.From, time.Now())
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	addr := net.JoinHostPort(m.Host, m.Port)
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	tlsCfg := &tls.Config{ServerName: m.Host}
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("tcp dial %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpSessionTimeout))
+	defer conn.Close()
+
+	if m.Port == "465" {
+		tlsConn := tls.Client(conn, tlsCfg)
+		if err := tlsConn.Handshake(); err != nil {
+			return fmt.Errorf("tls handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		return fmt.Errorf("smtp new client: %w", err)
+	}
+	defer client.Close()
+
+	if m.Port != "465" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsCfg); err != nil {
+				return fmt.Errorf("smtp STARTTLS: %w", err)
+			}
+		}
+	}
+	if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+		return fmt.Errorf("smtp auth: %w", err)
+	}
+
+	if err := client.Mail(m.From); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	to, _, _ := msg.addresses() // Bytes checked them
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("smtp RCPT TO: %w", err)
+	}
+	wc, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err = wc.Write(raw); err != nil {
+		return fmt.Errorf("smtp write body: %w", err)
+	}
+	if err := wc.Close(); err != nil {
+		return fmt.Errorf("smtp end DATA: %w", err)
+	}
+	return client.Quit()
+}
+
+// ─── Outbox (local dev) ──────────────────────────────────────────────────────
+
+// OutboxMailer writes each message to Dir as a .eml file that any mail client
+// can open, instead of sending it.
+type OutboxMailer struct {
+	Dir  string
+	From string
+}
+
+var outboxSlugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+func (m *OutboxMailer) Send(msg Message) error {
+	now := time.Now()
+	raw, err := msg.Bytes(m.From, now)
+	if err != nil {
+		return fmt.Errorf("build message: %w", err)
+	}
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("outbox dir: %w", err)
+	}
+	slug := strings.Trim(outboxSlugRe.ReplaceAllString(strings.ToLower(msg.To), "-"), "-")
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405.000000000"), slug)
+	path := filepath.Join(m.Dir, name)
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("outbox write: %w", err)
+	}
+	log.Printf("[mail] wrote %s (to=%s subject=%q)", path, msg.To, msg.Subject)
+	return nil
+}
+
+// ─── In-memory (tests) ───────────────────────────────────────────────────────
+
+// MemoryMailer records messages instead of sending them. Safe for concurrent use.
+type MemoryMailer struct {
+	mu   sync.Mutex
+	sent []Message
+	// Err, if set, is returned from Send and the message is not recorded.
+	Err error
+}
+
+func (m *MemoryMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of every message sent so far.
+func (m *MemoryMailer) Sent() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message(nil), m.sent...)
+}
+
+// logMailer is the unconfigured fallback: it logs the plain-text body so links
+// (e.g. password reset URLs) can still be followed in local development.
+type logMailer struct{}
+
+func (logMailer) Send(msg Message) error {
+	log.Printf("[mail] not configured — to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
@@ -0,0 +1,184 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseMIME decodes raw into its headers and the text/plain and text/html
+// parts, failing the test if the message isn't well-formed.
+func parseMIME(t *testing.T, raw []byte) (mail.Header, string, string) {
+	t.Helper()
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q (err %v)", m.Header.Get("Content-Type"), err)
+	}
+	var text, html string
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart() // transparently decodes quoted-printable
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		b, _ := io.ReadAll(p)
+		switch {
+		case strings.HasPrefix(p.Header.Get("Content-Type"), "text/plain"):
+			text = string(b)
+		case strings.HasPrefix(p.Header.Get("Content-Type"), "text/html"):
+			html = string(b)
+		}
+	}
+	return m.Header, text, html
+}
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{
+		To:      "ops@example.com",
+		ReplyTo: "student@example.com",
+		Subject: "MacTrack Feedback — Oct 18",
+		Text:    "plain body",
+		HTML:    "<p>" + strings.Repeat("long line ", 40) + "</p>",
+	}
+	raw, err := msg.Bytes("noreply@example.com", time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	h, text, html := parseMIME(t, raw)
+
+	from, err := mail.ParseAddress(h.Get("From"))
+	if err != nil || from.Name != "MacTrack" || from.Address != "noreply@example.com" {
+		t.Fatalf("unexpected From %q", h.Get("From"))
+	}
+	if replyTo, err := mail.ParseAddress(h.Get("Reply-To")); err != nil || replyTo.Address != "student@example.com" {
+		t.Fatalf("unexpected Reply-To %q", h.Get("Reply-To"))
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(h.Get("Subject"))
+	if subject != msg.Subject {
+		t.Fatalf("subject round-trip: got %q", subject)
+	}
+	if text != msg.Text || html != msg.HTML {
+		t.Fatalf("bodies did not round-trip:\ntext=%q\nhtml=%q", text, html)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line exceeds RFC 5322 limit (%d chars)", len(line))
+		}
+	}
+}
+
+// Addresses can come from user input; a line break in one must not start a
+// header of its own.
+func TestMessageBytes_HeaderInjection(t *testing.T) {
+	for _, msg := range []Message{
+		{To: "ops@example.com", ReplyTo: "a@example.com\r\nBcc: victim@example.com", Text: "x"},
+		{To: "ops@example.com\nBcc: victim@example.com", Text: "x"},
+		{To: "ops@example.com, victim@example.com", Text: "x"},
+	} {
+		if raw, err := msg.Bytes("noreply@example.com", time.Now()); err == nil {
+			t.Errorf("%+v: expected an error, got\n%s", msg, raw)
+		}
+	}
+
+	// The feedback form's email only becomes a Reply-To if it's an address...
+	msg, err := feedbackEmail("ops@example.com", FeedbackRequest{
+		Message: "hi", Email: "a@example.com\r\nBcc: victim@example.com",
+	}, time.Now())
+	if err != nil || msg.ReplyTo != "" {
+		t.Fatalf("feedbackEmail: ReplyTo %q, %v", msg.ReplyTo, err)
+	}
+	// ...and the queue refuses one that slips through.
+	repo := newTestRepo(t)
+	defer repo.Close()
+	msg.ReplyTo = "a@example.com\r\nBcc: victim@example.com"
+	if _, err := repo.EnqueueEmail(msg); err == nil {
+		t.Fatal("EnqueueEmail: expected an error")
+	}
+}
+
+func TestFeedbackEmail(t *testing.T) {
+	now := time.Date(2026, 10, 18, 15, 4, 0, 0, time.UTC)
+
+	t.Run("escapes user input in html", func(t *testing.T) {
+		msg, err := feedbackEmail("ops@example.com", FeedbackRequest{
+			Message: "<script>alert(1)</script>",
+			Email:   "a@example.com",
+			Page:    "/planner",
+		}, now)
+		if err != nil {
+			t.Fatalf("feedbackEmail: %v", err)
+		}
+		if strings.Contains(msg.HTML, "<script>") || !strings.Contains(msg.HTML, "&lt;script&gt;") {
+			t.Fatalf("message not escaped in html body")
+		}
+		if !strings.Contains(msg.HTML, `href="mailto:a@example.com"`) {
+			t.Fatalf("expected reply-to link in html body")
+		}
+		if !strings.Contains(msg.Text, "<script>alert(1)</script>") || !strings.Contains(msg.Text, "Page         : /planner") {
+			t.Fatalf("unexpected text body:\n%s", msg.Text)
+		}
+		if msg.To != "ops@example.com" || msg.ReplyTo != "a@example.com" {
+			t.Fatalf("unexpected addressing: %+v", msg)
+		}
+	})
+
+	t.Run("anonymous submission", func(t *testing.T) {
+		msg, err := feedbackEmail("ops@example.com", FeedbackRequest{Message: "hi"}, now)
+		if err != nil {
+			t.Fatalf("feedbackEmail: %v", err)
+		}
+		if !strings.Contains(msg.Text, "User email   : anonymous") || !strings.Contains(msg.Text, "Page         : unknown") {
+			t.Fatalf("unexpected text body:\n%s", msg.Text)
+		}
+		if strings.Contains(msg.HTML, "Reply-to") || msg.ReplyTo != "" {
+			t.Fatalf("anonymous feedback should have no reply-to")
+		}
+	})
+}
+
+func TestPasswordResetEmail(t *testing.T) {
+	url := "https://mactrack.example/reset-password?token=abc123"
+	msg, err := passwordResetEmail("ada@example.com", "", url, time.Now())
+	if err != nil {
+		t.Fatalf("passwordResetEmail: %v", err)
+	}
+	if msg.To != "ada@example.com" || msg.Subject == "" {
+		t.Fatalf("unexpected addressing: %+v", msg)
+	}
+	if !strings.Contains(msg.Text, "Hi there,") || !strings.Contains(msg.Text, url) {
+		t.Fatalf("unexpected text body:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, `href="`+url+`"`) {
+		t.Fatalf("reset link missing from html body")
+	}
+}
+
+func TestOutboxMailer(t *testing.T) {
+	dir := t.TempDir()
+	m := &OutboxMailer{Dir: filepath.Join(dir, "outbox"), From: "noreply@example.com"}
+	if err := m.Send(Message{To: "Ada@Example.com", Subject: "hello", Text: "t", HTML: "<p>h</p>"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "outbox", "*.eml"))
+	if len(files) != 1 || !strings.HasSuffix(files[0], "-ada-example-com.eml") {
+		t.Fatalf("expected one .eml file, got %v", files)
+	}
+	raw, _ := os.ReadFile(files[0])
+	if _, text, html := parseMIME(t, raw); text != "t" || html != "<p>h</p>" {
+		t.Fatalf("unexpected outbox contents: %q %q", text, html)
+	}
+}
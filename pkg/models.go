@@ -1,7 +1,5 @@
 package pkg
 
-import "strconv"
-
 // Core models for Course, Professor, Review
 
 type Course struct {
@@ -14,6 +12,34 @@ type Course struct {
 	AvgRating     *float64 `json:"avg_rating,omitempty"`
 	AvgDifficulty *float64 `json:"avg_difficulty,omitempty"`
 	NumRatings    *int     `json:"num_ratings,omitempty"`
+	// Snippet is set by full-text search: HTML-escaped text with the matched
+	// words wrapped in <mark>.
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// CatalogCourse is a course as the calendar lists it, independent of when
+// it's offered. Course rows are offerings of one of these.
+type CatalogCourse struct {
+	CatalogID    int    `json:"catalog_id"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	CourseName   string `json:"course_name"`
+	Coid         *int   `json:"coid"`
+	Units        int    `json:"units"`
+	// UnitsSource is "scraped" or "curated", or nil when Units is only
+	// guessed from the course number.
+	UnitsSource *string `json:"units_source"`
+}
+
+// CourseOffering is one term of a catalog course, or one section of it
+// when the term has several. Section is nil for a term's only, unnamed
+// section. ID is the courses row id, as used by /api/courses/{id}.
+type CourseOffering struct {
+	ID          int          `json:"id"`
+	Term        string       `json:"term"`
+	Section     *string      `json:"section"`
+	Professor   string       `json:"professor"`
+	Instructors []Instructor `json:"instructors"`
 }
 
 type Professor struct {
@@ -58,6 +84,9 @@ type Program struct {
 	TotalUnits  *int               `json:"total_units"`
 	CatalogYear string             `json:"catalog_year"`
 	Groups      []RequirementGroup `json:"groups"`
+	// StandingRules are the continuation and entry rules checked alongside
+	// the groups (see standing.go).
+	StandingRules []StandingRule `json:"standing_rules"`
 }
 
 type RequirementGroup struct {
@@ -94,6 +123,8 @@ type PlanItem struct {
 	Status       string  `json:"status"`
 	Grade        *string `json:"grade"`
 	Note         *string `json:"note"`
+	IsCustom     bool    `json:"is_custom"`
+	Units        int     `json:"units"`
 }
 
 // Validation result shapes
@@ -121,19 +152,11 @@ type ValidationResult struct {
 	UnitsRemaining      int             `json:"units_remaining"`
 	Groups              []GroupResult   `json:"groups"`
 	PrereqWarnings      []PrereqWarning `json:"prereq_warnings"`
-}
-
-// RecommendedCourse is returned by the recommendations endpoint.
-
-func UnitsFromCourseNumber(courseNumber string, defaultUnits int) int {
-	if len(courseNumber) < 2 {
-		return defaultUnits
-	}
-	// Last two characters are the unit count
-	suffix := courseNumber[len(courseNumber)-2:]
-	n, err := strconv.Atoi(suffix)
-	if err != nil || n == 0 {
-		return defaultUnits
-	}
-	return n
+	// TransferUnits is all the transfer credit the student holds. Credit
+	// with a McMaster equivalent counts towards the groups it meets;
+	// unspecified credit goes straight into TotalUnitsCompleted.
+	TransferUnits int `json:"transfer_units"`
+	// Standing is the plan against the program's standing rules; nil if
+	// the program has none.
+	Standing *Standing `json:"standing,omitempty"`
 }
@@ -0,0 +1,405 @@
+package pkg
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCConfig holds the relying-party settings for single sign-on.
+//
+// Env vars (all required except OIDC_CLIENT_SECRET and OIDC_SCOPES):
+//
+//	OIDC_ISSUER         – issuer URL, e.g. https://login.microsoftonline.com/<tenant>/v2.0
+//	OIDC_CLIENT_ID      – client ID registered with the identity provider
+//	OIDC_CLIENT_SECRET  – client secret; omit for public clients (PKCE only)
+//	OIDC_REDIRECT_URL   – the frontend callback page registered with the IdP
+//	OIDC_SCOPES         – space-separated, defaults to "openid email profile"
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCConfigFromEnv reads OIDCConfig from the environment.
+// Returns nil when SSO is not configured, in which case the OIDC routes 404.
+func OIDCConfigFromEnv() *OIDCConfig {
+	cfg := &OIDCConfig{
+		Issuer:       strings.TrimRight(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(getEnvOrDefault("OIDC_SCOPES", "openid email profile")),
+	}
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil
+	}
+	return cfg
+}
+
+// oidcDiscovery is the subset of /.well-known/openid-configuration we use.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jsonWebKey is a single entry of a JWKS document (RSA or EC public key).
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwksMinRefresh is the least time between two JWKS fetches. A token naming
+// an unknown kid sooner than that after the last fetch is rejected without
+// asking the IdP, so made-up kids can't make us fetch on every request.
+const jwksMinRefresh = time.Minute
+
+// OIDCProvider talks to a single OpenID Connect issuer. Discovery metadata and
+// signing keys are fetched lazily and cached; the key set is refetched when
+// an ID token names a kid we haven't seen (the IdP rotated its keys), at
+// most once per jwksMinRefresh.
+type OIDCProvider struct {
+	cfg    OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]interface{}
+	keysFetched time.Time     // when the last JWKS fetch started
+	keysFetch   chan struct{} // closed when the fetch under way, if any, ends
+}
+
+// NewOIDCProvider returns a provider for cfg. Nothing is fetched until the
+// first login, so a misconfigured issuer doesn't stop the API from starting.
+func NewOIDCProvider(cfg OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// OIDCIdentity is what we take from a verified ID token.
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// idTokenClaims are the ID token claims we read on top of the registered ones.
+type idTokenClaims struct {
+	Nonce         string      `json:"nonce"`
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"` // bool, but some IdPs send "true"
+	Name          string      `json:"name"`
+	jwt.RegisteredClaims
+}
+
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	if err := p.getJSON(ctx, p.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimRight(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer mismatch %q != %q", d.Issuer, p.cfg.Issuer)
+	}
+	if d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: missing endpoints")
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+// signingKey returns the public key for kid, refreshing the JWKS if the kid
+// is unknown and the last fetch was at least jwksMinRefresh ago. The fetch
+// runs without holding p.mu; callers that need it meanwhile wait for it.
+func (p *OIDCProvider) signingKey(ctx context.Context, kid string) (interface{}, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if key, ok := p.keys[kid]; ok {
+		p.mu.Unlock()
+		return key, nil
+	}
+	if done := p.keysFetch; done != nil {
+		p.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return p.cachedKey(kid)
+	}
+	if !p.keysFetched.IsZero() && time.Since(p.keysFetched) < jwksMinRefresh {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("oidc jwks: no key with kid %q", kid)
+	}
+	done := make(chan struct{})
+	p.keysFetch, p.keysFetched = done, time.Now()
+	p.mu.Unlock()
+
+	keys, err := p.fetchKeys(ctx, d.JWKSURI)
+	p.mu.Lock()
+	if err == nil {
+		p.keys = keys
+	}
+	p.keysFetch = nil
+	close(done)
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return p.cachedKey(kid)
+}
+
+func (p *OIDCProvider) cachedKey(kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("oidc jwks: no key with kid %q", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys fetches the JWKS at uri and returns its signing keys by kid.
+func (p *OIDCProvider) fetchKeys(ctx context.Context, uri string) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, uri, &set); err != nil {
+		return nil, fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := map[string]interface{}{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue // skip key types we don't support rather than failing the whole set
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// AuthCodeURL builds the authorization request URL for the given state, nonce
+// and PKCE verifier (the S256 challenge is derived here).
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(d.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc authorization endpoint: %w", err)
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", pkceChallenge(codeVerifier))
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange redeems an authorization code at the token endpoint and verifies
+// the returned ID token against the expected nonce.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*OIDCIdentity, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tok struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("oidc token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tok.Error != "" {
+		return nil, fmt.Errorf("oidc token endpoint: %s %s %s", resp.Status, tok.Error, tok.ErrorDescription)
+	}
+	if tok.IDToken == "" {
+		return nil, errors.New("oidc token response has no id_token")
+	}
+	return p.verifyIDToken(ctx, tok.IDToken, nonce)
+}
+
+// verifyIDToken checks the ID token's signature against the issuer's JWKS and
+// validates iss, aud, exp and nonce. iss must be exactly the issuer discovery
+// returned, trailing slash and all; only our configured copy is trimmed.
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, raw, nonce string) (*OIDCIdentity, error) {
+	d, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var claims idTokenClaims
+	_, err = jwt.ParseWithClaims(raw, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id token: %w", err)
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("invalid id token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid id token: missing sub")
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+	return &OIDCIdentity{
+		Issuer:        p.cfg.Issuer,
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(strings.ToLower(claims.Email)),
+		EmailVerified: verified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// randomURLToken returns n random bytes encoded as unpadded base64url — used
+// for state, nonce and the PKCE code verifier (32 bytes → 43 chars).
+func randomURLToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// pkceChallenge derives the S256 code_challenge for a verifier (RFC 7636 §4.2).
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,173 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// seedProgram creates a program with one group requiring the given courses,
+// returning its id.
+func seedProgram(t *testing.T, repo *Repository, unitsRequired int, courses ...string) int {
+	t.Helper()
+	res, err := repo.DB.Exec(`INSERT INTO programs(poid, name, catalog_year) VALUES (1, 'Test Program', '2025-2026')`)
+	if err != nil {
+		t.Fatalf("seed program: %v", err)
+	}
+	programID, _ := res.LastInsertId()
+	res, err = repo.DB.Exec(`INSERT INTO requirement_groups(program_id, display_order, heading, heading_level, units_required)
+		VALUES (?, 1, 'Core', 3, ?)`, programID, unitsRequired)
+	if err != nil {
+		t.Fatalf("seed group: %v", err)
+	}
+	groupID, _ := res.LastInsertId()
+	for i, c := range courses {
+		if _, err := repo.DB.Exec(`INSERT INTO requirement_courses(group_id, display_order, course_code)
+			VALUES (?, ?, ?)`, groupID, i+1, c); err != nil {
+			t.Fatalf("seed course %s: %v", c, err)
+		}
+	}
+	return int(programID)
+}
+
+func postBatch(t *testing.T, repo *Repository, userID int, query, ops string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/api/users/"+strconv.Itoa(userID)+"/plan/batch"+query,
+		strings.NewReader(`{"operations":`+ops+`}`))
+	PlanBatchHandler(repo, &Service{Repo: repo}).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPlanBatchHandler_AppliesAllAndValidates(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, [][2]interface{}{{1, "Fall"}, {1, "Winter"}})
+	programID := seedProgram(t, repo, 6, "COMPSCI 1MD3", "COMPSCI 1XC3")
+	if _, err := repo.DB.Exec(`INSERT INTO catalog_courses(subject, course_number) VALUES ('COMPSCI', '1MD3')`); err != nil {
+		t.Fatalf("seed catalogue: %v", err)
+	}
+	items, _ := repo.GetPlanItems(userID)
+	fall, winter := items[0].PlanItemID, items[1].PlanItemID
+
+	rr := postBatch(t, repo, userID, "?program_id="+strconv.Itoa(programID), `[
+		{"op": "add", "year_index": 1, "season": "Fall", "subject": "COMPSCI", "course_number": "1MD3", "status": "COMPLETED", "grade": "A"},
+		{"op": "move", "plan_item_id": `+strconv.Itoa(fall)+`, "year_index": 2, "season": "Winter"},
+		{"op": "update", "plan_item_id": `+strconv.Itoa(fall)+`, "note": "retake"},
+		{"op": "delete", "plan_item_id": `+strconv.Itoa(winter)+`}
+	]`)
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Plan       []UserPlanItem    `json:"plan"`
+		Validation *ValidationResult `json:"validation"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Plan) != 2 {
+		t.Fatalf("expected 2 items, got %+v", resp.Plan)
+	}
+	added, moved := resp.Plan[0], resp.Plan[1]
+	if added.CourseNumber != "1MD3" || added.Status != "COMPLETED" || added.Grade == nil || *added.Grade != "A" {
+		t.Errorf("added item: %+v", added)
+	}
+	if moved.PlanItemID != fall || moved.YearIndex != 2 || moved.Season != "Winter" || moved.Note == nil || *moved.Note != "retake" {
+		t.Errorf("moved item: %+v", moved)
+	}
+	if resp.Validation == nil || resp.Validation.TotalUnitsCompleted != 3 || resp.Validation.UnitsRemaining != 3 {
+		t.Errorf("validation: %+v", resp.Validation)
+	}
+
+	// One event per operation.
+	if _, total, _ := repo.ListPlanEvents(userID, 0, 0); total != 4 {
+		t.Errorf("expected 4 plan events, got %d", total)
+	}
+}
+
+func TestPlanBatchHandler_MoveNormalizesSeason(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, [][2]interface{}{{1, "Fall"}})
+	items, _ := repo.GetPlanItems(userID)
+
+	rr := postBatch(t, repo, userID, "", `[{"op": "move", "plan_item_id": `+strconv.Itoa(items[0].PlanItemID)+`, "year_index": 1, "season": "winter"}]`)
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Plan []UserPlanItem `json:"plan"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Plan) != 1 || resp.Plan[0].Season != "Winter" {
+		t.Errorf("moved item: %+v", resp.Plan)
+	}
+}
+
+func TestPlanBatchHandler_RollsBackOnFailure(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, [][2]interface{}{{1, "Fall"}, {1, "Winter"}})
+	items, _ := repo.GetPlanItems(userID)
+	fall := strconv.Itoa(items[0].PlanItemID)
+	before := planStatuses(t, repo, userID)
+	if _, err := repo.DB.Exec(`INSERT INTO catalog_courses(subject, course_number)
+		VALUES ('MATH', '1A01'), ('COMPSCI', '1MD3')`); err != nil {
+		t.Fatalf("seed catalogue: %v", err)
+	}
+
+	// Malformed operations are all reported, nothing applied.
+	rr := postBatch(t, repo, userID, "", `[
+		{"op": "delete", "plan_item_id": `+fall+`},
+		{"op": "move", "plan_item_id": `+fall+`, "year_index": 9, "season": "Fall"},
+		{"op": "rename"}
+	]`)
+	if rr.Code != 400 {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+	var resp struct {
+		Errors []PlanOpError `json:"errors"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Errors) != 2 || resp.Errors[0].Index != 1 || resp.Errors[1].Index != 2 {
+		t.Errorf("errors: %+v", resp.Errors)
+	}
+
+	// A failure part-way through rolls back the earlier operations.
+	rr = postBatch(t, repo, userID, "", `[
+		{"op": "update", "plan_item_id": `+fall+`, "status": "COMPLETED"},
+		{"op": "add", "year_index": 1, "season": "Winter", "subject": "MATH", "course_number": "1A01"}
+	]`)
+	if rr.Code != 400 {
+		t.Fatalf("duplicate add: expected 400, got %d", rr.Code)
+	}
+	resp.Errors = nil
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Errors) != 1 || resp.Errors[0].Index != 1 || !strings.Contains(resp.Errors[0].Message, "already") {
+		t.Errorf("errors: %+v", resp.Errors)
+	}
+
+	// Adds are checked against the catalogue.
+	rr = postBatch(t, repo, userID, "", `[{"op": "add", "year_index": 1, "season": "Fall", "subject": "COMPSCI", "course_number": "9Z99"}]`)
+	if rr.Code != 400 || !strings.Contains(rr.Body.String(), "course_number") {
+		t.Errorf("unknown course: got %d %s", rr.Code, rr.Body.String())
+	}
+
+	// Another user's item is not found.
+	otherID, _ := seedUser(t, repo, "other@example.com", RoleStudent)
+	rr = postBatch(t, repo, otherID, "", `[{"op": "delete", "plan_item_id": `+fall+`}]`)
+	if rr.Code != 400 || !strings.Contains(rr.Body.String(), "not found") {
+		t.Errorf("foreign item: got %d %s", rr.Code, rr.Body.String())
+	}
+
+	after := planStatuses(t, repo, userID)
+	if len(after) != len(before) || after["1A00"] != "PLANNED" {
+		t.Errorf("plan changed after failed batches: %v", after)
+	}
+	if _, total, _ := repo.ListPlanEvents(userID, 0, 0); total != 0 {
+		t.Errorf("expected no plan events, got %d", total)
+	}
+}
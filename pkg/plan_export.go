@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Plan export formats for GET /api/users/{id}/plan/export. JSON is the
+// PlanDocument that POST /api/users/{id}/plan/import reads back.
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatICS  = "ics"
+	ExportFormatHTML = "html"
+	ExportFormatJSON = "json"
+)
+
+// The printable audit is compiled in like the email templates.
+//
+//go:embed templates/export/*.tmpl
+var exportTemplateFS embed.FS
+
+// ExportTerm is one plan bucket with its courses, in academic order.
+type ExportTerm struct {
+	YearIndex int
+	Season    string
+	Term      *Term // nil when the bucket can't be placed on the calendar
+	Items     []UserPlanItem
+}
+
+// groupPlanByTerm splits a plan from GetUserPlan (already in academic
+// order) into its buckets.
+func groupPlanByTerm(items []UserPlanItem) []ExportTerm {
+	var out []ExportTerm
+	for _, it := range items {
+		if n := len(out); n == 0 || out[n-1].YearIndex != it.YearIndex || out[n-1].Season != it.Season {
+			out = append(out, ExportTerm{YearIndex: it.YearIndex, Season: it.Season, Term: it.Term})
+		}
+		out[len(out)-1].Items = append(out[len(out)-1].Items, it)
+	}
+	return out
+}
+
+// planDocument builds the JSON export.
+func planDocument(startYear *int, items []UserPlanItem) PlanDocument {
+	doc := PlanDocument{Version: PlanDocumentVersion, StartYear: startYear, Items: []PlanDocumentItem{}}
+	for _, it := range items {
+		doc.Items = append(doc.Items, PlanDocumentItem{
+			YearIndex: it.YearIndex, Season: it.Season,
+			Subject: it.Subject, CourseNumber: it.CourseNumber,
+			Status: it.Status, Grade: it.Grade, Note: it.Note, IsCustom: it.IsCustom,
+		})
+	}
+	return doc
+}
+
+// writePlanCSV writes one row per plan item. The columns are ones
+// ParseTranscriptCSV reads, so the file can be imported again.
+func writePlanCSV(w io.Writer, items []UserPlanItem) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"year_index", "season", "term", "subject", "course_number", "course_name", "units", "status", "grade", "note"})
+	for _, it := range items {
+		var term string
+		if it.Term != nil {
+			term = it.Term.Code
+		}
+		cw.Write([]string{
+			strconv.Itoa(it.YearIndex), it.Season, term,
+			it.Subject, it.CourseNumber, deref(it.CourseName),
+			strconv.Itoa(it.Units),
+			it.Status, deref(it.Grade), deref(it.Note),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// writePlanICS writes an iCalendar file with one all-day event spanning
+// each plan term, listing its courses. Buckets with no calendar term are
+// left out.
+func writePlanICS(w io.Writer, userID int, terms []ExportTerm, now time.Time) error {
+	var b strings.Builder
+	line := func(s string) { b.WriteString(foldICSLine(s)) }
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//MacTrack//Degree Plan//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:Degree plan")
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, t := range terms {
+		if t.Term == nil {
+			continue
+		}
+		start, err1 := time.Parse("2006-01-02", t.Term.StartDate)
+		end, err2 := time.Parse("2006-01-02", t.Term.EndDate)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		var desc []string
+		for _, it := range t.Items {
+			d := it.Subject + " " + it.CourseNumber
+			if it.CourseName != nil {
+				d += " " + *it.CourseName
+			}
+			d += " — " + it.Status
+			if it.Grade != nil {
+				d += " (" + *it.Grade + ")"
+			}
+			desc = append(desc, d)
+		}
+		noun := "courses"
+		if len(t.Items) == 1 {
+			noun = "course"
+		}
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:plan-%d-%d-%s@mactrack", userID, t.Term.Year, strings.ToLower(t.Term.Season)))
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+		// DTEND is exclusive for all-day events.
+		line("DTEND;VALUE=DATE:" + end.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeICS(fmt.Sprintf("%s (Year %d): %d %s", t.Term.Code, t.YearIndex, len(t.Items), noun)))
+		line("DESCRIPTION:" + escapeICS(strings.Join(desc, "\n")))
+		line("TRANSP:TRANSPARENT")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escapeICS(s string) string { return icsEscaper.Replace(s) }
+
+// foldICSLine terminates a content line with CRLF, folding it at 75 octets
+// as RFC 5545 requires (without splitting a UTF-8 sequence).
+func foldICSLine(s string) string {
+	var b strings.Builder
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // the leading space counts
+	}
+	b.WriteString(s + "\r\n")
+	return b.String()
+}
+
+// PlanAuditData feeds templates/export/plan.html.tmpl.
+type PlanAuditData struct {
+	Name       string
+	Generated  string
+	Terms      []ExportTerm
+	Program    *Program
+	Validation *ValidationResult
+	GPA        *float64
+}
+
+// renderPlanHTML writes the printable degree audit: a single HTML page
+// with its styles inline, so it can be saved or printed as-is.
+func renderPlanHTML(w io.Writer, data PlanAuditData) error {
+	tmpl, err := htmltemplate.New("plan.html.tmpl").Funcs(htmltemplate.FuncMap{
+		"deref": deref,
+		"gpa":   func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) },
+	}).ParseFS(exportTemplateFS, "templates/export/plan.html.tmpl")
+	if err != nil {
+		return fmt.Errorf("parse plan template: %w", err)
+	}
+	return tmpl.Execute(w, data)
+}
@@ -0,0 +1,216 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// seedExportPlan gives a user with start year 2024 a small plan: two
+// completed first-year courses, one planned, and a custom item.
+func seedExportPlan(t *testing.T, repo *Repository) int {
+	t.Helper()
+	for _, code := range []string{"COMPSCI 1MD3", "COMPSCI 1XC3", "MATH 1ZA3"} {
+		f := strings.Fields(code)
+		if _, err := repo.DB.Exec(`INSERT INTO catalog_courses(subject, course_number) VALUES (?, ?)`, f[0], f[1]); err != nil {
+			t.Fatalf("seed catalogue: %v", err)
+		}
+	}
+	userID, _ := seedUser(t, repo, "export@example.com", RoleStudent)
+	if _, err := repo.DB.Exec(`UPDATE users SET start_year = 2024 WHERE user_id = ?`, userID); err != nil {
+		t.Fatal(err)
+	}
+	aPlus, b, note := "A+", "B", "with lab, section C01"
+	for _, s := range []PlanItemState{
+		{YearIndex: 1, Season: "Fall", Subject: "COMPSCI", CourseNumber: "1MD3", Status: "COMPLETED", Grade: &aPlus, Note: &note},
+		{YearIndex: 1, Season: "Fall", Subject: "MATH", CourseNumber: "1ZA3", Status: "COMPLETED", Grade: &b},
+		{YearIndex: 1, Season: "Winter", Subject: "COMPSCI", CourseNumber: "1XC3", Status: "PLANNED"},
+		{YearIndex: 2, Season: "Fall", Subject: "ARTSCI", CourseNumber: "2XX3", Status: "PLANNED", IsCustom: true},
+	} {
+		s.UserID = userID
+		if _, err := repo.AddPlanItem(userID, s); err != nil {
+			t.Fatalf("AddPlanItem %s %s: %v", s.Subject, s.CourseNumber, err)
+		}
+	}
+	return userID
+}
+
+func getExport(t *testing.T, repo *Repository, userID int, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/users/"+strconv.Itoa(userID)+"/plan/export"+query, nil)
+	PlanExportHandler(repo, &Service{Repo: repo}).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestPlanExportHandler_JSONRoundTrip(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedExportPlan(t, repo)
+
+	rr := getExport(t, repo, userID, "?format=json")
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	exported := rr.Body.String()
+	var doc PlanDocument
+	if err := json.Unmarshal([]byte(exported), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.Version != PlanDocumentVersion || doc.StartYear == nil || *doc.StartYear != 2024 || len(doc.Items) != 4 {
+		t.Fatalf("document: %+v", doc)
+	}
+
+	// Importing the document into another account reproduces the plan.
+	otherID, _ := seedUser(t, repo, "copy@example.com", RoleStudent)
+	ir := httptest.NewRecorder()
+	PlanImportHandler(repo).ServeHTTP(ir, httptest.NewRequest("POST",
+		"/api/users/"+strconv.Itoa(otherID)+"/plan/import?commit=true", strings.NewReader(exported)))
+	if ir.Code != 200 {
+		t.Fatalf("import: expected 200, got %d: %s", ir.Code, ir.Body.String())
+	}
+	rr = getExport(t, repo, otherID, "?format=json")
+	var again PlanDocument
+	if err := json.Unmarshal(rr.Body.Bytes(), &again); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !reflect.DeepEqual(again.Items, doc.Items) {
+		t.Errorf("round trip changed the plan:\n got %+v\nwant %+v", again.Items, doc.Items)
+	}
+}
+
+func TestPlanExportHandler_CSV(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedExportPlan(t, repo)
+
+	rr := getExport(t, repo, userID, "?format=csv")
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, "degree-plan.csv") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	if len(lines) != 5 || lines[0] != "year_index,season,term,subject,course_number,course_name,units,status,grade,note" {
+		t.Fatalf("csv:\n%s", rr.Body.String())
+	}
+	if lines[1] != `1,Fall,2024 Fall,COMPSCI,1MD3,,3,COMPLETED,A+,"with lab, section C01"` {
+		t.Errorf("first row = %q", lines[1])
+	}
+
+	// The CSV reads back as an import.
+	courses, err := ParseTranscriptCSV(strings.NewReader(rr.Body.String()))
+	if err != nil {
+		t.Fatalf("ParseTranscriptCSV: %v", err)
+	}
+	if len(courses) != 4 || courses[0].Term != "2024 Fall" || courses[0].Grade == nil || *courses[0].Grade != "A+" ||
+		courses[2].Status != "PLANNED" || courses[0].Note == nil {
+		t.Errorf("re-imported rows: %+v", courses)
+	}
+}
+
+func TestPlanExportHandler_ICS(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedExportPlan(t, repo)
+
+	rr := getExport(t, repo, userID, "?format=ics")
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(body, "END:VCALENDAR\r\n") {
+		t.Errorf("calendar isn't CRLF-delimited:\n%q", body)
+	}
+	if n := strings.Count(body, "BEGIN:VEVENT"); n != 3 {
+		t.Errorf("expected an event per term (3), got %d", n)
+	}
+	for _, want := range []string{
+		"UID:plan-" + strconv.Itoa(userID) + "-2024-fall@mactrack",
+		"DTSTART;VALUE=DATE:2024",
+		"SUMMARY:2024 Fall (Year 1): 2 courses",
+		`COMPSCI 1MD3 — COMPLETED (A+)\nMATH 1ZA3`,
+	} {
+		if !strings.Contains(strings.ReplaceAll(body, "\r\n ", ""), want) {
+			t.Errorf("calendar missing %q:\n%s", want, body)
+		}
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	long := "DESCRIPTION:" + strings.Repeat("é", 60)
+	got := foldICSLine(long)
+	if strings.ReplaceAll(got, "\r\n ", "") != long+"\r\n" {
+		t.Errorf("unfolding doesn't restore the line: %q", got)
+	}
+	for _, part := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+		if len(part) > 75 {
+			t.Errorf("part longer than 75 octets: %q", part)
+		}
+		if !utf8.ValidString(part) {
+			t.Errorf("fold split a UTF-8 sequence: %q", part)
+		}
+	}
+}
+
+func TestPlanExportHandler_HTML(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedExportPlan(t, repo)
+	programID := seedProgram(t, repo, 9, "COMPSCI 1MD3", "COMPSCI 1XC3", "MATH 1ZA3")
+
+	rr := getExport(t, repo, userID, "?format=html&program_id="+strconv.Itoa(programID))
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"<!DOCTYPE html>", "<style>", "Test Program", "Degree audit",
+		"Units completed</td><td>6", "Outstanding", "COMPSCI 1XC3",
+		"Courses by term", "2024 Fall", "ARTSCI 2XX3",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("audit missing %q", want)
+		}
+	}
+	if strings.Contains(body, "<link") || strings.Contains(body, "<script src") {
+		t.Error("audit should be self-contained")
+	}
+
+	// Without a program it's just the plan.
+	rr = getExport(t, repo, userID, "?format=html")
+	if rr.Code != 200 || strings.Contains(rr.Body.String(), "Degree audit") {
+		t.Errorf("plan without program: %d", rr.Code)
+	}
+	if rr = getExport(t, repo, userID, "?format=html&program_id=999"); rr.Code != 404 {
+		t.Errorf("unknown program: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestPlanExportHandler_BadFormat(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedExportPlan(t, repo)
+	if rr := getExport(t, repo, userID, "?format=pdf"); rr.Code != 400 {
+		t.Errorf("expected 400, got %d", rr.Code)
+	}
+	if rr := getExport(t, repo, userID+100, "?format=csv"); rr.Code != 404 {
+		t.Errorf("unknown user: expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,198 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanHistory_UndoEachAction(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, nil)
+
+	item, err := repo.AddPlanItem(userID, PlanItemState{UserID: userID, YearIndex: 1, Season: "Fall", Subject: "COMPSCI", CourseNumber: "1MD3"})
+	if err != nil {
+		t.Fatalf("AddPlanItem: %v", err)
+	}
+	grade := "A"
+	if err := repo.SetPlanItemStatus(userID, item.PlanItemID, "COMPLETED", &grade); err != nil {
+		t.Fatalf("SetPlanItemStatus: %v", err)
+	}
+	if err := repo.RemovePlanItem(userID, item.PlanItemID); err != nil {
+		t.Fatalf("RemovePlanItem: %v", err)
+	}
+
+	events, total, err := repo.ListPlanEvents(userID, 0, 0)
+	if err != nil {
+		t.Fatalf("ListPlanEvents: %v", err)
+	}
+	if total != 3 || len(events) != 3 {
+		t.Fatalf("expected 3 events, got %d (total %d)", len(events), total)
+	}
+	del, upd, add := events[0], events[1], events[2]
+	if add.Action != PlanEventAdd || upd.Action != PlanEventUpdate || del.Action != PlanEventDelete {
+		t.Fatalf("unexpected actions: %s %s %s", add.Action, upd.Action, del.Action)
+	}
+	if upd.Before.Status != "PLANNED" || upd.After.Status != "COMPLETED" || *upd.After.Grade != "A" {
+		t.Errorf("update event states: %+v → %+v", upd.Before, upd.After)
+	}
+	if del.After != nil || add.Before != nil {
+		t.Errorf("add/delete should have one side nil")
+	}
+
+	// Undoing the add first conflicts: the item has since been deleted.
+	if _, err := repo.UndoPlanEvent(userID, userID, add.EventID); err != ErrPlanConflict {
+		t.Fatalf("undo add out of order: expected ErrPlanConflict, got %v", err)
+	}
+
+	// Undo delete brings the item back under its old id.
+	if _, err := repo.UndoPlanEvent(userID, userID, del.EventID); err != nil {
+		t.Fatalf("undo delete: %v", err)
+	}
+	if got := planStatuses(t, repo, userID)["1MD3"]; got != "COMPLETED" {
+		t.Fatalf("after undo delete: status %q", got)
+	}
+	// Undo update puts it back to PLANNED.
+	if _, err := repo.UndoPlanEvent(userID, userID, upd.EventID); err != nil {
+		t.Fatalf("undo update: %v", err)
+	}
+	if got := planStatuses(t, repo, userID)["1MD3"]; got != "PLANNED" {
+		t.Fatalf("after undo update: status %q", got)
+	}
+	// Undo add removes it.
+	if _, err := repo.UndoPlanEvent(userID, userID, add.EventID); err != nil {
+		t.Fatalf("undo add: %v", err)
+	}
+	if _, ok := planStatuses(t, repo, userID)["1MD3"]; ok {
+		t.Fatalf("after undo add: item still present")
+	}
+
+	events, _, _ = repo.ListPlanEvents(userID, 0, 0)
+	if len(events) != 6 || events[0].Source != PlanSourceUndo || *events[0].RevertsEventID != add.EventID {
+		t.Errorf("undos should be recorded, got %d events, newest %+v", len(events), events[0])
+	}
+
+	if _, err := repo.UndoPlanEvent(userID, userID+1, add.EventID); err != ErrNotFound {
+		t.Errorf("another user's event: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPlanHistory_AppendOnly(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, nil)
+	if _, err := repo.AddPlanItem(userID, PlanItemState{UserID: userID, YearIndex: 1, Season: "Fall", Subject: "COMPSCI", CourseNumber: "1MD3"}); err != nil {
+		t.Fatalf("AddPlanItem: %v", err)
+	}
+	if _, err := repo.DB.Exec(`UPDATE plan_events SET source = 'tampered'`); err == nil {
+		t.Fatal("expected UPDATE on plan_events to fail")
+	}
+}
+
+func TestPlanHistory_Restore(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, [][2]interface{}{{1, "Fall"}})
+
+	keep, err := repo.AddPlanItem(userID, PlanItemState{UserID: userID, YearIndex: 1, Season: "Winter", Subject: "COMPSCI", CourseNumber: "1XC3"})
+	if err != nil {
+		t.Fatalf("AddPlanItem: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	asOf := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := repo.AddPlanItem(userID, PlanItemState{UserID: userID, YearIndex: 2, Season: "Fall", Subject: "COMPSCI", CourseNumber: "2C03"}); err != nil {
+		t.Fatalf("AddPlanItem: %v", err)
+	}
+	if err := repo.SetPlanItemStatus(userID, keep.PlanItemID, "IN_PROGRESS", nil); err != nil {
+		t.Fatalf("SetPlanItemStatus: %v", err)
+	}
+	if err := repo.RemovePlanItem(userID, keep.PlanItemID); err != nil {
+		t.Fatalf("RemovePlanItem: %v", err)
+	}
+
+	body := `{"as_of":"` + asOf.Format(time.RFC3339Nano) + `"}`
+	req := httptest.NewRequest("POST", "/api/users/"+strconv.Itoa(userID)+"/plan/restore", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	RestorePlanHandler(repo).ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("restore: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var res struct {
+		Reverted int `json:"reverted"`
+	}
+	json.NewDecoder(rr.Body).Decode(&res)
+	if res.Reverted != 3 {
+		t.Errorf("expected 3 events reverted, got %d", res.Reverted)
+	}
+
+	got := planStatuses(t, repo, userID)
+	if len(got) != 2 || got["1XC3"] != "PLANNED" || got["1A00"] != "PLANNED" {
+		t.Errorf("plan after restore: %v", got)
+	}
+}
+
+func TestPlanHistoryHandlers(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, nil)
+	base := "/api/users/" + strconv.Itoa(userID) + "/plan"
+
+	item, err := repo.AddPlanItem(userID, PlanItemState{UserID: userID, YearIndex: 1, Season: "Fall", Subject: "COMPSCI", CourseNumber: "1MD3"})
+	if err != nil {
+		t.Fatalf("AddPlanItem: %v", err)
+	}
+	if err := repo.SetPlanItemStatus(userID, item.PlanItemID, "COMPLETED", nil); err != nil {
+		t.Fatalf("SetPlanItemStatus: %v", err)
+	}
+
+	rr := httptest.NewRecorder()
+	PlanHistoryHandler(repo).ServeHTTP(rr, httptest.NewRequest("GET", base+"/history?limit=1", nil))
+	if rr.Code != 200 {
+		t.Fatalf("history: expected 200, got %d", rr.Code)
+	}
+	var hist struct {
+		Events []PlanEvent `json:"events"`
+		Total  int         `json:"total"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&hist); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if hist.Total != 2 || len(hist.Events) != 1 || hist.Events[0].Action != PlanEventUpdate {
+		t.Fatalf("history page: total %d, events %+v", hist.Total, hist.Events)
+	}
+	updateID := hist.Events[0].EventID
+
+	addID := strconv.Itoa(updateID - 1)
+	rr = httptest.NewRecorder()
+	UndoPlanEventHandler(repo).ServeHTTP(rr, httptest.NewRequest("POST", base+"/history/"+addID+"/undo", nil))
+	if rr.Code != 409 {
+		t.Errorf("undo of superseded add: expected 409, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	UndoPlanEventHandler(repo).ServeHTTP(rr, httptest.NewRequest("POST", base+"/history/999/undo", nil))
+	if rr.Code != 404 {
+		t.Errorf("undo of unknown event: expected 404, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	UndoPlanEventHandler(repo).ServeHTTP(rr, httptest.NewRequest("POST", base+"/history/"+strconv.Itoa(updateID)+"/undo", nil))
+	if rr.Code != 200 {
+		t.Fatalf("undo update: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if got := planStatuses(t, repo, userID)["1MD3"]; got != "PLANNED" {
+		t.Errorf("after undo: status %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	RestorePlanHandler(repo).ServeHTTP(rr, httptest.NewRequest("POST", base+"/restore", strings.NewReader(`{"as_of":"yesterday"}`)))
+	if rr.Code != 400 {
+		t.Errorf("bad as_of: expected 400, got %d", rr.Code)
+	}
+}
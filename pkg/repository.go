@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -21,51 +22,105 @@ type RequisiteRow struct {
 	Kind            string `json:"kind"`
 }
 
-var mcmasterGPAScale = map[string]float64{
-	"A+": 12.0, "A": 11.0, "A-": 10.0,
-	"B+": 9.0, "B": 8.0, "B-": 7.0,
-	"C+": 6.0, "C": 5.0, "C-": 4.0,
-	"D+": 3.0, "D": 2.0, "D-": 1.0,
-	"F": 0.0,
+// GradedCourse is a course in the user's plan and the grade recorded for
+// it, if any.
+type GradedCourse struct {
+	PlanItemID   int    `json:"plan_item_id"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	YearIndex    int    `json:"year_index"`
+	Season       string `json:"season"`
+	Status       string `json:"status"`
+	Units        int    `json:"units"`
+	IsCustom     bool   `json:"is_custom"`
+	Grade        Grade  `json:"grade"`
 }
 
-func (r *Repository) GetUserGPA(userID int) (gpa float64, ok bool, err error) {
+// GetCompletedCourses returns every completed item in the user's plan in
+// academic order, graded or not, custom items included. Transfer credit
+// isn't a plan item and isn't included.
+func (r *Repository) GetCompletedCourses(userID int) ([]GradedCourse, error) {
+	return r.planCourses(userID, "COMPLETED")
+}
+
+// GetRemainingCourses returns the planned and in-progress items in the
+// user's plan in academic order, custom items included.
+func (r *Repository) GetRemainingCourses(userID int) ([]GradedCourse, error) {
+	return r.planCourses(userID, "PLANNED", "IN_PROGRESS")
+}
+
+func (r *Repository) planCourses(userID int, statuses ...string) ([]GradedCourse, error) {
+	args := []interface{}{userID}
+	for _, s := range statuses {
+		args = append(args, s)
+	}
 	rows, err := r.query(`
-        SELECT pi.course_number, pi.grade
+        SELECT pi.plan_item_id, pi.subject, pi.course_number, pt.year_index, pt.season, pi.status, pi.is_custom, pi.grade, cc.units
         FROM plan_items pi
         JOIN plan_terms pt ON pt.plan_term_id = pi.plan_term_id
+        LEFT JOIN catalog_courses cc ON cc.subject = pi.subject AND cc.course_number = pi.course_number
         WHERE pt.user_id = ?
-          AND pi.status = 'COMPLETED'
-          AND pi.grade IS NOT NULL
-          AND pi.grade != ''`,
-		userID)
+          AND pi.status IN (?`+strings.Repeat(", ?", len(statuses)-1)+`)
+        ORDER BY pt.year_index, `+planSeasonOrderSQL+`, pi.subject, pi.course_number`,
+		args...)
 	if err != nil {
-		return 0, false, err
+		return nil, err
 	}
 	defer rows.Close()
 
-	totalPoints := 0.0
-	totalUnits := 0
-
+	out := []GradedCourse{}
 	for rows.Next() {
-		var courseNumber, grade string
-		if err := rows.Scan(&courseNumber, &grade); err != nil {
-			return 0, false, err
+		var c GradedCourse
+		var grade sql.NullString
+		var units sql.NullInt64
+		if err := rows.Scan(&c.PlanItemID, &c.Subject, &c.CourseNumber, &c.YearIndex, &c.Season, &c.Status, &c.IsCustom, &grade, &units); err != nil {
+			return nil, err
 		}
-		points, exists := mcmasterGPAScale[strings.ToUpper(strings.TrimSpace(grade))]
-		if !exists {
-			continue // skip unrecognised grade strings
+		c.Units = unitsOr(units, c.CourseNumber)
+		if strings.TrimSpace(grade.String) != "" {
+			c.Grade = ParseGrade(grade.String)
 		}
-		// Weight by real unit value from course number suffix
-		units := UnitsFromCourseNumber(courseNumber, 3)
-		totalPoints += points * float64(units)
-		totalUnits += units
+		out = append(out, c)
 	}
+	return out, rows.Err()
+}
+
+// GetGradedCourses returns the user's completed, graded McMaster courses in
+// academic order. Custom plan items and transfer credit aren't included;
+// they never count towards the GPA.
+func (r *Repository) GetGradedCourses(userID int) ([]GradedCourse, error) {
+	completed, err := r.GetCompletedCourses(userID)
+	if err != nil {
+		return nil, err
+	}
+	out := []GradedCourse{}
+	for _, c := range completed {
+		if !c.IsCustom && c.Grade.Raw != "" {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
 
-	if totalUnits == 0 {
-		return 0, false, nil // no graded courses yet
+// gradedUnits is what ComputeGPA needs from each course.
+func gradedUnits(courses []GradedCourse) []GradedUnits {
+	out := make([]GradedUnits, len(courses))
+	for i, c := range courses {
+		out[i] = GradedUnits{Units: c.Units, Grade: c.Grade}
 	}
-	return totalPoints / float64(totalUnits), true, nil
+	return out
+}
+
+// GetUserGPA is the unit-weighted GPA on the McMaster 12-point scale over
+// the user's graded, completed McMaster courses. Custom plan items,
+// transfer credit and pass/fail grades don't count.
+func (r *Repository) GetUserGPA(userID int) (gpa float64, ok bool, err error) {
+	courses, err := r.GetGradedCourses(userID)
+	if err != nil {
+		return 0, false, err
+	}
+	gpa, _, ok = ComputeGPA(gradedUnits(courses), McMaster12)
+	return gpa, ok, nil
 }
 
 // GetProgramWithGroups loads a Program with its full requirement group tree
@@ -217,6 +272,10 @@ func (r *Repository) GetProgramWithGroups(programID int) (*Program, error) {
 		p.Groups = append(p.Groups, *groupMap[id])
 	}
 
+	if p.StandingRules, err = r.ListStandingRules(programID); err != nil {
+		return nil, fmt.Errorf("load standing rules: %w", err)
+	}
+
 	return &p, nil
 }
 
@@ -249,6 +308,10 @@ func (r *Repository) GetRequisites(subject, courseNumber string) ([]RequisiteRow
 type Repository struct {
 	DB     *sql.DB
 	driver string // "postgres" or "sqlite3"
+
+	// searchBackend is detected on first use; see repository_search.go.
+	searchOnce sync.Once
+	searchMode string
 }
 
 // paramRe matches bare ? parameter placeholders used in SQLite-style queries.
@@ -324,6 +387,54 @@ func (r *Repository) ExecReturningID(q, pkCol string, args ...interface{}) (int6
 	return r.execReturningID(q, pkCol, args...)
 }
 
+// Tx wraps a *sql.Tx with the same driver adaptation as Repository, so
+// multi-statement writes can use the usual ? placeholders on both dialects.
+type Tx struct {
+	tx *sql.Tx
+	r  *Repository
+}
+
+func (t *Tx) query(q string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.Query(t.r.adaptQuery(q), args...)
+}
+
+func (t *Tx) queryRow(q string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRow(t.r.adaptQuery(q), args...)
+}
+
+func (t *Tx) exec(q string, args ...interface{}) (sql.Result, error) {
+	return t.tx.Exec(t.r.adaptQuery(q), args...)
+}
+
+// execReturningID mirrors Repository.execReturningID inside the transaction.
+func (t *Tx) execReturningID(q, pkCol string, args ...interface{}) (int64, error) {
+	if t.r.driver == "postgres" {
+		var id int64
+		err := t.tx.QueryRow(t.r.adaptQuery(q)+" RETURNING "+pkCol, args...).Scan(&id)
+		return id, err
+	}
+	res, err := t.tx.Exec(t.r.adaptQuery(q), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// withTx runs fn inside a transaction, committing if it returns nil and
+// rolling back otherwise. fn must only use the Tx it is given — going back
+// to r.query mid-transaction would grab a second pooled connection.
+func (r *Repository) withTx(fn func(tx *Tx) error) error {
+	sqlTx, err := r.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	if err := fn(&Tx{tx: sqlTx, r: r}); err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+	return sqlTx.Commit()
+}
+
 // NewRepository opens a database connection.
 func NewRepository(dsn string) (*Repository, error) {
 	isPostgres := strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") || strings.Contains(dsn, "host=")
@@ -362,121 +473,6 @@ func NewRepository(dsn string) (*Repository, error) {
 	return &Repository{DB: db, driver: driver}, nil
 }
 
-// SearchCourses searches courses by subject, number, name, or professor.
-// It supports multi-token AND search: the query is split on whitespace and every
-// token must independently match at least one column (subject, course_number,
-// course_name, or professor). This lets searches like "compsci 2" or "software eng"
-// work correctly even though those strings never appear verbatim in a single column.
-//
-// level filters by the first digit of course_number (e.g. "2" = 2000-level courses).
-// term filters by partial match on the term string (e.g. "Fall", "Winter").
-// Either filter is ignored when empty or "all".
-// limit ≤ 0 means no cap (returns all matches). offset is 0-based.
-// Returns the matching page of courses plus the total number of matches.
-func (r *Repository) SearchCourses(q, level, term string, limit, offset int) ([]Course, int, error) {
-	tokens := strings.Fields(strings.TrimSpace(q))
-
-	// Build WHERE clause — one condition per token, all ANDed together.
-	// Each condition checks all four searchable columns with OR.
-	var whereParts []string
-	var args []interface{}
-	for _, tok := range tokens {
-		pat := "%" + tok + "%"
-		whereParts = append(whereParts,
-			"(c.subject LIKE ? OR c.course_number LIKE ? OR c.course_name LIKE ? OR c.professor LIKE ?)")
-		args = append(args, pat, pat, pat, pat)
-	}
-
-	// Level filter: course_number must start with the given digit (e.g. "2" → 2000-level).
-	if level != "" && level != "all" {
-		whereParts = append(whereParts, "c.course_number LIKE ?")
-		args = append(args, level+"%")
-	}
-
-	// Term filter: term string must contain the given value (e.g. "Fall", "Winter").
-	if term != "" && term != "all" {
-		whereParts = append(whereParts, "c.term LIKE ?")
-		args = append(args, "%"+term+"%")
-	}
-
-	where := ""
-	if len(whereParts) > 0 {
-		where = "WHERE " + strings.Join(whereParts, " AND ")
-	}
-
-	// Count total matches first (needed for pagination metadata).
-	countQuery := fmt.Sprintf(
-		"SELECT COUNT(*) FROM courses c %s", where)
-	var total int
-	if err := r.queryRow(countQuery, args...).Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("count courses: %w", err)
-	}
-
-	// Fetch the requested page with aggregated instructor stats.
-	var pageQuery string
-	var pageArgs []interface{}
-	pageArgs = append(pageArgs, args...)
-	if limit > 0 {
-		pageQuery = fmt.Sprintf(
-			`SELECT c.id, c.subject, c.course_number, c.course_name, c.professor, c.term,
-			        AVG(i.ext_avg_rating), AVG(i.ext_avg_difficulty), SUM(i.ext_num_ratings)
-			 FROM courses c
-			 LEFT JOIN course_instructors ci ON c.id = ci.course_row_id
-			 LEFT JOIN instructors i ON ci.instructor_id = i.instructor_id AND i.ext_avg_rating IS NOT NULL
-			 %s
-			 GROUP BY c.id
-			 ORDER BY c.subject, c.course_number LIMIT ? OFFSET ?`,
-			where)
-		pageArgs = append(pageArgs, limit, offset)
-	} else {
-		pageQuery = fmt.Sprintf(
-			`SELECT c.id, c.subject, c.course_number, c.course_name, c.professor, c.term,
-			        AVG(i.ext_avg_rating), AVG(i.ext_avg_difficulty), SUM(i.ext_num_ratings)
-			 FROM courses c
-			 LEFT JOIN course_instructors ci ON c.id = ci.course_row_id
-			 LEFT JOIN instructors i ON ci.instructor_id = i.instructor_id AND i.ext_avg_rating IS NOT NULL
-			 %s
-			 GROUP BY c.id
-			 ORDER BY c.subject, c.course_number LIMIT -1 OFFSET ?`,
-			where)
-		pageArgs = append(pageArgs, offset)
-	}
-
-	rows, err := r.query(pageQuery, pageArgs...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("search courses: %w", err)
-	}
-	defer rows.Close()
-
-	out := []Course{}
-	for rows.Next() {
-		var c Course
-		var courseName, professor sql.NullString
-		var avgRating, avgDifficulty sql.NullFloat64
-		var numRatings sql.NullInt64
-		if err := rows.Scan(&c.ID, &c.Subject, &c.CourseNumber, &courseName, &professor, &c.Term,
-			&avgRating, &avgDifficulty, &numRatings); err != nil {
-			return nil, 0, err
-		}
-		c.CourseName = courseName.String
-		c.Professor = professor.String
-		if avgRating.Valid {
-			v := avgRating.Float64
-			c.AvgRating = &v
-		}
-		if avgDifficulty.Valid {
-			v := avgDifficulty.Float64
-			c.AvgDifficulty = &v
-		}
-		if numRatings.Valid {
-			v := int(numRatings.Int64)
-			c.NumRatings = &v
-		}
-		out = append(out, c)
-	}
-	return out, total, rows.Err()
-}
-
 // GetCourseByID fetches a single course by id.
 func (r *Repository) GetCourseByID(id int) (*Course, error) {
 	row := r.queryRow(`SELECT id, subject, course_number, course_name, professor, term FROM courses WHERE id = ?`, id)
@@ -531,11 +527,12 @@ func (r *Repository) GetAllPrograms() ([]Program, error) {
 // GetPlanItems fetches plan items for a user (all terms).
 func (r *Repository) GetPlanItems(userID int) ([]PlanItem, error) {
 	rows, err := r.query(`
-		SELECT pi.plan_item_id, pi.plan_term_id, pi.subject, pi.course_number, pi.status, pi.grade, pi.note
+		SELECT pi.plan_item_id, pi.plan_term_id, pi.subject, pi.course_number, pi.status, pi.grade, pi.note, pi.is_custom, cc.units
 		FROM plan_items pi
 		JOIN plan_terms pt ON pi.plan_term_id = pt.plan_term_id
+		LEFT JOIN catalog_courses cc ON cc.subject = pi.subject AND cc.course_number = pi.course_number
 		WHERE pt.user_id = ?
-		ORDER BY pt.year_index, pt.season, pi.plan_term_id, pi.plan_item_id
+		ORDER BY pt.year_index, `+planSeasonOrderSQL+`, pi.plan_term_id, pi.plan_item_id
 	`, userID)
 	if err != nil {
 		return nil, err
@@ -546,9 +543,11 @@ func (r *Repository) GetPlanItems(userID int) ([]PlanItem, error) {
 	for rows.Next() {
 		var pi PlanItem
 		var grade, note sql.NullString
-		if err := rows.Scan(&pi.PlanItemID, &pi.PlanTermID, &pi.Subject, &pi.CourseNumber, &pi.Status, &grade, &note); err != nil {
+		var units sql.NullInt64
+		if err := rows.Scan(&pi.PlanItemID, &pi.PlanTermID, &pi.Subject, &pi.CourseNumber, &pi.Status, &grade, &note, &pi.IsCustom, &units); err != nil {
 			return nil, err
 		}
+		pi.Units = unitsOr(units, pi.CourseNumber)
 		if grade.Valid {
 			pi.Grade = &grade.String
 		}
@@ -718,19 +717,21 @@ type User struct {
 	PasswordHash string  `json:"-"` // The `-` tag means this field is never serialized to JSON
 	Program      *string `json:"program,omitempty"`
 	YearOfStudy  *int    `json:"year_of_study,omitempty"`
+	StartYear    *int    `json:"start_year,omitempty"` // calendar year of the first Fall term
+	Role         Role    `json:"role"`
 }
 
 // GetUserByEmail looks up a user by their email address.
 // Returns (nil, nil) if no user found — not an error, just not found.
 func (r *Repository) GetUserByEmail(email string) (*User, error) {
 	row := r.queryRow(
-		`SELECT user_id, email, display_name, password_hash, program, year_of_study
+		`SELECT user_id, email, display_name, password_hash, program, year_of_study, start_year, role
 		 FROM users WHERE email = ?`, email,
 	)
 	var u User
 	var program sql.NullString
-	var year sql.NullInt64
-	if err := row.Scan(&u.UserID, &u.Email, &u.DisplayName, &u.PasswordHash, &program, &year); err != nil {
+	var year, startYear sql.NullInt64
+	if err := row.Scan(&u.UserID, &u.Email, &u.DisplayName, &u.PasswordHash, &program, &year, &startYear, &u.Role); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -743,6 +744,10 @@ func (r *Repository) GetUserByEmail(email string) (*User, error) {
 		v := int(year.Int64)
 		u.YearOfStudy = &v
 	}
+	if startYear.Valid {
+		v := int(startYear.Int64)
+		u.StartYear = &v
+	}
 	return &u, nil
 }
 
@@ -768,6 +773,7 @@ func (r *Repository) CreateUser(email, displayName, passwordHash string, program
 		UserID:      int(id),
 		Email:       email,
 		DisplayName: displayName,
+		Role:        RoleStudent, // column default
 	}
 	if program != nil {
 		u.Program = program
@@ -782,12 +788,12 @@ func (r *Repository) CreateUser(email, displayName, passwordHash string, program
 // Used after token validation to attach full user info to a request.
 func (r *Repository) GetUserByID(id int) (*User, error) {
 	row := r.queryRow(
-		`SELECT user_id, email, display_name, program, year_of_study FROM users WHERE user_id = ?`, id,
+		`SELECT user_id, email, display_name, program, year_of_study, start_year, role FROM users WHERE user_id = ?`, id,
 	)
 	var u User
 	var program sql.NullString
-	var year sql.NullInt64
-	if err := row.Scan(&u.UserID, &u.Email, &u.DisplayName, &program, &year); err != nil {
+	var year, startYear sql.NullInt64
+	if err := row.Scan(&u.UserID, &u.Email, &u.DisplayName, &program, &year, &startYear, &u.Role); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -800,6 +806,10 @@ func (r *Repository) GetUserByID(id int) (*User, error) {
 		v := int(year.Int64)
 		u.YearOfStudy = &v
 	}
+	if startYear.Valid {
+		v := int(startYear.Int64)
+		u.StartYear = &v
+	}
 	return &u, nil
 }
 
@@ -832,59 +842,100 @@ func (r *Repository) UpdateUserProfile(userID int, program *string, yearOfStudy
 	return err
 }
 
-// AdvanceUserYear increments year_of_study by 1 (ceiling 8) and bulk-marks any
-// PLANNED or IN_PROGRESS plan items from prior year buckets as COMPLETED.
-// If newProgram is non-nil, the user's program is also updated to that value
-// (used when an Engineering I student chooses a specialization).
-// Returns the new year, number of auto-completed items, and the final program name.
-func (r *Repository) AdvanceUserYear(userID int, newProgram *string) (newYear, completedCount int, finalProgram string, err error) {
+// YearAdvance describes what AdvanceUserYear does (or, from
+// PreviewAdvanceUserYear, would do) to a user.
+type YearAdvance struct {
+	// StartYear is the calendar year the student's first Fall was in, from
+	// CurrentUserTerm; advancing pins it if it was only inferred.
+	StartYear   int              `json:"start_year"`
+	CurrentYear int              `json:"current_year"`
+	NewYear     int              `json:"new_year"`
+	NewProgram  string           `json:"new_program"`
+	Unconfirmed []PlanItemChange `json:"unconfirmed"`
+}
+
+// PreviewAdvanceUserYear works out what AdvanceUserYear(userID, newProgram, at)
+// would change without changing anything. Items are confirmed in every year
+// the student is leaving and, as in RolloverUser, in any later term that had
+// already ended at the given time.
+func (r *Repository) PreviewAdvanceUserYear(userID int, newProgram *string, at time.Time) (*YearAdvance, error) {
 	u, err := r.GetUserByID(userID)
 	if err != nil {
-		return 0, 0, "", err
+		return nil, err
 	}
 	if u == nil {
-		return 0, 0, "", fmt.Errorf("user %d not found", userID)
+		return nil, fmt.Errorf("user %d not found", userID)
 	}
 
-	currentYear := 1
-	if u.YearOfStudy != nil {
-		currentYear = *u.YearOfStudy
-	}
-	newYear = currentYear + 1
-	if newYear > 8 {
-		return currentYear, 0, "", fmt.Errorf("already at maximum year (8)")
-	}
-
-	// Bulk-complete all planned/in-progress items that belong to past year buckets.
-	// The subquery pattern works on both SQLite and PostgreSQL.
-	result, err := r.exec(`
-		UPDATE plan_items SET status = 'COMPLETED'
-		WHERE plan_item_id IN (
-			SELECT pi.plan_item_id
-			FROM plan_items pi
-			JOIN plan_terms pt ON pi.plan_term_id = pt.plan_term_id
-			WHERE pt.user_id = ?
-			  AND pt.year_index < ?
-			  AND pi.status IN ('PLANNED', 'IN_PROGRESS')
-		)`, userID, newYear)
+	ut, err := r.CurrentUserTerm(u, at)
 	if err != nil {
-		return 0, 0, "", fmt.Errorf("bulk-complete past items: %w", err)
+		return nil, err
 	}
-	affected, _ := result.RowsAffected()
-
-	// Persist the new year (and optionally the new program) on the user row.
-	if err = r.UpdateUserProfile(userID, newProgram, &newYear); err != nil {
-		return 0, 0, "", fmt.Errorf("update profile: %w", err)
+	adv := &YearAdvance{StartYear: ut.StartYear, CurrentYear: 1}
+	if u.YearOfStudy != nil {
+		adv.CurrentYear = *u.YearOfStudy
+	}
+	adv.NewYear = adv.CurrentYear + 1
+	if adv.NewYear > 8 {
+		return nil, fmt.Errorf("already at maximum year (8)")
 	}
-
-	// Determine the program name to echo back to the caller.
 	if newProgram != nil {
-		finalProgram = *newProgram
+		adv.NewProgram = *newProgram
 	} else if u.Program != nil {
-		finalProgram = *u.Program
+		adv.NewProgram = *u.Program
+	}
+
+	var lookupErr error
+	ended := r.termEnded(ut, at, &lookupErr)
+	adv.Unconfirmed, err = r.planItemsToConfirm(userID, func(yearIndex int, season string) bool {
+		return yearIndex < adv.NewYear || ended(yearIndex, season)
+	})
+	if err == nil {
+		err = lookupErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return adv, nil
+}
+
+// AdvanceUserYear increments year_of_study by 1 (ceiling 8) and moves any
+// PLANNED or IN_PROGRESS plan items from prior year buckets to UNCONFIRMED,
+// for the student to mark completed or dropped.
+// If newProgram is non-nil, the user's program is also updated to that value
+// (used when an Engineering I student chooses a specialization).
+// Returns the new year, number of items moved to UNCONFIRMED, and the final
+// program name.
+func (r *Repository) AdvanceUserYear(userID int, newProgram *string, at time.Time) (newYear, unconfirmedCount int, finalProgram string, err error) {
+	adv, err := r.PreviewAdvanceUserYear(userID, newProgram, at)
+	if err != nil {
+		return 0, 0, "", err
 	}
 
-	return newYear, int(affected), finalProgram, nil
+	err = r.withTx(func(tx *Tx) error {
+		if err := markUnconfirmed(tx, planChange{actorID: userID, source: PlanSourceAdvanceYear}, adv.Unconfirmed); err != nil {
+			return err
+		}
+		// Persist the new year (and optionally the new program) on the user
+		// row. The start year is pinned too, or inferring it from the new
+		// year_of_study would move the student's plan a year along the
+		// calendar.
+		if newProgram != nil {
+			_, err = tx.exec(`UPDATE users SET start_year = ?, year_of_study = ?, program = ? WHERE user_id = ?`,
+				adv.StartYear, adv.NewYear, *newProgram, userID)
+		} else {
+			_, err = tx.exec(`UPDATE users SET start_year = ?, year_of_study = ? WHERE user_id = ?`,
+				adv.StartYear, adv.NewYear, userID)
+		}
+		if err != nil {
+			return fmt.Errorf("update profile: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, "", err
+	}
+	return adv.NewYear, len(adv.Unconfirmed), adv.NewProgram, nil
 }
 
 // SearchInstructors searches instructors by name or department.
@@ -892,71 +943,117 @@ func (r *Repository) AdvanceUserYear(userID int, newProgram *string) (newYear, c
 // limit ≤ 0 means no cap. offset is 0-based.
 // Returns the matching page of instructors plus the total number of matches.
 func (r *Repository) SearchInstructors(q, department string, minRating float64, limit, offset int) ([]Instructor, int, error) {
+	res, err := r.FindInstructors(InstructorQuery{
+		Q: q, Department: department, MinRating: minRating, Limit: limit, Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Instructors, res.Total, nil
+}
+
+// InstructorQuery describes an instructor search. Paging works as in
+// CourseQuery: by Offset, or by a Cursor from a previous page.
+type InstructorQuery struct {
+	Q             string
+	Department    string
+	MinRating     float64
+	Limit, Offset int
+	Cursor        string
+	SkipTotal     bool // don't count the matches; Total is -1
+}
+
+// InstructorResults is one page of FindInstructors.
+type InstructorResults struct {
+	Instructors []Instructor
+	Total       int
+	NextCursor  string
+	PrevCursor  string
+}
+
+// instructorKeys orders instructors by name; name_normalized is unique and
+// breaks ties between namesakes.
+var instructorKeys = []sortKey{{expr: "name"}, {expr: "name_normalized"}}
+
+// FindInstructors runs an instructor search, ordered by name.
+func (r *Repository) FindInstructors(iq InstructorQuery) (*InstructorResults, error) {
 	var whereParts []string
 	var args []interface{}
 
 	// Search by name or department
-	if q != "" {
-		pat := "%" + q + "%"
+	if iq.Q != "" {
+		pat := "%" + iq.Q + "%"
 		whereParts = append(whereParts, "(name LIKE ? OR department LIKE ?)")
 		args = append(args, pat, pat)
 	}
 
 	// Department filter
-	if department != "" && department != "all" {
+	if iq.Department != "" && iq.Department != "all" {
 		whereParts = append(whereParts, "department = ?")
-		args = append(args, department)
+		args = append(args, iq.Department)
 	}
 
 	// Min rating filter
-	if minRating > 0 {
+	if iq.MinRating > 0 {
 		whereParts = append(whereParts, "ext_avg_rating >= ?")
-		args = append(args, minRating)
+		args = append(args, iq.MinRating)
+	}
+
+	// Count total matches
+	res := &InstructorResults{Instructors: []Instructor{}, Total: -1}
+	if !iq.SkipTotal {
+		where := ""
+		if len(whereParts) > 0 {
+			where = "WHERE " + strings.Join(whereParts, " AND ")
+		}
+		if err := r.queryRow("SELECT COUNT(*) FROM instructors "+where, args...).Scan(&res.Total); err != nil {
+			return nil, fmt.Errorf("count instructors: %w", err)
+		}
 	}
 
+	// Fetch the requested page, plus one row to tell whether there's another.
+	var cur *pageCursor
+	offset := iq.Offset
+	if iq.Cursor != "" {
+		var err error
+		if cur, err = decodeCursor(iq.Cursor, "name", len(instructorKeys)); err != nil {
+			return nil, err
+		}
+		cond, condArgs := keysetCondition(instructorKeys, cur.Keys, cur.Before)
+		whereParts = append(whereParts, cond)
+		args = append(args, condArgs...)
+		offset = 0
+	}
+	backward := cur != nil && cur.Before
 	where := ""
 	if len(whereParts) > 0 {
 		where = "WHERE " + strings.Join(whereParts, " AND ")
 	}
-
-	// Count total matches
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM instructors %s", where)
-	var total int
-	if err := r.queryRow(countQuery, args...).Scan(&total); err != nil {
-		return nil, 0, fmt.Errorf("count instructors: %w", err)
-	}
-
-	// Fetch the requested page
-	var pageQuery string
-	pageArgs := append([]interface{}{}, args...)
+	limit := iq.Limit
 	if limit > 0 {
-		pageQuery = fmt.Sprintf(`
-			SELECT instructor_id, name, department, external_source, external_id, external_url,
-			       ext_avg_rating, ext_avg_difficulty, ext_num_ratings, ext_last_scraped
-			FROM instructors %s ORDER BY name LIMIT ? OFFSET ?`, where)
-		pageArgs = append(pageArgs, limit, offset)
-	} else {
-		pageQuery = fmt.Sprintf(`
-			SELECT instructor_id, name, department, external_source, external_id, external_url,
-			       ext_avg_rating, ext_avg_difficulty, ext_num_ratings, ext_last_scraped
-			FROM instructors %s ORDER BY name LIMIT -1 OFFSET ?`, where)
-		pageArgs = append(pageArgs, offset)
+		limit++
 	}
+	order, _ := orderByClause(instructorKeys, backward)
+	limitClause, pageArgs := searchLimitClause(args, limit, offset)
 
-	rows, err := r.query(pageQuery, pageArgs...)
+	rows, err := r.query(fmt.Sprintf(`
+		SELECT instructor_id, name, department, external_source, external_id, external_url,
+		       ext_avg_rating, ext_avg_difficulty, ext_num_ratings, ext_last_scraped, name_normalized
+		FROM instructors %s ORDER BY %s %s`, where, order, limitClause), pageArgs...)
 	if err != nil {
-		return nil, 0, fmt.Errorf("search instructors: %w", err)
+		return nil, fmt.Errorf("search instructors: %w", err)
 	}
 	defer rows.Close()
 
-	out := []Instructor{}
+	var rowKeys [][]interface{}
 	for rows.Next() {
 		var i Instructor
 		var dept, extSource, extID, extURL, lastScraped sql.NullString
 		var avgRating, avgDiff sql.NullFloat64
 		var numRatings sql.NullInt64
-		if err := rows.Scan(&i.ID, &i.Name, &dept, &extSource, &extID, &extURL, &avgRating, &avgDiff, &numRatings, &lastScraped); err != nil {
-			return nil, 0, err
+		var nameNormalized string
+		if err := rows.Scan(&i.ID, &i.Name, &dept, &extSource, &extID, &extURL, &avgRating, &avgDiff, &numRatings, &lastScraped, &nameNormalized); err != nil {
+			return nil, err
 		}
 		i.Department = dept.String
 		i.ExternalSource = extSource.String
@@ -973,9 +1070,27 @@ func (r *Repository) SearchInstructors(q, department string, minRating float64,
 			i.NumRatings = &n
 		}
 		i.LastScraped = lastScraped.String
-		out = append(out, i)
+		res.Instructors = append(res.Instructors, i)
+		rowKeys = append(rowKeys, []interface{}{i.Name, nameNormalized})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	more := iq.Limit > 0 && len(res.Instructors) > iq.Limit
+	if more {
+		res.Instructors, rowKeys = res.Instructors[:iq.Limit], rowKeys[:iq.Limit]
+	}
+	if backward {
+		for i, j := 0, len(rowKeys)-1; i < j; i, j = i+1, j-1 {
+			res.Instructors[i], res.Instructors[j] = res.Instructors[j], res.Instructors[i]
+			rowKeys[i], rowKeys[j] = rowKeys[j], rowKeys[i]
+		}
+	}
+	if iq.Limit > 0 {
+		res.NextCursor, res.PrevCursor = pageLinks("name", rowKeys, more, offset, cur)
 	}
-	return out, total, rows.Err()
+	return res, nil
 }
 
 // GetInstructorByID fetches a single instructor by their internal ID.
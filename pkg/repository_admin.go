@@ -0,0 +1,651 @@
+package pkg
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by write helpers when the target row does not exist,
+// so handlers can map it to a 404 without inspecting driver errors.
+var ErrNotFound = errors.New("not found")
+
+// ─── Audit log ───────────────────────────────────────────────────────────────
+
+// AuditEntry is one row of admin_audit_log.
+type AuditEntry struct {
+	AuditID     int             `json:"audit_id"`
+	ActorUserID *int            `json:"actor_user_id"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"target_type"`
+	TargetID    string          `json:"target_id"`
+	Details     json.RawMessage `json:"details,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// insertAudit writes one audit row inside an existing transaction.
+// details is marshalled to JSON; pass nil when there is nothing to record.
+// actorID 0 records a system action (e.g. a scheduled job) with no actor.
+func insertAudit(tx *Tx, actorID int, action, targetType, targetID string, details interface{}) error {
+	var actor interface{}
+	if actorID > 0 {
+		actor = actorID
+	}
+	var detailsJSON interface{}
+	if details != nil {
+		b, err := json.Marshal(details)
+		if err != nil {
+			return fmt.Errorf("marshal audit details: %w", err)
+		}
+		detailsJSON = string(b)
+	}
+	_, err := tx.exec(`
+		INSERT INTO admin_audit_log (actor_user_id, action, target_type, target_id, details, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		actor, action, targetType, targetID, detailsJSON, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("write audit log: %w", err)
+	}
+	return nil
+}
+
+// adminAction runs fn and records the audit row in the same transaction, so an
+// admin change is never committed without its audit entry (and vice versa).
+func (r *Repository) adminAction(actorID int, action, targetType, targetID string, details interface{}, fn func(tx *Tx) error) error {
+	return r.withTx(func(tx *Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return insertAudit(tx, actorID, action, targetType, targetID, details)
+	})
+}
+
+// RecordAdminAction writes a standalone audit entry. Used for actions whose
+// work happens outside a single transaction, such as data jobs.
+func (r *Repository) RecordAdminAction(actorID int, action, targetType, targetID string, details interface{}) error {
+	return r.withTx(func(tx *Tx) error {
+		return insertAudit(tx, actorID, action, targetType, targetID, details)
+	})
+}
+
+// ListAuditLog returns audit entries newest first, optionally filtered by
+// target type and/or actor. limit ≤ 0 means no cap.
+func (r *Repository) ListAuditLog(targetType string, actorID, limit, offset int) ([]AuditEntry, int, error) {
+	var whereParts []string
+	var args []interface{}
+	if targetType != "" {
+		whereParts = append(whereParts, "target_type = ?")
+		args = append(args, targetType)
+	}
+	if actorID > 0 {
+		whereParts = append(whereParts, "actor_user_id = ?")
+		args = append(args, actorID)
+	}
+	where := ""
+	if len(whereParts) > 0 {
+		where = "WHERE " + strings.Join(whereParts, " AND ")
+	}
+
+	var total int
+	if err := r.queryRow("SELECT COUNT(*) FROM admin_audit_log "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count audit log: %w", err)
+	}
+
+	pageArgs := append([]interface{}{}, args...)
+	limitClause := "LIMIT -1 OFFSET ?"
+	if limit > 0 {
+		limitClause = "LIMIT ? OFFSET ?"
+		pageArgs = append(pageArgs, limit)
+	}
+	pageArgs = append(pageArgs, offset)
+
+	rows, err := r.query(fmt.Sprintf(`
+		SELECT audit_id, actor_user_id, action, target_type, target_id, details, created_at
+		FROM admin_audit_log %s
+		ORDER BY audit_id DESC %s`, where, limitClause), pageArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list audit log: %w", err)
+	}
+	defer rows.Close()
+
+	out := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var actor sql.NullInt64
+		var targetID, details sql.NullString
+		if err := rows.Scan(&e.AuditID, &actor, &e.Action, &e.TargetType, &targetID, &details, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if actor.Valid {
+			v := int(actor.Int64)
+			e.ActorUserID = &v
+		}
+		e.TargetID = targetID.String
+		if details.Valid && details.String != "" {
+			e.Details = json.RawMessage(details.String)
+		}
+		out = append(out, e)
+	}
+	return out, total, rows.Err()
+}
+
+// ─── Users ───────────────────────────────────────────────────────────────────
+
+// AdminUserUpdate lists the user fields an admin may change.
+// nil fields are left untouched.
+type AdminUserUpdate struct {
+	Role        *Role   `json:"role"`
+	DisplayName *string `json:"display_name"`
+	Program     *string `json:"program"`
+	YearOfStudy *int    `json:"year_of_study"`
+}
+
+// ListUsers searches users by email or display name, optionally filtered by role.
+// limit ≤ 0 means no cap. Returns the page plus the total number of matches.
+func (r *Repository) ListUsers(q string, role Role, limit, offset int) ([]User, int, error) {
+	var whereParts []string
+	var args []interface{}
+	if q != "" {
+		pat := "%" + q + "%"
+		whereParts = append(whereParts, "(email LIKE ? OR display_name LIKE ?)")
+		args = append(args, pat, pat)
+	}
+	if role != "" {
+		whereParts = append(whereParts, "role = ?")
+		args = append(args, role)
+	}
+	where := ""
+	if len(whereParts) > 0 {
+		where = "WHERE " + strings.Join(whereParts, " AND ")
+	}
+
+	var total int
+	if err := r.queryRow("SELECT COUNT(*) FROM users "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count users: %w", err)
+	}
+
+	pageArgs := append([]interface{}{}, args...)
+	limitClause := "LIMIT -1 OFFSET ?"
+	if limit > 0 {
+		limitClause = "LIMIT ? OFFSET ?"
+		pageArgs = append(pageArgs, limit)
+	}
+	pageArgs = append(pageArgs, offset)
+
+	rows, err := r.query(fmt.Sprintf(`
+		SELECT user_id, email, display_name, program, year_of_study, role
+		FROM users %s
+		ORDER BY user_id %s`, where, limitClause), pageArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	out := []User{}
+	for rows.Next() {
+		var u User
+		var program sql.NullString
+		var year sql.NullInt64
+		if err := rows.Scan(&u.UserID, &u.Email, &u.DisplayName, &program, &year, &u.Role); err != nil {
+			return nil, 0, err
+		}
+		if program.Valid {
+			u.Program = &program.String
+		}
+		if year.Valid {
+			v := int(year.Int64)
+			u.YearOfStudy = &v
+		}
+		out = append(out, u)
+	}
+	return out, total, rows.Err()
+}
+
+// AdminUpdateUser applies upd to a user and records the change.
+// Returns ErrNotFound if the user does not exist.
+func (r *Repository) AdminUpdateUser(actorID, userID int, upd AdminUserUpdate) error {
+	var sets []string
+	var args []interface{}
+	if upd.Role != nil {
+		sets = append(sets, "role = ?")
+		args = append(args, *upd.Role)
+	}
+	if upd.DisplayName != nil {
+		sets = append(sets, "display_name = ?")
+		args = append(args, *upd.DisplayName)
+	}
+	if upd.Program != nil {
+		sets = append(sets, "program = ?")
+		args = append(args, *upd.Program)
+	}
+	if upd.YearOfStudy != nil {
+		sets = append(sets, "year_of_study = ?")
+		args = append(args, *upd.YearOfStudy)
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	args = append(args, userID)
+
+	return r.adminAction(actorID, "user.update", "user", strconv.Itoa(userID), upd, func(tx *Tx) error {
+		res, err := tx.exec("UPDATE users SET "+strings.Join(sets, ", ")+" WHERE user_id = ?", args...)
+		if err != nil {
+			return fmt.Errorf("update user: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// AdminDeleteUser removes a user account; plan terms, items and reviews
+// cascade via their foreign keys.
+func (r *Repository) AdminDeleteUser(actorID, userID int) error {
+	u, err := r.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return ErrNotFound
+	}
+	details := map[string]string{"email": u.Email, "display_name": u.DisplayName}
+	return r.adminAction(actorID, "user.delete", "user", strconv.Itoa(userID), details, func(tx *Tx) error {
+		_, err := tx.exec(`DELETE FROM users WHERE user_id = ?`, userID)
+		return err
+	})
+}
+
+// ─── Review moderation ───────────────────────────────────────────────────────
+
+// ReviewKind selects between the course_reviews and instructor_reviews tables.
+type ReviewKind string
+
+const (
+	CourseReview     ReviewKind = "course"
+	InstructorReview ReviewKind = "instructor"
+)
+
+// reviewTables maps a ReviewKind to its table. Only these names are ever
+// interpolated into SQL, so user input can never reach the query text.
+var reviewTables = map[ReviewKind]string{
+	CourseReview:     "course_reviews",
+	InstructorReview: "instructor_reviews",
+}
+
+// ModeratedReview is a review row as seen by the moderation queue.
+// Course reviews fill Subject/CourseNumber/Difficulty; instructor reviews fill InstructorID.
+type ModeratedReview struct {
+	ReviewID     int        `json:"review_id"`
+	Kind         ReviewKind `json:"kind"`
+	UserID       int        `json:"user_id"`
+	Subject      string     `json:"subject,omitempty"`
+	CourseNumber string     `json:"course_number,omitempty"`
+	InstructorID *int       `json:"instructor_id,omitempty"`
+	Rating       int        `json:"rating"`
+	Difficulty   *int       `json:"difficulty,omitempty"`
+	Text         *string    `json:"text"`
+	CreatedAt    string     `json:"created_at"`
+}
+
+// ListReviews returns reviews of the given kind, newest first.
+func (r *Repository) ListReviews(kind ReviewKind, limit, offset int) ([]ModeratedReview, int, error) {
+	table, ok := reviewTables[kind]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown review kind %q", kind)
+	}
+
+	var total int
+	if err := r.queryRow("SELECT COUNT(*) FROM " + table).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count reviews: %w", err)
+	}
+
+	cols := "review_id, user_id, subject, course_number, NULL, rating, difficulty, text, created_at"
+	if kind == InstructorReview {
+		cols = "review_id, user_id, '', '', instructor_id, rating, NULL, text, created_at"
+	}
+	args := []interface{}{}
+	limitClause := "LIMIT -1 OFFSET ?"
+	if limit > 0 {
+		limitClause = "LIMIT ? OFFSET ?"
+		args = append(args, limit)
+	}
+	args = append(args, offset)
+
+	rows, err := r.query(fmt.Sprintf(`SELECT %s FROM %s ORDER BY review_id DESC %s`, cols, table, limitClause), args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	out := []ModeratedReview{}
+	for rows.Next() {
+		rv := ModeratedReview{Kind: kind}
+		var instructorID, difficulty sql.NullInt64
+		var text sql.NullString
+		var createdAt interface{}
+		if err := rows.Scan(&rv.ReviewID, &rv.UserID, &rv.Subject, &rv.CourseNumber, &instructorID,
+			&rv.Rating, &difficulty, &text, &createdAt); err != nil {
+			return nil, 0, err
+		}
+		if instructorID.Valid {
+			v := int(instructorID.Int64)
+			rv.InstructorID = &v
+		}
+		if difficulty.Valid {
+			v := int(difficulty.Int64)
+			rv.Difficulty = &v
+		}
+		if text.Valid {
+			rv.Text = &text.String
+		}
+		// created_at is TEXT on SQLite and TIMESTAMPTZ on Postgres.
+		switch v := createdAt.(type) {
+		case time.Time:
+			rv.CreatedAt = v.UTC().Format(time.RFC3339)
+		case string:
+			rv.CreatedAt = v
+		case []byte:
+			rv.CreatedAt = string(v)
+		}
+		out = append(out, rv)
+	}
+	return out, total, rows.Err()
+}
+
+// AdminDeleteReview removes a review and records who removed it and why.
+func (r *Repository) AdminDeleteReview(actorID int, kind ReviewKind, reviewID int, reason string) error {
+	table, ok := reviewTables[kind]
+	if !ok {
+		return fmt.Errorf("unknown review kind %q", kind)
+	}
+	details := map[string]string{"kind": string(kind), "reason": reason}
+	return r.adminAction(actorID, "review.delete", table, strconv.Itoa(reviewID), details, func(tx *Tx) error {
+		res, err := tx.exec("DELETE FROM "+table+" WHERE review_id = ?", reviewID)
+		if err != nil {
+			return fmt.Errorf("delete review: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// ─── Requisites ──────────────────────────────────────────────────────────────
+
+// Requisite is a full requisites row including its primary key, used by the
+// admin editor (RequisiteRow omits the ID and target course).
+type Requisite struct {
+	ReqID           int     `json:"req_id"`
+	Subject         string  `json:"subject"`
+	CourseNumber    string  `json:"course_number"`
+	ReqSubject      string  `json:"req_subject"`
+	ReqCourseNumber string  `json:"req_course_number"`
+	Kind            string  `json:"kind"`
+	Note            *string `json:"note"`
+}
+
+// ListRequisiteRows returns every requisite row for a course, with IDs.
+func (r *Repository) ListRequisiteRows(subject, courseNumber string) ([]Requisite, error) {
+	rows, err := r.query(`
+		SELECT req_id, subject, course_number, req_subject, req_course_number, kind, note
+		FROM requisites
+		WHERE subject = ? AND course_number = ?
+		ORDER BY kind, req_subject, req_course_number`, subject, courseNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Requisite{}
+	for rows.Next() {
+		var rq Requisite
+		var note sql.NullString
+		if err := rows.Scan(&rq.ReqID, &rq.Subject, &rq.CourseNumber, &rq.ReqSubject,
+			&rq.ReqCourseNumber, &rq.Kind, &note); err != nil {
+			return nil, err
+		}
+		if note.Valid {
+			rq.Note = &note.String
+		}
+		out = append(out, rq)
+	}
+	return out, rows.Err()
+}
+
+// AdminCreateRequisite inserts a requisite row and returns its new ID.
+func (r *Repository) AdminCreateRequisite(actorID int, rq Requisite) (int, error) {
+	var id int64
+	err := r.withTx(func(tx *Tx) error {
+		var err error
+		id, err = tx.execReturningID(`
+			INSERT INTO requisites (subject, course_number, req_subject, req_course_number, kind, note)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			"req_id",
+			rq.Subject, rq.CourseNumber, rq.ReqSubject, rq.ReqCourseNumber, rq.Kind, rq.Note,
+		)
+		if err != nil {
+			return fmt.Errorf("insert requisite: %w", err)
+		}
+		rq.ReqID = int(id)
+		return insertAudit(tx, actorID, "requisite.create", "requisite", strconv.FormatInt(id, 10), rq)
+	})
+	return int(id), err
+}
+
+// AdminDeleteRequisite removes a requisite row by ID.
+func (r *Repository) AdminDeleteRequisite(actorID, reqID int) error {
+	return r.adminAction(actorID, "requisite.delete", "requisite", strconv.Itoa(reqID), nil, func(tx *Tx) error {
+		res, err := tx.exec(`DELETE FROM requisites WHERE req_id = ?`, reqID)
+		if err != nil {
+			return fmt.Errorf("delete requisite: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// ─── Requirement groups ──────────────────────────────────────────────────────
+
+// RequirementGroupUpdate lists the requirement_groups fields an admin may change.
+// nil fields are left untouched.
+type RequirementGroupUpdate struct {
+	Heading         *string `json:"heading"`
+	UnitsRequired   *int    `json:"units_required"`
+	CoursesRequired *int    `json:"courses_required"`
+	IsElective      *bool   `json:"is_elective"`
+	IsContainer     *bool   `json:"is_container"`
+}
+
+// AdminUpdateRequirementGroup applies upd to a requirement group.
+func (r *Repository) AdminUpdateRequirementGroup(actorID, groupID int, upd RequirementGroupUpdate) error {
+	var sets []string
+	var args []interface{}
+	if upd.Heading != nil {
+		sets = append(sets, "heading = ?")
+		args = append(args, *upd.Heading)
+	}
+	if upd.UnitsRequired != nil {
+		sets = append(sets, "units_required = ?")
+		args = append(args, *upd.UnitsRequired)
+	}
+	if upd.CoursesRequired != nil {
+		sets = append(sets, "courses_required = ?")
+		args = append(args, *upd.CoursesRequired)
+	}
+	if upd.IsElective != nil {
+		sets = append(sets, "is_elective = ?")
+		args = append(args, boolToInt(*upd.IsElective))
+	}
+	if upd.IsContainer != nil {
+		sets = append(sets, "is_container = ?")
+		args = append(args, boolToInt(*upd.IsContainer))
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+	args = append(args, groupID)
+
+	return r.adminAction(actorID, "requirement_group.update", "requirement_group", strconv.Itoa(groupID), upd, func(tx *Tx) error {
+		res, err := tx.exec("UPDATE requirement_groups SET "+strings.Join(sets, ", ")+" WHERE group_id = ?", args...)
+		if err != nil {
+			return fmt.Errorf("update requirement group: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// AdminAddRequirementCourse appends a course (or ad-hoc text row) to a group.
+// display_order is placed after the group's current last course.
+func (r *Repository) AdminAddRequirementCourse(actorID, groupID int, rc RequirementCourse) (int, error) {
+	var id int64
+	err := r.withTx(func(tx *Tx) error {
+		var exists int
+		if err := tx.queryRow(`SELECT COUNT(*) FROM requirement_groups WHERE group_id = ?`, groupID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrNotFound
+		}
+
+		var nextOrder int
+		if err := tx.queryRow(`SELECT COALESCE(MAX(display_order), 0) + 1 FROM requirement_courses WHERE group_id = ?`,
+			groupID).Scan(&nextOrder); err != nil {
+			return err
+		}
+
+		var err error
+		id, err = tx.execReturningID(`
+			INSERT INTO requirement_courses (group_id, display_order, coid, course_code, course_name, is_or_with_next, adhoc_text)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			"req_course_id",
+			groupID, nextOrder, rc.Coid, rc.CourseCode, rc.CourseName, boolToInt(rc.IsOrWithNext), rc.AdhocText,
+		)
+		if err != nil {
+			return fmt.Errorf("insert requirement course: %w", err)
+		}
+		rc.ReqCourseID = int(id)
+		rc.GroupID = groupID
+		rc.DisplayOrder = nextOrder
+		return insertAudit(tx, actorID, "requirement_course.create", "requirement_course", strconv.FormatInt(id, 10), rc)
+	})
+	return int(id), err
+}
+
+// AdminDeleteRequirementCourse removes a single course row from a group.
+func (r *Repository) AdminDeleteRequirementCourse(actorID, reqCourseID int) error {
+	return r.adminAction(actorID, "requirement_course.delete", "requirement_course", strconv.Itoa(reqCourseID), nil, func(tx *Tx) error {
+		res, err := tx.exec(`DELETE FROM requirement_courses WHERE req_course_id = ?`, reqCourseID)
+		if err != nil {
+			return fmt.Errorf("delete requirement course: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+// ─── Data jobs ───────────────────────────────────────────────────────────────
+
+// professorSplitRe splits the free-text courses.professor column into names.
+var professorSplitRe = regexp.MustCompile(`[,\n\r]+`)
+
+// LinkCourseInstructors rebuilds instructors and course_instructors from the
+// free-text courses.professor column. It is the in-process equivalent of
+// cmd/fillinstructors and is safe to re-run: existing links are left alone.
+// Returns the number of new course↔instructor links created.
+func (r *Repository) LinkCourseInstructors() (int, error) {
+	type courseProf struct {
+		id        int
+		professor string
+	}
+
+	linked := 0
+	err := r.withTx(func(tx *Tx) error {
+		rows, err := tx.query(`SELECT id, professor FROM courses WHERE professor IS NOT NULL AND professor != ''`)
+		if err != nil {
+			return fmt.Errorf("load courses: %w", err)
+		}
+		var courses []courseProf
+		for rows.Next() {
+			var c courseProf
+			if err := rows.Scan(&c.id, &c.professor); err != nil {
+				rows.Close()
+				return err
+			}
+			courses = append(courses, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, c := range courses {
+			seen := map[string]bool{}
+			for _, raw := range professorSplitRe.Split(c.professor, -1) {
+				name := strings.TrimSpace(raw)
+				normalized := strings.ToLower(name)
+				if name == "" || normalized == "staff" || normalized == "tba" || seen[normalized] {
+					continue
+				}
+				seen[normalized] = true
+
+				if _, err := tx.exec(`
+					INSERT INTO instructors (name, name_normalized) VALUES (?, ?)
+					ON CONFLICT DO NOTHING`, name, normalized); err != nil {
+					return fmt.Errorf("insert instructor %q: %w", name, err)
+				}
+				var instructorID int
+				if err := tx.queryRow(`SELECT instructor_id FROM instructors WHERE name_normalized = ?`,
+					normalized).Scan(&instructorID); err != nil {
+					return fmt.Errorf("lookup instructor %q: %w", name, err)
+				}
+				res, err := tx.exec(`
+					INSERT INTO course_instructors (course_row_id, instructor_id) VALUES (?, ?)
+					ON CONFLICT DO NOTHING`, c.id, instructorID)
+				if err != nil {
+					return fmt.Errorf("link instructor %q: %w", name, err)
+				}
+				if n, _ := res.RowsAffected(); n > 0 {
+					linked++
+				}
+			}
+		}
+		return nil
+	})
+	return linked, err
+}
+
+// PurgeExpiredResetTokens deletes password-reset tokens that have expired or
+// been used. Returns the number of rows removed.
+func (r *Repository) PurgeExpiredResetTokens() (int, error) {
+	res, err := r.exec(
+		`DELETE FROM password_reset_tokens WHERE expires_at < ? OR used_at IS NOT NULL`, time.Now(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// boolToInt converts a bool to the 0/1 integers the schema uses for flags.
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
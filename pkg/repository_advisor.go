@@ -0,0 +1,283 @@
+package pkg
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Advisor link statuses.
+const (
+	AdvisorPending  = "pending"
+	AdvisorActive   = "active"
+	AdvisorDeclined = "declined"
+	AdvisorEnded    = "ended"
+)
+
+// ErrAlreadyAdvising is returned when an advisor invites a student who is
+// already on their caseload.
+var ErrAlreadyAdvising = errors.New("student is already on this advisor's caseload")
+
+// ErrNotAStudent is returned when an advisor invites someone who isn't a
+// student account.
+var ErrNotAStudent = errors.New("user is not a student")
+
+// AdvisorLink is an advisor's access to one student's plan. The names and
+// emails are of both sides, for whichever side is looking.
+type AdvisorLink struct {
+	LinkID       int        `json:"link_id"`
+	AdvisorID    int        `json:"advisor_id"`
+	AdvisorName  string     `json:"advisor_name"`
+	AdvisorEmail string     `json:"advisor_email"`
+	StudentID    int        `json:"student_id"`
+	StudentName  string     `json:"student_name"`
+	StudentEmail string     `json:"student_email"`
+	ProgramID    *int       `json:"program_id"`
+	Status       string     `json:"status"`
+	InvitedAt    time.Time  `json:"invited_at"`
+	RespondedAt  *time.Time `json:"responded_at"`
+}
+
+const advisorLinkSelect = `
+	SELECT l.link_id, l.advisor_id, a.display_name, a.email, l.student_id, s.display_name, s.email,
+	       l.program_id, l.status, l.invited_at, l.responded_at
+	FROM advisor_students l
+	JOIN users a ON a.user_id = l.advisor_id
+	JOIN users s ON s.user_id = l.student_id`
+
+func scanAdvisorLink(row interface{ Scan(...interface{}) error }) (*AdvisorLink, error) {
+	var l AdvisorLink
+	var programID sql.NullInt64
+	var responded sql.NullTime
+	if err := row.Scan(&l.LinkID, &l.AdvisorID, &l.AdvisorName, &l.AdvisorEmail, &l.StudentID, &l.StudentName,
+		&l.StudentEmail, &programID, &l.Status, &l.InvitedAt, &responded); err != nil {
+		return nil, err
+	}
+	if programID.Valid {
+		id := int(programID.Int64)
+		l.ProgramID = &id
+	}
+	if responded.Valid {
+		l.RespondedAt = &responded.Time
+	}
+	return &l, nil
+}
+
+func (r *Repository) listAdvisorLinks(where string, args ...interface{}) ([]AdvisorLink, error) {
+	rows, err := r.query(advisorLinkSelect+` WHERE `+where+` ORDER BY l.link_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []AdvisorLink{}
+	for rows.Next() {
+		l, err := scanAdvisorLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *l)
+	}
+	return out, rows.Err()
+}
+
+// ListStudentAdvisors returns the student's pending invitations and current
+// advisors.
+func (r *Repository) ListStudentAdvisors(studentID int) ([]AdvisorLink, error) {
+	return r.listAdvisorLinks(`l.student_id = ? AND l.status IN ('pending', 'active')`, studentID)
+}
+
+// ListAdvisees returns the advisor's links with the given status.
+func (r *Repository) ListAdvisees(advisorID int, status string) ([]AdvisorLink, error) {
+	return r.listAdvisorLinks(`l.advisor_id = ? AND l.status = ?`, advisorID, status)
+}
+
+// IsAdvisorOf reports whether studentID has accepted advisorID as their
+// advisor (and the advisor still has the advisor role).
+func (r *Repository) IsAdvisorOf(advisorID, studentID int) (bool, error) {
+	var n int
+	err := r.queryRow(`
+		SELECT COUNT(*) FROM advisor_students l
+		JOIN users a ON a.user_id = l.advisor_id
+		WHERE l.advisor_id = ? AND l.student_id = ? AND l.status = 'active' AND a.role = ?`,
+		advisorID, studentID, RoleAdvisor).Scan(&n)
+	return n > 0, err
+}
+
+// InviteStudent invites the student with the given email onto the advisor's
+// caseload. Inviting again refreshes a pending invitation, or renews one
+// the student declined or that was ended. Returns ErrNotFound if there's no
+// such user, ErrNotAStudent if they aren't a student, and ErrAlreadyAdvising
+// if the link is already active.
+func (r *Repository) InviteStudent(advisorID int, email string, programID *int) (*AdvisorLink, error) {
+	student, err := r.GetUserByEmail(strings.TrimSpace(strings.ToLower(email)))
+	if err != nil {
+		return nil, err
+	}
+	if student == nil {
+		return nil, ErrNotFound
+	}
+	if student.Role != RoleStudent {
+		return nil, ErrNotAStudent
+	}
+
+	now := time.Now().UTC()
+	var linkID int64
+	err = r.withTx(func(tx *Tx) error {
+		var status string
+		err := tx.queryRow(`SELECT link_id, status FROM advisor_students WHERE advisor_id = ? AND student_id = ?`,
+			advisorID, student.UserID).Scan(&linkID, &status)
+		switch {
+		case err == sql.ErrNoRows:
+			linkID, err = tx.execReturningID(`
+				INSERT INTO advisor_students (advisor_id, student_id, program_id, status, invited_at)
+				VALUES (?, ?, ?, 'pending', ?)`,
+				"link_id", advisorID, student.UserID, programID, now)
+			return err
+		case err != nil:
+			return err
+		case status == AdvisorActive:
+			return ErrAlreadyAdvising
+		}
+		_, err = tx.exec(`UPDATE advisor_students
+			SET program_id = ?, status = 'pending', invited_at = ?, responded_at = NULL
+			WHERE link_id = ?`, programID, now, linkID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return scanAdvisorLink(r.queryRow(advisorLinkSelect+` WHERE l.link_id = ?`, linkID))
+}
+
+// RespondToAdvisor accepts or declines the advisor's pending invitation.
+// Returns ErrNotFound if there is none.
+func (r *Repository) RespondToAdvisor(studentID, advisorID int, accept bool) error {
+	status := AdvisorDeclined
+	if accept {
+		status = AdvisorActive
+	}
+	return r.setAdvisorLinkStatus(advisorID, studentID, status, AdvisorPending)
+}
+
+// EndAdvisorLink removes the student from the advisor's caseload, or
+// withdraws a pending invitation. Either side may end it. Returns
+// ErrNotFound if there's nothing to end.
+func (r *Repository) EndAdvisorLink(advisorID, studentID int) error {
+	return r.setAdvisorLinkStatus(advisorID, studentID, AdvisorEnded, AdvisorPending, AdvisorActive)
+}
+
+func (r *Repository) setAdvisorLinkStatus(advisorID, studentID int, to string, from ...string) error {
+	args := []interface{}{to, time.Now().UTC(), advisorID, studentID}
+	for _, f := range from {
+		args = append(args, f)
+	}
+	res, err := r.exec(`UPDATE advisor_students SET status = ?, responded_at = ?
+		WHERE advisor_id = ? AND student_id = ? AND status IN (?`+strings.Repeat(", ?", len(from)-1)+`)`, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PlanItemComment is an advisor's comment on one of a student's plan items.
+type PlanItemComment struct {
+	CommentID  int       `json:"comment_id"`
+	PlanItemID int       `json:"plan_item_id"`
+	AuthorID   *int      `json:"author_id"`
+	AuthorName *string   `json:"author_name"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// planItemOwned reports whether the plan item belongs to userID.
+func (r *Repository) planItemOwned(userID, planItemID int) (bool, error) {
+	var n int
+	err := r.queryRow(`
+		SELECT COUNT(*) FROM plan_items pi
+		JOIN plan_terms pt ON pt.plan_term_id = pi.plan_term_id
+		WHERE pi.plan_item_id = ? AND pt.user_id = ?`, planItemID, userID).Scan(&n)
+	return n > 0, err
+}
+
+// ListPlanItemComments returns the comments on one of userID's plan items,
+// oldest first. Returns ErrNotFound if the item isn't theirs.
+func (r *Repository) ListPlanItemComments(userID, planItemID int) ([]PlanItemComment, error) {
+	owned, err := r.planItemOwned(userID, planItemID)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, ErrNotFound
+	}
+	rows, err := r.query(`
+		SELECT c.comment_id, c.plan_item_id, c.author_id, u.display_name, c.body, c.created_at
+		FROM plan_item_comments c
+		LEFT JOIN users u ON u.user_id = c.author_id
+		WHERE c.plan_item_id = ?
+		ORDER BY c.comment_id`, planItemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PlanItemComment{}
+	for rows.Next() {
+		var c PlanItemComment
+		var authorID sql.NullInt64
+		var authorName sql.NullString
+		if err := rows.Scan(&c.CommentID, &c.PlanItemID, &authorID, &authorName, &c.Body, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		if authorID.Valid {
+			id := int(authorID.Int64)
+			c.AuthorID = &id
+		}
+		if authorName.Valid {
+			c.AuthorName = &authorName.String
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// AddPlanItemComment stores a comment by authorID on one of userID's plan
+// items. Returns ErrNotFound if the item isn't theirs.
+func (r *Repository) AddPlanItemComment(userID, planItemID, authorID int, body string) (*PlanItemComment, error) {
+	owned, err := r.planItemOwned(userID, planItemID)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, ErrNotFound
+	}
+	c := PlanItemComment{PlanItemID: planItemID, AuthorID: &authorID, Body: body, CreatedAt: time.Now().UTC()}
+	id, err := r.execReturningID(`
+		INSERT INTO plan_item_comments (plan_item_id, author_id, body, created_at) VALUES (?, ?, ?, ?)`,
+		"comment_id", planItemID, authorID, body, c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert comment: %w", err)
+	}
+	c.CommentID = int(id)
+	if u, err := r.GetUserByID(authorID); err == nil && u != nil {
+		c.AuthorName = &u.DisplayName
+	}
+	return &c, nil
+}
+
+// DeletePlanItemComment removes authorID's comment from the plan item.
+// Returns ErrNotFound if there's no such comment by them.
+func (r *Repository) DeletePlanItemComment(planItemID, commentID, authorID int) error {
+	res, err := r.exec(`DELETE FROM plan_item_comments WHERE comment_id = ? AND plan_item_id = ? AND author_id = ?`,
+		commentID, planItemID, authorID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// GetCatalogCourse fetches a catalogue course by subject and number.
+// Returns (nil, nil) if there is no such course.
+func (r *Repository) GetCatalogCourse(subject, courseNumber string) (*CatalogCourse, error) {
+	var c CatalogCourse
+	var courseName, unitsSource sql.NullString
+	var coid, units sql.NullInt64
+	err := r.queryRow(`
+		SELECT catalog_id, subject, course_number, course_name, coid, units, units_source
+		FROM catalog_courses
+		WHERE subject = ? AND course_number = ?`, subject, courseNumber).Scan(
+		&c.CatalogID, &c.Subject, &c.CourseNumber, &courseName, &coid, &units, &unitsSource)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.CourseName = courseName.String
+	if coid.Valid {
+		n := int(coid.Int64)
+		c.Coid = &n
+	}
+	c.Units = unitsOr(units, c.CourseNumber)
+	if unitsSource.Valid {
+		c.UnitsSource = &unitsSource.String
+	}
+	return &c, nil
+}
+
+// AdminSetCourseUnits records units as the curated unit value of a
+// catalogue course, which a later scrape won't overwrite. A nil units
+// clears the value, so the course number guess applies until the next
+// scrape. Returns ErrNotFound if there is no such course.
+func (r *Repository) AdminSetCourseUnits(actorID int, subject, courseNumber string, units *int) (*CatalogCourse, error) {
+	var source *string
+	if units != nil {
+		s := UnitsCurated
+		source = &s
+	}
+	details := map[string]interface{}{"units": units}
+	err := r.adminAction(actorID, "catalog_course.units", "catalog_course", subject+" "+courseNumber, details, func(tx *Tx) error {
+		res, err := tx.exec(`UPDATE catalog_courses SET units = ?, units_source = ? WHERE subject = ? AND course_number = ?`,
+			units, source, subject, courseNumber)
+		if err != nil {
+			return fmt.Errorf("set course units: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetCatalogCourse(subject, courseNumber)
+}
+
+// GetCourseOfferings lists every offering of a catalogue course with the
+// instructors linked to each, in calendar order of term (terms missing from
+// academic_terms last, by code) then by section.
+func (r *Repository) GetCourseOfferings(catalogID int) ([]CourseOffering, error) {
+	rows, err := r.query(`
+		SELECT c.id, c.term, c.section, c.professor
+		FROM courses c
+		LEFT JOIN academic_terms t ON t.code = c.term
+		WHERE c.catalog_id = ?
+		ORDER BY t.ordinal IS NULL, t.ordinal, c.term, COALESCE(c.section, ''), c.id`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []CourseOffering{}
+	index := map[int]int{}
+	for rows.Next() {
+		var o CourseOffering
+		var section, professor sql.NullString
+		if err := rows.Scan(&o.ID, &o.Term, &section, &professor); err != nil {
+			return nil, err
+		}
+		if section.Valid {
+			o.Section = &section.String
+		}
+		o.Professor = professor.String
+		o.Instructors = []Instructor{}
+		index[o.ID] = len(out)
+		out = append(out, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// One query for all offerings' instructors rather than one per offering.
+	rows, err = r.query(`
+		SELECT ci.course_row_id, i.instructor_id, i.name, i.department,
+		       i.ext_avg_rating, i.ext_avg_difficulty, i.ext_num_ratings
+		FROM course_instructors ci
+		JOIN courses c ON c.id = ci.course_row_id
+		JOIN instructors i ON i.instructor_id = ci.instructor_id
+		WHERE c.catalog_id = ?
+		ORDER BY i.name`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var courseRowID int
+		var i Instructor
+		var dept sql.NullString
+		var avgRating, avgDiff sql.NullFloat64
+		var numRatings sql.NullInt64
+		if err := rows.Scan(&courseRowID, &i.ID, &i.Name, &dept, &avgRating, &avgDiff, &numRatings); err != nil {
+			return nil, err
+		}
+		i.Department = dept.String
+		if avgRating.Valid {
+			i.AvgRating = &avgRating.Float64
+		}
+		if avgDiff.Valid {
+			i.AvgDifficulty = &avgDiff.Float64
+		}
+		if numRatings.Valid {
+			n := int(numRatings.Int64)
+			i.NumRatings = &n
+		}
+		if k, ok := index[courseRowID]; ok {
+			out[k].Instructors = append(out[k].Instructors, i)
+		}
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,225 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Outbound email statuses. See migrations/016_outbound_emails.sql.
+const (
+	EmailPending = "pending"
+	EmailSending = "sending"
+	EmailSent    = "sent"
+	EmailDead    = "dead"
+)
+
+// OutboundEmail is a row of the outbound_emails queue.
+type OutboundEmail struct {
+	EmailID       int        `json:"email_id"`
+	Message                  // to, reply_to, subject, text, html
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     *string    `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at"`
+}
+
+// EnqueueEmail adds msg to the outbound queue, due immediately. A message
+// with a malformed address is refused here rather than retried until it
+// fails for good.
+func (r *Repository) EnqueueEmail(msg Message) (int, error) {
+	if _, _, err := msg.addresses(); err != nil {
+		return 0, fmt.Errorf("enqueue email: %w", err)
+	}
+	id, err := r.execReturningID(
+		`INSERT INTO outbound_emails (to_address, reply_to, subject, text_body, html_body, next_attempt_at)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		"email_id",
+		msg.To, nullIfEmpty(msg.ReplyTo), msg.Subject, msg.Text, nullIfEmpty(msg.HTML), time.Now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue email: %w", err)
+	}
+	return int(id), nil
+}
+
+// ClaimDueEmails marks up to limit due messages as sending and returns them.
+// A message is due when it is pending and its next_attempt_at has passed, or
+// when it is sending but its lease has expired (the worker that claimed it
+// died). Each row is claimed with a conditional UPDATE so two workers never
+// both deliver the same message.
+func (r *Repository) ClaimDueEmails(limit int, lease time.Duration) ([]OutboundEmail, error) {
+	now := time.Now()
+	rows, err := r.query(`
+		SELECT email_id FROM outbound_emails
+		WHERE status IN (?, ?) AND next_attempt_at <= ?
+		ORDER BY next_attempt_at, email_id
+		LIMIT ?`, EmailPending, EmailSending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("find due emails: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := []OutboundEmail{}
+	for _, id := range ids {
+		res, err := r.exec(`
+			UPDATE outbound_emails SET status = ?, next_attempt_at = ?
+			WHERE email_id = ? AND status IN (?, ?) AND next_attempt_at <= ?`,
+			EmailSending, now.Add(lease), id, EmailPending, EmailSending, now)
+		if err != nil {
+			return out, fmt.Errorf("claim email %d: %w", id, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // another worker got it
+		}
+		e, err := r.GetOutboundEmail(id)
+		if err != nil {
+			return out, err
+		}
+		if e != nil {
+			out = append(out, *e)
+		}
+	}
+	return out, nil
+}
+
+// MarkEmailSent records a successful delivery.
+func (r *Repository) MarkEmailSent(id int) error {
+	_, err := r.exec(
+		`UPDATE outbound_emails SET status = ?, attempts = attempts + 1, sent_at = ?, last_error = NULL WHERE email_id = ?`,
+		EmailSent, time.Now(), id,
+	)
+	return err
+}
+
+// MarkEmailFailed records a failed attempt. The message is retried at
+// retryAt, or moved to the dead state when retryAt is nil.
+func (r *Repository) MarkEmailFailed(id int, sendErr error, retryAt *time.Time) error {
+	status, next := EmailDead, time.Now()
+	if retryAt != nil {
+		status, next = EmailPending, *retryAt
+	}
+	_, err := r.exec(
+		`UPDATE outbound_emails SET status = ?, attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE email_id = ?`,
+		status, next, sendErr.Error(), id,
+	)
+	return err
+}
+
+// GetOutboundEmail returns one queued message, or (nil, nil) if it doesn't exist.
+func (r *Repository) GetOutboundEmail(id int) (*OutboundEmail, error) {
+	rows, err := r.query(outboundEmailSelect+` WHERE email_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	list, err := scanOutboundEmails(rows)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// ListOutboundEmails returns queued messages newest first. status filters to
+// one status; the special value "stuck" means anything not yet delivered that
+// has already failed at least once (retrying or dead). limit ≤ 0 means no cap.
+func (r *Repository) ListOutboundEmails(status string, limit, offset int) ([]OutboundEmail, int, error) {
+	where := ""
+	var args []interface{}
+	switch status {
+	case "":
+	case "stuck":
+		where = " WHERE status <> ? AND attempts > 0"
+		args = append(args, EmailSent)
+	default:
+		where = " WHERE status = ?"
+		args = append(args, status)
+	}
+
+	var total int
+	if err := r.queryRow("SELECT COUNT(*) FROM outbound_emails"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count outbound emails: %w", err)
+	}
+
+	pageArgs := append([]interface{}{}, args...)
+	limitClause := " LIMIT -1 OFFSET ?"
+	if limit > 0 {
+		limitClause = " LIMIT ? OFFSET ?"
+		pageArgs = append(pageArgs, limit)
+	}
+	pageArgs = append(pageArgs, offset)
+
+	rows, err := r.query(outboundEmailSelect+where+" ORDER BY email_id DESC"+limitClause, pageArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list outbound emails: %w", err)
+	}
+	list, err := scanOutboundEmails(rows)
+	return list, total, err
+}
+
+// AdminRetryEmail puts an undelivered message back in the queue, due now, with
+// its attempt counter reset. Sent messages are left alone (ErrNotFound).
+func (r *Repository) AdminRetryEmail(actorID, id int) error {
+	return r.adminAction(actorID, "email.retry", "outbound_email", fmt.Sprint(id), nil, func(tx *Tx) error {
+		res, err := tx.exec(
+			`UPDATE outbound_emails SET status = ?, attempts = 0, next_attempt_at = ? WHERE email_id = ? AND status <> ?`,
+			EmailPending, time.Now(), id, EmailSent,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+const outboundEmailSelect = `
+	SELECT email_id, to_address, reply_to, subject, text_body, html_body,
+	       status, attempts, next_attempt_at, last_error, created_at, sent_at
+	FROM outbound_emails`
+
+func scanOutboundEmails(rows *sql.Rows) ([]OutboundEmail, error) {
+	defer rows.Close()
+	out := []OutboundEmail{}
+	for rows.Next() {
+		var e OutboundEmail
+		var replyTo, html, lastErr sql.NullString
+		var sentAt sql.NullTime
+		if err := rows.Scan(&e.EmailID, &e.To, &replyTo, &e.Subject, &e.Text, &html,
+			&e.Status, &e.Attempts, &e.NextAttemptAt, &lastErr, &e.CreatedAt, &sentAt); err != nil {
+			return nil, err
+		}
+		e.ReplyTo, e.HTML = replyTo.String, html.String
+		if lastErr.Valid {
+			e.LastError = &lastErr.String
+		}
+		if sentAt.Valid {
+			e.SentAt = &sentAt.Time
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// nullIfEmpty maps "" to SQL NULL for optional text columns.
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,271 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Feedback statuses.
+const (
+	FeedbackOpen     = "open"
+	FeedbackResolved = "resolved"
+)
+
+// Feedback is one submission to POST /api/feedback.
+type Feedback struct {
+	FeedbackID int        `json:"feedback_id"`
+	Message    string     `json:"message"`
+	Page       *string    `json:"page"`
+	Email      *string    `json:"email"`
+	UserID     *int       `json:"user_id"`
+	UserAgent  *string    `json:"user_agent"`
+	Status     string     `json:"status"`
+	Tags       []string   `json:"tags"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	ResolvedBy *int       `json:"resolved_by"`
+}
+
+// CreateFeedback stores a new open submission and returns its ID.
+func (r *Repository) CreateFeedback(f Feedback) (int, error) {
+	id, err := r.execReturningID(
+		`INSERT INTO feedback (message, page, email, user_id, user_agent, status, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		"feedback_id",
+		f.Message, f.Page, f.Email, f.UserID, f.UserAgent, FeedbackOpen, time.Now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("create feedback: %w", err)
+	}
+	return int(id), nil
+}
+
+// GetFeedback returns one submission with its tags, or (nil, nil).
+func (r *Repository) GetFeedback(id int) (*Feedback, error) {
+	list, _, err := r.listFeedback("WHERE f.feedback_id = ?", []interface{}{id}, 1, 0)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+// FeedbackFilter narrows ListFeedback. Empty fields don't filter.
+type FeedbackFilter struct {
+	Status string
+	Tag    string
+	Page   string
+	Q      string // substring of the message or email
+}
+
+// ListFeedback returns submissions newest first. limit ≤ 0 means no cap.
+func (r *Repository) ListFeedback(filter FeedbackFilter, limit, offset int) ([]Feedback, int, error) {
+	var whereParts []string
+	var args []interface{}
+	if filter.Status != "" {
+		whereParts = append(whereParts, "f.status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Tag != "" {
+		whereParts = append(whereParts, "EXISTS (SELECT 1 FROM feedback_tags t WHERE t.feedback_id = f.feedback_id AND t.tag = ?)")
+		args = append(args, normalizeFeedbackTag(filter.Tag))
+	}
+	if filter.Page != "" {
+		whereParts = append(whereParts, "f.page = ?")
+		args = append(args, filter.Page)
+	}
+	if filter.Q != "" {
+		whereParts = append(whereParts, "(f.message LIKE ? OR f.email LIKE ?)")
+		args = append(args, "%"+filter.Q+"%", "%"+filter.Q+"%")
+	}
+	where := ""
+	if len(whereParts) > 0 {
+		where = "WHERE " + strings.Join(whereParts, " AND ")
+	}
+	return r.listFeedback(where, args, limit, offset)
+}
+
+func (r *Repository) listFeedback(where string, args []interface{}, limit, offset int) ([]Feedback, int, error) {
+	var total int
+	if err := r.queryRow("SELECT COUNT(*) FROM feedback f "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count feedback: %w", err)
+	}
+
+	pageArgs := append([]interface{}{}, args...)
+	limitClause := "LIMIT -1 OFFSET ?"
+	if limit > 0 {
+		limitClause = "LIMIT ? OFFSET ?"
+		pageArgs = append(pageArgs, limit)
+	}
+	pageArgs = append(pageArgs, offset)
+
+	rows, err := r.query(fmt.Sprintf(`
+		SELECT f.feedback_id, f.message, f.page, f.email, f.user_id, f.user_agent,
+		       f.status, f.created_at, f.resolved_at, f.resolved_by
+		FROM feedback f %s
+		ORDER BY f.feedback_id DESC %s`, where, limitClause), pageArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list feedback: %w", err)
+	}
+	defer rows.Close()
+
+	out := []Feedback{}
+	byID := map[int]int{}
+	for rows.Next() {
+		var f Feedback
+		var page, email, ua sql.NullString
+		var userID, resolvedBy sql.NullInt64
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&f.FeedbackID, &f.Message, &page, &email, &userID, &ua,
+			&f.Status, &f.CreatedAt, &resolvedAt, &resolvedBy); err != nil {
+			return nil, 0, err
+		}
+		f.Page, f.Email, f.UserAgent = nullStringPtr(page), nullStringPtr(email), nullStringPtr(ua)
+		if userID.Valid {
+			v := int(userID.Int64)
+			f.UserID = &v
+		}
+		if resolvedBy.Valid {
+			v := int(resolvedBy.Int64)
+			f.ResolvedBy = &v
+		}
+		if resolvedAt.Valid {
+			f.ResolvedAt = &resolvedAt.Time
+		}
+		f.Tags = []string{}
+		byID[f.FeedbackID] = len(out)
+		out = append(out, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	if len(out) == 0 {
+		return out, total, nil
+	}
+	placeholders := make([]string, 0, len(out))
+	idArgs := make([]interface{}, 0, len(out))
+	for _, f := range out {
+		placeholders = append(placeholders, "?")
+		idArgs = append(idArgs, f.FeedbackID)
+	}
+	tagRows, err := r.query(`SELECT feedback_id, tag FROM feedback_tags WHERE feedback_id IN (`+
+		strings.Join(placeholders, ",")+`) ORDER BY tag`, idArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list feedback tags: %w", err)
+	}
+	defer tagRows.Close()
+	for tagRows.Next() {
+		var id int
+		var tag string
+		if err := tagRows.Scan(&id, &tag); err != nil {
+			return nil, 0, err
+		}
+		out[byID[id]].Tags = append(out[byID[id]].Tags, tag)
+	}
+	return out, total, tagRows.Err()
+}
+
+// FeedbackUpdate is the body of PATCH /api/admin/feedback/{id}. Tags, when
+// present, replaces the whole tag set.
+type FeedbackUpdate struct {
+	Status *string   `json:"status"`
+	Tags   *[]string `json:"tags"`
+}
+
+// AdminUpdateFeedback changes a submission's status and/or tags. Resolving
+// stamps resolved_at/resolved_by; reopening clears them.
+func (r *Repository) AdminUpdateFeedback(actorID, id int, upd FeedbackUpdate) error {
+	if upd.Tags != nil {
+		tags := normalizeFeedbackTags(*upd.Tags)
+		upd.Tags = &tags
+	}
+	return r.adminAction(actorID, "feedback.update", "feedback", strconv.Itoa(id), upd, func(tx *Tx) error {
+		var exists int
+		if err := tx.queryRow(`SELECT COUNT(*) FROM feedback WHERE feedback_id = ?`, id).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrNotFound
+		}
+
+		if upd.Status != nil {
+			var err error
+			if *upd.Status == FeedbackResolved {
+				_, err = tx.exec(`UPDATE feedback SET status = ?, resolved_at = ?, resolved_by = ? WHERE feedback_id = ?`,
+					FeedbackResolved, time.Now(), actorID, id)
+			} else {
+				_, err = tx.exec(`UPDATE feedback SET status = ?, resolved_at = NULL, resolved_by = NULL WHERE feedback_id = ?`,
+					*upd.Status, id)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if upd.Tags != nil {
+			if _, err := tx.exec(`DELETE FROM feedback_tags WHERE feedback_id = ?`, id); err != nil {
+				return err
+			}
+			for _, tag := range *upd.Tags {
+				if _, err := tx.exec(`INSERT INTO feedback_tags (feedback_id, tag) VALUES (?, ?)`, id, tag); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ListFeedbackTags returns every tag in use with how many submissions carry it.
+func (r *Repository) ListFeedbackTags() (map[string]int, error) {
+	rows, err := r.query(`SELECT tag, COUNT(*) FROM feedback_tags GROUP BY tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]int{}
+	for rows.Next() {
+		var tag string
+		var n int
+		if err := rows.Scan(&tag, &n); err != nil {
+			return nil, err
+		}
+		out[tag] = n
+	}
+	return out, rows.Err()
+}
+
+// normalizeFeedbackTag lowercases and trims a tag and turns inner whitespace
+// into dashes, so "UI Bug" and "ui-bug" are the same tag.
+func normalizeFeedbackTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+}
+
+// normalizeFeedbackTags normalises, dedupes and sorts tags, dropping empties.
+func normalizeFeedbackTags(tags []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, t := range tags {
+		t = normalizeFeedbackTag(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// nullStringPtr converts a nullable column to *string.
+func nullStringPtr(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
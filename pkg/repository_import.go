@@ -0,0 +1,219 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// What an import will do with each row.
+const (
+	ImportAdd    = "add"
+	ImportUpdate = "update" // same course already in that term; status/grade replaced
+	ImportSkip   = "skip"   // unreadable, invalid, or already in the plan as-is
+)
+
+// ImportRow is an imported course placed in the user's plan.
+type ImportRow struct {
+	ImportedCourse
+	Action string `json:"action"`
+	// Unknown marks a course missing from the catalogue; it's imported as
+	// a custom item.
+	Unknown bool `json:"unknown,omitempty"`
+	// Conflict describes how the row clashes with the existing plan.
+	Conflict       string `json:"conflict,omitempty"`
+	ExistingItemID int    `json:"existing_item_id,omitempty"`
+}
+
+// ImportPreview is what an import would do (or, once Committed, did).
+type ImportPreview struct {
+	Format    string `json:"format"`
+	StartYear int    `json:"start_year"`
+	// SetsStartYear is true when the user had no start year and the import
+	// takes it from the earliest transcript term.
+	SetsStartYear bool        `json:"sets_start_year"`
+	Rows          []ImportRow `json:"rows"`
+	Added         int         `json:"added"`
+	Updated       int         `json:"updated"`
+	Skipped       int         `json:"skipped"`
+	Unknown       int         `json:"unknown"`
+	Conflicts     int         `json:"conflicts"`
+	Committed     bool        `json:"committed"`
+}
+
+// PreviewPlanImport places imported courses in u's plan without changing
+// anything. Transcript terms are mapped to plan years from u's start year,
+// or from the earliest term in the import if u hasn't set one.
+func (r *Repository) PreviewPlanImport(u *User, format string, courses []ImportedCourse, at time.Time) (*ImportPreview, error) {
+	p := &ImportPreview{Format: format, Rows: []ImportRow{}}
+	switch {
+	case u.StartYear != nil:
+		p.StartYear = *u.StartYear
+	default:
+		for _, c := range courses {
+			if t, ok := ParseTermCode(c.Term); ok && c.Error == "" && (p.StartYear == 0 || t.AcademicYear() < p.StartYear) {
+				p.StartYear = t.AcademicYear()
+			}
+		}
+		p.SetsStartYear = p.StartYear != 0
+		if !p.SetsStartYear {
+			y, err := r.UserStartYear(u, at)
+			if err != nil {
+				return nil, err
+			}
+			p.StartYear = y
+		}
+	}
+
+	plan, err := r.GetUserPlan(u.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("load plan: %w", err)
+	}
+	inPlan := map[string][]UserPlanItem{}
+	for _, pi := range plan {
+		code := pi.Subject + " " + pi.CourseNumber
+		inPlan[code] = append(inPlan[code], pi)
+	}
+	seen := map[string]int{} // bucket + code → line
+
+	for _, c := range courses {
+		row := ImportRow{ImportedCourse: c, Action: ImportSkip}
+		if t, ok := ParseTermCode(c.Term); ok {
+			row.YearIndex = t.AcademicYear() - p.StartYear + 1
+			row.Season = t.Season
+		}
+		if row.Error == "" {
+			if err := r.placeImportRow(&row, inPlan, seen); err != nil {
+				return nil, err
+			}
+		}
+
+		switch row.Action {
+		case ImportAdd:
+			p.Added++
+		case ImportUpdate:
+			p.Updated++
+		default:
+			p.Skipped++
+		}
+		if row.Unknown {
+			p.Unknown++
+		}
+		if row.Conflict != "" {
+			p.Conflicts++
+		}
+		p.Rows = append(p.Rows, row)
+	}
+	return p, nil
+}
+
+// placeImportRow checks one readable row and decides its action.
+func (r *Repository) placeImportRow(row *ImportRow, inPlan map[string][]UserPlanItem, seen map[string]int) error {
+	s := PlanItemState{
+		YearIndex: row.YearIndex, Season: row.Season,
+		Subject: row.Subject, CourseNumber: row.CourseNumber,
+		Status: row.Status, Grade: row.Grade, Note: row.Note, IsCustom: row.IsCustom,
+	}
+	if !s.IsCustom && s.Subject != "" && s.CourseNumber != "" {
+		inCatalog, _, err := catalogLookup(r, strings.ToUpper(s.Subject), strings.ToUpper(s.CourseNumber))
+		if err != nil {
+			return err
+		}
+		row.Unknown = !inCatalog
+		s.IsCustom = !inCatalog
+	}
+	fields, err := checkPlanItem(r, &s)
+	if err != nil {
+		return err
+	}
+	row.YearIndex, row.Season = s.YearIndex, s.Season
+	row.Subject, row.CourseNumber, row.IsCustom = s.Subject, s.CourseNumber, s.IsCustom
+	if len(fields) > 0 {
+		row.Error = (&PlanItemError{Fields: fields}).Error()
+		return nil
+	}
+
+	code := s.Subject + " " + s.CourseNumber
+	bucket := fmt.Sprintf("%d %s %s", s.YearIndex, s.Season, code)
+	if line, dup := seen[bucket]; dup {
+		row.Error = fmt.Sprintf("same course and term as line %d", line)
+		return nil
+	}
+	seen[bucket] = row.Line
+
+	row.Action = ImportAdd
+	for _, pi := range inPlan[code] {
+		if pi.YearIndex != s.YearIndex || pi.Season != s.Season {
+			row.Conflict = fmt.Sprintf("also in plan in year %d %s as %s", pi.YearIndex, pi.Season, pi.Status)
+			continue
+		}
+		row.ExistingItemID = pi.PlanItemID
+		if pi.Status == s.Status && strPtrEqual(pi.Grade, s.Grade) {
+			row.Action, row.Conflict = ImportSkip, ""
+			return nil
+		}
+		row.Action = ImportUpdate
+		row.Conflict = fmt.Sprintf("already in plan as %s", pi.Status)
+		if pi.Grade != nil {
+			row.Conflict += " (" + *pi.Grade + ")"
+		}
+		return nil
+	}
+	return nil
+}
+
+// CommitPlanImport applies an import to u's plan in one transaction,
+// recording each change in the plan history, and returns the preview it
+// applied. Rows the preview skips are left out.
+func (r *Repository) CommitPlanImport(actorID int, u *User, format string, courses []ImportedCourse, at time.Time) (*ImportPreview, error) {
+	p, err := r.PreviewPlanImport(u, format, courses, at)
+	if err != nil {
+		return nil, err
+	}
+	ch := planChange{actorID: actorID, source: PlanSourceImport}
+	err = r.withTx(func(tx *Tx) error {
+		for _, row := range p.Rows {
+			switch row.Action {
+			case ImportAdd:
+				_, err := insertPlanItem(tx, ch, PlanItemState{
+					UserID: u.UserID, YearIndex: row.YearIndex, Season: row.Season,
+					Subject: row.Subject, CourseNumber: row.CourseNumber,
+					Status: row.Status, Grade: row.Grade, Note: row.Note, IsCustom: row.IsCustom,
+				})
+				if isUniqueViolation(err) {
+					return ErrPlanConflict
+				}
+				if err != nil {
+					return fmt.Errorf("import line %d: %w", row.Line, err)
+				}
+			case ImportUpdate:
+				before, err := getPlanItemState(tx, row.ExistingItemID)
+				if err != nil {
+					return err
+				}
+				if before == nil {
+					return ErrPlanConflict
+				}
+				after := *before
+				after.Status, after.Grade = row.Status, row.Grade
+				if row.Note != nil {
+					after.Note = row.Note
+				}
+				if err := updatePlanItem(tx, ch, *before, after); err != nil {
+					return fmt.Errorf("import line %d: %w", row.Line, err)
+				}
+			}
+		}
+		if p.SetsStartYear {
+			if _, err := tx.exec(`UPDATE users SET start_year = ? WHERE user_id = ?`, p.StartYear, u.UserID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.Committed = true
+	return p, nil
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"database/sql"
+	"time"
+)
+
+// OIDCLoginState is an in-flight SSO login, keyed by the OAuth state value.
+type OIDCLoginState struct {
+	State        string
+	CodeVerifier string
+	Nonce        string
+}
+
+// CreateOIDCLoginState stores the state, nonce and PKCE verifier generated at
+// /api/auth/oidc/start so the callback can complete the exchange.
+func (r *Repository) CreateOIDCLoginState(s OIDCLoginState, expiresAt time.Time) error {
+	_, err := r.exec(
+		`INSERT INTO oidc_login_states (state, code_verifier, nonce, expires_at) VALUES (?, ?, ?, ?)`,
+		s.State, s.CodeVerifier, s.Nonce, expiresAt,
+	)
+	return err
+}
+
+// ConsumeOIDCLoginState deletes and returns the login state for state.
+// Returns (nil, nil) when the state is unknown, already used, or expired,
+// so a callback URL can never be replayed.
+func (r *Repository) ConsumeOIDCLoginState(state string) (*OIDCLoginState, error) {
+	var s OIDCLoginState
+	var expiresAt time.Time
+	err := r.withTx(func(tx *Tx) error {
+		err := tx.queryRow(
+			`SELECT state, code_verifier, nonce, expires_at FROM oidc_login_states WHERE state = ?`, state,
+		).Scan(&s.State, &s.CodeVerifier, &s.Nonce, &expiresAt)
+		if err != nil {
+			return err
+		}
+		res, err := tx.exec(`DELETE FROM oidc_login_states WHERE state = ?`, state)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return sql.ErrNoRows // a concurrent callback got there first
+		}
+		return nil
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(expiresAt) {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// PurgeExpiredOIDCLoginStates removes abandoned logins. Returns rows deleted.
+func (r *Repository) PurgeExpiredOIDCLoginStates() (int, error) {
+	res, err := r.exec(`DELETE FROM oidc_login_states WHERE expires_at < ?`, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+// GetUserByIdentity returns the user linked to an IdP subject, or (nil, nil)
+// if the subject has never signed in.
+func (r *Repository) GetUserByIdentity(issuer, subject string) (*User, error) {
+	var userID int
+	err := r.queryRow(
+		`SELECT user_id FROM user_identities WHERE issuer = ? AND subject = ?`, issuer, subject,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.GetUserByID(userID)
+}
+
+// LinkUserIdentity records that issuer/subject belongs to userID. Linking the
+// same subject twice is a no-op.
+func (r *Repository) LinkUserIdentity(userID int, issuer, subject, email string) error {
+	_, err := r.exec(
+		`INSERT INTO user_identities (issuer, subject, user_id, email) VALUES (?, ?, ?, ?)
+		 ON CONFLICT DO NOTHING`,
+		issuer, subject, userID, email,
+	)
+	return err
+}
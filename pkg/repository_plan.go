@@ -0,0 +1,841 @@
+package pkg
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Every change to a plan item goes through this file so it can be recorded
+// in plan_events, in the same transaction as the change. An event stores
+// the item's state before and after, which is enough to undo it or to
+// rewind the whole plan to an earlier time.
+
+// ErrPlanConflict is returned when an undo or restore can't be applied
+// because the plan has changed since the event (e.g. undoing an edit to an
+// item that was later deleted).
+var ErrPlanConflict = errors.New("plan has changed since this event")
+
+// Plan event actions.
+const (
+	PlanEventAdd    = "add"
+	PlanEventUpdate = "update"
+	PlanEventDelete = "delete"
+)
+
+// Plan event sources: what made the change.
+const (
+	PlanSourceEdit        = "edit"
+	PlanSourceAdvanceYear = "advance-year"
+	PlanSourceRollover    = "rollover"
+	PlanSourceUndo        = "undo"
+	PlanSourceRestore     = "restore"
+	PlanSourceImport      = "import"
+)
+
+// PlanItemState is a plan item as recorded in plan_events: enough to put it
+// back exactly, term included.
+type PlanItemState struct {
+	PlanItemID   int     `json:"plan_item_id"`
+	UserID       int     `json:"-"`
+	YearIndex    int     `json:"year_index"`
+	Season       string  `json:"season"`
+	Subject      string  `json:"subject"`
+	CourseNumber string  `json:"course_number"`
+	Status       string  `json:"status"`
+	Grade        *string `json:"grade"`
+	Note         *string `json:"note"`
+	IsCustom     bool    `json:"is_custom"`
+}
+
+func (s *PlanItemState) equal(o *PlanItemState) bool {
+	if s == nil || o == nil {
+		return s == o
+	}
+	return s.PlanItemID == o.PlanItemID && s.YearIndex == o.YearIndex && s.Season == o.Season &&
+		s.Subject == o.Subject && s.CourseNumber == o.CourseNumber && s.Status == o.Status &&
+		strPtrEqual(s.Grade, o.Grade) && strPtrEqual(s.Note, o.Note) && s.IsCustom == o.IsCustom
+}
+
+func strPtrEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// PlanEvent is one row of plan_events.
+type PlanEvent struct {
+	EventID        int            `json:"event_id"`
+	UserID         int            `json:"user_id"`
+	PlanItemID     int            `json:"plan_item_id"`
+	Action         string         `json:"action"`
+	Before         *PlanItemState `json:"before"`
+	After          *PlanItemState `json:"after"`
+	ActorUserID    *int           `json:"actor_user_id"`
+	Source         string         `json:"source"`
+	RevertsEventID *int           `json:"reverts_event_id,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// planChange says who made a change and why; revertsEventID is set by undo.
+type planChange struct {
+	actorID        int // 0 for system changes
+	source         string
+	revertsEventID int
+}
+
+func recordPlanEvent(tx *Tx, ch planChange, userID, itemID int, action string, before, after *PlanItemState) error {
+	var actor, reverts interface{}
+	if ch.actorID > 0 {
+		actor = ch.actorID
+	}
+	if ch.revertsEventID > 0 {
+		reverts = ch.revertsEventID
+	}
+	stateJSON := func(s *PlanItemState) (interface{}, error) {
+		if s == nil {
+			return nil, nil
+		}
+		b, err := json.Marshal(s)
+		return string(b), err
+	}
+	b, err := stateJSON(before)
+	if err != nil {
+		return err
+	}
+	a, err := stateJSON(after)
+	if err != nil {
+		return err
+	}
+	_, err = tx.exec(`
+		INSERT INTO plan_events (user_id, plan_item_id, action, before_state, after_state,
+		                         actor_user_id, source, reverts_event_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, itemID, action, b, a, actor, ch.source, reverts, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("record plan event: %w", err)
+	}
+	return nil
+}
+
+// getPlanItemState loads an item with its term. Returns (nil, nil) if it
+// doesn't exist.
+func getPlanItemState(tx *Tx, itemID int) (*PlanItemState, error) {
+	var s PlanItemState
+	var grade, note sql.NullString
+	err := tx.queryRow(`
+		SELECT pi.plan_item_id, pt.user_id, pt.year_index, pt.season,
+		       pi.subject, pi.course_number, pi.status, pi.grade, pi.note, pi.is_custom
+		FROM plan_items pi
+		JOIN plan_terms pt ON pt.plan_term_id = pi.plan_term_id
+		WHERE pi.plan_item_id = ?`, itemID).Scan(
+		&s.PlanItemID, &s.UserID, &s.YearIndex, &s.Season,
+		&s.Subject, &s.CourseNumber, &s.Status, &grade, &note, &s.IsCustom)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if grade.Valid {
+		s.Grade = &grade.String
+	}
+	if note.Valid {
+		s.Note = &note.String
+	}
+	return &s, nil
+}
+
+// resolvePlanTerm returns the user's plan_terms row for the bucket,
+// creating it if needed.
+func resolvePlanTerm(tx *Tx, userID, yearIndex int, season string) (int, error) {
+	var id int
+	err := tx.queryRow(`
+		SELECT plan_term_id FROM plan_terms
+		WHERE user_id = ? AND year_index = ? AND season = ?`,
+		userID, yearIndex, season).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+	newID, err := tx.execReturningID(
+		`INSERT INTO plan_terms (user_id, year_index, season) VALUES (?, ?, ?)`,
+		"plan_term_id", userID, yearIndex, season)
+	if err != nil {
+		return 0, fmt.Errorf("create plan term: %w", err)
+	}
+	return int(newID), nil
+}
+
+// insertPlanItem adds s to the plan and records it. A non-zero
+// s.PlanItemID reinserts a deleted item under its old id (undo/restore),
+// so later events about it still apply.
+func insertPlanItem(tx *Tx, ch planChange, s PlanItemState) (*PlanItemState, error) {
+	termID, err := resolvePlanTerm(tx, s.UserID, s.YearIndex, s.Season)
+	if err != nil {
+		return nil, err
+	}
+	if s.PlanItemID > 0 {
+		_, err = tx.exec(`
+			INSERT INTO plan_items (plan_item_id, plan_term_id, subject, course_number, status, grade, note, is_custom)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.PlanItemID, termID, s.Subject, s.CourseNumber, s.Status, s.Grade, s.Note, boolToInt(s.IsCustom))
+	} else {
+		var id int64
+		id, err = tx.execReturningID(`
+			INSERT INTO plan_items (plan_term_id, subject, course_number, status, grade, note, is_custom)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			"plan_item_id", termID, s.Subject, s.CourseNumber, s.Status, s.Grade, s.Note, boolToInt(s.IsCustom))
+		s.PlanItemID = int(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert plan item: %w", err)
+	}
+	if err := recordPlanEvent(tx, ch, s.UserID, s.PlanItemID, PlanEventAdd, nil, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// updatePlanItem changes an item from before to after (which may be in a
+// different term) and records it. No-op changes aren't recorded.
+func updatePlanItem(tx *Tx, ch planChange, before, after PlanItemState) error {
+	if before.equal(&after) {
+		return nil
+	}
+	termID, err := resolvePlanTerm(tx, before.UserID, after.YearIndex, after.Season)
+	if err != nil {
+		return err
+	}
+	_, err = tx.exec(`
+		UPDATE plan_items
+		SET plan_term_id = ?, subject = ?, course_number = ?, status = ?, grade = ?, note = ?, is_custom = ?
+		WHERE plan_item_id = ?`,
+		termID, after.Subject, after.CourseNumber, after.Status, after.Grade, after.Note, boolToInt(after.IsCustom),
+		before.PlanItemID)
+	if err != nil {
+		return fmt.Errorf("update plan item: %w", err)
+	}
+	return recordPlanEvent(tx, ch, before.UserID, before.PlanItemID, PlanEventUpdate, &before, &after)
+}
+
+// deletePlanItem removes an item and records it.
+func deletePlanItem(tx *Tx, ch planChange, s PlanItemState) error {
+	if _, err := tx.exec(`DELETE FROM plan_items WHERE plan_item_id = ?`, s.PlanItemID); err != nil {
+		return fmt.Errorf("delete plan item: %w", err)
+	}
+	return recordPlanEvent(tx, ch, s.UserID, s.PlanItemID, PlanEventDelete, &s, nil)
+}
+
+// AddPlanItem adds s to s.UserID's plan, as PLANNED if s.Status is empty,
+// and returns it with its new id. It doesn't check the course; see
+// CheckPlanItem.
+func (r *Repository) AddPlanItem(actorID int, s PlanItemState) (*PlanItemState, error) {
+	if s.Status == "" {
+		s.Status = "PLANNED"
+	}
+	s.PlanItemID = 0
+	var out *PlanItemState
+	err := r.withTx(func(tx *Tx) error {
+		var err error
+		out, err = insertPlanItem(tx, planChange{actorID: actorID, source: PlanSourceEdit}, s)
+		return err
+	})
+	return out, err
+}
+
+// SetPlanItemStatus sets an item's status and grade. Returns ErrNotFound if
+// the item doesn't exist.
+func (r *Repository) SetPlanItemStatus(actorID, itemID int, status string, grade *string) error {
+	return r.withTx(func(tx *Tx) error {
+		before, err := getPlanItemState(tx, itemID)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return ErrNotFound
+		}
+		after := *before
+		after.Status, after.Grade = status, grade
+		return updatePlanItem(tx, planChange{actorID: actorID, source: PlanSourceEdit}, *before, after)
+	})
+}
+
+// RemovePlanItem deletes an item. Returns ErrNotFound if it doesn't exist.
+func (r *Repository) RemovePlanItem(actorID, itemID int) error {
+	return r.withTx(func(tx *Tx) error {
+		s, err := getPlanItemState(tx, itemID)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return ErrNotFound
+		}
+		return deletePlanItem(tx, planChange{actorID: actorID, source: PlanSourceEdit}, *s)
+	})
+}
+
+// validPlanStatuses mirrors the plan_items status CHECK constraint.
+var validPlanStatuses = map[string]bool{
+	"PLANNED": true, "IN_PROGRESS": true, "COMPLETED": true, "DROPPED": true, "UNCONFIRMED": true,
+}
+
+// UserPlanItem is a plan item as the planner shows it: with its bucket,
+// the catalogue name and the calendar term the bucket falls in.
+type UserPlanItem struct {
+	PlanItemID   int     `json:"plan_item_id"`
+	PlanTermID   int     `json:"plan_term_id"`
+	Subject      string  `json:"subject"`
+	CourseNumber string  `json:"course_number"`
+	Status       string  `json:"status"`
+	Grade        *string `json:"grade"`
+	Note         *string `json:"note"`
+	YearIndex    int     `json:"year_index"`
+	Season       string  `json:"season"`
+	IsCustom     bool    `json:"is_custom"`
+	CourseName   *string `json:"course_name"`
+	Units        int     `json:"units"`
+	Term         *Term   `json:"term,omitempty"`
+}
+
+// GetUserPlan returns the user's whole plan in academic order.
+func (r *Repository) GetUserPlan(userID int) ([]UserPlanItem, error) {
+	// Join plan_items → plan_terms → catalog_courses to get the course
+	// name and units in one query; the catalogue has one row per course, so no
+	// grouping over offerings is needed.
+	rows, err := r.query(`
+		SELECT pi.plan_item_id, pi.plan_term_id,
+		       pi.subject, pi.course_number,
+		       pi.status, pi.grade, pi.note, pi.is_custom,
+		       pt.year_index, pt.season,
+		       cc.course_name, cc.units
+		FROM plan_items pi
+		JOIN plan_terms pt ON pt.plan_term_id = pi.plan_term_id
+		LEFT JOIN catalog_courses cc ON cc.subject = pi.subject
+		       AND cc.course_number = pi.course_number
+		WHERE pt.user_id = ?
+		ORDER BY pt.year_index, `+planSeasonOrderSQL+`, pi.subject, pi.course_number`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []UserPlanItem{}
+	for rows.Next() {
+		var pi UserPlanItem
+		var grade, note, courseName sql.NullString
+		var units sql.NullInt64
+		if err := rows.Scan(
+			&pi.PlanItemID, &pi.PlanTermID,
+			&pi.Subject, &pi.CourseNumber,
+			&pi.Status, &grade, &note, &pi.IsCustom,
+			&pi.YearIndex, &pi.Season,
+			&courseName, &units,
+		); err != nil {
+			return nil, err
+		}
+		pi.Units = unitsOr(units, pi.CourseNumber)
+		if grade.Valid {
+			pi.Grade = &grade.String
+		}
+		if note.Valid {
+			pi.Note = &note.String
+		}
+		if courseName.Valid {
+			pi.CourseName = &courseName.String
+		}
+		items = append(items, pi)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Place each bucket on the real calendar. A failure here only loses
+	// the dates, so log it and return the plan without them.
+	if u, err := r.GetUserByID(userID); err == nil && u != nil {
+		if startYear, err := r.UserStartYear(u, time.Now()); err != nil {
+			log.Printf("plan terms for user %d: %v", userID, err)
+		} else {
+			terms := map[string]*Term{}
+			for i := range items {
+				key := strconv.Itoa(items[i].YearIndex) + items[i].Season
+				if _, ok := terms[key]; !ok {
+					t, err := r.PlanTerm(startYear, items[i].YearIndex, items[i].Season)
+					if err != nil {
+						log.Printf("plan term for user %d: %v", userID, err)
+					}
+					terms[key] = t
+				}
+				items[i].Term = terms[key]
+			}
+		}
+	}
+	return items, nil
+}
+
+// ─── Input checks ────────────────────────────────────────────────────────────
+
+// FieldError is a problem with one field of a plan item.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// PlanItemError is a plan item that failed CheckPlanItem.
+type PlanItemError struct {
+	Fields []FieldError
+}
+
+func (e *PlanItemError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// rowQuerier is satisfied by both *Repository and *Tx.
+type rowQuerier interface {
+	queryRow(q string, args ...interface{}) *sql.Row
+}
+
+// CheckPlanItem normalises a plan item about to be added (trimmed,
+// upper-case course code; canonical season) and reports any field the
+// database would reject, or that names a course missing from the
+// catalogue. Custom items (transfer credit, courses from elsewhere) skip
+// the catalogue check.
+func (r *Repository) CheckPlanItem(s *PlanItemState) ([]FieldError, error) {
+	return checkPlanItem(r, s)
+}
+
+func checkPlanItem(q rowQuerier, s *PlanItemState) ([]FieldError, error) {
+	var fields []FieldError
+	bad := func(field, msg string) { fields = append(fields, FieldError{field, msg}) }
+
+	s.Subject = strings.ToUpper(strings.TrimSpace(s.Subject))
+	s.CourseNumber = strings.ToUpper(strings.TrimSpace(s.CourseNumber))
+	if s.YearIndex < 1 || s.YearIndex > 8 {
+		bad("year_index", "must be between 1 and 8")
+	}
+	if i := seasonIndex(strings.TrimSpace(s.Season)); i < 0 {
+		bad("season", "must be Fall, Winter, Spring or Summer")
+	} else {
+		s.Season = termSeasons[i].name
+	}
+	if s.Status != "" && !validPlanStatuses[s.Status] {
+		bad("status", "must be PLANNED, IN_PROGRESS, COMPLETED, DROPPED or UNCONFIRMED")
+	}
+	if s.Subject == "" {
+		bad("subject", "is required")
+	}
+	if s.CourseNumber == "" {
+		bad("course_number", "is required")
+	}
+	if s.IsCustom || s.Subject == "" || s.CourseNumber == "" {
+		return fields, nil
+	}
+
+	inCatalog, subjectKnown, err := catalogLookup(q, s.Subject, s.CourseNumber)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case !subjectKnown:
+		bad("subject", "unknown subject; set is_custom for a course from outside the catalogue")
+	case !inCatalog:
+		bad("course_number", fmt.Sprintf("%s %s is not in the course catalogue; set is_custom for a custom or transfer course", s.Subject, s.CourseNumber))
+	}
+	return fields, nil
+}
+
+// catalogLookup reports whether subject + courseNumber is in the catalogue,
+// and whether any course in subject is.
+func catalogLookup(q rowQuerier, subject, courseNumber string) (inCatalog, subjectKnown bool, err error) {
+	err = q.queryRow(`
+		SELECT EXISTS (SELECT 1 FROM catalog_courses WHERE subject = ? AND course_number = ?),
+		       EXISTS (SELECT 1 FROM catalog_courses WHERE subject = ?)`,
+		subject, courseNumber, subject).Scan(&inCatalog, &subjectKnown)
+	if err != nil {
+		return false, false, fmt.Errorf("check catalogue: %w", err)
+	}
+	return inCatalog, subjectKnown, nil
+}
+
+// ─── Batch edits ─────────────────────────────────────────────────────────────
+
+// Batch operation kinds.
+const (
+	PlanOpAdd    = "add"
+	PlanOpMove   = "move"
+	PlanOpUpdate = "update"
+	PlanOpDelete = "delete"
+)
+
+// PlanOp is one operation in a batch edit:
+//
+//	add:    year_index, season, subject, course_number; status (default
+//	        PLANNED), grade, note and is_custom optional
+//	move:   plan_item_id, year_index, season
+//	update: plan_item_id and any of status, grade, note ("" clears grade
+//	        or note; absent leaves them alone)
+//	delete: plan_item_id
+type PlanOp struct {
+	Op           string  `json:"op"`
+	PlanItemID   int     `json:"plan_item_id,omitempty"`
+	YearIndex    int     `json:"year_index,omitempty"`
+	Season       string  `json:"season,omitempty"`
+	Subject      string  `json:"subject,omitempty"`
+	CourseNumber string  `json:"course_number,omitempty"`
+	Status       string  `json:"status,omitempty"`
+	Grade        *string `json:"grade,omitempty"`
+	Note         *string `json:"note,omitempty"`
+	IsCustom     bool    `json:"is_custom,omitempty"`
+}
+
+// check reports what's wrong with the operation on its own, before it
+// touches the database; "" means nothing.
+func (op PlanOp) check() string {
+	bucket := func() string {
+		if op.YearIndex < 1 || op.YearIndex > 8 {
+			return "year_index must be between 1 and 8"
+		}
+		if seasonIndex(op.Season) < 0 {
+			return "season must be Fall, Winter, Spring or Summer"
+		}
+		return ""
+	}
+	switch op.Op {
+	case PlanOpAdd:
+		if op.Subject == "" || op.CourseNumber == "" {
+			return "subject and course_number are required"
+		}
+		if op.Status != "" && !validPlanStatuses[op.Status] {
+			return "invalid status"
+		}
+		return bucket()
+	case PlanOpMove:
+		if op.PlanItemID == 0 {
+			return "plan_item_id is required"
+		}
+		return bucket()
+	case PlanOpUpdate:
+		if op.PlanItemID == 0 {
+			return "plan_item_id is required"
+		}
+		if op.Status != "" && !validPlanStatuses[op.Status] {
+			return "invalid status"
+		}
+		if op.Status == "" && op.Grade == nil && op.Note == nil {
+			return "nothing to update"
+		}
+		return ""
+	case PlanOpDelete:
+		if op.PlanItemID == 0 {
+			return "plan_item_id is required"
+		}
+		return ""
+	}
+	return "op must be add, move, update or delete"
+}
+
+// PlanOpError is why one operation in a batch failed.
+type PlanOpError struct {
+	Index   int    `json:"index"`
+	Op      string `json:"op"`
+	Message string `json:"error"`
+}
+
+// PlanBatchError is returned by ApplyPlanBatch when operations are invalid
+// or can't be applied. Nothing in the batch has been applied.
+type PlanBatchError struct {
+	Errors []PlanOpError
+}
+
+func (e *PlanBatchError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, oe := range e.Errors {
+		msgs[i] = fmt.Sprintf("op %d (%s): %s", oe.Index, oe.Op, oe.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ApplyPlanBatch applies ops to userID's plan in order, in one transaction:
+// either all of them take effect or none do. Every operation is checked up
+// front, so a *PlanBatchError lists all the malformed ones; once applying
+// starts it reports the first that fails (an item that isn't the user's,
+// or a course already in the target term).
+func (r *Repository) ApplyPlanBatch(actorID, userID int, ops []PlanOp) error {
+	var invalid []PlanOpError
+	for i, op := range ops {
+		if msg := op.check(); msg != "" {
+			invalid = append(invalid, PlanOpError{Index: i, Op: op.Op, Message: msg})
+		}
+	}
+	if len(invalid) > 0 {
+		return &PlanBatchError{Errors: invalid}
+	}
+
+	ch := planChange{actorID: actorID, source: PlanSourceEdit}
+	return r.withTx(func(tx *Tx) error {
+		for i, op := range ops {
+			fail := func(msg string) error {
+				return &PlanBatchError{Errors: []PlanOpError{{Index: i, Op: op.Op, Message: msg}}}
+			}
+			var itemErr *PlanItemError
+			err := applyPlanOp(tx, ch, userID, op)
+			switch {
+			case err == nil:
+				continue
+			case errors.Is(err, ErrNotFound):
+				return fail(fmt.Sprintf("plan item %d not found", op.PlanItemID))
+			case errors.As(err, &itemErr):
+				return fail(itemErr.Error())
+			case isUniqueViolation(err):
+				return fail("course is already in that term")
+			default:
+				return fmt.Errorf("op %d (%s): %w", i, op.Op, err)
+			}
+		}
+		return nil
+	})
+}
+
+func applyPlanOp(tx *Tx, ch planChange, userID int, op PlanOp) error {
+	if op.Op == PlanOpAdd {
+		s := PlanItemState{
+			UserID: userID, YearIndex: op.YearIndex, Season: op.Season,
+			Subject: op.Subject, CourseNumber: op.CourseNumber,
+			Status: op.Status, Grade: op.Grade, Note: op.Note, IsCustom: op.IsCustom,
+		}
+		if s.Status == "" {
+			s.Status = "PLANNED"
+		}
+		fields, err := checkPlanItem(tx, &s)
+		if err != nil {
+			return err
+		}
+		if len(fields) > 0 {
+			return &PlanItemError{Fields: fields}
+		}
+		_, err = insertPlanItem(tx, ch, s)
+		return err
+	}
+
+	before, err := getPlanItemState(tx, op.PlanItemID)
+	if err != nil {
+		return err
+	}
+	if before == nil || before.UserID != userID {
+		return ErrNotFound
+	}
+	after := *before
+	switch op.Op {
+	case PlanOpMove:
+		// check matched the season in any case; store the canonical name.
+		after.YearIndex, after.Season = op.YearIndex, termSeasons[seasonIndex(op.Season)].name
+	case PlanOpUpdate:
+		if op.Status != "" {
+			after.Status = op.Status
+		}
+		if op.Grade != nil {
+			after.Grade = op.Grade
+			if *op.Grade == "" {
+				after.Grade = nil
+			}
+		}
+		if op.Note != nil {
+			after.Note = op.Note
+			if *op.Note == "" {
+				after.Note = nil
+			}
+		}
+	case PlanOpDelete:
+		return deletePlanItem(tx, ch, *before)
+	}
+	return updatePlanItem(tx, ch, *before, after)
+}
+
+// ─── History ─────────────────────────────────────────────────────────────────
+
+const planEventColumns = `event_id, user_id, plan_item_id, action, before_state, after_state,
+	actor_user_id, source, reverts_event_id, created_at`
+
+func scanPlanEvent(row interface{ Scan(...interface{}) error }) (*PlanEvent, error) {
+	var e PlanEvent
+	var before, after sql.NullString
+	var actor, reverts sql.NullInt64
+	if err := row.Scan(&e.EventID, &e.UserID, &e.PlanItemID, &e.Action, &before, &after,
+		&actor, &e.Source, &reverts, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, p := range []struct {
+		raw sql.NullString
+		dst **PlanItemState
+	}{{before, &e.Before}, {after, &e.After}} {
+		if !p.raw.Valid {
+			continue
+		}
+		var s PlanItemState
+		if err := json.Unmarshal([]byte(p.raw.String), &s); err != nil {
+			return nil, fmt.Errorf("plan event %d: %w", e.EventID, err)
+		}
+		s.UserID = e.UserID
+		*p.dst = &s
+	}
+	if actor.Valid {
+		v := int(actor.Int64)
+		e.ActorUserID = &v
+	}
+	if reverts.Valid {
+		v := int(reverts.Int64)
+		e.RevertsEventID = &v
+	}
+	return &e, nil
+}
+
+// ListPlanEvents returns a user's plan history newest first, with the total
+// number of events. limit ≤ 0 means no cap.
+func (r *Repository) ListPlanEvents(userID, limit, offset int) ([]PlanEvent, int, error) {
+	var total int
+	if err := r.queryRow(`SELECT COUNT(*) FROM plan_events WHERE user_id = ?`, userID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count plan events: %w", err)
+	}
+
+	args := []interface{}{userID}
+	limitClause := "LIMIT -1 OFFSET ?"
+	if limit > 0 {
+		limitClause = "LIMIT ? OFFSET ?"
+		args = append(args, limit)
+	}
+	args = append(args, offset)
+	rows, err := r.query(`SELECT `+planEventColumns+` FROM plan_events
+		WHERE user_id = ? ORDER BY event_id DESC `+limitClause, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list plan events: %w", err)
+	}
+	defer rows.Close()
+
+	out := []PlanEvent{}
+	for rows.Next() {
+		e, err := scanPlanEvent(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *e)
+	}
+	return out, total, rows.Err()
+}
+
+// revertPlanEvent applies the inverse of e. With check set, the item must
+// still be exactly as e left it, otherwise ErrPlanConflict; restore skips
+// the check because it unwinds events newest first, so each inverse finds
+// the plan as its event left it.
+func revertPlanEvent(tx *Tx, ch planChange, e *PlanEvent, check bool) error {
+	cur, err := getPlanItemState(tx, e.PlanItemID)
+	if err != nil {
+		return err
+	}
+	if check && !cur.equal(e.After) {
+		return ErrPlanConflict
+	}
+	switch {
+	case e.Before == nil: // undo an add
+		if cur == nil {
+			return nil
+		}
+		return deletePlanItem(tx, ch, *cur)
+	case cur == nil: // undo a delete
+		if _, err := insertPlanItem(tx, ch, *e.Before); err != nil {
+			if isUniqueViolation(err) {
+				return ErrPlanConflict
+			}
+			return err
+		}
+		return nil
+	default:
+		if err := updatePlanItem(tx, ch, *cur, *e.Before); err != nil {
+			if isUniqueViolation(err) {
+				return ErrPlanConflict
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// UndoPlanEvent reverts one event in userID's plan history, recording the
+// revert as a new event. Returns ErrNotFound if the event isn't userID's and
+// ErrPlanConflict if the item has changed since.
+func (r *Repository) UndoPlanEvent(actorID, userID, eventID int) (*PlanEvent, error) {
+	var undone *PlanEvent
+	err := r.withTx(func(tx *Tx) error {
+		e, err := scanPlanEvent(tx.queryRow(`SELECT `+planEventColumns+` FROM plan_events
+			WHERE event_id = ? AND user_id = ?`, eventID, userID))
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		undone = e
+		return revertPlanEvent(tx, planChange{actorID: actorID, source: PlanSourceUndo, revertsEventID: e.EventID}, e, true)
+	})
+	return undone, err
+}
+
+// RestorePlan rewinds userID's plan to how it was at asOf by reverting
+// every later event, newest first. The reverts are recorded too, so a
+// restore can itself be undone. Returns the number of events reverted.
+func (r *Repository) RestorePlan(actorID, userID int, asOf time.Time) (int, error) {
+	var n int
+	err := r.withTx(func(tx *Tx) error {
+		rows, err := tx.query(`SELECT `+planEventColumns+` FROM plan_events
+			WHERE user_id = ? AND created_at > ? ORDER BY event_id DESC`, userID, asOf.UTC())
+		if err != nil {
+			return err
+		}
+		var events []*PlanEvent
+		for rows.Next() {
+			e, err := scanPlanEvent(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			events = append(events, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			ch := planChange{actorID: actorID, source: PlanSourceRestore, revertsEventID: e.EventID}
+			if err := revertPlanEvent(tx, ch, e, false); err != nil {
+				return fmt.Errorf("revert event %d: %w", e.EventID, err)
+			}
+		}
+		n = len(events)
+		return nil
+	})
+	return n, err
+}
+
+// isUniqueViolation reports whether err is a UNIQUE constraint failure on
+// either driver.
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") || strings.Contains(msg, "duplicate key value")
+}
@@ -0,0 +1,706 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Course search backends. FindCourses picks one the first time it runs:
+//
+//   - fts5:     SQLite with the courses_fts table from 018_courses_fts.sql and
+//     a binary built with -tags sqlite_fts5.
+//   - tsvector: Postgres with courses.search_vector (postgres_schema.sql).
+//   - like:     anything else, e.g. the test schema or an old database.
+//
+// The ranked backends fall back to LIKE when they find nothing, so infix
+// matches like "truct" → "Data Structures" keep working.
+const (
+	searchLike     = "like"
+	searchFTS5     = "fts5"
+	searchTSVector = "tsvector"
+)
+
+// Snippet highlight markers. The database wraps matches in these control
+// characters; formatSnippet escapes the text and swaps them for <mark> tags,
+// so course names can never inject markup.
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+// searchBackend returns the backend FindCourses uses on this database.
+func (r *Repository) searchBackend() string {
+	r.searchOnce.Do(func() {
+		r.searchMode = r.detectSearchBackend()
+		log.Printf("[search] using %s backend", r.searchMode)
+	})
+	return r.searchMode
+}
+
+func (r *Repository) detectSearchBackend() string {
+	var n int
+	if r.driver == "postgres" {
+		err := r.queryRow(`SELECT COUNT(*) FROM information_schema.columns
+			WHERE table_name = 'courses' AND column_name = 'search_vector'`).Scan(&n)
+		if err != nil || n == 0 {
+			return searchLike
+		}
+		return searchTSVector
+	}
+
+	if err := r.queryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'courses_fts'`).Scan(&n); err != nil || n == 0 {
+		return searchLike
+	}
+	var fts5 int
+	if err := r.queryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil || fts5 == 0 {
+		log.Printf("[search] courses_fts exists but this binary lacks FTS5; rebuild with -tags sqlite_fts5")
+		return searchLike
+	}
+	return searchFTS5
+}
+
+// RebuildSearchIndex repopulates the full-text index from the courses table
+// and returns the active backend. Only the SQLite index needs this (after a
+// bulk load with triggers disabled, say); Postgres keeps search_vector
+// current as a generated column.
+func (r *Repository) RebuildSearchIndex() (string, error) {
+	backend := r.searchBackend()
+	if backend == searchFTS5 {
+		if _, err := r.exec(`INSERT INTO courses_fts(courses_fts) VALUES ('rebuild')`); err != nil {
+			return backend, fmt.Errorf("rebuild courses_fts: %w", err)
+		}
+	}
+	return backend, nil
+}
+
+// CourseQuery describes a course search: free text, filters, sort order and
+// page. Slice filters match any of their values; empty fields don't filter.
+type CourseQuery struct {
+	Q             string
+	Subjects      []string
+	Levels        []string // first digit of course_number, e.g. "2" = 2000-level
+	Terms         []string // partial match on term, e.g. "Fall"
+	Units         []int
+	MinRating     *float64 // average instructor rating
+	MaxDifficulty *float64 // average instructor difficulty
+	HasPrereqs    *bool
+	Sort          string // one of courseSortOrders; "" means relevance
+	Limit, Offset int    // Limit ≤ 0 means no cap
+	Cursor        string // from CourseResults; replaces Offset when set
+	SkipTotal     bool   // don't count the matches; Total is -1
+	Facets        bool   // also compute CourseResults.Facets
+}
+
+// FacetCount is one filter chip: a value and how many courses have it.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// CourseResults is one page of FindCourses. NextCursor and PrevCursor are
+// set when there is a page in that direction (never when Limit ≤ 0).
+type CourseResults struct {
+	Courses    []Course                `json:"courses"`
+	Total      int                     `json:"total"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+	PrevCursor string                  `json:"prev_cursor,omitempty"`
+	Facets     map[string][]FacetCount `json:"facets,omitempty"`
+}
+
+// courseCodeKeys orders by course code, then term. A term can hold several
+// sections of a course, each its own row, so every sort ends with the row
+// id to make the order total.
+var courseCodeKeys = []sortKey{{expr: "c.subject"}, {expr: "c.course_number"}, {expr: "c.term"}, {expr: "c.id"}}
+
+// courseSortOrders maps the ?sort= values to their leading sort key.
+// "relevance" is handled separately because its expression depends on the
+// backend; it falls back to "code" for empty or unranked queries.
+var courseSortOrders = map[string]*sortKey{
+	"relevance":   nil,
+	"code":        nil,
+	"rating":      {expr: "s.avg_rating", desc: true, nullsLast: true},
+	"difficulty":  {expr: "s.avg_difficulty", nullsLast: true},
+	"num_ratings": {expr: "s.num_ratings", desc: true, nullsLast: true},
+}
+
+// Facet names, also used as the keys of CourseResults.Facets.
+const (
+	facetSubject    = "subject"
+	facetLevel      = "level"
+	facetTerm       = "term"
+	facetHasPrereqs = "has_prereqs"
+	facetRating     = "rating"
+)
+
+// courseFacetKeys are the SQL expressions each facet groups by.
+var courseFacetKeys = map[string]string{
+	facetSubject:    "c.subject",
+	facetLevel:      "SUBSTR(c.course_number, 1, 1)",
+	facetTerm:       "c.term",
+	facetHasPrereqs: "CASE WHEN " + hasPrereqsSQL + " THEN 'true' ELSE 'false' END",
+	facetRating: `CASE WHEN s.avg_rating IS NULL THEN 'unrated'
+	                   WHEN s.avg_rating >= 4 THEN '4+'
+	                   WHEN s.avg_rating >= 3 THEN '3-4'
+	                   WHEN s.avg_rating >= 2 THEN '2-3'
+	                   ELSE '<2' END`,
+}
+
+// ratingBuckets is the display order of the rating facet.
+var ratingBuckets = []string{"4+", "3-4", "2-3", "<2", "unrated"}
+
+const hasPrereqsSQL = `EXISTS (SELECT 1 FROM requisites rq
+	WHERE rq.subject = c.subject AND rq.course_number = c.course_number AND rq.kind = 'PREREQ')`
+
+// courseUnitsSQL is each course's units, from its catalogue course or
+// guessed from its number.
+var courseUnitsSQL = unitsSQL(
+	"(SELECT cc.units FROM catalog_courses cc WHERE cc.catalog_id = c.catalog_id)", "c.course_number")
+
+// courseStatsJoin attaches each course's aggregated instructor stats as s.*,
+// one row per course, so they can be filtered and sorted on directly.
+const courseStatsJoin = `LEFT JOIN (
+	SELECT ci.course_row_id,
+	       AVG(i.ext_avg_rating) AS avg_rating,
+	       AVG(i.ext_avg_difficulty) AS avg_difficulty,
+	       SUM(i.ext_num_ratings) AS num_ratings
+	FROM course_instructors ci
+	JOIN instructors i ON ci.instructor_id = i.instructor_id AND i.ext_avg_rating IS NOT NULL
+	GROUP BY ci.course_row_id
+) s ON s.course_row_id = c.id`
+
+// SearchCourses searches courses by subject, number, name, or professor.
+// It supports multi-token AND search: the query is split on whitespace and every
+// token must independently match at least one column (subject, course_number,
+// course_name, or professor). This lets searches like "compsci 2" or "software eng"
+// work correctly even though those strings never appear verbatim in a single column.
+//
+// level filters by the first digit of course_number (e.g. "2" = 2000-level courses).
+// term filters by partial match on the term string (e.g. "Fall", "Winter").
+// Either filter is ignored when empty or "all".
+// limit ≤ 0 means no cap (returns all matches). offset is 0-based.
+// Returns the matching page of courses plus the total number of matches.
+// See FindCourses for ranking, code normalisation and the other filters.
+func (r *Repository) SearchCourses(q, level, term string, limit, offset int) ([]Course, int, error) {
+	res, err := r.FindCourses(CourseQuery{
+		Q: q, Levels: []string{level}, Terms: []string{term}, Limit: limit, Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Courses, res.Total, nil
+}
+
+// FindCourses runs a course search. The text query is tried in turn as a
+// normalised course code ("cs 2c3" → "COMPSCI 2C03"), as typed, and finally
+// by edit distance against course names; the first that matches anything
+// wins. With a full-text backend, results are ranked by relevance (code
+// matches outweigh name matches, which outweigh professor matches), every
+// token is a prefix match ("algo" finds "Algorithms"), and each course
+// carries a highlighted Snippet.
+//
+// Facet counts apply every filter except the facet's own, so a client can
+// show how many results each alternative chip would give.
+func (r *Repository) FindCourses(cq CourseQuery) (*CourseResults, error) {
+	lead, ok := courseSortOrders[cq.Sort]
+	if cq.Sort != "" && !ok {
+		return nil, fmt.Errorf("unknown course sort %q", cq.Sort)
+	}
+	sortName := cq.Sort
+	if sortName == "" {
+		sortName = "relevance"
+	}
+
+	filters, filterArgs := courseFilters(cq, "")
+	var m courseMatch
+	var total int
+	found := false
+	for _, next := range r.courseMatches(cq.Q) {
+		candidate, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		m = candidate
+		if found, total, err = r.countCourseMatch(m, filters, filterArgs, !cq.SkipTotal); err != nil {
+			return nil, err
+		}
+		if found {
+			break
+		}
+	}
+
+	res := &CourseResults{Courses: []Course{}, Total: total}
+	if found {
+		keys := courseCodeKeys
+		if sortName == "relevance" && m.rank != nil {
+			keys = append([]sortKey{*m.rank}, keys...)
+		} else if lead != nil {
+			keys = append([]sortKey{*lead}, keys...)
+		}
+		if err := r.courseMatchPage(m, cq, sortName, keys, filters, filterArgs, res); err != nil {
+			return nil, err
+		}
+	}
+
+	if cq.Facets {
+		res.Facets = map[string][]FacetCount{}
+		for _, facet := range []string{facetSubject, facetLevel, facetTerm, facetHasPrereqs, facetRating} {
+			counts, err := r.courseFacet(m, cq, facet)
+			if err != nil {
+				return nil, err
+			}
+			res.Facets[facet] = counts
+		}
+	}
+	return res, nil
+}
+
+// countCourseMatch reports whether m matches anything under the filters and,
+// when count is set, how many courses; otherwise total is -1.
+func (r *Repository) countCourseMatch(m courseMatch, filters []string, filterArgs []interface{}, count bool) (found bool, total int, err error) {
+	if !count {
+		q, args := m.build("1", nil, filters, filterArgs, "LIMIT 1", nil)
+		var one int
+		switch err := r.queryRow(q, args...).Scan(&one); err {
+		case nil:
+			return true, -1, nil
+		case sql.ErrNoRows:
+			return false, -1, nil
+		default:
+			return false, -1, fmt.Errorf("probe courses (%s): %w", m.name, err)
+		}
+	}
+	q, args := m.build("COUNT(*)", nil, filters, filterArgs, "", nil)
+	if err := r.queryRow(q, args...).Scan(&total); err != nil {
+		return false, 0, fmt.Errorf("count courses (%s): %w", m.name, err)
+	}
+	return total > 0, total, nil
+}
+
+// courseMatchPage fetches the page of m selected by cq's cursor or offset
+// into res, along with the cursors either side of it.
+func (r *Repository) courseMatchPage(m courseMatch, cq CourseQuery, sortName string, keys []sortKey, filters []string, filterArgs []interface{}, res *CourseResults) error {
+	var cur *pageCursor
+	offset := cq.Offset
+	if cq.Cursor != "" {
+		var err error
+		if cur, err = decodeCursor(cq.Cursor, sortName, len(keys)); err != nil {
+			return err
+		}
+		cond, condArgs := keysetCondition(keys, cur.Keys, cur.Before)
+		filters = append(append([]string{}, filters...), cond)
+		filterArgs = append(append([]interface{}{}, filterArgs...), condArgs...)
+		offset = 0
+	}
+	backward := cur != nil && cur.Before
+
+	// One extra row tells us whether there's another page after this one.
+	limit := cq.Limit
+	if limit > 0 {
+		limit++
+	}
+	order, orderArgs := orderByClause(keys, backward)
+	limitClause, tailArgs := searchLimitClause(orderArgs, limit, offset)
+	snippet := m.snippet
+	if snippet == "" {
+		snippet = "''"
+	}
+	keyCols, keyArgs := keyColumns(keys)
+	q, args := m.build(
+		`c.id, c.subject, c.course_number, c.course_name, c.professor, c.term,
+		 s.avg_rating, s.avg_difficulty, s.num_ratings, `+snippet+`, `+keyCols,
+		append(append([]interface{}{}, m.snippetArgs...), keyArgs...),
+		filters, filterArgs,
+		"ORDER BY "+order+" "+limitClause, tailArgs)
+	rows, err := r.query(q, args...)
+	if err != nil {
+		return fmt.Errorf("search courses (%s): %w", m.name, err)
+	}
+	courses, rowKeys, err := scanSearchCourses(rows, len(keys))
+	if err != nil {
+		return err
+	}
+
+	more := cq.Limit > 0 && len(courses) > cq.Limit
+	if more {
+		courses, rowKeys = courses[:cq.Limit], rowKeys[:cq.Limit]
+	}
+	if backward {
+		for i, j := 0, len(courses)-1; i < j; i, j = i+1, j-1 {
+			courses[i], courses[j] = courses[j], courses[i]
+			rowKeys[i], rowKeys[j] = rowKeys[j], rowKeys[i]
+		}
+	}
+	res.Courses = courses
+	if cq.Limit > 0 {
+		res.NextCursor, res.PrevCursor = pageLinks(sortName, rowKeys, more, offset, cur)
+	}
+	return nil
+}
+
+// courseFacet counts the matching courses per value of one facet.
+func (r *Repository) courseFacet(m courseMatch, cq CourseQuery, facet string) ([]FacetCount, error) {
+	filters, filterArgs := courseFilters(cq, facet)
+	q, args := m.build(courseFacetKeys[facet]+", COUNT(*)", nil, filters, filterArgs, "GROUP BY 1", nil)
+	rows, err := r.query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("course facet %s: %w", facet, err)
+	}
+	defer rows.Close()
+
+	out := []FacetCount{}
+	for rows.Next() {
+		var fc FacetCount
+		var value sql.NullString
+		if err := rows.Scan(&value, &fc.Count); err != nil {
+			return nil, err
+		}
+		fc.Value = value.String
+		out = append(out, fc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if facet == facetRating {
+		rank := map[string]int{}
+		for i, b := range ratingBuckets {
+			rank[b] = i
+		}
+		sort.Slice(out, func(i, j int) bool { return rank[out[i].Value] < rank[out[j].Value] })
+	} else {
+		sort.Slice(out, func(i, j int) bool { return out[i].Value < out[j].Value })
+	}
+	return out, nil
+}
+
+// courseFilters returns the WHERE conditions for cq's filters, leaving out
+// the one that belongs to the except facet ("" keeps them all).
+func courseFilters(cq CourseQuery, except string) ([]string, []interface{}) {
+	var parts []string
+	var args []interface{}
+	anyOf := func(cond string, values []interface{}) {
+		if len(values) == 0 {
+			return
+		}
+		ors := make([]string, len(values))
+		for i := range values {
+			ors[i] = cond
+		}
+		parts = append(parts, "("+strings.Join(ors, " OR ")+")")
+		args = append(args, values...)
+	}
+	// "" and "all" are what the course browser sends for "no filter".
+	values := func(in []string, format func(string) string) []interface{} {
+		var out []interface{}
+		for _, v := range in {
+			if v = strings.TrimSpace(v); v != "" && v != "all" {
+				out = append(out, format(v))
+			}
+		}
+		return out
+	}
+
+	if except != facetSubject {
+		anyOf("c.subject = ?", values(cq.Subjects, strings.ToUpper))
+	}
+	// Level: course_number must start with the given digit (e.g. "2" → 2000-level).
+	if except != facetLevel {
+		anyOf("c.course_number LIKE ?", values(cq.Levels, func(v string) string { return v + "%" }))
+	}
+	// Term: term string must contain the given value (e.g. "Fall", "Winter").
+	if except != facetTerm {
+		anyOf("c.term LIKE ?", values(cq.Terms, func(v string) string { return "%" + v + "%" }))
+	}
+	if len(cq.Units) > 0 {
+		units := make([]interface{}, len(cq.Units))
+		for i, u := range cq.Units {
+			units[i] = u
+		}
+		anyOf(courseUnitsSQL+" = ?", units)
+	}
+	if cq.MinRating != nil && except != facetRating {
+		parts = append(parts, "s.avg_rating >= ?")
+		args = append(args, *cq.MinRating)
+	}
+	if cq.MaxDifficulty != nil {
+		parts = append(parts, "s.avg_difficulty <= ?")
+		args = append(args, *cq.MaxDifficulty)
+	}
+	if cq.HasPrereqs != nil && except != facetHasPrereqs {
+		if *cq.HasPrereqs {
+			parts = append(parts, hasPrereqsSQL)
+		} else {
+			parts = append(parts, "NOT "+hasPrereqsSQL)
+		}
+	}
+	return parts, args
+}
+
+// courseMatch is the text-matching part of a course search, produced by one
+// of the backends and combined with filters, sort and page by build.
+type courseMatch struct {
+	name string // for error messages
+
+	with      string // CTE body, exposed as m
+	withArgs  []interface{}
+	join      string
+	where     []string
+	whereArgs []interface{}
+
+	snippet     string // SELECT expression for Course.Snippet, "" for none
+	snippetArgs []interface{}
+	rank        *sortKey // relevance order, nil for none
+}
+
+// build assembles a query over the matched courses. Arguments are collected
+// in the order their placeholders appear, which Postgres's $n numbering
+// depends on.
+func (m courseMatch) build(cols string, colArgs []interface{}, filters []string, filterArgs []interface{}, tail string, tailArgs []interface{}) (string, []interface{}) {
+	var sb strings.Builder
+	var args []interface{}
+	if m.with != "" {
+		sb.WriteString("WITH m AS MATERIALIZED (" + m.with + ")\n")
+		args = append(args, m.withArgs...)
+	}
+	sb.WriteString("SELECT " + cols + "\nFROM courses c " + m.join + "\n" + courseStatsJoin + "\n")
+	args = append(args, colArgs...)
+
+	where := append(append([]string{}, m.where...), filters...)
+	if len(where) > 0 {
+		sb.WriteString("WHERE " + strings.Join(where, " AND ") + "\n")
+	}
+	args = append(args, m.whereArgs...)
+	args = append(args, filterArgs...)
+
+	sb.WriteString(tail)
+	args = append(args, tailArgs...)
+	return sb.String(), args
+}
+
+// courseMatches returns the text matches FindCourses tries for q, in order.
+// They're built lazily because the fuzzy one scans course names.
+func (r *Repository) courseMatches(q string) []func() (courseMatch, bool, error) {
+	if len(searchTokens(q)) == 0 {
+		return []func() (courseMatch, bool, error){
+			func() (courseMatch, bool, error) { return courseMatch{name: "all"}, true, nil },
+		}
+	}
+
+	var queries []string
+	if normalized, ok := normalizeCourseQuery(q); ok {
+		queries = append(queries, normalized)
+	}
+	queries = append(queries, q)
+
+	var out []func() (courseMatch, bool, error)
+	for _, s := range queries {
+		switch r.searchBackend() {
+		case searchFTS5:
+			out = append(out, func() (courseMatch, bool, error) { return fts5CourseMatch(s), true, nil })
+		case searchTSVector:
+			out = append(out, func() (courseMatch, bool, error) { return tsvectorCourseMatch(s), true, nil })
+		}
+		out = append(out, func() (courseMatch, bool, error) { return likeCourseMatch(s), true, nil })
+	}
+	return append(out, func() (courseMatch, bool, error) { return r.fuzzyCourseMatch(q) })
+}
+
+// likeCourseMatch is the unranked LIKE search: every whitespace-separated
+// token must appear in at least one of the four searchable columns.
+func likeCourseMatch(q string) courseMatch {
+	m := courseMatch{name: searchLike}
+	for _, tok := range strings.Fields(q) {
+		pat := "%" + tok + "%"
+		m.where = append(m.where,
+			"(c.subject LIKE ? OR c.course_number LIKE ? OR c.course_name LIKE ? OR c.professor LIKE ?)")
+		m.whereArgs = append(m.whereArgs, pat, pat, pat, pat)
+	}
+	return m
+}
+
+// fts5CourseMatch is a ranked prefix search against courses_fts. bm25
+// weights the columns subject, course_number, course_name, professor. The
+// match runs in a MATERIALIZED CTE because SQLite refuses bm25() and
+// snippet() once the subquery is flattened into the outer query.
+func fts5CourseMatch(q string) courseMatch {
+	tokens := searchTokens(q)
+	quoted := make([]string, len(tokens))
+	for i, tok := range tokens {
+		quoted[i] = `"` + tok + `"*`
+	}
+	return courseMatch{
+		name: searchFTS5,
+		with: `SELECT rowid AS id,
+		              bm25(courses_fts, 8.0, 8.0, 4.0, 1.0) AS rank,
+		              snippet(courses_fts, -1, char(2), char(3), '…', 12) AS snip
+		       FROM courses_fts WHERE courses_fts MATCH ?`,
+		withArgs: []interface{}{strings.Join(quoted, " ")},
+		join:     "JOIN m ON m.id = c.id",
+		snippet:  "m.snip",
+		rank:     &sortKey{expr: "m.rank"},
+	}
+}
+
+// tsvectorCourseMatch is a ranked prefix search against
+// courses.search_vector, plus a pg_trgm similarity match on course_name so
+// small typos in a name ("algoritms") still find something.
+func tsvectorCourseMatch(q string) courseMatch {
+	tokens := searchTokens(q)
+	prefixed := make([]string, len(tokens))
+	for i, tok := range tokens {
+		prefixed[i] = tok + ":*"
+	}
+	tsq := strings.Join(prefixed, " & ")
+	q = strings.TrimSpace(q)
+	return courseMatch{
+		name:      searchTSVector,
+		where:     []string{"(c.search_vector @@ to_tsquery('simple', ?) OR c.course_name % ?)"},
+		whereArgs: []interface{}{tsq, q},
+		snippet: `ts_headline('simple',
+		                      concat_ws(' ', c.subject, c.course_number, c.course_name, c.professor),
+		                      to_tsquery('simple', ?), ?)`,
+		snippetArgs: []interface{}{tsq, "StartSel=" + snippetStart + ", StopSel=" + snippetStop + ", MinWords=3, MaxWords=16"},
+		rank: &sortKey{
+			expr: "(ts_rank_cd(c.search_vector, to_tsquery('simple', ?)) + similarity(c.course_name, ?))",
+			args: []interface{}{tsq, q},
+			desc: true,
+		},
+	}
+}
+
+// fuzzyMatchLimit caps how many courses a fuzzy search returns, which also
+// bounds the IN list it builds.
+const fuzzyMatchLimit = 200
+
+// fuzzyCourseMatch is the last resort for queries nothing else matched: it
+// compares the query's words against course names by edit distance, so
+// "algoritms" or "strucutres" still find something. Only names with a word
+// starting with the same letter as each query word are compared, so a typo
+// in the first letter isn't forgiven. ok is false when no name is close
+// enough.
+func (r *Repository) fuzzyCourseMatch(q string) (courseMatch, bool, error) {
+	queryTokens := searchTokens(q)
+	where := []string{"c.course_name IS NOT NULL"}
+	var args []interface{}
+	for _, tok := range queryTokens {
+		if utf8.RuneCountInString(tok) < 4 { // fuzzyNameMatch ignores these
+			continue
+		}
+		where = append(where, "(' ' || LOWER(c.course_name)) LIKE ?")
+		args = append(args, "% "+string([]rune(tok)[:1])+"%")
+	}
+	if len(args) == 0 {
+		return courseMatch{}, false, nil
+	}
+
+	rows, err := r.query(`SELECT c.id, c.course_name FROM courses c WHERE `+strings.Join(where, " AND ")+` ORDER BY c.id`, args...)
+	if err != nil {
+		return courseMatch{}, false, fmt.Errorf("fuzzy search courses: %w", err)
+	}
+	defer rows.Close()
+
+	var placeholders []string
+	var ids []interface{}
+	for rows.Next() && len(ids) < fuzzyMatchLimit {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return courseMatch{}, false, err
+		}
+		if fuzzyNameMatch(queryTokens, searchTokens(name)) {
+			placeholders = append(placeholders, "?")
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return courseMatch{}, false, err
+	}
+	return courseMatch{
+		name:      "fuzzy",
+		where:     []string{"c.id IN (" + strings.Join(placeholders, ",") + ")"},
+		whereArgs: ids,
+	}, true, nil
+}
+
+// searchLimitClause appends the LIMIT/OFFSET arguments to a copy of args and
+// returns the matching clause. limit ≤ 0 means no cap.
+func searchLimitClause(args []interface{}, limit, offset int) (string, []interface{}) {
+	args = append([]interface{}{}, args...)
+	if limit > 0 {
+		return "LIMIT ? OFFSET ?", append(args, limit, offset)
+	}
+	return "LIMIT -1 OFFSET ?", append(args, offset)
+}
+
+// scanSearchCourses reads rows of (course columns, instructor aggregates,
+// snippet, nKeys sort keys) as selected by courseMatchPage.
+func scanSearchCourses(rows *sql.Rows, nKeys int) ([]Course, [][]interface{}, error) {
+	defer rows.Close()
+	out := []Course{}
+	var keys [][]interface{}
+	for rows.Next() {
+		var c Course
+		var courseName, professor, snip sql.NullString
+		var avgRating, avgDifficulty sql.NullFloat64
+		var numRatings sql.NullInt64
+		rowKeys := make([]interface{}, nKeys)
+		dest := []interface{}{&c.ID, &c.Subject, &c.CourseNumber, &courseName, &professor, &c.Term,
+			&avgRating, &avgDifficulty, &numRatings, &snip}
+		for i := range rowKeys {
+			dest = append(dest, &rowKeys[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+		for i := range rowKeys {
+			rowKeys[i] = keyValue(rowKeys[i])
+		}
+		keys = append(keys, rowKeys)
+		c.CourseName = courseName.String
+		c.Professor = professor.String
+		c.Snippet = formatSnippet(snip.String)
+		if avgRating.Valid {
+			v := avgRating.Float64
+			c.AvgRating = &v
+		}
+		if avgDifficulty.Valid {
+			v := avgDifficulty.Float64
+			c.AvgDifficulty = &v
+		}
+		if numRatings.Valid {
+			v := int(numRatings.Int64)
+			c.NumRatings = &v
+		}
+		out = append(out, c)
+	}
+	return out, keys, rows.Err()
+}
+
+// searchTokens lowercases q and splits it into runs of letters and digits,
+// which is also how both full-text tokenizers split the indexed text. Any
+// punctuation, including FTS query syntax, is dropped.
+func searchTokens(q string) []string {
+	return strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// formatSnippet HTML-escapes a highlighted snippet and turns the highlight
+// markers into <mark> tags. Snippets without highlights are dropped.
+func formatSnippet(s string) string {
+	if !strings.Contains(s, snippetStart) {
+		return ""
+	}
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, snippetStart, "<mark>")
+	return strings.ReplaceAll(s, snippetStop, "</mark>")
+}
@@ -0,0 +1,415 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// newFTSTestRepo is newTestRepo plus the courses_fts index from
+// 018_courses_fts.sql. go-sqlite3 only includes FTS5 when built with
+// -tags sqlite_fts5 (as CI does), so the test is skipped otherwise.
+func newFTSTestRepo(t *testing.T) *Repository {
+	t.Helper()
+	repo := newTestRepo(t)
+	b, err := os.ReadFile(filepath.Join("..", "migrations", "018_courses_fts.sql"))
+	if err != nil {
+		t.Fatalf("read 018_courses_fts.sql: %v", err)
+	}
+	if _, err := repo.DB.Exec(string(b)); err != nil {
+		repo.Close()
+		if strings.Contains(err.Error(), "no such module: fts5") {
+			t.Skip("sqlite3 built without FTS5; run with -tags sqlite_fts5")
+		}
+		t.Fatalf("exec 018_courses_fts.sql: %v", err)
+	}
+	return repo
+}
+
+func TestSearchCourses_FullText(t *testing.T) {
+	repo := newFTSTestRepo(t)
+	defer repo.Close()
+
+	for _, c := range []struct{ subject, num, name, prof string }{
+		{"COMPSCI", "2C03", "Data Structures & Algorithms", "Dr Smith"},
+		{"COMPSCI", "3AC3", "Algorithms and Complexity", "Dr Jones"},
+		{"MATH", "1ZA3", "Engineering Mathematics", "Dr Algorithms"},
+		{"HISTORY", "1A03", "World History", "Dr Brown"},
+	} {
+		if _, err := repo.DB.Exec(
+			`INSERT INTO courses(subject, course_number, course_name, professor, term)
+			 VALUES (?, ?, ?, ?, 'Fall 2025')`, c.subject, c.num, c.name, c.prof); err != nil {
+			t.Fatalf("seed %s %s: %v", c.subject, c.num, err)
+		}
+	}
+	if backend := repo.searchBackend(); backend != searchFTS5 {
+		t.Fatalf("expected fts5 backend, got %q", backend)
+	}
+
+	t.Run("prefix match ranks name above professor", func(t *testing.T) {
+		out, total, err := repo.SearchCourses("algo", "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("SearchCourses: %v", err)
+		}
+		if total != 3 || len(out) != 3 {
+			t.Fatalf("expected 3 matches, got total=%d len=%d", total, len(out))
+		}
+		if out[2].Subject != "MATH" {
+			t.Fatalf("professor-only match should rank last, got order %v", courseCodes(out))
+		}
+	})
+
+	t.Run("code tokens are ANDed and highlighted", func(t *testing.T) {
+		out, total, err := repo.SearchCourses("compsci 2c", "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("SearchCourses: %v", err)
+		}
+		if total != 1 || out[0].CourseNumber != "2C03" {
+			t.Fatalf("expected only 2C03, got %v", courseCodes(out))
+		}
+		if !strings.Contains(out[0].Snippet, "<mark>COMPSCI</mark>") {
+			t.Fatalf("expected highlighted subject, got %q", out[0].Snippet)
+		}
+	})
+
+	t.Run("snippet is html-escaped", func(t *testing.T) {
+		out, _, err := repo.SearchCourses("structures", "", "", 0, 0)
+		if err != nil || len(out) != 1 {
+			t.Fatalf("SearchCourses: %v (%d results)", err, len(out))
+		}
+		want := "Data <mark>Structures</mark> &amp; Algorithms"
+		if out[0].Snippet != want {
+			t.Fatalf("snippet = %q, want %q", out[0].Snippet, want)
+		}
+	})
+
+	t.Run("filters apply to ranked results", func(t *testing.T) {
+		_, total, err := repo.SearchCourses("algorithms", "3", "", 0, 0)
+		if err != nil {
+			t.Fatalf("SearchCourses: %v", err)
+		}
+		if total != 1 {
+			t.Fatalf("expected 1 3000-level match, got %d", total)
+		}
+	})
+
+	t.Run("infix falls back to LIKE", func(t *testing.T) {
+		out, total, err := repo.SearchCourses("truct", "", "", 0, 0)
+		if err != nil {
+			t.Fatalf("SearchCourses: %v", err)
+		}
+		if total != 1 || out[0].Snippet != "" {
+			t.Fatalf("expected one unranked LIKE match, got %+v", out)
+		}
+	})
+
+	t.Run("triggers keep index in sync", func(t *testing.T) {
+		if _, err := repo.DB.Exec(`UPDATE courses SET course_name = 'Ancient Civilisations' WHERE subject = 'HISTORY'`); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if _, err := repo.DB.Exec(`DELETE FROM courses WHERE subject = 'MATH'`); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, total, _ := repo.SearchCourses("civil", "", "", 0, 0); total != 1 {
+			t.Fatalf("updated name not indexed (total=%d)", total)
+		}
+		if _, total, _ := repo.SearchCourses("world", "", "", 0, 0); total != 0 {
+			t.Fatalf("old name still indexed (total=%d)", total)
+		}
+		if _, total, _ := repo.SearchCourses("algo", "", "", 0, 0); total != 2 {
+			t.Fatalf("deleted row still indexed (total=%d)", total)
+		}
+	})
+
+	t.Run("rebuild", func(t *testing.T) {
+		backend, err := repo.RebuildSearchIndex()
+		if err != nil || backend != searchFTS5 {
+			t.Fatalf("RebuildSearchIndex = %q, %v", backend, err)
+		}
+		if _, total, _ := repo.SearchCourses("algo", "", "", 0, 0); total != 2 {
+			t.Fatalf("expected 2 matches after rebuild, got %d", total)
+		}
+	})
+}
+
+// TestCoursesFTS_SetupMigrations builds a database from the migrations
+// scripts/db_setup.sh runs, full-text triggers included, and checks that the
+// writes the cmd tools and catalogue triggers make go through and leave the
+// index in sync. The program and requisite seeds are skipped; they don't
+// touch courses and take seconds to load.
+func TestCoursesFTS_SetupMigrations(t *testing.T) {
+	script, err := os.ReadFile(filepath.Join("..", "scripts", "db_setup.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	repo := &Repository{DB: db}
+	defer repo.Close()
+	var fts5 int
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5); err != nil || fts5 == 0 {
+		t.Skip("sqlite3 built without FTS5; run with -tags sqlite_fts5")
+	}
+	for _, m := range regexp.MustCompile(`< (migrations/\S+\.sql)`).FindAllStringSubmatch(string(script), -1) {
+		if strings.HasSuffix(m[1], "_seed.sql") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join("..", m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(b)); err != nil {
+			t.Fatalf("%s: %v", m[1], err)
+		}
+	}
+
+	for _, stmt := range []string{
+		// cmd/backfillcoids
+		`UPDATE courses SET coid = coid WHERE id IN (SELECT id FROM courses LIMIT 5)`,
+		`INSERT INTO courses(subject, course_number, course_name, professor, term)
+		 VALUES ('COMPSCI', '4ZZ3', 'Quantum Algorithms', 'Dr Qubit', '2026 Winter')`,
+		`UPDATE courses SET professor = 'Dr Entangle' WHERE professor = 'Dr Qubit'`,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+	if _, err := db.Exec(`INSERT INTO courses_fts(courses_fts) VALUES ('integrity-check')`); err != nil {
+		t.Fatalf("index out of sync: %v", err)
+	}
+	for q, want := range map[string]string{"entangle": "COMPSCI 4ZZ3", "qubit": ""} {
+		found, err := repo.FindCourses(CourseQuery{Q: q})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(courseCodes(found.Courses), ","); got != want {
+			t.Errorf("%q after writes: got %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestSearchCourses_LikeBackend(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	backend, err := repo.RebuildSearchIndex()
+	if err != nil || backend != searchLike {
+		t.Fatalf("RebuildSearchIndex = %q, %v; want like backend without courses_fts", backend, err)
+	}
+}
+
+func TestFindCourses_FiltersFacetsSort(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	// rating/difficulty come from the linked instructors; "" = no instructor.
+	for _, c := range []struct {
+		subject, num, term string
+		rating, difficulty float64
+	}{
+		{"COMPSCI", "1MD3", "Fall 2025", 4.5, 2.0},
+		{"COMPSCI", "2C03", "Winter 2026", 3.2, 4.1},
+		{"COMPSCI", "3AC3", "Fall 2025", 0, 0},
+		{"SFWRENG", "2AA4", "Winter 2026", 2.5, 3.0},
+		{"ENGINEER", "1P13", "Fall 2025", 3.9, 3.5},
+	} {
+		res, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, professor, term)
+			VALUES (?, ?, 'Course', 'Prof', ?)`, c.subject, c.num, c.term)
+		if err != nil {
+			t.Fatalf("seed %s %s: %v", c.subject, c.num, err)
+		}
+		if c.rating == 0 {
+			continue
+		}
+		courseID, _ := res.LastInsertId()
+		name := "Prof " + c.subject + c.num
+		res, err = repo.DB.Exec(`INSERT INTO instructors(name, name_normalized, ext_avg_rating, ext_avg_difficulty, ext_num_ratings)
+			VALUES (?, ?, ?, ?, ?)`, name, strings.ToLower(name), c.rating, c.difficulty, int(c.rating*10))
+		if err != nil {
+			t.Fatalf("seed instructor: %v", err)
+		}
+		instructorID, _ := res.LastInsertId()
+		if _, err := repo.DB.Exec(`INSERT INTO course_instructors(course_row_id, instructor_id) VALUES (?, ?)`, courseID, instructorID); err != nil {
+			t.Fatalf("link instructor: %v", err)
+		}
+	}
+	if _, err := repo.DB.Exec(`INSERT INTO requisites(subject, course_number, req_subject, req_course_number, kind)
+		VALUES ('COMPSCI', '2C03', 'COMPSCI', '1MD3', 'PREREQ')`); err != nil {
+		t.Fatalf("seed requisite: %v", err)
+	}
+
+	ptr := func(f float64) *float64 { return &f }
+	yes := true
+	for _, tc := range []struct {
+		name string
+		cq   CourseQuery
+		want []string
+	}{
+		{"subjects any-of", CourseQuery{Subjects: []string{"compsci", "SFWRENG"}},
+			[]string{"COMPSCI 1MD3", "COMPSCI 2C03", "COMPSCI 3AC3", "SFWRENG 2AA4"}},
+		{"levels and terms", CourseQuery{Levels: []string{"1", "2"}, Terms: []string{"Winter"}},
+			[]string{"COMPSCI 2C03", "SFWRENG 2AA4"}},
+		{"units", CourseQuery{Units: []int{13}}, []string{"ENGINEER 1P13"}},
+		{"min rating", CourseQuery{MinRating: ptr(3.5)}, []string{"COMPSCI 1MD3", "ENGINEER 1P13"}},
+		{"max difficulty", CourseQuery{MaxDifficulty: ptr(3.0)}, []string{"COMPSCI 1MD3", "SFWRENG 2AA4"}},
+		{"has prereqs", CourseQuery{HasPrereqs: &yes}, []string{"COMPSCI 2C03"}},
+		{"sort by rating", CourseQuery{Sort: "rating"},
+			[]string{"COMPSCI 1MD3", "ENGINEER 1P13", "COMPSCI 2C03", "SFWRENG 2AA4", "COMPSCI 3AC3"}},
+		{"sort by difficulty", CourseQuery{Sort: "difficulty", Subjects: []string{"COMPSCI"}},
+			[]string{"COMPSCI 1MD3", "COMPSCI 2C03", "COMPSCI 3AC3"}},
+		{"sort by num ratings", CourseQuery{Sort: "num_ratings", Limit: 2},
+			[]string{"COMPSCI 1MD3", "ENGINEER 1P13"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := repo.FindCourses(tc.cq)
+			if err != nil {
+				t.Fatalf("FindCourses: %v", err)
+			}
+			if got := courseCodes(res.Courses); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("facets ignore their own filter", func(t *testing.T) {
+		res, err := repo.FindCourses(CourseQuery{Subjects: []string{"COMPSCI"}, MinRating: ptr(3.0), Facets: true})
+		if err != nil {
+			t.Fatalf("FindCourses: %v", err)
+		}
+		if res.Total != 2 {
+			t.Fatalf("expected 2 matches, got %d", res.Total)
+		}
+		want := map[string][]FacetCount{
+			facetSubject:    {{"COMPSCI", 2}, {"ENGINEER", 1}},
+			facetLevel:      {{"1", 1}, {"2", 1}},
+			facetTerm:       {{"Fall 2025", 1}, {"Winter 2026", 1}},
+			facetHasPrereqs: {{"false", 1}, {"true", 1}},
+			facetRating:     {{"4+", 1}, {"3-4", 1}, {"unrated", 1}},
+		}
+		for facet, counts := range want {
+			if fmt.Sprint(res.Facets[facet]) != fmt.Sprint(counts) {
+				t.Errorf("facet %s = %v, want %v", facet, res.Facets[facet], counts)
+			}
+		}
+	})
+
+	t.Run("unknown sort", func(t *testing.T) {
+		if _, err := repo.FindCourses(CourseQuery{Sort: "popularity"}); err == nil {
+			t.Fatal("expected an error for an unknown sort")
+		}
+	})
+}
+
+func TestFindCourses_Fuzzy(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	seed := func(num, name string) {
+		t.Helper()
+		if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, term)
+			VALUES ('COMPSCI', ?, ?, 'Fall 2025')`, num, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	seed("2C03", "Data Structures & Algorithms")
+	seed("1A03", "World History")
+	seed("2F03", "Théorie des éléments")
+
+	for q, want := range map[string]string{
+		"algoritms":            "COMPSCI 2C03",
+		"strucutres algoritms": "COMPSCI 2C03",
+		"élémemts":             "COMPSCI 2F03", // the first letter isn't one byte
+		"xlgorithms":           "",             // the first letter has to match
+		"wrl":                  "",             // too short to be fuzzy
+	} {
+		found, err := repo.FindCourses(CourseQuery{Q: q})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(courseCodes(found.Courses), ","); got != want {
+			t.Errorf("%q: got %q, want %q", q, got, want)
+		}
+	}
+
+	// However many names are close, the match stops at fuzzyMatchLimit.
+	for i := 0; i < fuzzyMatchLimit+5; i++ {
+		seed(fmt.Sprintf("9%03d", i), "Algorithms")
+	}
+	found, err := repo.FindCourses(CourseQuery{Q: "algoritms"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Total != fuzzyMatchLimit {
+		t.Errorf("got %d matches, want %d", found.Total, fuzzyMatchLimit)
+	}
+}
+
+func TestFormatSnippet(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"Data " + snippetStart + "Struct" + snippetStop + "ures", "Data <mark>Struct</mark>ures"},
+		{"<b>" + snippetStart + "x" + snippetStop, "&lt;b&gt;<mark>x</mark>"},
+		{"no highlight", ""},
+	} {
+		if got := formatSnippet(tc.in); got != tc.want {
+			t.Errorf("formatSnippet(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	if got := searchTokens(`CS-2C03 "algo"*`); strings.Join(got, ",") != "cs,2c03,algo" {
+		t.Errorf("searchTokens = %v", got)
+	}
+}
+
+func TestNormalizeCourseQuery(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+		ok       bool
+	}{
+		{"comp sci 2c3", "COMPSCI 2C03", true},
+		{"CS 2C03", "COMPSCI 2C03", true},
+		{"2c03", "2C03", true},
+		{"1a3", "1A03", true},
+		{"math 1za3", "MATH 1ZA3", true},
+		{"sfwr eng 2aa4", "SFWRENG 2AA4", true},
+		{"eng 1p13", "ENGINEER 1P13", true},
+		{"cs2c03 trees", "COMPSCI 2C03 TREES", true},
+		{"intro to cs 1md3", "INTRO TO COMPSCI 1MD3", true},
+		{"software eng", "", false},
+		{"cs", "", false},
+	} {
+		got, ok := normalizeCourseQuery(tc.in)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Errorf("normalizeCourseQuery(%q) = %q, %v; want %q, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"", "abc", 3},
+		{"algoritms", "algorithms", 1},
+		{"strucutres", "structures", 2},
+		{"kitten", "sitting", 3},
+	} {
+		if got := levenshtein(tc.a, tc.b); got != tc.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func courseCodes(cs []Course) []string {
+	out := make([]string, len(cs))
+	for i, c := range cs {
+		out[i] = c.Subject + " " + c.CourseNumber
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// PlanShare is a read-only link to a student's plan. The link token is only
+// known when the share is created (Token); after that it's looked up by hash.
+type PlanShare struct {
+	ShareID      int        `json:"share_id"`
+	UserID       int        `json:"-"`
+	Label        *string    `json:"label"`
+	ProgramID    *int       `json:"program_id"`
+	HideGrades   bool       `json:"hide_grades"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	ViewCount    int        `json:"view_count"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Token        string     `json:"token,omitempty"`
+}
+
+// Active reports whether the link still works at the given time.
+func (s PlanShare) Active(at time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || at.Before(*s.ExpiresAt))
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+const planShareColumns = `share_id, user_id, label, program_id, hide_grades, expires_at, revoked_at,
+	last_viewed_at, view_count, created_at`
+
+func scanPlanShare(row interface{ Scan(...interface{}) error }) (*PlanShare, error) {
+	var s PlanShare
+	var label sql.NullString
+	var programID sql.NullInt64
+	var hide int
+	var expires, revoked, viewed sql.NullTime
+	if err := row.Scan(&s.ShareID, &s.UserID, &label, &programID, &hide, &expires, &revoked,
+		&viewed, &s.ViewCount, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	if label.Valid {
+		s.Label = &label.String
+	}
+	if programID.Valid {
+		id := int(programID.Int64)
+		s.ProgramID = &id
+	}
+	s.HideGrades = hide != 0
+	if expires.Valid {
+		s.ExpiresAt = &expires.Time
+	}
+	if revoked.Valid {
+		s.RevokedAt = &revoked.Time
+	}
+	if viewed.Valid {
+		s.LastViewedAt = &viewed.Time
+	}
+	return &s, nil
+}
+
+// ListPlanShares returns the user's share links, newest first, including
+// revoked and expired ones.
+func (r *Repository) ListPlanShares(userID int) ([]PlanShare, error) {
+	rows, err := r.query(`SELECT `+planShareColumns+` FROM plan_shares
+		WHERE user_id = ? ORDER BY share_id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []PlanShare{}
+	for rows.Next() {
+		s, err := scanPlanShare(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// CreatePlanShare stores a new share link for s.UserID and returns it with
+// its token set. Only the token's hash is kept.
+func (r *Repository) CreatePlanShare(s PlanShare) (*PlanShare, error) {
+	token, err := randomURLToken(24)
+	if err != nil {
+		return nil, fmt.Errorf("share token: %w", err)
+	}
+	s.CreatedAt = time.Now().UTC()
+	id, err := r.execReturningID(`
+		INSERT INTO plan_shares (user_id, token_hash, label, program_id, hide_grades, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		"share_id", s.UserID, hashShareToken(token), s.Label, s.ProgramID, boolToInt(s.HideGrades), s.ExpiresAt, s.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert plan share: %w", err)
+	}
+	s.ShareID = int(id)
+	s.Token = token
+	return &s, nil
+}
+
+// RevokePlanShare turns off one of userID's share links. Revoking twice is
+// harmless. Returns ErrNotFound if the share doesn't exist or isn't theirs.
+func (r *Repository) RevokePlanShare(userID, shareID int) error {
+	res, err := r.exec(`UPDATE plan_shares SET revoked_at = COALESCE(revoked_at, ?)
+		WHERE share_id = ? AND user_id = ?`, time.Now().UTC(), shareID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// OpenPlanShare looks up an active share by its token and counts the view.
+// Returns (nil, nil) for a token that's unknown, revoked or expired, so
+// callers can't tell them apart.
+func (r *Repository) OpenPlanShare(token string, at time.Time) (*PlanShare, error) {
+	s, err := scanPlanShare(r.queryRow(`SELECT `+planShareColumns+` FROM plan_shares
+		WHERE token_hash = ?`, hashShareToken(token)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.Active(at) {
+		return nil, nil
+	}
+	if _, err := r.exec(`UPDATE plan_shares SET view_count = view_count + 1, last_viewed_at = ?
+		WHERE share_id = ?`, at.UTC(), s.ShareID); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// ListStandingRules returns a program's standing rules in display order.
+func (r *Repository) ListStandingRules(programID int) ([]StandingRule, error) {
+	rows, err := r.query(`
+		SELECT rule_id, program_id, kind, label, by_year, min_gpa, subject, course_number, min_grade, min_units, display_order
+		FROM program_standing_rules
+		WHERE program_id = ?
+		ORDER BY display_order, rule_id`, programID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []StandingRule{}
+	for rows.Next() {
+		var rule StandingRule
+		var byYear, minUnits sql.NullInt64
+		var minGPA sql.NullFloat64
+		var subject, courseNumber, minGrade sql.NullString
+		if err := rows.Scan(&rule.RuleID, &rule.ProgramID, &rule.Kind, &rule.Label, &byYear, &minGPA,
+			&subject, &courseNumber, &minGrade, &minUnits, &rule.DisplayOrder); err != nil {
+			return nil, err
+		}
+		if byYear.Valid {
+			y := int(byYear.Int64)
+			rule.ByYear = &y
+		}
+		if minGPA.Valid {
+			rule.MinGPA = &minGPA.Float64
+		}
+		if subject.Valid {
+			rule.Subject = &subject.String
+		}
+		if courseNumber.Valid {
+			rule.CourseNumber = &courseNumber.String
+		}
+		if minGrade.Valid {
+			rule.MinGrade = &minGrade.String
+		}
+		if minUnits.Valid {
+			u := int(minUnits.Int64)
+			rule.MinUnits = &u
+		}
+		out = append(out, rule)
+	}
+	return out, rows.Err()
+}
+
+// AdminCreateStandingRule adds a standing rule, already checked, after the
+// program's last one. Returns ErrNotFound if there's no such program.
+func (r *Repository) AdminCreateStandingRule(actorID int, rule StandingRule) (*StandingRule, error) {
+	err := r.withTx(func(tx *Tx) error {
+		var exists int
+		if err := tx.queryRow(`SELECT COUNT(*) FROM programs WHERE program_id = ?`, rule.ProgramID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrNotFound
+		}
+		if err := tx.queryRow(`SELECT COALESCE(MAX(display_order), 0) + 1 FROM program_standing_rules WHERE program_id = ?`,
+			rule.ProgramID).Scan(&rule.DisplayOrder); err != nil {
+			return err
+		}
+
+		// Only the fields for the rule's kind are kept.
+		var minGPA *float64
+		var subject, courseNumber, minGrade *string
+		var minUnits *int
+		switch rule.Kind {
+		case RuleMinGPA:
+			minGPA = rule.MinGPA
+		case RuleMinGrade:
+			subject, courseNumber, minGrade = rule.Subject, rule.CourseNumber, rule.MinGrade
+		case RuleMinUnits:
+			minUnits = rule.MinUnits
+		}
+		rule.MinGPA, rule.Subject, rule.CourseNumber, rule.MinGrade, rule.MinUnits = minGPA, subject, courseNumber, minGrade, minUnits
+
+		id, err := tx.execReturningID(`
+			INSERT INTO program_standing_rules
+				(program_id, kind, label, by_year, min_gpa, subject, course_number, min_grade, min_units, display_order)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			"rule_id",
+			rule.ProgramID, rule.Kind, rule.Label, rule.ByYear, minGPA, subject, courseNumber, minGrade, minUnits, rule.DisplayOrder,
+		)
+		if err != nil {
+			return fmt.Errorf("insert standing rule: %w", err)
+		}
+		rule.RuleID = int(id)
+		return insertAudit(tx, actorID, "standing_rule.create", "standing_rule", strconv.FormatInt(id, 10), rule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// AdminDeleteStandingRule removes a standing rule by ID.
+func (r *Repository) AdminDeleteStandingRule(actorID, ruleID int) error {
+	return r.adminAction(actorID, "standing_rule.delete", "standing_rule", strconv.Itoa(ruleID), nil, func(tx *Tx) error {
+		res, err := tx.exec(`DELETE FROM program_standing_rules WHERE rule_id = ?`, ruleID)
+		if err != nil {
+			return fmt.Errorf("delete standing rule: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const termColumns = `code, season, year, start_date, end_date, ordinal`
+
+func scanTerm(row interface{ Scan(...interface{}) error }) (*Term, error) {
+	var t Term
+	if err := row.Scan(&t.Code, &t.Season, &t.Year, &t.StartDate, &t.EndDate, &t.Ordinal); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// ListTerms returns the academic calendar for the given calendar years,
+// oldest first.
+func (r *Repository) ListTerms(fromYear, toYear int) ([]Term, error) {
+	rows, err := r.query(`SELECT `+termColumns+` FROM academic_terms
+		WHERE year BETWEEN ? AND ? ORDER BY ordinal`, fromYear, toYear)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Term{}
+	for rows.Next() {
+		t, err := scanTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// GetTerm looks a term up by code ("2025 Fall"). Terms missing from
+// academic_terms get the default calendar dates; (nil, nil) means the code
+// isn't a term at all.
+func (r *Repository) GetTerm(code string) (*Term, error) {
+	def, ok := ParseTermCode(code)
+	if !ok {
+		return nil, nil
+	}
+	t, err := scanTerm(r.queryRow(`SELECT `+termColumns+` FROM academic_terms WHERE code = ?`, def.Code))
+	if err == sql.ErrNoRows {
+		return &def, nil
+	}
+	return t, err
+}
+
+// CurrentTerm returns the term in session at the given time, per
+// academic_terms, falling back to the default calendar. Between two terms
+// (an exam break the calendar leaves uncovered) it returns the one that
+// just ended.
+func (r *Repository) CurrentTerm(at time.Time) (*Term, error) {
+	day := at.Format("2006-01-02")
+	t, err := scanTerm(r.queryRow(`SELECT `+termColumns+` FROM academic_terms
+		WHERE start_date <= ? AND ordinal >= (
+			SELECT COALESCE(MAX(ordinal), 0) FROM academic_terms WHERE end_date < ?)
+		ORDER BY ordinal DESC LIMIT 1`, day, day))
+	def := termAt(at)
+	if err == sql.ErrNoRows || (err == nil && def.Ordinal > t.Ordinal+1) {
+		// Before or well past the end of the seeded calendar.
+		return &def, nil
+	}
+	return t, err
+}
+
+// UserStartYear returns the calendar year u's first Fall term was in:
+// users.start_year when set, otherwise inferred from year_of_study and the
+// current term.
+func (r *Repository) UserStartYear(u *User, at time.Time) (int, error) {
+	if u.StartYear != nil {
+		return *u.StartYear, nil
+	}
+	cur, err := r.CurrentTerm(at)
+	if err != nil {
+		return 0, err
+	}
+	year := 1
+	if u.YearOfStudy != nil {
+		year = *u.YearOfStudy
+	}
+	return cur.AcademicYear() - (year - 1), nil
+}
+
+// PlanTerm maps a plan bucket to its calendar term for a student who
+// started in startYear.
+func (r *Repository) PlanTerm(startYear, yearIndex int, season string) (*Term, error) {
+	return r.GetTerm(planTermCode(startYear, yearIndex, season))
+}
+
+// CurrentUserTerm is the "where is this student now" resolver shared by the
+// year-advance and recommendation logic: the current calendar term, placed
+// in u's plan. YearIndex may fall outside 1-8 for students who haven't
+// started yet or have finished.
+func (r *Repository) CurrentUserTerm(u *User, at time.Time) (*UserTerm, error) {
+	cur, err := r.CurrentTerm(at)
+	if err != nil {
+		return nil, fmt.Errorf("current term: %w", err)
+	}
+	startYear, err := r.UserStartYear(u, at)
+	if err != nil {
+		return nil, err
+	}
+	return &UserTerm{
+		Term:      *cur,
+		StartYear: startYear,
+		YearIndex: cur.AcademicYear() - startYear + 1,
+	}, nil
+}
+
+// UpdateUserStartYear records the calendar year the user's first Fall term
+// was in, which anchors their plan to real terms.
+func (r *Repository) UpdateUserStartYear(userID, startYear int) error {
+	res, err := r.exec(`UPDATE users SET start_year = ? WHERE user_id = ?`, startYear, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -20,6 +20,9 @@ func newTestRepo(t *testing.T) *Repository {
 	if err != nil {
 		t.Fatalf("open db: %v", err)
 	}
+	// Every new connection to ":memory:" is a brand-new empty database, so pin
+	// the pool to one connection or transactions could land on a schema-less DB.
+	db.SetMaxOpenConns(1)
 
 	schemaPath := filepath.Join("..", "migrations", "schema_test.sql")
 	b, err := os.ReadFile(schemaPath)
@@ -191,6 +194,56 @@ func TestSearchCourses_MultiToken(t *testing.T) {
 			t.Fatalf("expected total=1, got %d", total)
 		}
 	})
+
+	// Course codes as students type them, subject aliases, and typos.
+	for _, c := range []struct{ subject, num, name string }{
+		{"COMPSCI", "2C03", "Binary Trees"},
+		{"COMPSCI", "1MD3", "Intro to Programming"},
+		{"SFWRENG", "2AA4", "Software Design I"},
+		{"ENGINEER", "1P13", "Integrated Cornerstone Design"},
+	} {
+		_, err := repo.DB.Exec(
+			`INSERT INTO courses(subject, course_number, course_name, professor, term)
+			 VALUES (?, ?, ?, 'Dr Y', '2025')`, c.subject, c.num, c.name)
+		if err != nil {
+			t.Fatalf("seed %s %s: %v", c.subject, c.num, err)
+		}
+	}
+	for _, tc := range []struct {
+		q    string
+		want string // "SUBJECT NUMBER" of the only expected match; "" for none
+	}{
+		{"comp sci 2c3", "COMPSCI 2C03"},
+		{"CS 2C03", "COMPSCI 2C03"},
+		{"2c03", "COMPSCI 2C03"},
+		{"2c3", "COMPSCI 2C03"},
+		{"cs2c03", "COMPSCI 2C03"},
+		{"CS-1md3", "COMPSCI 1MD3"},
+		{"sfwr eng 2aa4", "SFWRENG 2AA4"},
+		{"SFWR 2AA4", "SFWRENG 2AA4"},
+		{"eng 1p13", "ENGINEER 1P13"},
+		{"Algoritms", "ZZTEST 200X"},
+		{"binary tres", "COMPSCI 2C03"},
+		{"intergrated cornerstone", "ENGINEER 1P13"},
+		{"CS 9Z99", ""},
+		{"qwertyuiop", ""},
+	} {
+		t.Run("query "+tc.q, func(t *testing.T) {
+			out, total, err := repo.SearchCourses(tc.q, "", "", 0, 0)
+			if err != nil {
+				t.Fatalf("SearchCourses: %v", err)
+			}
+			if tc.want == "" {
+				if total != 0 {
+					t.Fatalf("expected no matches, got %v", courseCodes(out))
+				}
+				return
+			}
+			if total != 1 || len(out) != 1 || out[0].Subject+" "+out[0].CourseNumber != tc.want {
+				t.Fatalf("expected only %s, got total=%d %v", tc.want, total, courseCodes(out))
+			}
+		})
+	}
 }
 
 // TestSearchCourses_Pagination verifies that limit and offset control the
@@ -339,3 +392,36 @@ func TestSearchCourses_LevelTermFilter(t *testing.T) {
 		}
 	})
 }
+
+func TestCatalogCourses_FiledFromOfferings(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	// Two terms of one course: the later name wins and a coid backfilled on
+	// an offering reaches the catalogue entry.
+	for _, row := range [][2]string{{"2025 Fall", "Intro Programming"}, {"2026 Winter", "Introduction to Programming"}} {
+		if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, term) VALUES ('COMPSCI', '1MD3', ?, ?)`, row[1], row[0]); err != nil {
+			t.Fatalf("seed %v: %v", row, err)
+		}
+	}
+	if _, err := repo.DB.Exec(`UPDATE courses SET coid = 4242 WHERE term = '2025 Fall'`); err != nil {
+		t.Fatalf("backfill coid: %v", err)
+	}
+
+	c, err := repo.GetCatalogCourse("COMPSCI", "1MD3")
+	if err != nil || c == nil {
+		t.Fatalf("GetCatalogCourse: %v, %v", c, err)
+	}
+	if c.CourseName != "Introduction to Programming" || c.Coid == nil || *c.Coid != 4242 {
+		t.Fatalf("unexpected catalogue entry: %+v", c)
+	}
+
+	var n int
+	if err := repo.DB.QueryRow(`SELECT COUNT(*) FROM courses WHERE catalog_id = ?`, c.CatalogID).Scan(&n); err != nil || n != 2 {
+		t.Fatalf("expected both offerings filed under catalog_id %d, got %d (%v)", c.CatalogID, n, err)
+	}
+
+	if c, err := repo.GetCatalogCourse("COMPSCI", "9Z99"); c != nil || err != nil {
+		t.Fatalf("expected (nil, nil) for unknown course, got %v, %v", c, err)
+	}
+}
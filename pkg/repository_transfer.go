@@ -0,0 +1,176 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TransferCredit is credit granted for a course taken outside McMaster, or
+// before starting (AP, IB). It is either a specific McMaster equivalent
+// (Subject + CourseNumber) or unspecified credit at Level, optionally in a
+// Subject. Transfer credit counts towards degree requirements but never
+// towards the GPA.
+type TransferCredit struct {
+	TransferID     int       `json:"transfer_id"`
+	UserID         int       `json:"user_id"`
+	Institution    string    `json:"institution"`
+	OriginalCourse string    `json:"original_course"`
+	Subject        *string   `json:"subject"`
+	CourseNumber   *string   `json:"course_number"`
+	Units          int       `json:"units"`
+	Level          *int      `json:"level"`
+	Note           *string   `json:"note"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Code is the McMaster equivalent ("COMPSCI 1MD3"), or "" for unspecified
+// credit.
+func (t TransferCredit) Code() string {
+	if t.Subject == nil || t.CourseNumber == nil {
+		return ""
+	}
+	return *t.Subject + " " + *t.CourseNumber
+}
+
+const transferColumns = `transfer_id, user_id, institution, original_course, subject, course_number,
+	units, level, note, created_at`
+
+func scanTransferCredit(row interface{ Scan(...interface{}) error }) (*TransferCredit, error) {
+	var t TransferCredit
+	var subject, number, note sql.NullString
+	var level sql.NullInt64
+	if err := row.Scan(&t.TransferID, &t.UserID, &t.Institution, &t.OriginalCourse, &subject, &number,
+		&t.Units, &level, &note, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	if subject.Valid {
+		t.Subject = &subject.String
+	}
+	if number.Valid {
+		t.CourseNumber = &number.String
+	}
+	if level.Valid {
+		l := int(level.Int64)
+		t.Level = &l
+	}
+	if note.Valid {
+		t.Note = &note.String
+	}
+	return &t, nil
+}
+
+// ListTransferCredits returns the user's transfer credit, oldest first.
+func (r *Repository) ListTransferCredits(userID int) ([]TransferCredit, error) {
+	rows, err := r.query(`SELECT `+transferColumns+` FROM transfer_credits
+		WHERE user_id = ? ORDER BY transfer_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []TransferCredit{}
+	for rows.Next() {
+		t, err := scanTransferCredit(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *t)
+	}
+	return out, rows.Err()
+}
+
+// CheckTransferCredit normalises t (trimmed, upper-case course code; units
+// defaulted from a specific equivalent's number) and reports any field
+// that's missing or invalid. A specific equivalent must be in the
+// catalogue.
+func (r *Repository) CheckTransferCredit(t *TransferCredit) ([]FieldError, error) {
+	var fields []FieldError
+	bad := func(field, msg string) { fields = append(fields, FieldError{field, msg}) }
+
+	trim := func(p **string, upper bool) {
+		if *p == nil {
+			return
+		}
+		v := strings.TrimSpace(**p)
+		if upper {
+			v = strings.ToUpper(v)
+		}
+		if v == "" {
+			*p = nil
+			return
+		}
+		*p = &v
+	}
+	t.Institution = strings.TrimSpace(t.Institution)
+	t.OriginalCourse = strings.TrimSpace(t.OriginalCourse)
+	trim(&t.Subject, true)
+	trim(&t.CourseNumber, true)
+	trim(&t.Note, false)
+
+	if t.Institution == "" {
+		bad("institution", "is required")
+	}
+	if t.OriginalCourse == "" {
+		bad("original_course", "is required")
+	}
+	if t.Level != nil && (*t.Level < 1 || *t.Level > 4) {
+		bad("level", "must be between 1 and 4")
+	}
+	if t.CourseNumber != nil {
+		if t.Subject == nil {
+			bad("subject", "is required with course_number")
+		} else {
+			inCatalog, _, err := catalogLookup(r, *t.Subject, *t.CourseNumber)
+			if err != nil {
+				return nil, err
+			}
+			if !inCatalog {
+				bad("course_number", fmt.Sprintf("%s is not in the course catalogue; leave course_number empty for unspecified credit", t.Code()))
+			}
+		}
+		if t.Units == 0 {
+			units, err := courseUnits(r, deref(t.Subject), *t.CourseNumber)
+			if err != nil {
+				return nil, err
+			}
+			t.Units = units
+		}
+	} else if t.Level == nil {
+		bad("level", "is required for unspecified credit (no course_number)")
+	}
+	if t.Units <= 0 || t.Units > 30 {
+		bad("units", "must be between 1 and 30")
+	}
+	return fields, nil
+}
+
+// CreateTransferCredit stores t for t.UserID and returns it with its id.
+// Call CheckTransferCredit first.
+func (r *Repository) CreateTransferCredit(t TransferCredit) (*TransferCredit, error) {
+	t.CreatedAt = time.Now().UTC()
+	id, err := r.execReturningID(`
+		INSERT INTO transfer_credits (user_id, institution, original_course, subject, course_number,
+		                              units, level, note, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		"transfer_id", t.UserID, t.Institution, t.OriginalCourse, t.Subject, t.CourseNumber,
+		t.Units, t.Level, t.Note, t.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("insert transfer credit: %w", err)
+	}
+	t.TransferID = int(id)
+	return &t, nil
+}
+
+// DeleteTransferCredit removes one of userID's transfer credits. Returns
+// ErrNotFound if it doesn't exist or isn't theirs.
+func (r *Repository) DeleteTransferCredit(userID, transferID int) error {
+	res, err := r.exec(`DELETE FROM transfer_credits WHERE transfer_id = ? AND user_id = ?`, transferID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// Term rollover: once a term ends, the courses a student had PLANNED or
+// IN_PROGRESS in it are moved to UNCONFIRMED for them to resolve, and
+// year_of_study catches up with the calendar. The job is idempotent, so it
+// runs on a timer (RunRolloverWorker in cmd/api, a daily schedule in
+// cmd/lambda) rather than at the exact moment a term ends.
+
+// PlanItemChange is a plan item whose status is (or would be) changed.
+type PlanItemChange struct {
+	PlanItemID   int    `json:"plan_item_id"`
+	Subject      string `json:"subject"`
+	CourseNumber string `json:"course_number"`
+	YearIndex    int    `json:"year_index"`
+	Season       string `json:"season"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+}
+
+// planItemsToConfirm lists the user's PLANNED and IN_PROGRESS items in the
+// plan buckets past reports as over, as changes to UNCONFIRMED.
+func (r *Repository) planItemsToConfirm(userID int, past func(yearIndex int, season string) bool) ([]PlanItemChange, error) {
+	rows, err := r.query(`
+		SELECT pi.plan_item_id, pi.subject, pi.course_number, pi.status, pt.year_index, pt.season
+		FROM plan_items pi
+		JOIN plan_terms pt ON pi.plan_term_id = pt.plan_term_id
+		WHERE pt.user_id = ? AND pi.status IN ('PLANNED', 'IN_PROGRESS')
+		ORDER BY pt.year_index, `+planSeasonOrderSQL+`, pi.subject, pi.course_number`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var all []PlanItemChange
+	for rows.Next() {
+		c := PlanItemChange{To: "UNCONFIRMED"}
+		if err := rows.Scan(&c.PlanItemID, &c.Subject, &c.CourseNumber, &c.From, &c.YearIndex, &c.Season); err != nil {
+			return nil, err
+		}
+		all = append(all, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// past may query too, so only call it once the rows are closed.
+	rows.Close()
+
+	out := []PlanItemChange{}
+	for _, c := range all {
+		if past(c.YearIndex, c.Season) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// termEnded returns a planItemsToConfirm test for plan buckets whose
+// calendar term, placed by ut, had ended at the given time. A bucket with no
+// calendar term hasn't ended. The first lookup error is left in *errp.
+func (r *Repository) termEnded(ut *UserTerm, at time.Time, errp *error) func(yearIndex int, season string) bool {
+	today := at.Format("2006-01-02")
+	ended := map[string]bool{}
+	return func(yearIndex int, season string) bool {
+		code := planTermCode(ut.StartYear, yearIndex, season)
+		if done, ok := ended[code]; ok {
+			return done
+		}
+		t, err := r.GetTerm(code)
+		if err != nil && *errp == nil {
+			*errp = err
+		}
+		ended[code] = t != nil && t.EndDate < today
+		return ended[code]
+	}
+}
+
+// markUnconfirmed applies changes from planItemsToConfirm, recording each
+// in the plan history. Any item the student changed in the meantime is
+// skipped.
+func markUnconfirmed(tx *Tx, ch planChange, changes []PlanItemChange) error {
+	for _, c := range changes {
+		cur, err := getPlanItemState(tx, c.PlanItemID)
+		if err != nil {
+			return err
+		}
+		if cur == nil || (cur.Status != "PLANNED" && cur.Status != "IN_PROGRESS") {
+			continue
+		}
+		after := *cur
+		after.Status = "UNCONFIRMED"
+		if err := updatePlanItem(tx, ch, *cur, after); err != nil {
+			return fmt.Errorf("mark unconfirmed: %w", err)
+		}
+	}
+	return nil
+}
+
+// Rollover is what RolloverUser changed for one user.
+type Rollover struct {
+	UserID      int              `json:"user_id"`
+	StartYear   int              `json:"start_year"`
+	OldYear     int              `json:"old_year"`
+	NewYear     int              `json:"new_year"`
+	Unconfirmed []PlanItemChange `json:"unconfirmed"`
+}
+
+// RolloverUser brings u up to date with the calendar at the given time:
+// items in plan terms that have ended become UNCONFIRMED, and year_of_study
+// advances (never retreats) to the year the current term falls in. The
+// first run also pins users.start_year, since inferring it from
+// year_of_study would otherwise move it along with every advance.
+func (r *Repository) RolloverUser(u *User, at time.Time) (*Rollover, error) {
+	ut, err := r.CurrentUserTerm(u, at)
+	if err != nil {
+		return nil, err
+	}
+	ro := &Rollover{UserID: u.UserID, StartYear: ut.StartYear, OldYear: 1}
+	if u.YearOfStudy != nil {
+		ro.OldYear = *u.YearOfStudy
+	}
+	ro.NewYear = max(ro.OldYear, min(ut.YearIndex, 8))
+
+	var lookupErr error
+	ro.Unconfirmed, err = r.planItemsToConfirm(u.UserID, r.termEnded(ut, at, &lookupErr))
+	if err == nil {
+		err = lookupErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ro.Unconfirmed) == 0 && ro.NewYear == ro.OldYear && u.StartYear != nil && u.YearOfStudy != nil {
+		return ro, nil
+	}
+	err = r.withTx(func(tx *Tx) error {
+		if err := markUnconfirmed(tx, planChange{source: PlanSourceRollover}, ro.Unconfirmed); err != nil {
+			return err
+		}
+		_, err := tx.exec(`UPDATE users SET start_year = ?, year_of_study = ? WHERE user_id = ?`,
+			ro.StartYear, ro.NewYear, u.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rollover user %d: %w", u.UserID, err)
+	}
+	return ro, nil
+}
+
+// TermRolloverResult summarises one run of RunTermRollover.
+type TermRolloverResult struct {
+	Users       int // users checked
+	Advanced    int // users whose year_of_study went up
+	Unconfirmed int // plan items moved to UNCONFIRMED
+}
+
+// RunTermRollover rolls over every user who has told us where they are in
+// their degree (year_of_study or start_year); others have no calendar to
+// roll over against. A failure for one user is logged and skipped.
+func RunTermRollover(repo *Repository, at time.Time) (TermRolloverResult, error) {
+	var res TermRolloverResult
+	rows, err := repo.query(`SELECT user_id FROM users
+		WHERE year_of_study IS NOT NULL OR start_year IS NOT NULL
+		ORDER BY user_id`)
+	if err != nil {
+		return res, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return res, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+
+	var failed int
+	for _, id := range ids {
+		u, err := repo.GetUserByID(id)
+		if err != nil || u == nil {
+			continue
+		}
+		res.Users++
+		ro, err := repo.RolloverUser(u, at)
+		if err != nil {
+			log.Printf("[rollover] %v", err)
+			failed++
+			continue
+		}
+		if ro.NewYear > ro.OldYear {
+			res.Advanced++
+		}
+		res.Unconfirmed += len(ro.Unconfirmed)
+	}
+	if failed > 0 {
+		return res, fmt.Errorf("%d of %d users failed to roll over", failed, res.Users)
+	}
+	return res, nil
+}
+
+// RunRolloverWorker runs the term rollover every interval until ctx is
+// cancelled. Used by cmd/api; the Lambda deployment runs it on a schedule
+// instead.
+func RunRolloverWorker(ctx context.Context, repo *Repository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if res, err := RunTermRollover(repo, time.Now()); err != nil {
+			log.Printf("[rollover] error: %v", err)
+		} else if res.Advanced > 0 || res.Unconfirmed > 0 {
+			log.Printf("[rollover] %d users advanced, %d items unconfirmed", res.Advanced, res.Unconfirmed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RolloverInterval reads ROLLOVER_INTERVAL (a Go duration such as "1h"),
+// defaulting to 6h.
+func RolloverInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("ROLLOVER_INTERVAL")); err == nil && d > 0 {
+		return d
+	}
+	return 6 * time.Hour
+}
@@ -0,0 +1,185 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// seedPlan creates a student and one PLANNED item per (year_index, season)
+// bucket, returning the user id. Each call makes a new student.
+func seedPlan(t *testing.T, repo *Repository, yearOfStudy int, startYear interface{}, buckets [][2]interface{}) int {
+	t.Helper()
+	var n int
+	if err := repo.DB.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatalf("count users: %v", err)
+	}
+	userID, _ := seedUser(t, repo, fmt.Sprintf("roll%d@example.com", n+1), RoleStudent)
+	if _, err := repo.DB.Exec(`UPDATE users SET year_of_study = ?, start_year = ? WHERE user_id = ?`,
+		yearOfStudy, startYear, userID); err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	for i, b := range buckets {
+		res, err := repo.DB.Exec(`INSERT INTO plan_terms(user_id, year_index, season) VALUES (?, ?, ?)`, userID, b[0], b[1])
+		if err != nil {
+			t.Fatalf("seed plan_term %v: %v", b, err)
+		}
+		termID, _ := res.LastInsertId()
+		if _, err := repo.DB.Exec(`INSERT INTO plan_items(plan_term_id, subject, course_number, status)
+			VALUES (?, 'MATH', ?, 'PLANNED')`, termID, "1A0"+strconv.Itoa(i)); err != nil {
+			t.Fatalf("seed plan_item %v: %v", b, err)
+		}
+	}
+	return int(userID)
+}
+
+func planStatuses(t *testing.T, repo *Repository, userID int) map[string]string {
+	t.Helper()
+	items, err := repo.GetPlanItems(userID)
+	if err != nil {
+		t.Fatalf("GetPlanItems: %v", err)
+	}
+	out := map[string]string{}
+	for _, pi := range items {
+		out[pi.CourseNumber] = pi.Status
+	}
+	return out
+}
+
+func TestAdvanceUserYear_PreviewThenApply(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, [][2]interface{}{{1, "Fall"}, {1, "Winter"}, {2, "Fall"}})
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/users/"+strconv.Itoa(userID)+"/advance-year/preview?specialization=Software", nil)
+	AdvanceYearPreviewHandler(repo).ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var adv YearAdvance
+	if err := json.NewDecoder(rr.Body).Decode(&adv); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if adv.CurrentYear != 1 || adv.NewYear != 2 || adv.NewProgram != "Software" || len(adv.Unconfirmed) != 2 {
+		t.Fatalf("unexpected preview: %+v", adv)
+	}
+	if adv.Unconfirmed[0].Season != "Fall" || adv.Unconfirmed[1].Season != "Winter" {
+		t.Fatalf("expected year 1 Fall then Winter, got %+v", adv.Unconfirmed)
+	}
+	if got := planStatuses(t, repo, userID); got["1A00"] != "PLANNED" {
+		t.Fatalf("preview changed the plan: %v", got)
+	}
+
+	newYear, n, program, err := repo.AdvanceUserYear(userID, nil, time.Now())
+	if err != nil || newYear != 2 || n != 2 || program != "" {
+		t.Fatalf("AdvanceUserYear = %d, %d, %q, %v", newYear, n, program, err)
+	}
+	got := planStatuses(t, repo, userID)
+	if got["1A00"] != "UNCONFIRMED" || got["1A01"] != "UNCONFIRMED" || got["1A02"] != "PLANNED" {
+		t.Fatalf("unexpected statuses after advance: %v", got)
+	}
+}
+
+// The manual advance and the rollover job place the student on the calendar
+// the same way, so they agree on which terms have ended.
+func TestAdvanceUserYear_AgreesWithRollover(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	// Started in 2024 but still says first year: by October 2026 their year 1
+	// and year 2 Falls have ended, year 3 Winter hasn't.
+	userID := seedPlan(t, repo, 1, 2024, [][2]interface{}{{1, "Fall"}, {2, "Fall"}, {3, "Winter"}})
+	at, _ := time.Parse("2006-01-02", "2026-10-01")
+
+	adv, err := repo.PreviewAdvanceUserYear(userID, nil, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adv.StartYear != 2024 || adv.NewYear != 2 || len(adv.Unconfirmed) != 2 ||
+		adv.Unconfirmed[0].CourseNumber != "1A00" || adv.Unconfirmed[1].CourseNumber != "1A01" {
+		t.Fatalf("preview: %+v", adv)
+	}
+	u, _ := repo.GetUserByID(userID)
+	ro, err := repo.RolloverUser(u, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ro.Unconfirmed) != len(adv.Unconfirmed) {
+		t.Fatalf("rollover confirms %+v, preview %+v", ro.Unconfirmed, adv.Unconfirmed)
+	}
+	for i := range ro.Unconfirmed {
+		if ro.Unconfirmed[i].PlanItemID != adv.Unconfirmed[i].PlanItemID {
+			t.Fatalf("rollover confirms %+v, preview %+v", ro.Unconfirmed, adv.Unconfirmed)
+		}
+	}
+}
+
+func TestAdvanceUserYear_PinsStartYear(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID := seedPlan(t, repo, 1, nil, [][2]interface{}{{1, "Fall"}})
+	at, _ := time.Parse("2006-01-02", "2026-04-15")
+
+	// Inferred from first year in Winter 2026: a 2025 start. Advancing must
+	// keep it there rather than re-inferring 2026 from year 2.
+	if _, _, _, err := repo.AdvanceUserYear(userID, nil, at); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := repo.GetUserByID(userID)
+	if u.StartYear == nil || *u.StartYear != 2025 || *u.YearOfStudy != 2 {
+		t.Fatalf("expected year 2 from 2025, got %v from %v", u.YearOfStudy, u.StartYear)
+	}
+}
+
+func TestRunTermRollover(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	// A first-year who set no start year; it's inferred as 2025.
+	userID := seedPlan(t, repo, 1, nil, [][2]interface{}{{1, "Fall"}, {1, "Winter"}, {2, "Fall"}})
+
+	at := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	// Mid-Fall: nothing has ended yet, but the start year gets pinned.
+	res, err := RunTermRollover(repo, at("2025-10-01"))
+	if err != nil || res.Users != 1 || res.Advanced != 0 || res.Unconfirmed != 0 {
+		t.Fatalf("October run = %+v, %v", res, err)
+	}
+	u, _ := repo.GetUserByID(userID)
+	if u.StartYear == nil || *u.StartYear != 2025 {
+		t.Fatalf("expected start_year pinned to 2025, got %v", u.StartYear)
+	}
+
+	// January: Fall 2025 is over.
+	if res, err := RunTermRollover(repo, at("2026-01-10")); err != nil || res.Unconfirmed != 1 {
+		t.Fatalf("January run = %+v, %v", res, err)
+	}
+	if got := planStatuses(t, repo, userID); got["1A00"] != "UNCONFIRMED" || got["1A01"] != "PLANNED" {
+		t.Fatalf("unexpected statuses in January: %v", got)
+	}
+
+	// The student resolves it; a rerun leaves their answer alone.
+	if _, err := repo.DB.Exec(`UPDATE plan_items SET status = 'COMPLETED' WHERE course_number = '1A00'`); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if res, err := RunTermRollover(repo, at("2026-01-11")); err != nil || res.Unconfirmed != 0 {
+		t.Fatalf("rerun = %+v, %v", res, err)
+	}
+
+	// September 2026: Winter is over and they're now in second year.
+	if res, err := RunTermRollover(repo, at("2026-09-15")); err != nil || res.Advanced != 1 || res.Unconfirmed != 1 {
+		t.Fatalf("September run = %+v, %v", res, err)
+	}
+	u, _ = repo.GetUserByID(userID)
+	if *u.YearOfStudy != 2 || *u.StartYear != 2025 {
+		t.Fatalf("expected year 2 from 2025, got %d from %d", *u.YearOfStudy, *u.StartYear)
+	}
+	if got := planStatuses(t, repo, userID); got["1A00"] != "COMPLETED" || got["1A01"] != "UNCONFIRMED" || got["1A02"] != "PLANNED" {
+		t.Fatalf("unexpected statuses in September: %v", got)
+	}
+}
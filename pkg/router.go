@@ -32,7 +32,7 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 			http.NotFound(w, r)
 			return
 		}
-		RefreshHandler()(w, r)
+		RefreshHandler(repo)(w, r)
 	})
 	mux.HandleFunc("/api/auth/forgot-password", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -49,6 +49,19 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 		ResetPasswordHandler(repo)(w, r)
 	})
 
+	// --- SSO routes (public; only registered when OIDC_* is configured) ---
+	if cfg := OIDCConfigFromEnv(); cfg != nil {
+		provider := NewOIDCProvider(*cfg)
+		mux.HandleFunc("/api/auth/oidc/start", func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				http.NotFound(w, r)
+				return
+			}
+			OIDCStartHandler(repo, provider)(w, r)
+		})
+		mux.HandleFunc("/api/auth/oidc/callback", OIDCCallbackHandler(repo, provider))
+	}
+
 	// --- Course routes (public) ---
 	mux.HandleFunc("/api/courses", CoursesHandler(repo))
 	mux.HandleFunc("/api/courses/", func(w http.ResponseWriter, r *http.Request) {
@@ -58,6 +71,12 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 			return
 		}
 
+		// Dispatch offerings: GET /api/courses/<subject>/<number>/offerings
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/offerings") {
+			CourseOfferingsHandler(repo)(w, r)
+			return
+		}
+
 		// Dispatch: GET /api/courses/:id/instructors
 		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/instructors") {
 			CourseInstructorsHandler(repo)(w, r)
@@ -75,6 +94,10 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 		CourseHandler(repo)(w, r)
 	})
 
+	// --- Academic calendar (public) ---
+	mux.HandleFunc("/api/terms", TermsHandler(repo))
+	mux.HandleFunc("/api/terms/current", CurrentTermHandler(repo))
+
 	// --- Program routes (public) ---
 	mux.HandleFunc("/api/programs", ProgramsHandler(repo))
 	mux.HandleFunc("/api/programs/", ProgramRequirementsHandler(repo))
@@ -106,9 +129,31 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 
 	// --- User/plan routes (protected — JWT required) ---
 	mux.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {
+		// Read-only routes: the owner, or (for GET) an advisor the owner has
+		// accepted. Everything else is owner-only.
+		read := func(h http.HandlerFunc) http.HandlerFunc {
+			if r.Method == http.MethodGet {
+				return RequireAuth(RequireOwnerOrAdvisor(repo)(h))
+			}
+			return RequireAuth(RequireOwner(h))
+		}
+
+		// What-if GPA: POST /api/users/:id/gpa/what-if (saves nothing, so
+		// advisors may use it too)
+		if strings.HasSuffix(r.URL.Path, "/gpa/what-if") {
+			RequireAuth(RequireOwnerOrAdvisor(repo)(GPAWhatIfHandler(repo)))(w, r)
+			return
+		}
+
 		// GPA endpoint: GET /api/users/:id/gpa
 		if strings.HasSuffix(r.URL.Path, "/gpa") {
-			RequireAuth(RequireOwner(GetUserGPAHandler(repo)))(w, r)
+			read(GetUserGPAHandler(repo))(w, r)
+			return
+		}
+
+		// Year advance dry run: GET /api/users/:id/advance-year/preview
+		if strings.HasSuffix(r.URL.Path, "/advance-year/preview") {
+			RequireAuth(RequireOwner(AdvanceYearPreviewHandler(repo)))(w, r)
 			return
 		}
 
@@ -127,7 +172,82 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 
 		// Validation route: GET /api/users/:id/validation
 		if strings.HasSuffix(r.URL.Path, "/validation") {
-			RequireAuth(RequireOwner(GetUserValidationHandler(repo, svc)))(w, r)
+			read(GetUserValidationHandler(repo, svc))(w, r)
+			return
+		}
+
+		// Transfer credit: GET or POST /api/users/:id/transfer-credits,
+		// DELETE /api/users/:id/transfer-credits/:transferId
+		if strings.HasSuffix(r.URL.Path, "/transfer-credits") {
+			read(TransferCreditsHandler(repo))(w, r)
+			return
+		}
+		if strings.Contains(r.URL.Path, "/transfer-credits/") {
+			RequireAuth(RequireOwner(DeleteTransferCreditHandler(repo)))(w, r)
+			return
+		}
+
+		// Plan history: GET /api/users/:id/plan/history,
+		// POST /api/users/:id/plan/history/:eventId/undo and
+		// POST /api/users/:id/plan/restore
+		if strings.HasSuffix(r.URL.Path, "/plan/history") {
+			read(PlanHistoryHandler(repo))(w, r)
+			return
+		}
+		if strings.Contains(r.URL.Path, "/plan/history/") && strings.HasSuffix(r.URL.Path, "/undo") {
+			RequireAuth(RequireOwner(UndoPlanEventHandler(repo)))(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/plan/restore") {
+			RequireAuth(RequireOwner(RestorePlanHandler(repo)))(w, r)
+			return
+		}
+
+		// Plan import: POST /api/users/:id/plan/import
+		if strings.HasSuffix(r.URL.Path, "/plan/import") {
+			RequireAuth(RequireOwner(PlanImportHandler(repo)))(w, r)
+			return
+		}
+
+		// Plan export: GET /api/users/:id/plan/export
+		if strings.HasSuffix(r.URL.Path, "/plan/export") {
+			read(PlanExportHandler(repo, svc))(w, r)
+			return
+		}
+
+		// Advisors: GET /api/users/:id/advisors,
+		// POST /api/users/:id/advisors/:advisorId/accept (or /decline),
+		// DELETE /api/users/:id/advisors/:advisorId
+		if strings.HasSuffix(r.URL.Path, "/advisors") {
+			RequireAuth(RequireOwner(StudentAdvisorsHandler(repo)))(w, r)
+			return
+		}
+		if strings.Contains(r.URL.Path, "/advisors/") {
+			RequireAuth(RequireOwner(StudentAdvisorHandler(repo)))(w, r)
+			return
+		}
+
+		// Advisor comments: GET or POST /api/users/:id/plan/:itemId/comments,
+		// DELETE /api/users/:id/plan/:itemId/comments/:commentId
+		if strings.Contains(r.URL.Path, "/plan/") && (strings.HasSuffix(r.URL.Path, "/comments") || strings.Contains(r.URL.Path, "/comments/")) {
+			RequireAuth(RequireOwnerOrAdvisor(repo)(PlanItemCommentsHandler(repo)))(w, r)
+			return
+		}
+
+		// Share links: GET or POST /api/users/:id/plan/shares,
+		// DELETE /api/users/:id/plan/shares/:shareId
+		if strings.HasSuffix(r.URL.Path, "/plan/shares") {
+			RequireAuth(RequireOwner(PlanSharesHandler(repo)))(w, r)
+			return
+		}
+		if strings.Contains(r.URL.Path, "/plan/shares/") {
+			RequireAuth(RequireOwner(RevokePlanShareHandler(repo)))(w, r)
+			return
+		}
+
+		// Batch plan edits: POST /api/users/:id/plan/batch
+		if strings.HasSuffix(r.URL.Path, "/plan/batch") {
+			RequireAuth(RequireOwner(PlanBatchHandler(repo, svc)))(w, r)
 			return
 		}
 
@@ -135,7 +255,7 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 		if strings.HasSuffix(r.URL.Path, "/plan") {
 			switch r.Method {
 			case http.MethodGet:
-				RequireAuth(RequireOwner(GetUserPlanHandler(repo, svc)))(w, r)
+				read(GetUserPlanHandler(repo, svc))(w, r)
 			case http.MethodPost:
 				RequireAuth(RequireOwner(PostUserPlanHandler(repo)))(w, r)
 			default:
@@ -160,8 +280,89 @@ func NewMux(repo *Repository, svc *Service) http.Handler {
 		http.NotFound(w, r)
 	})
 
+	// --- Shared plans (public; the link token is the credential) ---
+	mux.HandleFunc("/api/shared/", SharedPlanHandler(repo, svc))
+
 	// --- Feedback route (public) ---
-	mux.HandleFunc("/api/feedback", FeedbackHandler())
+	mux.HandleFunc("/api/feedback", OptionalAuth(FeedbackHandler(repo)))
+
+	// --- Advisor routes (protected — JWT with advisor role required) ---
+	mux.HandleFunc("/api/advisor/", func(w http.ResponseWriter, r *http.Request) {
+		advisor := func(h http.HandlerFunc) {
+			RequireAuth(RequireRole(RoleAdvisor)(h))(w, r)
+		}
+
+		parts := advisorPathParts(r)
+		switch parts[0] {
+		case "invitations":
+			advisor(AdvisorInvitationsHandler(repo))
+		case "students":
+			if len(parts) == 1 {
+				advisor(AdvisorCaseloadHandler(svc))
+			} else {
+				advisor(AdvisorStudentHandler(repo))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	// --- Admin routes (protected — JWT with admin role required) ---
+	mux.HandleFunc("/api/admin/", func(w http.ResponseWriter, r *http.Request) {
+		admin := func(h http.HandlerFunc) {
+			RequireAuth(RequireRole(RoleAdmin)(h))(w, r)
+		}
+
+		parts := adminPathParts(r)
+		switch parts[0] {
+		case "users":
+			if len(parts) == 1 {
+				admin(AdminUsersHandler(repo))
+			} else {
+				admin(AdminUserHandler(repo))
+			}
+		case "reviews":
+			if len(parts) == 1 {
+				admin(AdminReviewsHandler(repo))
+			} else {
+				admin(AdminReviewHandler(repo))
+			}
+		case "requisites":
+			if len(parts) == 1 {
+				admin(AdminRequisitesHandler(repo))
+			} else {
+				admin(AdminRequisiteHandler(repo))
+			}
+		case "requirement-groups":
+			admin(AdminRequirementGroupHandler(repo))
+		case "requirement-courses":
+			admin(AdminRequirementCourseHandler(repo))
+		case "programs":
+			admin(AdminStandingRulesHandler(repo))
+		case "standing-rules":
+			admin(AdminStandingRuleHandler(repo))
+		case "courses":
+			admin(AdminCourseUnitsHandler(repo))
+		case "emails":
+			if len(parts) == 1 {
+				admin(AdminEmailsHandler(repo))
+			} else {
+				admin(AdminEmailRetryHandler(repo))
+			}
+		case "feedback":
+			if len(parts) == 1 {
+				admin(AdminFeedbackListHandler(repo))
+			} else {
+				admin(AdminFeedbackHandler(repo))
+			}
+		case "jobs":
+			admin(AdminJobsHandler(repo))
+		case "audit-log":
+			admin(AdminAuditLogHandler(repo))
+		default:
+			http.NotFound(w, r)
+		}
+	})
 
 	// Normalize incoming paths to strip a leading stage prefix (e.g. "/prod")
 	// when the remainder starts with "/api/...". API Gateway often includes
@@ -4,7 +4,6 @@ package pkg
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 )
 
@@ -12,23 +11,38 @@ type Service struct {
 	Repo *Repository
 }
 
-func unitsFromCourseNumber(courseNumber string, defaultUnits int) int {
-	if len(courseNumber) < 2 {
-		return defaultUnits
+// ValidateUserPlan loads the user's plan items and transfer credit and
+// validates them against program, including the program's standing rules.
+func (s *Service) ValidateUserPlan(userID int, program *Program) (ValidationResult, error) {
+	planItems, err := s.Repo.GetPlanItems(userID)
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("load plan items: %w", err)
 	}
-	// Last two characters encode the unit count
-	suffix := courseNumber[len(courseNumber)-2:]
-	n, err := strconv.Atoi(suffix)
-	if err != nil || n == 0 {
-		return defaultUnits
+	transfers, err := s.Repo.ListTransferCredits(userID)
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("load transfer credits: %w", err)
+	}
+	result, err := s.ValidatePlan(planItems, transfers, program)
+	if err != nil || len(program.StandingRules) == 0 {
+		return result, err
 	}
-	return n
-}
 
-func (s *Service) ValidatePlan(planItems []PlanItem, program *Program) (ValidationResult, error) {
-	// Fallback unit value when the course number suffix can't be parsed
-	const defaultUnitsPerCourse = 3
+	// Standing rules need each course's year, which plan items don't carry.
+	completed, err := s.Repo.GetCompletedCourses(userID)
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("load completed courses: %w", err)
+	}
+	remaining, err := s.Repo.GetRemainingCourses(userID)
+	if err != nil {
+		return ValidationResult{}, fmt.Errorf("load remaining courses: %w", err)
+	}
+	result.Standing = EvaluateStanding(program.StandingRules, completed, remaining, transfers)
+	return result, nil
+}
 
+// ValidatePlan checks planItems and transfers against program. Each plan
+// item's Units must be filled in, as GetPlanItems does.
+func (s *Service) ValidatePlan(planItems []PlanItem, transfers []TransferCredit, program *Program) (ValidationResult, error) {
 	// Build set of completed courses keyed as "SUBJECT COURSENUMBER"
 	// e.g. "COMPSCI 2C03", "ENGINEER 1P13"
 	completedSet := map[string]PlanItem{}
@@ -39,6 +53,25 @@ func (s *Service) ValidatePlan(planItems []PlanItem, program *Program) (Validati
 		}
 	}
 
+	// Transfer credit with a McMaster equivalent counts as that course
+	// completed, worth the units granted. Unspecified credit can't meet a
+	// named requirement, so it only adds to the unit total.
+	unspecifiedUnits, totalTransferUnits := 0, 0
+	for _, t := range transfers {
+		totalTransferUnits += t.Units
+		code := t.Code()
+		if code == "" {
+			unspecifiedUnits += t.Units
+			continue
+		}
+		if _, ok := completedSet[code]; !ok {
+			completedSet[code] = PlanItem{Subject: *t.Subject, CourseNumber: *t.CourseNumber, Status: "COMPLETED", Units: t.Units}
+		}
+	}
+	unitsFor := func(code string) int {
+		return completedSet[code].Units
+	}
+
 	prereqWarnings := []PrereqWarning{}
 	for _, pi := range planItems {
 		statusUpper := strings.ToUpper(pi.Status)
@@ -134,7 +167,7 @@ func (s *Service) ValidatePlan(planItems []PlanItem, program *Program) (Validati
 		if g.UnitsRequired != nil {
 			unitsReq = *g.UnitsRequired
 		} else if g.CoursesRequired != nil {
-			unitsReq = (*g.CoursesRequired) * defaultUnitsPerCourse
+			unitsReq = (*g.CoursesRequired) * DefaultCourseUnits
 		}
 		if unitsReq > 0 {
 			totalRequired += unitsReq
@@ -176,11 +209,7 @@ func (s *Service) ValidatePlan(planItems []PlanItem, program *Program) (Validati
 
 				if matched {
 					// Use the actual unit value of the completed course
-					units := unitsFromCourseNumber(
-						strings.TrimSpace(strings.SplitN(matchedCode, " ", 2)[1]),
-						defaultUnitsPerCourse,
-					)
-					unitsCompleted += units
+					unitsCompleted += unitsFor(matchedCode)
 				} else {
 					// None completed — add all options to missing list
 					for _, c := range chain {
@@ -197,9 +226,7 @@ func (s *Service) ValidatePlan(planItems []PlanItem, program *Program) (Validati
 
 			// Single required course (no OR alternative)
 			if _, ok := completedSet[code]; ok {
-				// Parse units from the course number suffix (e.g. "1P13" → 13)
-				units := unitsFromCourseNumber(strings.SplitN(rc.CourseCode, " ", 2)[1], defaultUnitsPerCourse)
-				unitsCompleted += units
+				unitsCompleted += unitsFor(code)
 			} else {
 				if code != "" {
 					missing = append(missing, code)
@@ -238,6 +265,8 @@ func (s *Service) ValidatePlan(planItems []PlanItem, program *Program) (Validati
 		walkGroup(root)
 	}
 
+	totalCompleted += unspecifiedUnits
+
 	unitsRemaining := totalRequired - totalCompleted
 	if unitsRemaining < 0 {
 		unitsRemaining = 0
@@ -249,5 +278,98 @@ func (s *Service) ValidatePlan(planItems []PlanItem, program *Program) (Validati
 		UnitsRemaining:      unitsRemaining,
 		Groups:              groupResults,
 		PrereqWarnings:      prereqWarnings,
+		TransferUnits:       totalTransferUnits,
 	}, nil
 }
+
+// CaseloadEntry summarises one student on an advisor's caseload.
+type CaseloadEntry struct {
+	StudentID   int      `json:"student_id"`
+	Name        string   `json:"name"`
+	Email       string   `json:"email"`
+	YearOfStudy *int     `json:"year_of_study"`
+	ProgramID   *int     `json:"program_id"`
+	Program     *string  `json:"program"`
+	GPA         *float64 `json:"gpa"`
+	// Validation is nil when the advisor hasn't set a program for the
+	// student; PrereqWarnings come from the same validation.
+	Validation     *ValidationSummary `json:"validation"`
+	PrereqWarnings []PrereqWarning    `json:"prereq_warnings"`
+}
+
+// ValidationSummary is the headline of a ValidationResult: unit totals, the
+// requirement groups still outstanding and, if the program has standing
+// rules, the overall standing (pass, at_risk or fail).
+type ValidationSummary struct {
+	TotalUnitsRequired  int      `json:"total_units_required"`
+	TotalUnitsCompleted int      `json:"total_units_completed"`
+	UnitsRemaining      int      `json:"units_remaining"`
+	Outstanding         []string `json:"outstanding"`
+	Standing            string   `json:"standing,omitempty"`
+}
+
+// Caseload summarises every student who has accepted advisorID: GPA and,
+// for students the advisor set a program for, validation against it.
+func (s *Service) Caseload(advisorID int) ([]CaseloadEntry, error) {
+	links, err := s.Repo.ListAdvisees(advisorID, AdvisorActive)
+	if err != nil {
+		return nil, err
+	}
+	programs := map[int]*Program{}
+	out := make([]CaseloadEntry, 0, len(links))
+	for _, l := range links {
+		student, err := s.Repo.GetUserByID(l.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		if student == nil {
+			continue
+		}
+		e := CaseloadEntry{
+			StudentID: l.StudentID, Name: l.StudentName, Email: l.StudentEmail,
+			YearOfStudy: student.YearOfStudy, ProgramID: l.ProgramID, Program: student.Program,
+			PrereqWarnings: []PrereqWarning{},
+		}
+		if gpa, ok, err := s.Repo.GetUserGPA(l.StudentID); err != nil {
+			return nil, err
+		} else if ok {
+			e.GPA = &gpa
+		}
+
+		if l.ProgramID != nil {
+			program, cached := programs[*l.ProgramID]
+			if !cached {
+				if program, err = s.Repo.GetProgramWithGroups(*l.ProgramID); err != nil {
+					return nil, err
+				}
+				programs[*l.ProgramID] = program
+			}
+			if program != nil {
+				result, err := s.ValidateUserPlan(l.StudentID, program)
+				if err != nil {
+					return nil, fmt.Errorf("validate student %d: %w", l.StudentID, err)
+				}
+				e.Program = &program.Name
+				e.Validation = &ValidationSummary{
+					TotalUnitsRequired:  result.TotalUnitsRequired,
+					TotalUnitsCompleted: result.TotalUnitsCompleted,
+					UnitsRemaining:      result.UnitsRemaining,
+					Outstanding:         []string{},
+				}
+				for _, g := range result.Groups {
+					if !g.IsHeader && !g.Satisfied {
+						e.Validation.Outstanding = append(e.Validation.Outstanding, g.Heading)
+					}
+				}
+				if result.Standing != nil {
+					e.Validation.Standing = result.Standing.Status
+				}
+				if result.PrereqWarnings != nil {
+					e.PrereqWarnings = result.PrereqWarnings
+				}
+			}
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
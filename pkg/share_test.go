@@ -0,0 +1,203 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanShareLinks(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	userID := seedExportPlan(t, repo)
+	programID := seedProgram(t, repo, 9, "COMPSCI 1MD3", "COMPSCI 1XC3", "MATH 1ZA3")
+	token, err := GenerateAccessToken(userID, "export@example.com", RoleStudent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherToken := seedUser(t, repo, "other@example.com", RoleStudent)
+
+	do := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	sharesPath := "/api/users/" + strconv.Itoa(userID) + "/plan/shares"
+	create := func(body string) (int, string) {
+		t.Helper()
+		rr := do("POST", sharesPath, token, body)
+		if rr.Code != 201 {
+			t.Fatalf("create share %s: expected 201, got %d: %s", body, rr.Code, rr.Body.String())
+		}
+		var out struct {
+			ShareID int    `json:"share_id"`
+			Token   string `json:"token"`
+			URL     string `json:"url"`
+		}
+		json.NewDecoder(rr.Body).Decode(&out)
+		if out.Token == "" || !strings.HasSuffix(out.URL, "/shared/"+out.Token) {
+			t.Fatalf("create share: token %q url %q", out.Token, out.URL)
+		}
+		return out.ShareID, out.Token
+	}
+
+	fullID, full := create(`{"label":"Advisor","program_id":` + strconv.Itoa(programID) + `}`)
+	_, hidden := create(`{"hide_grades":true}`)
+
+	// Anyone with the link sees the plan, validation and GPA, but nothing
+	// that identifies the account.
+	rr := do("GET", "/api/shared/"+full, "", "")
+	if rr.Code != 200 {
+		t.Fatalf("shared plan: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if cc := rr.Header().Get("Cache-Control"); !strings.Contains(cc, "no-store") {
+		t.Errorf("Cache-Control = %q", cc)
+	}
+	body := rr.Body.String()
+	for _, leak := range []string{"export@example.com", `"user_id"`, `"plan_item_id"`, "section C01"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("shared plan leaks %s: %s", leak, body)
+		}
+	}
+	var view SharedPlan
+	if err := json.Unmarshal(rr.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(view.Items) != 4 || view.Items[0].Grade == nil || view.GPA == nil || view.Validation == nil ||
+		view.Program == nil || *view.Program != "Test Program" || view.Label == nil || *view.Label != "Advisor" {
+		t.Errorf("shared plan: %s", body)
+	}
+	if view.Validation != nil && view.Validation.TotalUnitsCompleted != 6 {
+		t.Errorf("validation: %+v", view.Validation)
+	}
+
+	// A share that hides grades leaves out grades and the GPA.
+	rr = do("GET", "/api/shared/"+hidden, "", "")
+	if rr.Code != 200 {
+		t.Fatalf("hidden share: expected 200, got %d", rr.Code)
+	}
+	view = SharedPlan{}
+	json.Unmarshal(rr.Body.Bytes(), &view)
+	if !view.GradesHidden || view.GPA != nil || view.Validation != nil || strings.Contains(rr.Body.String(), `"grade"`) {
+		t.Errorf("hidden share shows grades: %s", rr.Body.String())
+	}
+
+	// Only the owner can list or revoke.
+	if rr := do("GET", sharesPath, otherToken, ""); rr.Code != 403 {
+		t.Errorf("list as another user: expected 403, got %d", rr.Code)
+	}
+	rr = do("GET", sharesPath, token, "")
+	var shares []PlanShare
+	json.NewDecoder(rr.Body).Decode(&shares)
+	if len(shares) != 2 || shares[1].ViewCount != 1 || shares[1].LastViewedAt == nil || shares[0].Token != "" {
+		t.Errorf("list shares: %s", rr.Body.String())
+	}
+
+	if rr := do("DELETE", sharesPath+"/"+strconv.Itoa(fullID), token, ""); rr.Code != 204 {
+		t.Fatalf("revoke: expected 204, got %d", rr.Code)
+	}
+	if rr := do("GET", "/api/shared/"+full, "", ""); rr.Code != 404 {
+		t.Errorf("revoked link: expected 404, got %d", rr.Code)
+	}
+	if rr := do("GET", "/api/shared/"+hidden, "", ""); rr.Code != 200 {
+		t.Errorf("other link should still work, got %d", rr.Code)
+	}
+	if rr := do("GET", "/api/shared/not-a-token", "", ""); rr.Code != 404 {
+		t.Errorf("unknown link: expected 404, got %d", rr.Code)
+	}
+	if rr := do("DELETE", sharesPath+"/999", token, ""); rr.Code != 404 {
+		t.Errorf("revoke unknown share: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestPlanShareLinks_Expiry(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID, _ := seedUser(t, repo, "expiry@example.com", RoleStudent)
+
+	now := time.Now().UTC()
+	expires := now.Add(time.Hour)
+	share, err := repo.CreatePlanShare(PlanShare{UserID: userID, ExpiresAt: &expires})
+	if err != nil {
+		t.Fatalf("CreatePlanShare: %v", err)
+	}
+	if s, err := repo.OpenPlanShare(share.Token, now); err != nil || s == nil {
+		t.Fatalf("before expiry: %v, %v", s, err)
+	}
+	if s, err := repo.OpenPlanShare(share.Token, now.Add(2*time.Hour)); err != nil || s != nil {
+		t.Errorf("after expiry: %v, %v", s, err)
+	}
+
+	rr := httptest.NewRecorder()
+	PlanSharesHandler(repo).ServeHTTP(rr, httptest.NewRequest("POST", "/api/users/"+strconv.Itoa(userID)+"/plan/shares",
+		strings.NewReader(`{"expires_at":"2001-01-01T00:00:00Z","program_id":999}`)))
+	if rr.Code != 400 || !strings.Contains(rr.Body.String(), "expires_at") || !strings.Contains(rr.Body.String(), "program_id") {
+		t.Errorf("past expiry and unknown program: %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPlanShareLinks_HideGradesStanding(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	userID := seedExportPlan(t, repo) // A+ in COMPSCI 1MD3, B in MATH 1ZA3: GPA 10
+	programID := seedProgram(t, repo, 9, "COMPSCI 1MD3", "COMPSCI 1XC3", "MATH 1ZA3")
+	minGPA, subject, number, minGrade := 7.0, "MATH", "1ZA3", "B-"
+	for _, rule := range []StandingRule{
+		{ProgramID: programID, Kind: RuleMinGPA, Label: "Continuation", MinGPA: &minGPA},
+		{ProgramID: programID, Kind: RuleMinGrade, Label: "Math", Subject: &subject, CourseNumber: &number, MinGrade: &minGrade},
+	} {
+		if _, err := repo.AdminCreateStandingRule(0, rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := GenerateAccessToken(userID, "export@example.com", RoleStudent)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	view := func(hideGrades bool) string {
+		t.Helper()
+		body := `{"program_id":` + strconv.Itoa(programID) + `,"hide_grades":` + strconv.FormatBool(hideGrades) + `}`
+		req := httptest.NewRequest("POST", "/api/users/"+strconv.Itoa(userID)+"/plan/shares", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		var share struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(rr.Body).Decode(&share)
+		if rr.Code != 201 || share.Token == "" {
+			t.Fatalf("create share: %d", rr.Code)
+		}
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest("GET", "/api/shared/"+share.Token, nil))
+		if rr.Code != 200 {
+			t.Fatalf("shared plan: expected 200, got %d", rr.Code)
+		}
+		return rr.Body.String()
+	}
+
+	if body := view(false); !strings.Contains(body, `"standing"`) || !strings.Contains(body, "GPA 10.0") {
+		t.Errorf("a share showing grades should show the standing: %s", body)
+	}
+	body := view(true)
+	for _, leak := range []string{`"standing"`, `"current"`, "GPA 10", "10.0", "B in MATH 1ZA3", "A+"} {
+		if strings.Contains(body, leak) {
+			t.Errorf("hidden share leaks %s: %s", leak, body)
+		}
+	}
+	if !strings.Contains(body, `"validation"`) {
+		t.Errorf("hidden share should still validate the plan: %s", body)
+	}
+}
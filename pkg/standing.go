@@ -0,0 +1,312 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Academic standing: program-level continuation and entry rules (minimum
+// GPA, minimum grade in a course, minimum units by a year), checked against
+// the plan alongside the requirement groups. Each rule is
+//
+//	pass     met by completed courses
+//	at_risk  not met yet, but the courses still planned could meet it
+//	fail     the plan as it stands can't meet it
+//
+// and the program's standing is the worst of its rules.
+
+// Standing rule kinds.
+const (
+	RuleMinGPA   = "min_gpa"
+	RuleMinGrade = "min_grade"
+	RuleMinUnits = "min_units"
+)
+
+// Standing states, best first.
+const (
+	StandingPass   = "pass"
+	StandingAtRisk = "at_risk"
+	StandingFail   = "fail"
+)
+
+// StandingRule is one of a program's continuation or entry rules. ByYear
+// limits a GPA or unit rule to the courses in years 1 to ByYear of the
+// plan; MinGPA is on the McMaster 12-point scale.
+type StandingRule struct {
+	RuleID       int      `json:"rule_id"`
+	ProgramID    int      `json:"program_id"`
+	Kind         string   `json:"kind"`
+	Label        string   `json:"label"`
+	ByYear       *int     `json:"by_year"`
+	MinGPA       *float64 `json:"min_gpa"`
+	Subject      *string  `json:"subject"`
+	CourseNumber *string  `json:"course_number"`
+	MinGrade     *string  `json:"min_grade"`
+	MinUnits     *int     `json:"min_units"`
+	DisplayOrder int      `json:"display_order"`
+}
+
+// Check normalises the rule (upper-case course code and grade) and reports
+// any field that's missing or out of range for its kind.
+func (rule *StandingRule) Check() []FieldError {
+	var fields []FieldError
+	bad := func(field, msg string) { fields = append(fields, FieldError{field, msg}) }
+
+	rule.Label = strings.TrimSpace(rule.Label)
+	if rule.Label == "" {
+		bad("label", "is required")
+	}
+	if rule.ByYear != nil && (*rule.ByYear < 1 || *rule.ByYear > 8) {
+		bad("by_year", "must be between 1 and 8")
+	}
+	switch rule.Kind {
+	case RuleMinGPA:
+		if rule.MinGPA == nil || *rule.MinGPA < 0 || *rule.MinGPA > McMaster12.Max {
+			bad("min_gpa", "must be between 0 and 12")
+		}
+	case RuleMinGrade:
+		for _, f := range []struct {
+			name string
+			v    **string
+		}{{"subject", &rule.Subject}, {"course_number", &rule.CourseNumber}, {"min_grade", &rule.MinGrade}} {
+			if *f.v == nil || strings.TrimSpace(**f.v) == "" {
+				bad(f.name, "is required")
+				continue
+			}
+			s := strings.ToUpper(strings.TrimSpace(**f.v))
+			*f.v = &s
+		}
+		if rule.MinGrade != nil {
+			if _, ok := McMaster12.Points[*rule.MinGrade]; !ok {
+				bad("min_grade", "must be a letter grade from A+ to F")
+			}
+		}
+	case RuleMinUnits:
+		if rule.MinUnits == nil || *rule.MinUnits <= 0 {
+			bad("min_units", "must be a positive number")
+		}
+	default:
+		bad("kind", "must be min_gpa, min_grade or min_units")
+	}
+	return fields
+}
+
+// StandingRuleResult is how the plan stands against one rule. Current is
+// the GPA or units the rule is measured on so far; Needed, for an at-risk
+// GPA rule, is the average needed over the courses still planned.
+type StandingRuleResult struct {
+	Rule    StandingRule `json:"rule"`
+	Status  string       `json:"status"`
+	Detail  string       `json:"detail"`
+	Current *float64     `json:"current"`
+	Needed  *float64     `json:"needed,omitempty"`
+}
+
+// Standing is the plan's standing against a program's rules.
+type Standing struct {
+	Status string               `json:"status"`
+	Rules  []StandingRuleResult `json:"rules"`
+}
+
+// EvaluateStanding checks the completed and remaining (planned and
+// in-progress) courses, as the repository returns them, and the transfer
+// credit against rules. Returns nil if there are no rules.
+func EvaluateStanding(rules []StandingRule, completed, remaining []GradedCourse, transfers []TransferCredit) *Standing {
+	if len(rules) == 0 {
+		return nil
+	}
+	st := &Standing{Status: StandingPass, Rules: []StandingRuleResult{}}
+	for _, rule := range rules {
+		var res StandingRuleResult
+		switch rule.Kind {
+		case RuleMinGPA:
+			res = evalMinGPA(rule, completed, remaining)
+		case RuleMinGrade:
+			res = evalMinGrade(rule, completed, remaining)
+		case RuleMinUnits:
+			res = evalMinUnits(rule, completed, remaining, transfers)
+		default:
+			continue
+		}
+		res.Rule = rule
+		if standingWorse(res.Status, st.Status) {
+			st.Status = res.Status
+		}
+		st.Rules = append(st.Rules, res)
+	}
+	return st
+}
+
+func standingWorse(a, b string) bool {
+	rank := map[string]int{StandingPass: 0, StandingAtRisk: 1, StandingFail: 2}
+	return rank[a] > rank[b]
+}
+
+// byYear keeps the courses in years 1 to year (all of them if year is nil).
+func byYear(courses []GradedCourse, year *int) []GradedCourse {
+	if year == nil {
+		return courses
+	}
+	var out []GradedCourse
+	for _, c := range courses {
+		if c.YearIndex <= *year {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func yearsPhrase(year *int) string {
+	if year == nil {
+		return ""
+	}
+	return fmt.Sprintf(" by the end of year %d", *year)
+}
+
+func evalMinGPA(rule StandingRule, completed, remaining []GradedCourse) StandingRuleResult {
+	minGPA := *rule.MinGPA
+	var counted, rest []GradedCourse
+	for _, c := range byYear(completed, rule.ByYear) {
+		if excludedReason(c) == "" {
+			counted = append(counted, c)
+		}
+	}
+	for _, c := range byYear(remaining, rule.ByYear) {
+		if !c.IsCustom {
+			rest = append(rest, c)
+		}
+	}
+	cur := gpaFigure(counted, McMaster12)
+	var res StandingRuleResult
+	if cur.HasGrades {
+		res.Current = &cur.GPA
+		if cur.GPA >= minGPA-1e-9 {
+			res.Status = StandingPass
+			res.Detail = fmt.Sprintf("GPA %.1f meets the minimum of %.1f", cur.GPA, minGPA)
+			return res
+		}
+	}
+
+	t := gpaTarget(counted, rest, McMaster12, minGPA)
+	switch {
+	case t.RemainingUnits == 0 && !cur.HasGrades:
+		res.Status = StandingAtRisk
+		res.Detail = fmt.Sprintf("no graded courses%s yet to meet a GPA of %.1f", yearsPhrase(rule.ByYear), minGPA)
+	case t.Reachable:
+		res.Status = StandingAtRisk
+		res.Needed = t.RequiredAverage
+		now := "no grades yet"
+		if cur.HasGrades {
+			now = fmt.Sprintf("GPA %.1f is below %.1f", cur.GPA, minGPA)
+		}
+		res.Detail = fmt.Sprintf("%s; needs an average of %.1f (%s) over the %d units still planned%s",
+			now, *t.RequiredAverage, t.RequiredLetter, t.RemainingUnits, yearsPhrase(rule.ByYear))
+	case t.RemainingUnits == 0:
+		res.Status = StandingFail
+		res.Detail = fmt.Sprintf("GPA %.1f is below the minimum of %.1f", cur.GPA, minGPA)
+	default:
+		res.Status = StandingFail
+		res.Detail = fmt.Sprintf("GPA %.1f can't reach %.1f even with an A+ in the %d units still planned%s",
+			cur.GPA, minGPA, t.RemainingUnits, yearsPhrase(rule.ByYear))
+	}
+	return res
+}
+
+// evalMinGrade takes the best graded attempt at the course. Transfer credit
+// carries no grade and doesn't meet a grade rule.
+func evalMinGrade(rule StandingRule, completed, remaining []GradedCourse) StandingRuleResult {
+	code := *rule.Subject + " " + *rule.CourseNumber
+	minPoints := McMaster12.Points[*rule.MinGrade]
+	is := func(c GradedCourse) bool {
+		return !c.IsCustom && strings.EqualFold(c.Subject, *rule.Subject) && strings.EqualFold(c.CourseNumber, *rule.CourseNumber)
+	}
+
+	var best *Grade
+	ungraded, passFail := false, false
+	for _, c := range byYear(completed, rule.ByYear) {
+		if !is(c) {
+			continue
+		}
+		switch {
+		case c.Grade.Counts():
+			if g := c.Grade; best == nil || McMaster12.Points[g.Letter] > McMaster12.Points[best.Letter] {
+				best = &g
+			}
+		case c.Grade.Raw == "":
+			ungraded = true
+		default:
+			passFail = true
+		}
+	}
+	planned := false
+	for _, c := range byYear(remaining, rule.ByYear) {
+		planned = planned || is(c)
+	}
+
+	var res StandingRuleResult
+	if best != nil {
+		points := McMaster12.Points[best.Letter]
+		res.Current = &points
+		if points >= minPoints {
+			res.Status = StandingPass
+			res.Detail = fmt.Sprintf("%s in %s meets the minimum of %s", best.Raw, code, *rule.MinGrade)
+			return res
+		}
+	}
+	switch {
+	case planned && best != nil:
+		res.Status = StandingAtRisk
+		res.Detail = fmt.Sprintf("%s in %s is below %s; the planned retake needs at least %s", best.Raw, code, *rule.MinGrade, *rule.MinGrade)
+	case planned:
+		res.Status = StandingAtRisk
+		res.Detail = fmt.Sprintf("%s is planned; it needs at least %s", code, *rule.MinGrade)
+	case ungraded:
+		res.Status = StandingAtRisk
+		res.Detail = fmt.Sprintf("%s is completed with no grade recorded; it needs at least %s", code, *rule.MinGrade)
+	case best != nil:
+		res.Status = StandingFail
+		res.Detail = fmt.Sprintf("%s in %s is below the minimum of %s", best.Raw, code, *rule.MinGrade)
+	case passFail:
+		res.Status = StandingFail
+		res.Detail = fmt.Sprintf("%s was taken pass/fail; it needs a grade of at least %s", code, *rule.MinGrade)
+	default:
+		res.Status = StandingFail
+		res.Detail = fmt.Sprintf("%s isn't in the plan%s; it needs at least %s", code, yearsPhrase(rule.ByYear), *rule.MinGrade)
+	}
+	return res
+}
+
+// evalMinUnits counts every completed course that earned credit, custom ones
+// included, and all transfer credit, which is granted before year 1. A
+// course with no grade, or one we can't read, is taken to have earned it.
+func evalMinUnits(rule StandingRule, completed, remaining []GradedCourse, transfers []TransferCredit) StandingRuleResult {
+	minUnits := *rule.MinUnits
+	done, planned := 0, 0
+	for _, c := range byYear(completed, rule.ByYear) {
+		if c.Grade.Raw == "" || c.Grade.Passed() || c.Grade.Excluded == GradeExcludedUnrecognised {
+			done += c.Units
+		}
+	}
+	for _, t := range transfers {
+		done += t.Units
+	}
+	for _, c := range byYear(remaining, rule.ByYear) {
+		planned += c.Units
+	}
+
+	current := float64(done)
+	res := StandingRuleResult{Current: &current}
+	switch {
+	case done >= minUnits:
+		res.Status = StandingPass
+		res.Detail = fmt.Sprintf("%d units completed meets the minimum of %d%s", done, minUnits, yearsPhrase(rule.ByYear))
+	case done+planned >= minUnits:
+		res.Status = StandingAtRisk
+		res.Detail = fmt.Sprintf("%d of %d units completed; the %d units still planned%s would meet it",
+			done, minUnits, planned, yearsPhrase(rule.ByYear))
+	default:
+		res.Status = StandingFail
+		res.Detail = fmt.Sprintf("%d units completed and %d planned%s is short of %d", done, planned, yearsPhrase(rule.ByYear), minUnits)
+	}
+	return res
+}
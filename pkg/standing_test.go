@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateStanding(t *testing.T) {
+	course := func(year int, season, subject, number, grade string) GradedCourse {
+		c := GradedCourse{YearIndex: year, Season: season, Subject: subject, CourseNumber: number, Units: 3}
+		if grade != "" {
+			c.Grade = ParseGrade(grade)
+		}
+		return c
+	}
+	completed := []GradedCourse{
+		course(1, "Fall", "COMPSCI", "1MD3", "A+"),
+		course(1, "Fall", "MATH", "1ZA3", "C"),
+		course(1, "Winter", "COMPSCI", "1XC3", "B"),
+		course(1, "Winter", "PNB", "1XD3", "P"),
+	}
+	custom := course(2, "Winter", "ARTSCI", "2XX3", "")
+	custom.IsCustom = true
+	remaining := []GradedCourse{
+		course(2, "Fall", "COMPSCI", "2C03", ""),
+		course(2, "Fall", "MATH", "1ZA3", ""),
+		course(2, "Winter", "STATS", "2D03", ""),
+		custom,
+	}
+	transfers := []TransferCredit{{Units: 6}}
+
+	i := func(v int) *int { return &v }
+	f := func(v float64) *float64 { return &v }
+	s := func(v string) *string { return &v }
+	minGPA := func(gpa float64, year *int) StandingRule {
+		return StandingRule{Kind: RuleMinGPA, Label: "GPA", MinGPA: &gpa, ByYear: year}
+	}
+	minGrade := func(subject, number, grade string, year *int) StandingRule {
+		return StandingRule{Kind: RuleMinGrade, Label: "Grade", Subject: s(subject), CourseNumber: s(number), MinGrade: s(grade), ByYear: year}
+	}
+	minUnits := func(units int, year *int) StandingRule {
+		return StandingRule{Kind: RuleMinUnits, Label: "Units", MinUnits: &units, ByYear: year}
+	}
+
+	for _, tt := range []struct {
+		name    string
+		rule    StandingRule
+		status  string
+		current *float64
+		needed  *float64
+	}{
+		// Year 1 GPA: (12+5+8)/3; the pass doesn't count.
+		{"year 1 GPA met", minGPA(8, i(1)), StandingPass, f(25.0 / 3), nil},
+		{"year 1 GPA missed, year over", minGPA(9, i(1)), StandingFail, f(25.0 / 3), nil},
+		// 75 points over 9 units, 9 McMaster units still planned: (9*18-75)/9.
+		{"GPA reachable", minGPA(9, nil), StandingAtRisk, f(25.0 / 3), f(87.0 / 9)},
+		{"GPA out of reach", minGPA(11.5, nil), StandingFail, f(25.0 / 3), nil},
+		{"grade met", minGrade("COMPSCI", "1MD3", "A", nil), StandingPass, f(12), nil},
+		{"grade met by year", minGrade("COMPSCI", "1XC3", "B", i(1)), StandingPass, f(8), nil},
+		{"grade below, retake planned", minGrade("MATH", "1ZA3", "C+", nil), StandingAtRisk, f(5), nil},
+		{"grade below, retake too late", minGrade("MATH", "1ZA3", "C+", i(1)), StandingFail, f(5), nil},
+		{"grade planned", minGrade("COMPSCI", "2C03", "B-", nil), StandingAtRisk, nil, nil},
+		{"taken pass/fail", minGrade("PNB", "1XD3", "C", nil), StandingFail, nil, nil},
+		{"not in plan", minGrade("SFWRENG", "2AA4", "C", nil), StandingFail, nil, nil},
+		// Year 1: four courses (the pass included) and the transfer credit.
+		{"units by year met", minUnits(18, i(1)), StandingPass, f(18), nil},
+		{"units by year missed", minUnits(19, i(1)), StandingFail, f(18), nil},
+		// 18 done and 12 planned, the custom course included.
+		{"units reachable", minUnits(30, nil), StandingAtRisk, f(18), nil},
+		{"units out of reach", minUnits(31, nil), StandingFail, f(18), nil},
+	} {
+		st := EvaluateStanding([]StandingRule{tt.rule}, completed, remaining, transfers)
+		if st == nil || len(st.Rules) != 1 {
+			t.Fatalf("%s: %+v", tt.name, st)
+		}
+		got := st.Rules[0]
+		if got.Status != tt.status || st.Status != tt.status || got.Detail == "" {
+			t.Errorf("%s: status %q (%s), want %q", tt.name, got.Status, got.Detail, tt.status)
+		}
+		if (got.Current == nil) != (tt.current == nil) || (got.Current != nil && !approx(*got.Current, *tt.current)) {
+			t.Errorf("%s: current %v, want %v", tt.name, got.Current, tt.current)
+		}
+		if (got.Needed == nil) != (tt.needed == nil) || (got.Needed != nil && !approx(*got.Needed, *tt.needed)) {
+			t.Errorf("%s: needed %v, want %v", tt.name, got.Needed, tt.needed)
+		}
+	}
+
+	// The standing is the worst of its rules.
+	st := EvaluateStanding([]StandingRule{minGPA(8, i(1)), minUnits(30, nil)}, completed, remaining, transfers)
+	if st.Status != StandingAtRisk || st.Rules[0].Status != StandingPass {
+		t.Errorf("pass and at risk: %+v", st)
+	}
+	if st := EvaluateStanding(nil, completed, remaining, transfers); st != nil {
+		t.Errorf("no rules: %+v", st)
+	}
+
+	// Nothing graded yet.
+	st = EvaluateStanding([]StandingRule{minGPA(6, nil)}, nil, remaining, nil)
+	if r := st.Rules[0]; r.Status != StandingAtRisk || r.Current != nil || r.Needed == nil || !approx(*r.Needed, 6) {
+		t.Errorf("nothing graded, courses planned: %+v", r)
+	}
+	st = EvaluateStanding([]StandingRule{minGPA(6, nil)}, nil, nil, nil)
+	if r := st.Rules[0]; r.Status != StandingAtRisk || r.Needed != nil {
+		t.Errorf("empty plan: %+v", r)
+	}
+}
+
+func TestStandingRuleCheck(t *testing.T) {
+	s := func(v string) *string { return &v }
+	rule := StandingRule{Kind: RuleMinGrade, Label: " Level II ", Subject: s(" math "), CourseNumber: s("1za3"), MinGrade: s("c+")}
+	if fields := rule.Check(); len(fields) != 0 || *rule.Subject != "MATH" || *rule.CourseNumber != "1ZA3" ||
+		*rule.MinGrade != "C+" || rule.Label != "Level II" {
+		t.Errorf("normalise: %+v %+v", fields, rule)
+	}
+
+	okGPA, gpa, year, units := 6.0, 13.0, 9, 0
+	for _, tt := range []struct {
+		rule  StandingRule
+		field string
+	}{
+		{StandingRule{Kind: "max_gpa", Label: "x"}, "kind"},
+		{StandingRule{Kind: RuleMinGPA, MinGPA: &okGPA}, "label"},
+		{StandingRule{Kind: RuleMinGPA, Label: "x"}, "min_gpa"},
+		{StandingRule{Kind: RuleMinGPA, Label: "x", MinGPA: &gpa}, "min_gpa"},
+		{StandingRule{Kind: RuleMinUnits, Label: "x", MinUnits: &units}, "min_units"},
+		{StandingRule{Kind: RuleMinUnits, Label: "x", MinUnits: &year, ByYear: &year}, "by_year"},
+		{StandingRule{Kind: RuleMinGrade, Label: "x", Subject: s("MATH"), MinGrade: s("B")}, "course_number"},
+		{StandingRule{Kind: RuleMinGrade, Label: "x", Subject: s("MATH"), CourseNumber: s("1ZA3"), MinGrade: s("E")}, "min_grade"},
+	} {
+		fields := tt.rule.Check()
+		if len(fields) != 1 || fields[0].Field != tt.field {
+			t.Errorf("%+v: got %+v, want one error on %s", tt.rule, fields, tt.field)
+		}
+	}
+}
+
+func TestStandingRules_AdminAndValidation(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+
+	studentID := seedExportPlan(t, repo) // Y1 Fall: A+ and B; COMPSCI 1XC3 planned
+	programID := seedProgram(t, repo, 9, "COMPSCI 1MD3", "COMPSCI 1XC3", "MATH 1ZA3")
+	studentToken, err := GenerateAccessToken(studentID, "export@example.com", RoleStudent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, adminToken := seedUser(t, repo, "admin@example.com", RoleAdmin)
+
+	do := func(method, path, bearer, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+bearer)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+	rulesPath := "/api/admin/programs/" + strconv.Itoa(programID) + "/standing-rules"
+
+	if rr := do("POST", rulesPath, studentToken, `{}`); rr.Code != 403 {
+		t.Errorf("student adding a rule: expected 403, got %d", rr.Code)
+	}
+	if rr := do("POST", rulesPath, adminToken, `{"kind":"min_gpa","label":"Continuation"}`); rr.Code != 400 {
+		t.Errorf("missing min_gpa: expected 400, got %d", rr.Code)
+	}
+	if rr := do("POST", "/api/admin/programs/9999/standing-rules", adminToken,
+		`{"kind":"min_units","label":"x","min_units":3}`); rr.Code != 404 {
+		t.Errorf("unknown program: expected 404, got %d", rr.Code)
+	}
+	var rules []StandingRule
+	for _, body := range []string{
+		`{"kind":"min_gpa","label":"Admission to Level II","min_gpa":6,"by_year":1,"min_units":99}`,
+		`{"kind":"min_grade","label":"COMPSCI 1XC3 for Level II","subject":"compsci","course_number":"1xc3","min_grade":"B"}`,
+	} {
+		rr := do("POST", rulesPath, adminToken, body)
+		if rr.Code != 201 {
+			t.Fatalf("add rule: expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var rule StandingRule
+		json.NewDecoder(rr.Body).Decode(&rule)
+		rules = append(rules, rule)
+	}
+	if rules[0].MinUnits != nil || rules[0].DisplayOrder != 1 || rules[1].DisplayOrder != 2 || *rules[1].Subject != "COMPSCI" {
+		t.Errorf("created rules: %+v", rules)
+	}
+
+	validate := func() ValidationResult {
+		rr := do("GET", "/api/users/"+strconv.Itoa(studentID)+"/validation?program_id="+strconv.Itoa(programID), studentToken, "")
+		if rr.Code != 200 {
+			t.Fatalf("validation: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var res ValidationResult
+		json.NewDecoder(rr.Body).Decode(&res)
+		return res
+	}
+	res := validate()
+	if res.Standing == nil || res.Standing.Status != StandingAtRisk || len(res.Standing.Rules) != 2 ||
+		res.Standing.Rules[0].Status != StandingPass || res.Standing.Rules[1].Status != StandingAtRisk ||
+		res.Standing.Rules[0].Rule.Label != "Admission to Level II" {
+		t.Errorf("standing: %+v", res.Standing)
+	}
+
+	rr := do("GET", rulesPath, adminToken, "")
+	var listed []StandingRule
+	json.NewDecoder(rr.Body).Decode(&listed)
+	if len(listed) != 2 {
+		t.Errorf("list rules: %s", rr.Body.String())
+	}
+	for _, rule := range rules {
+		if rr := do("DELETE", "/api/admin/standing-rules/"+strconv.Itoa(rule.RuleID), adminToken, ""); rr.Code != 204 {
+			t.Errorf("delete rule: expected 204, got %d", rr.Code)
+		}
+	}
+	if rr := do("DELETE", "/api/admin/standing-rules/"+strconv.Itoa(rules[0].RuleID), adminToken, ""); rr.Code != 404 {
+		t.Errorf("delete again: expected 404, got %d", rr.Code)
+	}
+	if res := validate(); res.Standing != nil {
+		t.Errorf("standing without rules: %+v", res.Standing)
+	}
+
+	var n int
+	repo.DB.QueryRow(`SELECT COUNT(*) FROM admin_audit_log WHERE target_type = 'standing_rule'`).Scan(&n)
+	if n != 4 {
+		t.Errorf("expected 4 audit entries, got %d", n)
+	}
+}
@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Term is one academic term. Code matches the free-text courses.term column
+// ("2025 Fall"), so offerings can be joined to the calendar. Ordinal
+// increases by one per term in calendar order, so comparing ordinals
+// compares terms chronologically; dates are ISO (YYYY-MM-DD) and inclusive.
+type Term struct {
+	Code      string `json:"code"`
+	Season    string `json:"season"`
+	Year      int    `json:"year"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Ordinal   int    `json:"ordinal"`
+}
+
+// termSeasons lists the seasons in calendar order with the default dates
+// used when academic_terms has no row for a term. Winter is the first term
+// of a calendar year but the second of an academic year, which starts in
+// Fall.
+var termSeasons = []struct {
+	name       string
+	start, end string // MM-DD
+}{
+	{"Winter", "01-01", "04-30"},
+	{"Spring", "05-01", "06-30"},
+	{"Summer", "07-01", "08-31"},
+	{"Fall", "09-01", "12-31"},
+}
+
+// planSeasonOrderSQL sorts plan_terms (aliased pt) within a year bucket in
+// academic order. Sorting on pt.season directly puts Fall after Winter.
+const planSeasonOrderSQL = `CASE pt.season WHEN 'Fall' THEN 1 WHEN 'Winter' THEN 2 WHEN 'Spring' THEN 3 WHEN 'Summer' THEN 4 END`
+
+func seasonIndex(season string) int {
+	for i, s := range termSeasons {
+		if strings.EqualFold(s.name, season) {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewTerm builds a term with the default calendar dates. ok is false for an
+// unknown season.
+func NewTerm(year int, season string) (t Term, ok bool) {
+	i := seasonIndex(season)
+	if i < 0 {
+		return Term{}, false
+	}
+	s := termSeasons[i]
+	return Term{
+		Code:      fmt.Sprintf("%d %s", year, s.name),
+		Season:    s.name,
+		Year:      year,
+		StartDate: fmt.Sprintf("%04d-%s", year, s.start),
+		EndDate:   fmt.Sprintf("%04d-%s", year, s.end),
+		Ordinal:   year*len(termSeasons) + i,
+	}, true
+}
+
+// ParseTermCode parses "2025 Fall" (or "Fall 2025") into a term with default
+// dates.
+func ParseTermCode(code string) (Term, bool) {
+	f := strings.Fields(code)
+	if len(f) != 2 {
+		return Term{}, false
+	}
+	year, err := strconv.Atoi(f[0])
+	season := f[1]
+	if err != nil {
+		year, err = strconv.Atoi(f[1])
+		season = f[0]
+	}
+	if err != nil {
+		return Term{}, false
+	}
+	return NewTerm(year, season)
+}
+
+// termAt returns the default-calendar term containing t.
+func termAt(t time.Time) Term {
+	md := t.Format("01-02")
+	for _, s := range termSeasons {
+		if md >= s.start && md <= s.end {
+			term, _ := NewTerm(t.Year(), s.name)
+			return term
+		}
+	}
+	// Unreachable: the default seasons cover the whole year.
+	term, _ := NewTerm(t.Year(), "Fall")
+	return term
+}
+
+// AcademicYear is the calendar year the term's academic year started in:
+// Fall 2025, Winter 2026 and Summer 2026 are all in academic year 2025.
+func (t Term) AcademicYear() int {
+	if t.Season == "Fall" {
+		return t.Year
+	}
+	return t.Year - 1
+}
+
+// planTermCode maps a plan bucket (year_index 1-8, season) to the calendar
+// term for a student who started in Fall of startYear.
+func planTermCode(startYear, yearIndex int, season string) string {
+	year := startYear + yearIndex - 1
+	if season != "Fall" {
+		year++
+	}
+	return fmt.Sprintf("%d %s", year, season)
+}
+
+// UserTerm is a calendar term placed in a student's plan.
+type UserTerm struct {
+	Term
+	StartYear int `json:"start_year"`
+	YearIndex int `json:"year_index"`
+}
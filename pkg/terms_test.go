@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTermCalendar(t *testing.T) {
+	fall, _ := ParseTermCode("2025 Fall")
+	winter, _ := ParseTermCode("Winter 2026")
+	if fall.Ordinal+1 != winter.Ordinal {
+		t.Fatalf("Fall 2025 (%d) should come right before Winter 2026 (%d)", fall.Ordinal, winter.Ordinal)
+	}
+	if fall.AcademicYear() != 2025 || winter.AcademicYear() != 2025 {
+		t.Fatalf("both should be in academic year 2025: %d, %d", fall.AcademicYear(), winter.AcademicYear())
+	}
+	if _, ok := ParseTermCode("2025 Autumn"); ok {
+		t.Fatalf("unknown season should not parse")
+	}
+
+	for _, c := range []struct {
+		date string
+		want string
+	}{
+		{"2025-09-01", "2025 Fall"},
+		{"2025-12-31", "2025 Fall"},
+		{"2026-01-01", "2026 Winter"},
+		{"2026-05-15", "2026 Spring"},
+		{"2026-08-31", "2026 Summer"},
+	} {
+		at, _ := time.Parse("2006-01-02", c.date)
+		if got := termAt(at).Code; got != c.want {
+			t.Errorf("termAt(%s) = %s, want %s", c.date, got, c.want)
+		}
+	}
+
+	for _, c := range []struct {
+		yearIndex int
+		season    string
+		want      string
+	}{
+		{1, "Fall", "2024 Fall"},
+		{1, "Winter", "2025 Winter"},
+		{1, "Summer", "2025 Summer"},
+		{2, "Fall", "2025 Fall"},
+	} {
+		if got := planTermCode(2024, c.yearIndex, c.season); got != c.want {
+			t.Errorf("planTermCode(2024, %d, %s) = %s, want %s", c.yearIndex, c.season, got, c.want)
+		}
+	}
+}
+
+func TestCurrentTerm(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	// Empty calendar: default dates.
+	cur, err := repo.CurrentTerm(day("2025-10-01"))
+	if err != nil || cur.Code != "2025 Fall" {
+		t.Fatalf("CurrentTerm = %+v, %v", cur, err)
+	}
+
+	// Calendar with a gap between Fall exams and Winter classes.
+	for _, row := range [][4]interface{}{
+		{"2025 Fall", "Fall", "2025-09-02", "2025-12-20"},
+		{"2026 Winter", "Winter", "2026-01-06", "2026-04-30"},
+	} {
+		term, _ := ParseTermCode(row[0].(string))
+		if _, err := repo.DB.Exec(`INSERT INTO academic_terms(code, season, year, start_date, end_date, ordinal)
+			VALUES (?, ?, ?, ?, ?, ?)`, row[0], row[1], term.Year, row[2], row[3], term.Ordinal); err != nil {
+			t.Fatalf("seed %v: %v", row, err)
+		}
+	}
+	for date, want := range map[string]string{
+		"2025-11-01": "2025 Fall",
+		"2025-12-28": "2025 Fall", // between terms: the one that just ended
+		"2026-01-06": "2026 Winter",
+		"2030-10-01": "2030 Fall", // past the seeded calendar
+	} {
+		cur, err := repo.CurrentTerm(day(date))
+		if err != nil || cur.Code != want {
+			t.Errorf("CurrentTerm(%s) = %+v, %v; want %s", date, cur, err, want)
+		}
+	}
+	if cur, _ := repo.CurrentTerm(day("2025-11-01")); cur.StartDate != "2025-09-02" {
+		t.Errorf("expected dates from academic_terms, got %+v", cur)
+	}
+
+	// A third-year student in Winter 2026 started in Fall 2023.
+	year := 3
+	ut, err := repo.CurrentUserTerm(&User{YearOfStudy: &year}, day("2026-02-01"))
+	if err != nil || ut.StartYear != 2023 || ut.YearIndex != 3 || ut.Code != "2026 Winter" {
+		t.Fatalf("CurrentUserTerm = %+v, %v", ut, err)
+	}
+	start := 2024
+	ut, err = repo.CurrentUserTerm(&User{YearOfStudy: &year, StartYear: &start}, day("2026-02-01"))
+	if err != nil || ut.YearIndex != 2 {
+		t.Fatalf("CurrentUserTerm with start_year = %+v, %v", ut, err)
+	}
+}
+
+func TestGetUserPlanHandler_TermOrder(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	res, err := repo.DB.Exec(`INSERT INTO users(email, display_name, password_hash, year_of_study, start_year)
+		VALUES ('terms@example.com', 'Terms', 'x', 1, 2025)`)
+	if err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	userID, _ := res.LastInsertId()
+	// Insert Winter first so the ordering can't come from insertion order.
+	for _, season := range []string{"Winter", "Fall"} {
+		res, err := repo.DB.Exec(`INSERT INTO plan_terms(user_id, year_index, season) VALUES (?, 1, ?)`, userID, season)
+		if err != nil {
+			t.Fatalf("seed plan_term: %v", err)
+		}
+		termID, _ := res.LastInsertId()
+		if _, err := repo.DB.Exec(`INSERT INTO plan_items(plan_term_id, subject, course_number, status)
+			VALUES (?, 'MATH', ?, 'PLANNED')`, termID, map[string]string{"Fall": "1ZA3", "Winter": "1ZB3"}[season]); err != nil {
+			t.Fatalf("seed plan_item: %v", err)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/users/"+strconv.FormatInt(userID, 10)+"/plan", nil)
+	GetUserPlanHandler(repo, &Service{Repo: repo}).ServeHTTP(rr, req)
+	if rr.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var items []struct {
+		Season string `json:"season"`
+		Term   *Term  `json:"term"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&items); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(items) != 2 || items[0].Season != "Fall" || items[1].Season != "Winter" {
+		t.Fatalf("expected Fall before Winter, got %+v", items)
+	}
+	if items[0].Term == nil || items[0].Term.Code != "2025 Fall" || items[1].Term == nil || items[1].Term.Code != "2026 Winter" {
+		t.Fatalf("unexpected terms: %+v, %+v", items[0].Term, items[1].Term)
+	}
+}
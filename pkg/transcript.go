@@ -0,0 +1,320 @@
+package pkg
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Plan import: a student's history comes in as a pasted unofficial
+// transcript, a CSV, or a plan document exported by this API, and is
+// parsed into ImportedCourse rows. Parsers only read; placing the rows in
+// the user's plan and checking them happens in PreviewPlanImport.
+
+// Import formats.
+const (
+	ImportFormatText = "text"
+	ImportFormatCSV  = "csv"
+	ImportFormatJSON = "json"
+)
+
+// PlanDocumentVersion is the current version of the JSON plan document.
+const PlanDocumentVersion = 1
+
+// PlanDocument is the versioned JSON form of a plan, as written by the
+// export endpoint and read back by import.
+type PlanDocument struct {
+	Version   int                `json:"version"`
+	StartYear *int               `json:"start_year,omitempty"`
+	Items     []PlanDocumentItem `json:"items"`
+}
+
+// PlanDocumentItem is one plan item in a PlanDocument.
+type PlanDocumentItem struct {
+	YearIndex    int     `json:"year_index"`
+	Season       string  `json:"season"`
+	Subject      string  `json:"subject"`
+	CourseNumber string  `json:"course_number"`
+	Status       string  `json:"status"`
+	Grade        *string `json:"grade,omitempty"`
+	Note         *string `json:"note,omitempty"`
+	IsCustom     bool    `json:"is_custom,omitempty"`
+}
+
+// ImportedCourse is one course read from an import. Transcript rows carry
+// a calendar Term ("2024 Winter") and get their plan bucket once the
+// student's start year is known; JSON and some CSV rows carry the bucket
+// directly. Error is set on rows that couldn't be read, which are skipped.
+type ImportedCourse struct {
+	Line         int     `json:"line"`
+	Term         string  `json:"term,omitempty"`
+	YearIndex    int     `json:"year_index,omitempty"`
+	Season       string  `json:"season,omitempty"`
+	Subject      string  `json:"subject"`
+	CourseNumber string  `json:"course_number"`
+	Units        int     `json:"units"`
+	Grade        *string `json:"grade"`
+	Status       string  `json:"status"`
+	Note         *string `json:"note,omitempty"`
+	IsCustom     bool    `json:"is_custom,omitempty"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// DetectImportFormat guesses the format of an import body from its
+// content type, falling back to its first non-blank character and a
+// comma-separated header line.
+func DetectImportFormat(contentType string, body []byte) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return ImportFormatJSON
+	case strings.Contains(contentType, "csv"):
+		return ImportFormatCSV
+	}
+	s := strings.TrimSpace(string(body))
+	if strings.HasPrefix(s, "{") {
+		return ImportFormatJSON
+	}
+	first, _, _ := strings.Cut(s, "\n")
+	first = strings.ToLower(first)
+	if strings.Count(first, ",") >= 2 && strings.Contains(first, "course") {
+		return ImportFormatCSV
+	}
+	return ImportFormatText
+}
+
+// ParseImport parses body in the given format.
+func ParseImport(format string, body []byte) ([]ImportedCourse, error) {
+	switch format {
+	case ImportFormatText:
+		return ParseTranscriptText(string(body)), nil
+	case ImportFormatCSV:
+		return ParseTranscriptCSV(strings.NewReader(string(body)))
+	case ImportFormatJSON:
+		return ParsePlanDocument(body)
+	}
+	return nil, fmt.Errorf("unknown import format %q", format)
+}
+
+// transcriptGrades are the grades a transcript may show besides the
+// letter grades on the GPA scale.
+var transcriptGrades = map[string]string{
+	"P": "COMPLETED", "COM": "COMPLETED", "CR": "COMPLETED",
+	"W": "DROPPED", "WD": "DROPPED",
+	"IP": "IN_PROGRESS",
+}
+
+// statusForGrade maps a transcript grade to a plan status. ok is false if
+// the grade isn't one we recognise.
+func statusForGrade(grade string) (status string, ok bool) {
+	if _, letter := McMaster12.Points[grade]; letter {
+		return "COMPLETED", true
+	}
+	status, ok = transcriptGrades[grade]
+	return status, ok
+}
+
+var (
+	// "2023 Fall", "Fall 2023", "Fall 2023 Term", "2024 Winter Session"
+	transcriptTermRe = regexp.MustCompile(`(?i)^\s*(?:(\d{4})\s+(fall|winter|spring|summer)|(fall|winter|spring|summer)\s+(\d{4}))\b`)
+	// "COMPSCI 1MD3 Intro to Programming 3.00 ...", "ENGINEER 1P13 ..."
+	transcriptCourseRe = regexp.MustCompile(`^\s*([A-Z][A-Z&]{1,9})\s+(\d[A-Z0-9]{2,3})\b\s*(.*)$`)
+	unitsRe            = regexp.MustCompile(`^\d{1,2}(?:\.\d+)?$`)
+)
+
+// ParseTranscriptText reads a pasted unofficial transcript: term headings
+// ("2023 Fall") followed by course lines starting with a course code, with
+// the units and grade somewhere after it ("COMPSCI 1MD3 Intro to
+// Programming 3.00 3.00 A+ 36.0"). Anything else (titles, term GPAs,
+// totals) is ignored. A course with no grade is taken as in progress.
+func ParseTranscriptText(text string) []ImportedCourse {
+	var out []ImportedCourse
+	term := ""
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if m := transcriptTermRe.FindStringSubmatch(line); m != nil {
+			code := m[1] + " " + m[2]
+			if m[1] == "" {
+				code = m[4] + " " + m[3]
+			}
+			if t, ok := ParseTermCode(code); ok {
+				term = t.Code
+			}
+			continue
+		}
+		m := transcriptCourseRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		c := ImportedCourse{Line: i + 1, Term: term, Subject: m[1], CourseNumber: m[2], Status: "IN_PROGRESS"}
+		if term == "" {
+			c.Error = "course appears before any term heading"
+		}
+
+		// Units are the first number after the code (attempted, then
+		// earned); the grade is the first recognised grade after them.
+		fields := strings.Fields(m[3])
+		for j, f := range fields {
+			if !unitsRe.MatchString(f) {
+				continue
+			}
+			u, _ := strconv.ParseFloat(f, 64)
+			c.Units = int(math.Round(u))
+			for _, g := range fields[j+1:] {
+				if status, ok := statusForGrade(strings.ToUpper(g)); ok {
+					grade := strings.ToUpper(g)
+					c.Grade, c.Status = &grade, status
+					break
+				}
+			}
+			break
+		}
+		if c.Units == 0 {
+			c.Units = UnitsFromCourseNumber(c.CourseNumber, DefaultCourseUnits)
+		}
+		if c.Grade != nil && c.Status == "IN_PROGRESS" {
+			c.Grade = nil
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// ParseTranscriptCSV reads a CSV with a header row. Recognised columns
+// (case-insensitive): term ("2023 Fall") or year_index and season;
+// subject and course_number, or course ("COMPSCI 1MD3"); and optionally
+// units, grade, status and note. Other columns are ignored.
+func ParseTranscriptCSV(r io.Reader) ([]ImportedCourse, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csv is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read csv header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	_, hasCourse := col["course"]
+	_, hasSubject := col["subject"]
+	_, hasNumber := col["course_number"]
+	if !hasCourse && !(hasSubject && hasNumber) {
+		return nil, errors.New("csv needs a course column, or subject and course_number columns")
+	}
+	_, hasTerm := col["term"]
+	_, hasYear := col["year_index"]
+	_, hasSeason := col["season"]
+	if !hasTerm && !(hasYear && hasSeason) {
+		return nil, errors.New("csv needs a term column, or year_index and season columns")
+	}
+
+	var out []ImportedCourse
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv line %d: %w", line, err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		if strings.Join(rec, "") == "" {
+			continue
+		}
+
+		c := ImportedCourse{Line: line, Subject: strings.ToUpper(get("subject")), CourseNumber: strings.ToUpper(get("course_number"))}
+		if code := get("course"); code != "" {
+			if f := strings.Fields(strings.ToUpper(code)); len(f) == 2 {
+				c.Subject, c.CourseNumber = f[0], f[1]
+			} else {
+				c.Error = fmt.Sprintf("course %q should look like \"COMPSCI 1MD3\"", code)
+			}
+		}
+		if term := get("term"); term != "" {
+			if t, ok := ParseTermCode(term); ok {
+				c.Term = t.Code
+			} else {
+				c.Error = fmt.Sprintf("term %q should look like \"2024 Winter\"", term)
+			}
+		} else {
+			c.YearIndex, _ = strconv.Atoi(get("year_index"))
+			c.Season = get("season")
+		}
+		if u := get("units"); u != "" {
+			f, err := strconv.ParseFloat(u, 64)
+			if err != nil {
+				c.Error = fmt.Sprintf("units %q is not a number", u)
+			}
+			c.Units = int(math.Round(f))
+		}
+		if c.Units == 0 {
+			c.Units = UnitsFromCourseNumber(c.CourseNumber, DefaultCourseUnits)
+		}
+		if g := strings.ToUpper(get("grade")); g != "" {
+			c.Grade = &g
+		}
+		c.Status = strings.ToUpper(get("status"))
+		if c.Status == "" {
+			c.Status = "IN_PROGRESS"
+			if c.Grade != nil {
+				if s, ok := statusForGrade(*c.Grade); ok {
+					c.Status = s
+				}
+			}
+		}
+		if n := get("note"); n != "" {
+			c.Note = &n
+		}
+		if c.Error == "" && (c.Subject == "" || c.CourseNumber == "") {
+			c.Error = "missing course"
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// ParsePlanDocument reads a JSON PlanDocument. Documents from a newer
+// version than this server understands are rejected rather than
+// half-read.
+func ParsePlanDocument(data []byte) ([]ImportedCourse, error) {
+	var doc PlanDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid plan document: %w", err)
+	}
+	if doc.Version < 1 || doc.Version > PlanDocumentVersion {
+		return nil, fmt.Errorf("unsupported plan document version %d (this server reads version %d)", doc.Version, PlanDocumentVersion)
+	}
+	out := make([]ImportedCourse, len(doc.Items))
+	for i, it := range doc.Items {
+		out[i] = ImportedCourse{
+			Line:         i + 1,
+			YearIndex:    it.YearIndex,
+			Season:       it.Season,
+			Subject:      strings.ToUpper(strings.TrimSpace(it.Subject)),
+			CourseNumber: strings.ToUpper(strings.TrimSpace(it.CourseNumber)),
+			Units:        UnitsFromCourseNumber(it.CourseNumber, DefaultCourseUnits),
+			Grade:        it.Grade,
+			Status:       it.Status,
+			Note:         it.Note,
+			IsCustom:     it.IsCustom,
+		}
+		if out[i].Status == "" {
+			out[i].Status = "PLANNED"
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,217 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// summarise renders a parsed course compactly for comparison:
+// "line:term-or-bucket:CODE:units:grade:status" or "line:error".
+func summarise(c ImportedCourse) string {
+	if c.Error != "" {
+		return fmt.Sprintf("%d:error", c.Line)
+	}
+	where := c.Term
+	if where == "" {
+		where = fmt.Sprintf("Y%d %s", c.YearIndex, c.Season)
+	}
+	grade := "-"
+	if c.Grade != nil {
+		grade = *c.Grade
+	}
+	return fmt.Sprintf("%d:%s:%s %s:%d:%s:%s", c.Line, where, c.Subject, c.CourseNumber, c.Units, grade, c.Status)
+}
+
+func TestParseImport(t *testing.T) {
+	tests := []struct {
+		file    string
+		format  string
+		want    []string
+		wantErr string
+	}{
+		{
+			file: "transcript_mosaic.txt", format: ImportFormatText,
+			want: []string{
+				"10:2023 Fall:COMPSCI 1MD3:3:A+:COMPLETED",
+				"11:2023 Fall:MATH 1ZA3:3:B:COMPLETED",
+				"12:2023 Fall:ENGINEER 1P13:13:A-:COMPLETED",
+				"17:2024 Winter:COMPSCI 1XC3:3:B+:COMPLETED",
+				"18:2024 Winter:MATH 1ZB3:3:W:DROPPED",
+				"19:2024 Winter:PHYSICS 1E03:3:COM:COMPLETED",
+				"22:2024 Fall:COMPSCI 2C03:3:-:IN_PROGRESS",
+				"23:2024 Fall:COMPSCI 2ME3:3:-:IN_PROGRESS",
+			},
+		},
+		{
+			file: "transcript_plain.txt", format: ImportFormatText,
+			want: []string{
+				"1:error",
+				"3:2022 Fall:COMPSCI 1JC3:3:A:COMPLETED",
+				"4:2022 Fall:ECON 1B03:3:C+:COMPLETED",
+				"7:2023 Winter:HIST 1DD3:3:F:COMPLETED",
+			},
+		},
+		{
+			file: "transcript.csv", format: ImportFormatCSV,
+			want: []string{
+				"2:2023 Fall:COMPSCI 1MD3:3:A+:COMPLETED",
+				"3:2023 Fall:MATH 1ZA3:3:B:COMPLETED",
+				"4:2024 Winter:COMPSCI 1XC3:3:W:DROPPED",
+				"5:2024 Fall:COMPSCI 2C03:3:-:IN_PROGRESS",
+				"6:error",
+			},
+		},
+		{
+			file: "plan_buckets.csv", format: ImportFormatCSV,
+			want: []string{
+				"2:Y1 Fall:COMPSCI 1MD3:3:A:COMPLETED",
+				"3:Y2 Fall:COMPSCI 2C03:3:-:PLANNED",
+				"4:error",
+			},
+		},
+		{
+			file: "plan_v1.json", format: ImportFormatJSON,
+			want: []string{
+				"1:Y1 Fall:COMPSCI 1MD3:3:A+:COMPLETED",
+				"2:Y1 Winter:COMPSCI 1XC3:3:-:IN_PROGRESS",
+				"3:Y2 Fall:HIST 101:1:-:PLANNED",
+			},
+		},
+		{file: "plan_v99.json", format: ImportFormatJSON, wantErr: "unsupported plan document version"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", tc.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := DetectImportFormat("", body); got != tc.format {
+				t.Errorf("DetectImportFormat = %q, want %q", got, tc.format)
+			}
+			courses, err := ParseImport(tc.format, body)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseImport: %v", err)
+			}
+			got := make([]string, len(courses))
+			for i, c := range courses {
+				got[i] = summarise(c)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("parsed:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(tc.want, "\n  "))
+			}
+		})
+	}
+}
+
+func TestParseTranscriptCSV_BadHeader(t *testing.T) {
+	for _, body := range []string{"", "term,grade\n2023 Fall,A\n", "subject,course_number,grade\nMATH,1ZA3,A\n"} {
+		if _, err := ParseTranscriptCSV(strings.NewReader(body)); err == nil {
+			t.Errorf("expected an error for %q", body)
+		}
+	}
+}
+
+func TestPlanImportHandler_PreviewThenCommit(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID, _ := seedUser(t, repo, "import@example.com", RoleStudent)
+	for _, code := range []string{"COMPSCI 1MD3", "MATH 1ZA3", "ENGINEER 1P13", "COMPSCI 1XC3", "MATH 1ZB3", "COMPSCI 2C03", "COMPSCI 2ME3"} {
+		f := strings.Fields(code)
+		if _, err := repo.DB.Exec(`INSERT INTO catalog_courses(subject, course_number) VALUES (?, ?)`, f[0], f[1]); err != nil {
+			t.Fatalf("seed catalogue: %v", err)
+		}
+	}
+	// Already planned: one course in the same term with another status, one
+	// identical, and one in a different term.
+	grade := "A+"
+	for _, s := range []PlanItemState{
+		{UserID: userID, YearIndex: 1, Season: "Winter", Subject: "COMPSCI", CourseNumber: "1XC3", Status: "PLANNED"},
+		{UserID: userID, YearIndex: 1, Season: "Fall", Subject: "COMPSCI", CourseNumber: "1MD3", Status: "COMPLETED", Grade: &grade},
+		{UserID: userID, YearIndex: 3, Season: "Fall", Subject: "COMPSCI", CourseNumber: "2ME3", Status: "PLANNED"},
+	} {
+		if _, err := repo.AddPlanItem(userID, s); err != nil {
+			t.Fatalf("AddPlanItem: %v", err)
+		}
+	}
+
+	body, err := os.ReadFile(filepath.Join("testdata", "transcript_mosaic.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/users/" + strconv.Itoa(userID) + "/plan/import"
+	run := func(query string) ImportPreview {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		PlanImportHandler(repo).ServeHTTP(rr, httptest.NewRequest("POST", path+query, strings.NewReader(string(body))))
+		if rr.Code != 200 {
+			t.Fatalf("import%s: expected 200, got %d: %s", query, rr.Code, rr.Body.String())
+		}
+		var p ImportPreview
+		if err := json.NewDecoder(rr.Body).Decode(&p); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return p
+	}
+
+	p := run("")
+	if p.Committed || p.Format != ImportFormatText || p.StartYear != 2023 || !p.SetsStartYear {
+		t.Errorf("preview header: %+v", p)
+	}
+	actions := map[string]ImportRow{}
+	for _, row := range p.Rows {
+		actions[row.Subject+" "+row.CourseNumber] = row
+	}
+	if a := actions["COMPSCI 1MD3"]; a.Action != ImportSkip {
+		t.Errorf("identical item should be skipped: %+v", a)
+	}
+	if a := actions["COMPSCI 1XC3"]; a.Action != ImportUpdate || a.Conflict == "" || a.YearIndex != 1 || a.Season != "Winter" {
+		t.Errorf("same-term item should be an update with a conflict: %+v", a)
+	}
+	if a := actions["COMPSCI 2ME3"]; a.Action != ImportAdd || !strings.Contains(a.Conflict, "year 3 Fall") || a.YearIndex != 2 {
+		t.Errorf("item planned elsewhere should be added with a conflict: %+v", a)
+	}
+	if a := actions["PHYSICS 1E03"]; !a.Unknown || !a.IsCustom || a.Action != ImportAdd {
+		t.Errorf("unknown course should be added as custom: %+v", a)
+	}
+	if p.Added != 6 || p.Updated != 1 || p.Skipped != 1 || p.Unknown != 1 || p.Conflicts != 2 {
+		t.Errorf("counts: added %d updated %d skipped %d unknown %d conflicts %d",
+			p.Added, p.Updated, p.Skipped, p.Unknown, p.Conflicts)
+	}
+	if got := planStatuses(t, repo, userID); len(got) != 3 {
+		t.Fatalf("preview must not change the plan: %v", got)
+	}
+
+	p = run("?commit=true")
+	if !p.Committed {
+		t.Fatal("expected committed")
+	}
+	got := planStatuses(t, repo, userID)
+	if got["1XC3"] != "COMPLETED" || got["1ZB3"] != "DROPPED" || got["2C03"] != "IN_PROGRESS" || got["1E03"] != "COMPLETED" {
+		t.Errorf("plan after commit: %v", got)
+	}
+	u, _ := repo.GetUserByID(userID)
+	if u.StartYear == nil || *u.StartYear != 2023 {
+		t.Errorf("start_year should be pinned to 2023, got %v", u.StartYear)
+	}
+	if _, total, _ := repo.ListPlanEvents(userID, 0, 0); total != 3+7 {
+		t.Errorf("expected 7 import events on top of 3 edits, got %d", total)
+	}
+
+	// Importing again changes nothing.
+	if p = run(""); p.Added != 0 || p.Updated != 0 {
+		t.Errorf("re-import should be a no-op: added %d updated %d", p.Added, p.Updated)
+	}
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTransferCreditsHandler(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID, _ := seedUser(t, repo, "transfer@example.com", RoleStudent)
+	if _, err := repo.DB.Exec(`INSERT INTO catalog_courses(subject, course_number) VALUES ('MATH', '1ZA3')`); err != nil {
+		t.Fatalf("seed catalogue: %v", err)
+	}
+	path := "/api/users/" + strconv.Itoa(userID) + "/transfer-credits"
+	post := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		TransferCreditsHandler(repo).ServeHTTP(rr, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := post(`{"institution": "College Board", "original_course": "AP Calculus BC", "subject": "math", "course_number": "1za3"}`)
+	if rr.Code != 201 {
+		t.Fatalf("equivalent: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"course_number":"1ZA3"`) || !strings.Contains(rr.Body.String(), `"units":3`) {
+		t.Errorf("equivalent should be normalised with units from the number: %s", rr.Body.String())
+	}
+
+	rr = post(`{"institution": "IBO", "original_course": "HL Economics", "subject": "ECON", "level": 1, "units": 6}`)
+	if rr.Code != 201 {
+		t.Fatalf("unspecified: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	for _, tc := range []struct {
+		name, body, field string
+	}{
+		{"missing institution", `{"original_course": "X", "level": 1, "units": 3}`, "institution"},
+		{"unknown equivalent", `{"institution": "U", "original_course": "X", "subject": "MATH", "course_number": "9Z99"}`, "course_number"},
+		{"unspecified without level", `{"institution": "U", "original_course": "X", "units": 3}`, "level"},
+		{"no units", `{"institution": "U", "original_course": "X", "level": 2}`, "units"},
+	} {
+		rr := post(tc.body)
+		if rr.Code != 400 || !strings.Contains(rr.Body.String(), `"field":"`+tc.field+`"`) {
+			t.Errorf("%s: expected 400 on %s, got %d: %s", tc.name, tc.field, rr.Code, rr.Body.String())
+		}
+	}
+
+	credits, err := repo.ListTransferCredits(userID)
+	if err != nil || len(credits) != 2 {
+		t.Fatalf("ListTransferCredits: %v, %d credits", err, len(credits))
+	}
+
+	otherID, _ := seedUser(t, repo, "other@example.com", RoleStudent)
+	rr = httptest.NewRecorder()
+	DeleteTransferCreditHandler(repo).ServeHTTP(rr, httptest.NewRequest("DELETE",
+		"/api/users/"+strconv.Itoa(otherID)+"/transfer-credits/"+strconv.Itoa(credits[0].TransferID), nil))
+	if rr.Code != 404 {
+		t.Errorf("delete another user's credit: expected 404, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	DeleteTransferCreditHandler(repo).ServeHTTP(rr, httptest.NewRequest("DELETE",
+		path+"/"+strconv.Itoa(credits[0].TransferID), nil))
+	if rr.Code != 204 {
+		t.Errorf("delete: expected 204, got %d", rr.Code)
+	}
+}
+
+func TestValidatePlan_TransferCredit(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	svc := &Service{Repo: repo}
+	userID, _ := seedUser(t, repo, "transfer@example.com", RoleStudent)
+	programID := seedProgram(t, repo, 6, "COMPSCI 1MD3", "COMPSCI 1XC3")
+	program, err := repo.GetProgramWithGroups(programID)
+	if err != nil {
+		t.Fatalf("GetProgramWithGroups: %v", err)
+	}
+
+	subject, number, level := "COMPSCI", "1MD3", 1
+	for _, tc := range []TransferCredit{
+		{UserID: userID, Institution: "Mohawk", OriginalCourse: "COMP 10001", Subject: &subject, CourseNumber: &number, Units: 3},
+		{UserID: userID, Institution: "IBO", OriginalCourse: "HL Physics", Level: &level, Units: 6},
+	} {
+		if _, err := repo.CreateTransferCredit(tc); err != nil {
+			t.Fatalf("CreateTransferCredit: %v", err)
+		}
+	}
+
+	res, err := svc.ValidateUserPlan(userID, program)
+	if err != nil {
+		t.Fatalf("ValidateUserPlan: %v", err)
+	}
+	if res.Groups[0].UnitsCompleted != 3 || len(res.Groups[0].MissingCourses) != 1 {
+		t.Errorf("equivalent should meet COMPSCI 1MD3: %+v", res.Groups[0])
+	}
+	if res.TotalUnitsCompleted != 9 || res.TransferUnits != 9 || res.UnitsRemaining != 0 {
+		t.Errorf("totals: completed %d, transfer %d, remaining %d",
+			res.TotalUnitsCompleted, res.TransferUnits, res.UnitsRemaining)
+	}
+}
+
+func TestGetUserGPA_ExcludesCustomAndTransfer(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	userID, _ := seedUser(t, repo, "gpa@example.com", RoleStudent)
+
+	aPlus, f := "A+", "F"
+	for _, s := range []PlanItemState{
+		{UserID: userID, YearIndex: 1, Season: "Fall", Subject: "COMPSCI", CourseNumber: "1MD3", Status: "COMPLETED", Grade: &aPlus},
+		{UserID: userID, YearIndex: 1, Season: "Fall", Subject: "HIST", CourseNumber: "101", Status: "COMPLETED", Grade: &f, IsCustom: true},
+	} {
+		if _, err := repo.AddPlanItem(userID, s); err != nil {
+			t.Fatalf("AddPlanItem: %v", err)
+		}
+	}
+	level := 1
+	if _, err := repo.CreateTransferCredit(TransferCredit{UserID: userID, Institution: "U", OriginalCourse: "X", Level: &level, Units: 6}); err != nil {
+		t.Fatalf("CreateTransferCredit: %v", err)
+	}
+
+	gpa, ok, err := repo.GetUserGPA(userID)
+	if err != nil || !ok || gpa != 12 {
+		t.Errorf("GetUserGPA = %v, %v, %v; want 12 from the McMaster course only", gpa, ok, err)
+	}
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Course units. A catalogue course's units are catalog_courses.units when
+// known, scraped from the academic calendar (cmd/scrapeunits) or curated by
+// an admin. Otherwise they're guessed from the course number by
+// UnitsFromCourseNumber. Validation, the GPA, search and the plan all read
+// units this way: in Go through courseUnits, in SQL through unitsSQL.
+
+// DefaultCourseUnits is what a course is taken to be worth when neither the
+// catalogue nor its number says.
+const DefaultCourseUnits = 3
+
+// Where a catalogue course's units came from.
+const (
+	UnitsScraped = "scraped"
+	UnitsCurated = "curated"
+)
+
+// UnitsFromCourseNumber guesses a course's units from its number, for
+// courses the catalogue has no units for. McMaster numbers end in the unit
+// count: "2C03" is 3 units, "1P13" is 13, "1A00" is a zero-unit lab, and
+// where the letters run on, "1ZA3" is 3. A number from elsewhere ending in
+// 00 ("HIST 100") isn't a unit count and gets defaultUnits.
+func UnitsFromCourseNumber(courseNumber string, defaultUnits int) int {
+	n := len(courseNumber)
+	digit := func(i int) bool { return i >= 0 && courseNumber[i] >= '0' && courseNumber[i] <= '9' }
+	switch {
+	case digit(n-2) && digit(n-1):
+		u, _ := strconv.Atoi(courseNumber[n-2:])
+		if u == 0 && !mcmasterNumber(courseNumber) {
+			return defaultUnits
+		}
+		return u
+	case digit(n - 1):
+		return int(courseNumber[n-1] - '0')
+	}
+	return defaultUnits
+}
+
+// mcmasterNumber reports whether courseNumber looks like a McMaster one: a
+// level digit then a letter, as in "1A00".
+func mcmasterNumber(courseNumber string) bool {
+	if len(courseNumber) < 2 {
+		return false
+	}
+	c := courseNumber[1]
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+// unitsOr returns the catalogue's units if known, else the guess from the
+// course number.
+func unitsOr(catalog sql.NullInt64, courseNumber string) int {
+	if catalog.Valid {
+		return int(catalog.Int64)
+	}
+	return UnitsFromCourseNumber(courseNumber, DefaultCourseUnits)
+}
+
+// unitsSQL is courseUnits as an SQL expression: catalogUnits (a column or
+// subquery, NULL when unknown) or else UnitsFromCourseNumber's guess from
+// the courseNumber column. Works in SQLite and PostgreSQL.
+func unitsSQL(catalogUnits, courseNumber string) string {
+	last2 := fmt.Sprintf("SUBSTR(%[1]s, LENGTH(%[1]s) - 1, 2)", courseNumber)
+	digit := func(pos string) string {
+		return fmt.Sprintf("SUBSTR(%[1]s, %[2]s, 1) BETWEEN '0' AND '9'", courseNumber, pos)
+	}
+	n := fmt.Sprintf("LENGTH(%s)", courseNumber)
+	return fmt.Sprintf(`COALESCE(%s, CASE
+	WHEN %s AND %s THEN CASE
+		WHEN %s <> '00' OR UPPER(SUBSTR(%s, 2, 1)) BETWEEN 'A' AND 'Z' THEN CAST(%s AS INTEGER)
+		ELSE %d END
+	WHEN %s THEN CAST(SUBSTR(%s, %s, 1) AS INTEGER)
+	ELSE %d END)`,
+		catalogUnits,
+		digit(n+" - 1"), digit(n),
+		last2, courseNumber, last2,
+		DefaultCourseUnits,
+		digit(n), courseNumber, n,
+		DefaultCourseUnits)
+}
+
+// courseUnits returns what a course is worth: the catalogue's units, or
+// the guess from its number if the catalogue doesn't know the course or
+// its units.
+func courseUnits(q rowQuerier, subject, courseNumber string) (int, error) {
+	var units sql.NullInt64
+	err := q.queryRow(`SELECT units FROM catalog_courses WHERE subject = ? AND course_number = ?`,
+		strings.ToUpper(subject), strings.ToUpper(courseNumber)).Scan(&units)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("look up units: %w", err)
+	}
+	return unitsOr(units, courseNumber), nil
+}
@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnitsFromCourseNumber(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+
+	for _, tt := range []struct {
+		number string
+		want   int
+	}{
+		{"2C03", 3},
+		{"1P13", 13},
+		{"1ZA3", 3},
+		{"4ZP6", 6},
+		{"1A00", 0},
+		{"1d04", 4},
+		{"101", 1},  // from a transcript: still the last two digits
+		{"100", 3},  // not a McMaster number, so 00 isn't a unit count
+		{"1ABC", 3}, // nothing to go on
+		{"7", 7},
+		{"", 3},
+	} {
+		if got := UnitsFromCourseNumber(tt.number, DefaultCourseUnits); got != tt.want {
+			t.Errorf("UnitsFromCourseNumber(%q) = %d, want %d", tt.number, got, tt.want)
+		}
+		// The SQL version has to agree, or search filters on different units.
+		var got int
+		if err := repo.DB.QueryRow(`SELECT `+unitsSQL("NULL", "t.n")+` FROM (SELECT ? AS n) t`, tt.number).Scan(&got); err != nil {
+			t.Fatalf("unitsSQL(%q): %v", tt.number, err)
+		}
+		if got != tt.want {
+			t.Errorf("unitsSQL(%q) = %d, want %d", tt.number, got, tt.want)
+		}
+	}
+}
+
+func TestCuratedCourseUnits(t *testing.T) {
+	repo := newTestRepo(t)
+	defer repo.Close()
+	mux := NewMux(repo, &Service{Repo: repo})
+	userID := seedExportPlan(t, repo) // A+ in COMPSCI 1MD3, B in MATH 1ZA3
+	_, adminToken := seedUser(t, repo, "admin@example.com", RoleAdmin)
+	_, studentToken := seedUser(t, repo, "student@example.com", RoleStudent)
+
+	put := func(token, course, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/admin/courses/"+course+"/units", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := put(studentToken, "MATH/1ZA3", `{"units":4}`); rr.Code != 403 {
+		t.Errorf("student: expected 403, got %d", rr.Code)
+	}
+	if rr := put(adminToken, "MATH/9Z99", `{"units":4}`); rr.Code != 404 {
+		t.Errorf("unknown course: expected 404, got %d", rr.Code)
+	}
+	if rr := put(adminToken, "MATH/1ZA3", `{"units":-1}`); rr.Code != 400 {
+		t.Errorf("negative units: expected 400, got %d", rr.Code)
+	}
+	rr := put(adminToken, "math/1za3", `{"units":4}`)
+	var course CatalogCourse
+	json.NewDecoder(rr.Body).Decode(&course)
+	if rr.Code != 200 || course.Units != 4 || course.UnitsSource == nil || *course.UnitsSource != UnitsCurated {
+		t.Fatalf("curate: %d %+v", rr.Code, course)
+	}
+
+	// The plan, the GPA and validation all weigh MATH 1ZA3 at 4 units.
+	items, err := repo.GetPlanItems(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range items {
+		if want := map[string]int{"1MD3": 3, "1ZA3": 4, "1XC3": 3, "2XX3": 3}[it.CourseNumber]; it.Units != want {
+			t.Errorf("%s %s: %d units, want %d", it.Subject, it.CourseNumber, it.Units, want)
+		}
+	}
+	if gpa, ok, err := repo.GetUserGPA(userID); err != nil || !ok || !approx(gpa, (12*3+8*4)/7.0) {
+		t.Errorf("GPA: %v %v %v", gpa, ok, err)
+	}
+	program, err := repo.GetProgramWithGroups(seedProgram(t, repo, 7, "COMPSCI 1MD3", "MATH 1ZA3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := (&Service{Repo: repo}).ValidateUserPlan(userID, program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.TotalUnitsCompleted != 7 || !res.Groups[0].Satisfied {
+		t.Errorf("validation: %d units, groups %+v", res.TotalUnitsCompleted, res.Groups)
+	}
+
+	// Search filters on the curated units, and on guessed ones for the rest.
+	for _, code := range []string{"MATH 1ZA3", "ENGINEER 1A00"} {
+		f := strings.Fields(code)
+		if _, err := repo.DB.Exec(`INSERT INTO courses(subject, course_number, course_name, term) VALUES (?, ?, 'Course', 'Fall 2025')`, f[0], f[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for units, want := range map[int]string{4: "MATH 1ZA3", 3: "", 0: "ENGINEER 1A00"} {
+		found, err := repo.FindCourses(CourseQuery{Units: []int{units}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(courseCodes(found.Courses), ","); got != want {
+			t.Errorf("%d units: got %q, want %q", units, got, want)
+		}
+	}
+
+	// Dropping the curated value goes back to the guess.
+	rr = put(adminToken, "MATH/1ZA3", `{"units":null}`)
+	course = CatalogCourse{}
+	json.NewDecoder(rr.Body).Decode(&course)
+	if rr.Code != 200 || course.Units != 3 || course.UnitsSource != nil {
+		t.Errorf("clear: %d %+v", rr.Code, course)
+	}
+}
@@ -16,4 +16,21 @@ sqlite3 $DB_PATH < migrations/009_programs_seed.sql
 sqlite3 $DB_PATH < migrations/010_requirement_groups_seed.sql
 sqlite3 $DB_PATH < migrations/011_requirement_courses_seed.sql
 sqlite3 $DB_PATH < migrations/012_missing_courses.sql
+sqlite3 $DB_PATH < migrations/013_password_reset_tokens.sql
+sqlite3 $DB_PATH < migrations/014_user_roles_audit_log.sql
+sqlite3 $DB_PATH < migrations/015_oidc.sql
+sqlite3 $DB_PATH < migrations/016_outbound_emails.sql
+sqlite3 $DB_PATH < migrations/017_feedback.sql
+sqlite3 $DB_PATH < migrations/018_courses_fts.sql
+sqlite3 $DB_PATH < migrations/019_course_catalog.sql
+sqlite3 $DB_PATH < migrations/020_academic_terms.sql
+sqlite3 $DB_PATH < migrations/021_plan_item_unconfirmed.sql
+sqlite3 $DB_PATH < migrations/022_plan_events.sql
+sqlite3 $DB_PATH < migrations/023_plan_item_custom.sql
+sqlite3 $DB_PATH < migrations/024_transfer_credits.sql
+sqlite3 $DB_PATH < migrations/025_plan_shares.sql
+sqlite3 $DB_PATH < migrations/026_advisors.sql
+sqlite3 $DB_PATH < migrations/027_program_standing_rules.sql
+sqlite3 $DB_PATH < migrations/028_catalog_units.sql
+sqlite3 $DB_PATH < migrations/029_course_sections.sql
 echo "Database ready."
@@ -3,22 +3,37 @@
  */
 
 /**
- * Parse units from a McMaster course number.
- * McMaster encodes unit count in the last 2 digits of the course number.
- * 
+ * Guess units from a McMaster course number, for courses the API hasn't
+ * given units for. Mirrors UnitsFromCourseNumber in pkg/units.go.
+ * McMaster numbers end in the unit count.
+ *
  * Examples:
  *   "2C03" → 3 units
  *   "1P13" → 13 units
  *   "1ZA3" → 3 units
  *   "4ZZ6" → 6 units
+ *   "1A00" → 0 units (a zero-unit lab)
  *
- * Falls back to defaultUnits (default 3) if parsing fails.
+ * Falls back to defaultUnits (default 3) if parsing fails, or for a
+ * non-McMaster number ending in 00 ("HIST 100").
  */
 export function unitsFromCourseNumber(courseNumber: string, defaultUnits = 3): number {
-  if (!courseNumber || courseNumber.length < 2) return defaultUnits;
-  const suffix = courseNumber.slice(-2);
-  const n = parseInt(suffix, 10);
-  return isNaN(n) || n === 0 ? defaultUnits : n;
+  if (!courseNumber) return defaultUnits;
+  const last2 = courseNumber.slice(-2);
+  if (/^\d\d$/.test(last2)) {
+    const n = parseInt(last2, 10);
+    return n === 0 && !/^.[A-Za-z]/.test(courseNumber) ? defaultUnits : n;
+  }
+  const last = courseNumber.slice(-1);
+  return /^\d$/.test(last) ? parseInt(last, 10) : defaultUnits;
+}
+
+/**
+ * Units of a plan item: the server's figure (from the course catalogue)
+ * when present, else the guess from its course number.
+ */
+export function planItemUnits(item: { course_number: string; units?: number }): number {
+  return item.units ?? unitsFromCourseNumber(item.course_number);
 }
 
 /**
@@ -10,7 +10,7 @@ import { Input } from "../components/ui/input";
 import { Label } from "../components/ui/label";
 import { useAuth } from "../contexts/AuthContext";
 import { authFetch, apiUrl } from "../lib/api";
-import { unitsFromCourseNumber } from "../lib/courseUtils";
+import { planItemUnits, unitsFromCourseNumber } from "../lib/courseUtils";
 import { getSubjectColor } from "../data/mockData";
 
 // ---------------------------------------------------------------------------
@@ -32,11 +32,12 @@ interface APIPlanItem {
   subject: string;
   course_number: string;
   course_name: string | null;
-  status: "PLANNED" | "IN_PROGRESS" | "COMPLETED" | "DROPPED";
+  status: "PLANNED" | "IN_PROGRESS" | "COMPLETED" | "DROPPED" | "UNCONFIRMED";
   grade: string | null;
   note: string | null;
   year_index: number;
   season: "Fall" | "Winter" | "Spring" | "Summer";
+  units?: number;
 }
 
 // ---------------------------------------------------------------------------
@@ -69,6 +70,8 @@ function statusStyle(status: APIPlanItem["status"]) {
       return "bg-amber-100 text-amber-700 border-amber-200 dark:bg-amber-950/60 dark:text-amber-300 dark:border-amber-800";
     case "DROPPED":
       return "bg-red-100 text-red-700 border-red-200 dark:bg-red-950/60 dark:text-red-300 dark:border-red-800";
+    case "UNCONFIRMED":
+      return "bg-violet-100 text-violet-700 border-violet-200 dark:bg-violet-950/60 dark:text-violet-300 dark:border-violet-800";
     default: // PLANNED
       return "bg-blue-100 text-blue-700 border-blue-200 dark:bg-blue-950/60 dark:text-blue-300 dark:border-blue-800";
   }
@@ -221,14 +224,14 @@ export function DegreePlanner() {
     }
   };
 
-  // Derived unit totals using real unit values from course number suffixes
+  // Derived unit totals using each item's units from the catalogue
   const completedItems = planItems.filter(pi => pi.status === "COMPLETED");
   const unitsCompleted = completedItems.reduce(
-    (sum, pi) => sum + unitsFromCourseNumber(pi.course_number), 0
+    (sum, pi) => sum + planItemUnits(pi), 0
   );
   const unitsPlanned = planItems
     .filter(pi => pi.status === "PLANNED")
-    .reduce((sum, pi) => sum + unitsFromCourseNumber(pi.course_number), 0);
+    .reduce((sum, pi) => sum + planItemUnits(pi), 0);
   const unitsRemaining = UNITS_TO_GRADUATE - unitsCompleted;
 
   const getCoursesByYearAndTerm = (year: number, term: string) => {
@@ -510,7 +513,7 @@ export function DegreePlanner() {
                 {TERMS.map(term => {
                   const termCourses = getCoursesByYearAndTerm(year, term);
                   const termUnits = termCourses.reduce(
-                    (sum, pi) => sum + unitsFromCourseNumber(pi.course_number), 0
+                    (sum, pi) => sum + planItemUnits(pi), 0
                   );
 
                   return (
@@ -575,6 +578,7 @@ export function DegreePlanner() {
                                       <SelectItem value="IN_PROGRESS">In Progress</SelectItem>
                                       <SelectItem value="COMPLETED">Completed</SelectItem>
                                       <SelectItem value="DROPPED">Dropped</SelectItem>
+                                      <SelectItem value="UNCONFIRMED" disabled>Unconfirmed</SelectItem>
                                     </SelectContent>
                                   </Select>
 
@@ -610,7 +614,7 @@ export function DegreePlanner() {
                               </div>
 
                               <div className="text-xs text-muted-foreground">
-                                {unitsFromCourseNumber(item.course_number)} units
+                                {planItemUnits(item)} units
                                 {item.grade && (
                                   <> · Grade: <span className="font-medium text-foreground">{item.grade}</span></>
                                 )}
@@ -18,7 +18,7 @@ import { Input } from "../components/ui/input";
 import { Label } from "../components/ui/label";
 import { useAuth } from "../contexts/AuthContext";
 import { authFetch } from "../lib/api";
-import { unitsFromCourseNumber } from "../lib/courseUtils";
+import { planItemUnits } from "../lib/courseUtils";
 import { AddToPlannerDialog } from "../components/AddToPlannerDialog";
 
 // ---------------------------------------------------------------------------
@@ -31,11 +31,12 @@ interface APIPlanItem {
   subject: string;
   course_number: string;
   course_name: string | null;
-  status: "PLANNED" | "IN_PROGRESS" | "COMPLETED" | "DROPPED";
+  status: "PLANNED" | "IN_PROGRESS" | "COMPLETED" | "DROPPED" | "UNCONFIRMED";
   grade: string | null;
   note: string | null;
   year_index: number;
   season: string;
+  units?: number;
 }
 
 interface APIProgram {
@@ -155,8 +156,8 @@ function isGatewayProgram(program?: string | null): boolean {
 
 // ---------------------------------------------------------------------------
 // AdvanceYearDialog
-// Confirms the user wants to move to the next academic year and explains the
-// auto-complete side-effect before anything is committed.
+// Confirms the user wants to move to the next academic year and explains that
+// unfinished courses become Unconfirmed before anything is committed.
 // ---------------------------------------------------------------------------
 
 function AdvanceYearDialog({
@@ -247,7 +248,7 @@ function AdvanceYearDialog({
                 You're moving from <strong>Year {currentYear}</strong> to{" "}
                 <strong>Year {nextYear}</strong>.{" "}
                 {pendingCount > 0
-                  ? `${pendingCount} course${pendingCount === 1 ? "" : "s"} still marked as Planned or In Progress in Year ${currentYear} (and earlier) will be automatically set to Completed.`
+                  ? `${pendingCount} course${pendingCount === 1 ? "" : "s"} still marked as Planned or In Progress in Year ${currentYear} (and earlier) will be set to Unconfirmed for you to mark as completed or dropped.`
                   : "There are no unfinished courses from previous years to carry over."}
                 {specLabel && (
                   <>
@@ -842,7 +843,7 @@ export function UserDashboard() {
   const currentYear = user?.yearOfStudy ?? 1;
   const MAX_YEAR = 4;
 
-  // How many planned/in-progress items from previous years would be auto-completed
+  // How many planned/in-progress items from previous years would need confirming
   const pendingCompletionCount = planItems.filter(
     pi => pi.year_index < currentYear + 1 &&
           (pi.status === "PLANNED" || pi.status === "IN_PROGRESS")
@@ -941,8 +942,9 @@ export function UserDashboard() {
   };
 
   const completedItems = planItems.filter(pi => pi.status === "COMPLETED");
+  // UNCONFIRMED items (from a term that has ended) stay here until resolved
   const plannedItems = planItems.filter(
-    pi => pi.status === "PLANNED" || pi.status === "IN_PROGRESS"
+    pi => pi.status === "PLANNED" || pi.status === "IN_PROGRESS" || pi.status === "UNCONFIRMED"
   );
 
   // Group planned items by year_index for the year-bucketed view
@@ -956,10 +958,10 @@ export function UserDashboard() {
     .sort((a, b) => a.yr - b.yr);
 
   const unitsCompleted = completedItems.reduce(
-    (sum, pi) => sum + unitsFromCourseNumber(pi.course_number), 0
+    (sum, pi) => sum + planItemUnits(pi), 0
   );
   const unitsPlanned = plannedItems.reduce(
-    (sum, pi) => sum + unitsFromCourseNumber(pi.course_number), 0
+    (sum, pi) => sum + planItemUnits(pi), 0
   );
   const unitsRemaining = UNITS_TO_GRADUATE - unitsCompleted;
   const progressPercent = Math.min((unitsCompleted / UNITS_TO_GRADUATE) * 100, 100);
@@ -1224,7 +1226,7 @@ export function UserDashboard() {
                 <Link key={item.plan_item_id} to={`/courses/${item.subject}/${item.course_number}`}>
                   <div className="flex items-center gap-3 flex-wrap p-4 border rounded-lg hover:bg-muted/50 transition-colors">
                     <h3 className="font-semibold">{item.subject} {item.course_number}</h3>
-                    <Badge variant="secondary">{unitsFromCourseNumber(item.course_number)} units</Badge>
+                    <Badge variant="secondary">{planItemUnits(item)} units</Badge>
                     <Badge variant="outline" className="bg-green-500/10 text-green-600 border-green-500/20">
                       Completed
                     </Badge>
@@ -1313,7 +1315,7 @@ export function UserDashboard() {
                             className="flex items-center gap-3 flex-wrap flex-1"
                           >
                             <h3 className="font-semibold">{item.subject} {item.course_number}</h3>
-                            <Badge variant="secondary">{unitsFromCourseNumber(item.course_number)} units</Badge>
+                            <Badge variant="secondary">{planItemUnits(item)} units</Badge>
                             <Badge variant="outline">
                               {item.season} · Year {item.year_index}
                             </Badge>
@@ -1332,6 +1334,7 @@ export function UserDashboard() {
                               <SelectItem value="IN_PROGRESS">In Progress</SelectItem>
                               <SelectItem value="COMPLETED">Completed</SelectItem>
                               <SelectItem value="DROPPED">Dropped</SelectItem>
+                              <SelectItem value="UNCONFIRMED" disabled>Unconfirmed</SelectItem>
                             </SelectContent>
                           </Select>
                         </div>